@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gmhorn/gremlin/archive/pkg/imageio"
+)
+
+// diffMain implements `gremlin diff [-heatmap out.png] a.exr b.exr`: it prints
+// difference statistics between two HDR images (.exr or .hdr) and optionally
+// writes a heatmap of the per-pixel color difference.
+func diffMain(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	heatmap := flags.String("heatmap", "", "write a ΔE heatmap PNG to this file")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gremlin diff [-heatmap out.png] a.exr b.exr")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	a, err := readImage(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := readImage(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	d, err := imageio.Compare(a, b)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("RMSE:    %g\n", d.RMSE)
+	fmt.Printf("mean ΔE: %g\n", d.MeanDeltaE)
+	fmt.Printf("max ΔE:  %g\n", d.MaxDeltaE)
+
+	if *heatmap == "" {
+		return nil
+	}
+	file, err := os.Create(*heatmap)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return png.Encode(file, d.Heatmap())
+}
+
+// readImage reads an OpenEXR or Radiance HDR image, by file extension.
+func readImage(name string) (*imageio.RGB, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var img *imageio.RGB
+	switch ext := strings.ToLower(filepath.Ext(name)); ext {
+	case ".exr":
+		img, err = imageio.ReadEXR(file)
+	case ".hdr", ".pic":
+		img, err = imageio.ReadHDR(file)
+	default:
+		return nil, fmt.Errorf("%s: unsupported image type %q", name, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return img, nil
+}
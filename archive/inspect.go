@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/gmhorn/gremlin/archive/pkg/asset"
+	"github.com/gmhorn/gremlin/archive/pkg/scene"
+)
+
+// inspectMain implements `gremlin inspect scene.json`: it loads a scene
+// without rendering it and prints what's in it and anything that looks wrong
+// (see scene.Report). It fails if anything does, so it can check scenes in
+// scripts.
+func inspectMain(args []string) error {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gremlin inspect scene.json")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	r, err := scene.Inspect(asset.FromEnv(), flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Println(r)
+	if n := len(r.Missing) + len(r.Problems); n > 0 {
+		return fmt.Errorf("%s: %d missing files or problems", flags.Arg(0), n)
+	}
+	return nil
+}
@@ -1,18 +1,75 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
 	"image/png"
 	"os"
+	"os/signal"
 	"runtime/pprof"
 
+	"github.com/gmhorn/gremlin/archive/pkg/asset"
 	"github.com/gmhorn/gremlin/archive/pkg/camera"
 	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
 	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/imageio"
+	"github.com/gmhorn/gremlin/archive/pkg/metrics"
 	"github.com/gmhorn/gremlin/archive/pkg/render"
+	"github.com/gmhorn/gremlin/archive/pkg/scene"
 	"github.com/gmhorn/gremlin/archive/pkg/shape"
 )
 
+var subcommands = map[string]func(args []string) error{
+	"diff":    diffMain,
+	"inspect": inspectMain,
+	"merge":   mergeMain,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	seed := flag.Uint64("seed", 0, "random seed, overriding the scene's; renders with different seeds can be merged")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: gremlin [-seed N] [scene.json]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var s *scene.Scene
+	switch flag.NArg() {
+	case 0:
+		s = defaultScene()
+	case 1:
+		var err error
+		if s, err = scene.Load(asset.FromEnv(), flag.Arg(0)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			s.Seed = *seed
+		}
+	})
+	film := s.Film
+	if s.Cull != nil {
+		fmt.Println(s.Cull)
+	}
+
 	profFile, err := os.Create("main.prof")
 	if err != nil {
 		panic(err)
@@ -20,24 +77,15 @@ func main() {
 	pprof.StartCPUProfile(profFile)
 	defer pprof.StopCPUProfile()
 
-	film := camera.NewFilm(1920, 1080)
-	cam := camera.NewPerspective(film.AspectRatio, 75.0)
-	cam.MoveTo(geo.V(-3, 3, 1)).PointAt(geo.V(0, 0, -1))
-
-	scene := []shape.Shape{
-		&shape.Sphere{
-			Center: geo.V(-0.5, 0, -1),
-			Radius: 0.5,
-		},
-		&shape.Sphere{
-			Center: geo.V(0, -100.5, -1),
-			Radius: 100,
-		},
-	}
+	// Ctrl-C stops rendering early, but still saves the partial image
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-	if err := render.Fixed(film, cam, scene); err != nil {
+	err = s.Render(ctx)
+	if err != nil && !errors.Is(err, context.Canceled) {
 		panic(err)
 	}
+	fmt.Println(metrics.Snapshot())
 
 	file, err := os.Create("main.png")
 	if err != nil {
@@ -45,8 +93,69 @@ func main() {
 	}
 	defer file.Close()
 
-	err = png.Encode(file, film.Image(colorspace.SRGB))
+	err = png.Encode(file, film.Image(s.Colorspace))
 	if err != nil {
 		panic(err)
 	}
+
+	if s.FalseColor != nil {
+		if err := writePNG("main.falsecolor.png", film.FalseColor(s.FalseColor)); err != nil {
+			panic(err)
+		}
+	}
+	if err := writeEXR("main.exr", film.RGB(s.Colorspace)); err != nil {
+		panic(err)
+	}
+	if err := writeFilm("main.film", film); err != nil {
+		panic(err)
+	}
+	for _, aov := range film.AOVs() {
+		if err := writeEXR("main."+aov.String()+".exr", film.AOV(aov, s.Colorspace)); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// defaultScene is rendered when no scene file is given.
+func defaultScene() *scene.Scene {
+	film := camera.NewFilm(1920, 1080)
+	film.EnableAOVs(camera.AOVNormal, camera.AOVDepth, camera.AOVAlbedo, camera.AOVVariance)
+	cam := camera.NewPerspective(film.AspectRatio, 75.0)
+	cam.MoveTo(geo.V(-3, 3, 1)).PointAt(geo.V(0, 0, -1))
+
+	return &scene.Scene{
+		Film:   film,
+		Camera: cam,
+		Shapes: []shape.Shape{
+			&shape.Sphere{
+				Center: geo.V(-0.5, 0, -1),
+				Radius: 0.5,
+			},
+			&shape.Sphere{
+				Center: geo.V(0, -100.5, -1),
+				Radius: 100,
+			},
+		},
+		Integrator: render.NewPathTracer(16),
+		Colorspace: colorspace.SRGB,
+		Samples:    32,
+	}
+}
+
+func writePNG(name string, img image.Image) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return png.Encode(file, img)
+}
+
+func writeEXR(name string, img *imageio.RGB) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return imageio.WriteEXR(file, img)
 }
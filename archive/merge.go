@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/gmhorn/gremlin/archive/pkg/camera"
+	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+)
+
+// mergeMain implements `gremlin merge [-exr out.exr] out.film in.film...`: it
+// sums independent renders of the same scene (e.g. rendered with different
+// -seed values on different machines) into one film. The output film can be
+// merged again later, so merging can be resumed as more renders come in.
+func mergeMain(args []string) error {
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+	exr := flags.String("exr", "", "also write the merged image to this OpenEXR file")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gremlin merge [-exr out.exr] out.film in.film...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() < 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var merged *camera.Film
+	for _, name := range flags.Args()[1:] {
+		film, err := readFilm(name)
+		if err != nil {
+			return err
+		}
+		if merged == nil {
+			merged = film
+		} else if err := merged.Add(film); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	if err := writeFilm(flags.Arg(0), merged); err != nil {
+		return err
+	}
+	if *exr != "" {
+		return writeEXR(*exr, merged.RGB(colorspace.SRGB))
+	}
+	return nil
+}
+
+func readFilm(name string) (*camera.Film, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	film, err := camera.ReadFilm(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return film, nil
+}
+
+func writeFilm(name string, film *camera.Film) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := film.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
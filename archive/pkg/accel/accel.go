@@ -0,0 +1,3 @@
+// Package accel provides acceleration structures for spatial queries over
+// scene data.
+package accel
@@ -0,0 +1,267 @@
+package accel
+
+import (
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/metrics"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+)
+
+// SAH build parameters. Costs are relative to the cost of a single primitive
+// intersection test.
+const (
+	sahBuckets       = 12
+	sahTraversalCost = 0.125
+	maxLeafShapes    = 4
+)
+
+// BVH is a bounding volume hierarchy over a list of shapes, built using the
+// surface area heuristic (SAH).
+//
+// Nodes are stored flattened in depth-first order, so the first child of an
+// interior node always immediately follows it, and only the index of the
+// second child needs storing. Shapes are reordered so that each leaf refers to
+// a contiguous range of them.
+//
+// https://www.pbr-book.org/3ed-2018/Primitives_and_Intersection_Acceleration/Bounding_Volume_Hierarchies
+// https://jacco.ompf2.com/2022/04/18/how-to-build-a-bvh-part-2-faster-rays/
+type BVH struct {
+	shapes []shape.Shape
+	nodes  []bvhNode
+}
+
+// bvhNode is a flattened BVH node. For leaves, offset is the index of the
+// first shape and count is the number of shapes. For interior nodes, count is
+// 0, offset is the index of the second child, and axis is the split axis.
+type bvhNode struct {
+	bounds geo.Bounds
+	offset int
+	count  int
+	axis   int
+}
+
+// buildShape caches per-shape data used during construction.
+type buildShape struct {
+	shape    shape.Shape
+	bounds   *geo.Bounds
+	centroid geo.Vec
+}
+
+// NewBVH builds a BVH over the given shapes, refining any that can't be
+// intersected first (see shape.Refine). The shapes slice is not modified.
+func NewBVH(shapes []shape.Shape) *BVH {
+	shapes = shape.Refine(shapes)
+	bvh := &BVH{shapes: make([]shape.Shape, 0, len(shapes))}
+	if len(shapes) == 0 {
+		return bvh
+	}
+
+	build := make([]buildShape, len(shapes))
+	for i, s := range shapes {
+		b := s.Bounds()
+		build[i] = buildShape{shape: s, bounds: b, centroid: b.Centroid()}
+	}
+
+	bvh.nodes = make([]bvhNode, 0, 2*len(shapes))
+	bvh.build(build)
+
+	metrics.AccelNodes.Add(int64(len(bvh.nodes)))
+	metrics.AccelPrimitives.Add(int64(len(bvh.shapes)))
+	return bvh
+}
+
+// build recursively constructs the subtree for the given shapes, returning the
+// index of its root node.
+func (bvh *BVH) build(shapes []buildShape) int {
+	idx := len(bvh.nodes)
+	bvh.nodes = append(bvh.nodes, bvhNode{})
+
+	bounds := shapes[0].bounds
+	centroids := geo.NewBounds(shapes[0].centroid, shapes[0].centroid)
+	for _, s := range shapes[1:] {
+		bounds = bounds.Union(s.bounds)
+		centroids = centroids.Extend(s.centroid)
+	}
+	bvh.nodes[idx].bounds = *bounds
+
+	mid, axis, ok := splitSAH(shapes, bounds, centroids)
+	if !ok {
+		bvh.nodes[idx].offset = len(bvh.shapes)
+		bvh.nodes[idx].count = len(shapes)
+		for _, s := range shapes {
+			bvh.shapes = append(bvh.shapes, s.shape)
+		}
+		return idx
+	}
+
+	bvh.build(shapes[:mid])
+	second := bvh.build(shapes[mid:])
+	bvh.nodes[idx].offset = second
+	bvh.nodes[idx].axis = axis
+	return idx
+}
+
+// splitSAH partitions the shapes in place along the best split found by
+// binning centroids into buckets and evaluating the SAH cost at each bucket
+// boundary. It returns the partition point and split axis, or false if the
+// shapes should stay together as a leaf.
+func splitSAH(shapes []buildShape, bounds, centroids *geo.Bounds) (int, int, bool) {
+	n := len(shapes)
+	if n == 1 {
+		return 0, 0, false
+	}
+
+	axis := centroids.MaxExtent()
+	lo, hi := centroids[0].Axis(axis), centroids[1].Axis(axis)
+	if hi == lo {
+		// all centroids coincide; no split will separate them
+		return 0, 0, false
+	}
+
+	bucketOf := func(s *buildShape) int {
+		b := int(sahBuckets * (s.centroid.Axis(axis) - lo) / (hi - lo))
+		if b == sahBuckets {
+			b--
+		}
+		return b
+	}
+
+	type bucket struct {
+		count  int
+		bounds *geo.Bounds
+	}
+	var buckets [sahBuckets]bucket
+	for i := range shapes {
+		b := &buckets[bucketOf(&shapes[i])]
+		b.count++
+		if b.bounds == nil {
+			b.bounds = shapes[i].bounds
+		} else {
+			b.bounds = b.bounds.Union(shapes[i].bounds)
+		}
+	}
+
+	// Sweep from each side to get bounds and counts below/above each boundary
+	var costs [sahBuckets - 1]float64
+	var below *geo.Bounds
+	countBelow := 0
+	for i := 0; i < sahBuckets-1; i++ {
+		below, countBelow = unionBucket(below, buckets[i].bounds), countBelow+buckets[i].count
+		costs[i] = float64(countBelow) * area(below)
+	}
+	var above *geo.Bounds
+	countAbove := 0
+	for i := sahBuckets - 1; i > 0; i-- {
+		above, countAbove = unionBucket(above, buckets[i].bounds), countAbove+buckets[i].count
+		costs[i-1] += float64(countAbove) * area(above)
+	}
+
+	best, bestCost := 0, math.Inf(1)
+	for i, c := range costs {
+		if c < bestCost {
+			best, bestCost = i, c
+		}
+	}
+	bestCost = sahTraversalCost + bestCost/bounds.SurfaceArea()
+
+	if n <= maxLeafShapes && bestCost >= float64(n) {
+		return 0, 0, false
+	}
+
+	// Partition in place: shapes in buckets <= best go first
+	mid := 0
+	for i := range shapes {
+		if bucketOf(&shapes[i]) <= best {
+			shapes[i], shapes[mid] = shapes[mid], shapes[i]
+			mid++
+		}
+	}
+
+	if mid == 0 || mid == n {
+		// Can't happen with non-degenerate centroid bounds, but be safe
+		mid = n / 2
+	}
+	return mid, axis, true
+}
+
+func unionBucket(a, b *geo.Bounds) *geo.Bounds {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	default:
+		return a.Union(b)
+	}
+}
+
+func area(b *geo.Bounds) float64 {
+	if b == nil {
+		return 0
+	}
+	return b.SurfaceArea()
+}
+
+// Intersect returns the closest intersection of the ray with the shapes in
+// the BVH. Like shape.Shape, only hits at positive t count. If nothing is hit,
+// it returns false. Hits on a shape.Aggregate are on the shape inside it that
+// was hit.
+func (bvh *BVH) Intersect(ray *geo.Ray) (shape.Intersection, bool) {
+	hit := shape.Intersection{T: math.Inf(1)}
+	if len(bvh.nodes) == 0 {
+		return hit, false
+	}
+
+	dirNeg := [3]bool{ray.Dir.X < 0, ray.Dir.Y < 0, ray.Dir.Z < 0}
+
+	// SAH doesn't bound the tree's depth (geometrically spaced shapes make
+	// very deep trees), so the stack can grow. It starts out in an array big
+	// enough for balanced trees of any realistic size, which stays off the heap.
+	var buf [64]int
+	stack := buf[:0]
+	idx := 0
+	for {
+		node := &bvh.nodes[idx]
+		if t0, _, found := node.bounds.Intersect(ray); found && t0 < hit.T {
+			if node.count > 0 {
+				for _, s := range bvh.shapes[node.offset : node.offset+node.count] {
+					if agg, ok := s.(shape.Aggregate); ok {
+						if h, found := agg.IntersectShape(ray); found && h.T < hit.T {
+							hit = h
+						}
+					} else if t := s.Intersect(ray); t > 0 && t < hit.T {
+						hit.T = t
+						hit.Shape = s
+					}
+				}
+			} else if dirNeg[node.axis] {
+				// visit the second child first, since it's nearer
+				stack = append(stack, idx+1)
+				idx = node.offset
+				continue
+			} else {
+				stack = append(stack, node.offset)
+				idx++
+				continue
+			}
+		}
+
+		if len(stack) == 0 {
+			break
+		}
+		idx = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+	}
+
+	return hit, hit.Shape != nil
+}
+
+// Bounds returns the bounds of everything in the BVH, or nil if it's empty.
+func (bvh *BVH) Bounds() *geo.Bounds {
+	if len(bvh.nodes) == 0 {
+		return nil
+	}
+	b := bvh.nodes[0].bounds
+	return &b
+}
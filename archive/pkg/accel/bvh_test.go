@@ -0,0 +1,130 @@
+package accel
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+	"github.com/stretchr/testify/assert"
+)
+
+var benchResultHit shape.Intersection
+
+func randomScene(rnd *rand.Rand, n int) []shape.Shape {
+	shapes := make([]shape.Shape, 0, n)
+	for i := 0; i < n; i++ {
+		center := geo.V(rnd.Float64()*20-10, rnd.Float64()*20-10, rnd.Float64()*20-10)
+		if i%2 == 0 {
+			shapes = append(shapes, &shape.Sphere{Center: center, Radius: rnd.Float64() * 0.5})
+		} else {
+			shapes = append(shapes, shape.NewTriangle(
+				center,
+				center.Plus(geo.V(rnd.Float64(), rnd.Float64(), 0)),
+				center.Plus(geo.V(0, rnd.Float64(), rnd.Float64())),
+			))
+		}
+	}
+	return shapes
+}
+
+func bruteForce(shapes []shape.Shape, ray *geo.Ray) (shape.Intersection, bool) {
+	hit := shape.Intersection{T: math.Inf(1)}
+	for _, s := range shapes {
+		if t := s.Intersect(ray); t > 0 && t < hit.T {
+			hit.T = t
+			hit.Shape = s
+		}
+	}
+	return hit, hit.Shape != nil
+}
+
+func TestBVH_Intersect(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	shapes := randomScene(rnd, 500)
+	bvh := NewBVH(shapes)
+
+	hits := 0
+	for i := 0; i < 2000; i++ {
+		origin := geo.V(rnd.Float64()*30-15, rnd.Float64()*30-15, rnd.Float64()*30-15)
+		ray := geo.NewRay(origin, geo.V(rnd.NormFloat64(), rnd.NormFloat64(), rnd.NormFloat64()))
+
+		expected, expectedFound := bruteForce(shapes, ray)
+		actual, found := bvh.Intersect(ray)
+
+		assert.Equal(t, expectedFound, found)
+		if expectedFound {
+			hits++
+			assert.Same(t, expected.Shape, actual.Shape)
+			assert.Equal(t, expected.T, actual.T)
+		}
+	}
+	assert.Greater(t, hits, 0)
+}
+
+// pair is a shape that can only be intersected by refining it to its parts.
+type pair [2]shape.Shape
+
+func (p *pair) Intersect(*geo.Ray) float64            { panic("pair must be refined") }
+func (p *pair) Interaction(geo.Vec) shape.Interaction { panic("pair must be refined") }
+func (p *pair) Bounds() *geo.Bounds                   { return p[0].Bounds().Union(p[1].Bounds()) }
+func (p *pair) Surface() material.Material            { return nil }
+func (p *pair) CanIntersect() bool                    { return false }
+func (p *pair) Refine() []shape.Shape                 { return p[:] }
+
+func TestBVH_Refine(t *testing.T) {
+	a := &shape.Sphere{Center: geo.V(0, 0, -5), Radius: 1}
+	b := &shape.Sphere{Center: geo.V(3, 0, -5), Radius: 1}
+	bvh := NewBVH([]shape.Shape{&pair{a, b}})
+
+	hit, found := bvh.Intersect(geo.NewRay(geo.V(3, 0, 0), geo.V(0, 0, -1)))
+	assert.True(t, found)
+	assert.Same(t, b, hit.Shape)
+}
+
+func TestBVH_Skewed(t *testing.T) {
+	// Geometrically spaced spheres: SAH splits peel off only the few biggest,
+	// so the tree is far deeper than a balanced one (90 levels here).
+	var shapes []shape.Shape
+	for i := 0; i < 300; i++ {
+		x := math.Pow(2, float64(i))
+		shapes = append(shapes, &shape.Sphere{Center: geo.V(x, 0, 0), Radius: x / 4})
+	}
+	bvh := NewBVH(shapes)
+
+	// coming from far out, the nearest sphere is the biggest
+	ray := geo.NewRay(geo.V(math.Pow(2, 301), 0, 0), geo.V(-1, 0, 0))
+	hit, found := bvh.Intersect(ray)
+	assert.True(t, found)
+	assert.Same(t, shapes[299], hit.Shape)
+
+	// going the other way, it descends the whole depth, leaving a far child on
+	// the stack at every level
+	ray = geo.NewRay(geo.V(-1, 0, 0), geo.V(1, 0, 0))
+	hit, found = bvh.Intersect(ray)
+	assert.True(t, found)
+	assert.Same(t, shapes[0], hit.Shape)
+}
+
+func TestBVH_Empty(t *testing.T) {
+	bvh := NewBVH(nil)
+	_, found := bvh.Intersect(geo.NewRay(geo.Origin, geo.V(0, 0, -1)))
+	assert.False(t, found)
+	assert.Nil(t, bvh.Bounds())
+}
+
+func BenchmarkBVH_Intersect(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	bvh := NewBVH(randomScene(rnd, 10000))
+	rays := make([]*geo.Ray, 1024)
+	for i := range rays {
+		rays[i] = geo.NewRay(geo.V(0, 0, 15), geo.V(rnd.Float64()-0.5, rnd.Float64()-0.5, -1))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchResultHit, _ = bvh.Intersect(rays[i%len(rays)])
+	}
+}
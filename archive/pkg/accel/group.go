@@ -0,0 +1,67 @@
+package accel
+
+import (
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+)
+
+// Group is a BVH over some shapes that's a shape itself, a shape.Aggregate,
+// so they can be placed together, e.g. a mesh's faces by one instance.
+//
+// A Group has no surface of its own: its hits are on the shapes in it, so
+// asking it for an interaction panics.
+type Group struct {
+	bvh    *BVH
+	bounds *geo.Bounds
+}
+
+// NewGroup builds a BVH over the shapes, refining them like NewBVH. It panics
+// if there are none.
+func NewGroup(shapes []shape.Shape) *Group {
+	bvh := NewBVH(shapes)
+	bounds := bvh.Bounds()
+	if bounds == nil {
+		panic("empty group")
+	}
+	return &Group{bvh: bvh, bounds: bounds}
+}
+
+// NewMotionInstance places the shapes together with an animated transform,
+// with one instance of a Group of them, so the transform is worked out once
+// per ray rather than for each shape (see shape.MotionInstance).
+func NewMotionInstance(shapes []shape.Shape, motion *geo.AnimatedTransform) *shape.MotionInstance {
+	return &shape.MotionInstance{Shape: NewGroup(shapes), Motion: motion}
+}
+
+func (g *Group) Intersect(ray *geo.Ray) float64 {
+	hit, found := g.bvh.Intersect(ray)
+	if !found {
+		return -1
+	}
+	return hit.T
+}
+
+// IntersectShape implements shape.Aggregate.
+func (g *Group) IntersectShape(ray *geo.Ray) (shape.Intersection, bool) {
+	return g.bvh.Intersect(ray)
+}
+
+// Shapes implements shape.Aggregate.
+func (g *Group) Shapes() []shape.Shape {
+	return g.bvh.shapes
+}
+
+// Interaction implements shape.Shape, by panicking: see Group.
+func (g *Group) Interaction(point geo.Vec) shape.Interaction {
+	panic("interaction with a group rather than the shape in it that was hit")
+}
+
+func (g *Group) Bounds() *geo.Bounds {
+	return g.bounds
+}
+
+// Surface returns nil: the shapes in the group have their own.
+func (g *Group) Surface() material.Material {
+	return nil
+}
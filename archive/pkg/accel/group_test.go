@@ -0,0 +1,74 @@
+package accel
+
+import (
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+	"github.com/stretchr/testify/assert"
+)
+
+// rayRecorder is a sphere that records the rays it's tested against.
+type rayRecorder struct {
+	shape.Sphere
+	rays *[]*geo.Ray
+}
+
+func (r *rayRecorder) Intersect(ray *geo.Ray) float64 {
+	*r.rays = append(*r.rays, ray)
+	return r.Sphere.Intersect(ray)
+}
+
+func TestGroup(t *testing.T) {
+	a := &shape.Sphere{Center: geo.V(0, 0, -5), Radius: 1}
+	b := &shape.Sphere{Center: geo.V(3, 0, -5), Radius: 1}
+	g := NewGroup([]shape.Shape{a, b})
+	assert.Equal(t, &geo.Bounds{geo.V(-1, -1, -6), geo.V(4, 1, -4)}, g.Bounds())
+
+	ray := geo.NewRay(geo.V(3, 0, 0), geo.V(0, 0, -1))
+	assert.InDelta(t, 4.0, g.Intersect(ray), 1e-9)
+	hit, found := g.IntersectShape(ray)
+	assert.True(t, found)
+	assert.Same(t, b, hit.Shape)
+	assert.Less(t, g.Intersect(geo.NewRay(geo.V(10, 0, 0), geo.V(0, 0, -1))), 0.0)
+
+	assert.Panics(t, func() { NewGroup(nil) })
+}
+
+func TestNewMotionInstance(t *testing.T) {
+	// nested spheres moving from x = 0 to x = 4 over [0, 1], in a scene with
+	// a static one
+	var rays []*geo.Ray
+	var spheres []shape.Shape
+	for _, r := range []float64{1, 0.75, 0.5} {
+		spheres = append(spheres, &rayRecorder{shape.Sphere{Radius: r}, &rays})
+	}
+	motion := geo.NewAnimatedTransform(geo.Identity, 0, geo.Shift(geo.V(4, 0, 0)), 1)
+	static := &shape.Sphere{Center: geo.V(-5, 0, 0), Radius: 1}
+	bvh := NewBVH([]shape.Shape{NewMotionInstance(spheres, motion), static})
+
+	ray := geo.NewRayAt(geo.V(3, 0, 5), geo.V(0, 0, -1), 0.75)
+	hit, found := bvh.Intersect(ray)
+	if assert.True(t, found) {
+		assert.InDelta(t, 4.0, hit.T, 1e-9)
+
+		// the hit is on the sphere that was hit, moved with the group
+		in := hit.Shape.(*shape.MotionInstance)
+		assert.Same(t, spheres[0], in.Shape)
+		assert.Same(t, motion, in.Motion)
+		n := hit.Interaction(ray).Normal
+		assert.InDelta(t, 1.0, n.Z, 1e-9)
+	}
+
+	// the ray was moved into the group's space once, for all of its spheres
+	assert.Len(t, rays, 3)
+	for _, r := range rays {
+		assert.Same(t, rays[0], r)
+	}
+
+	_, found = bvh.Intersect(geo.NewRayAt(geo.V(3, 5, 0), geo.V(0, -1, 0), 0))
+	assert.False(t, found)
+	hit, found = bvh.Intersect(geo.NewRay(geo.V(-5, 5, 0), geo.V(0, -1, 0)))
+	assert.True(t, found)
+	assert.Same(t, static, hit.Shape)
+}
@@ -0,0 +1,102 @@
+package accel
+
+import (
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+)
+
+// HashGrid is a uniform grid of cells over 3D points. Only occupied cells are
+// stored (in a hash map keyed by integer cell coordinates), so the grid is
+// unbounded and memory use is proportional to the number of points, not the
+// volume they span.
+//
+// Queries are fastest when the query radius is close to the cell size. For
+// photon mapping, that means constructing the grid with the gather radius.
+//
+// Points are referred to by the index they were inserted at, so callers can
+// keep whatever per-point payload they need (photon power, cached irradiance,
+// etc) in a parallel slice.
+//
+// https://www.pbr-book.org/3ed-2018/Light_Transport_III_Bidirectional_Methods/Stochastic_Progressive_Photon_Mapping
+type HashGrid struct {
+	cellSize float64
+	points   []geo.Vec
+	cells    map[cell][]int
+}
+
+// cell is the integer coordinate of a grid cell.
+type cell [3]int
+
+// NewHashGrid creates an empty grid with the given cell size. Panics if the
+// cell size isn't positive.
+func NewHashGrid(cellSize float64) *HashGrid {
+	if cellSize <= 0 {
+		panic("HashGrid must have positive cell size")
+	}
+
+	return &HashGrid{
+		cellSize: cellSize,
+		cells:    make(map[cell][]int),
+	}
+}
+
+// Insert adds a point to the grid and returns its index.
+func (g *HashGrid) Insert(p geo.Vec) int {
+	idx := len(g.points)
+	g.points = append(g.points, p)
+
+	c := g.cellOf(p)
+	g.cells[c] = append(g.cells[c], idx)
+	return idx
+}
+
+// Len returns the number of points in the grid.
+func (g *HashGrid) Len() int {
+	return len(g.points)
+}
+
+// Point returns the point at the given index.
+func (g *HashGrid) Point(idx int) geo.Vec {
+	return g.points[idx]
+}
+
+// InRadius calls fn with the index and squared distance of every point within
+// distance r of p. Points are visited in no particular order.
+func (g *HashGrid) InRadius(p geo.Vec, r float64, fn func(idx int, distSq float64)) {
+	lo := g.cellOf(p.Minus(geo.V(r, r, r)))
+	hi := g.cellOf(p.Plus(geo.V(r, r, r)))
+	rSq := r * r
+
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			for z := lo[2]; z <= hi[2]; z++ {
+				for _, idx := range g.cells[cell{x, y, z}] {
+					if distSq := g.points[idx].Minus(p).LenSquared(); distSq <= rSq {
+						fn(idx, distSq)
+					}
+				}
+			}
+		}
+	}
+}
+
+// Nearest returns the index of the point closest to p, considering only points
+// within distance maxDist. If there are no such points it returns false.
+func (g *HashGrid) Nearest(p geo.Vec, maxDist float64) (int, bool) {
+	nearest, nearestDistSq := -1, math.Inf(1)
+	g.InRadius(p, maxDist, func(idx int, distSq float64) {
+		if distSq < nearestDistSq {
+			nearest, nearestDistSq = idx, distSq
+		}
+	})
+	return nearest, nearest >= 0
+}
+
+func (g *HashGrid) cellOf(p geo.Vec) cell {
+	return cell{
+		int(math.Floor(p.X / g.cellSize)),
+		int(math.Floor(p.Y / g.cellSize)),
+		int(math.Floor(p.Z / g.cellSize)),
+	}
+}
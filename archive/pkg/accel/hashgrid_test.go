@@ -0,0 +1,51 @@
+package accel
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashGrid_InRadius(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	grid := NewHashGrid(0.5)
+	for i := 0; i < 1000; i++ {
+		grid.Insert(geo.V(rnd.Float64()*4-2, rnd.Float64()*4-2, rnd.Float64()*4-2))
+	}
+
+	query := geo.V(0.1, -0.3, 0.7)
+	radius := 0.6
+
+	expected := []int{}
+	for i := 0; i < grid.Len(); i++ {
+		if grid.Point(i).Minus(query).Len() <= radius {
+			expected = append(expected, i)
+		}
+	}
+
+	actual := []int{}
+	grid.InRadius(query, radius, func(idx int, _ float64) {
+		actual = append(actual, idx)
+	})
+	sort.Ints(actual)
+
+	assert.NotEmpty(t, expected)
+	assert.Equal(t, expected, actual)
+}
+
+func TestHashGrid_Nearest(t *testing.T) {
+	grid := NewHashGrid(1)
+	grid.Insert(geo.V(0, 0, 0))
+	grid.Insert(geo.V(-1.5, 0, 0))
+	grid.Insert(geo.V(3, 3, 3))
+
+	idx, found := grid.Nearest(geo.V(-1, 0, 0), 2)
+	assert.True(t, found)
+	assert.Equal(t, 1, idx)
+
+	_, found = grid.Nearest(geo.V(10, 10, 10), 2)
+	assert.False(t, found)
+}
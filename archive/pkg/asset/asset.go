@@ -0,0 +1,166 @@
+// Package asset resolves references to external files (meshes, textures, etc)
+// so that loaders don't have to care where those files actually live.
+//
+// A Resolver holds an ordered search path. Relative names are tried against
+// each entry in turn; entries can be directories on disk or any io/fs.FS
+// (e.g. an embed.FS of test data, or an archive). Names may also use a
+// "scheme://" prefix to address a specific mounted filesystem directly, and
+// may reference environment variables ($HOME/textures/wood.png).
+package asset
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// PathEnv is the environment variable consulted by FromEnv. Like PATH, it's a
+// list of directories separated by os.PathListSeparator.
+const PathEnv = "GREMLIN_ASSET_PATH"
+
+// Resolver finds assets by name. The zero value is a Resolver with an empty
+// search path, which can still open absolute paths.
+type Resolver struct {
+	search  []location
+	schemes map[string]fs.FS
+}
+
+// location is a single search path entry.
+type location interface {
+	open(name string) (fs.File, error)
+	String() string
+}
+
+// NewResolver creates a Resolver that searches the given directories, in
+// order.
+func NewResolver(dirs ...string) *Resolver {
+	r := &Resolver{}
+	for _, dir := range dirs {
+		r.AddDir(dir)
+	}
+	return r
+}
+
+// FromEnv creates a Resolver whose search path is taken from the PathEnv
+// environment variable. Empty entries are skipped.
+func FromEnv() *Resolver {
+	r := &Resolver{}
+	for _, dir := range filepath.SplitList(os.Getenv(PathEnv)) {
+		if dir != "" {
+			r.AddDir(dir)
+		}
+	}
+	return r
+}
+
+// AddDir appends a directory on disk to the search path. Environment
+// variables in dir are expanded.
+func (r *Resolver) AddDir(dir string) *Resolver {
+	r.search = append(r.search, dirLocation(os.ExpandEnv(dir)))
+	return r
+}
+
+// AddFS appends a filesystem to the search path.
+func (r *Resolver) AddFS(fsys fs.FS) *Resolver {
+	r.search = append(r.search, fsLocation{fsys})
+	return r
+}
+
+// Mount registers a filesystem under the given scheme, so that names like
+// "scheme://some/file.png" are opened from it.
+func (r *Resolver) Mount(scheme string, fsys fs.FS) *Resolver {
+	if r.schemes == nil {
+		r.schemes = make(map[string]fs.FS)
+	}
+	r.schemes[scheme] = fsys
+	return r
+}
+
+// Relative returns a copy of this Resolver with dir searched first. Loaders
+// use this so that assets are found relative to the file that references
+// them (e.g. textures next to a scene file).
+func (r *Resolver) Relative(dir string) *Resolver {
+	search := make([]location, 0, len(r.search)+1)
+	search = append(search, dirLocation(dir))
+	search = append(search, r.search...)
+	return &Resolver{search: search, schemes: r.schemes}
+}
+
+// Open opens the named asset. Environment variables in the name are expanded
+// first. Then:
+//
+//   - names with a scheme ("scheme://path") are opened from the filesystem
+//     mounted for that scheme
+//   - absolute paths are opened directly
+//   - everything else is tried against each search path entry in order
+//
+// If the asset can't be found, the returned error wraps fs.ErrNotExist.
+func (r *Resolver) Open(name string) (fs.File, error) {
+	name = os.ExpandEnv(name)
+
+	if scheme, rest, ok := strings.Cut(name, "://"); ok {
+		fsys, found := r.schemes[scheme]
+		if !found {
+			return nil, fmt.Errorf("asset %q: unknown scheme %q", name, scheme)
+		}
+		return fsys.Open(rest)
+	}
+
+	if filepath.IsAbs(name) {
+		return os.Open(name)
+	}
+
+	for _, loc := range r.search {
+		f, err := loc.open(name)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("asset %q not found in %v: %w", name, r.search, fs.ErrNotExist)
+}
+
+// ReadFile reads the whole named asset.
+func (r *Resolver) ReadFile(name string) ([]byte, error) {
+	f, err := r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+type dirLocation string
+
+func (d dirLocation) open(name string) (fs.File, error) {
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+func (d dirLocation) String() string {
+	return string(d)
+}
+
+type fsLocation struct {
+	fsys fs.FS
+}
+
+func (l fsLocation) open(name string) (fs.File, error) {
+	name = path.Clean(filepath.ToSlash(name))
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return l.fsys.Open(name)
+}
+
+func (l fsLocation) String() string {
+	return fmt.Sprintf("%T", l.fsys)
+}
@@ -0,0 +1,75 @@
+package asset
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver_Open(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "tex"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tex", "wood.png"), []byte("disk"), 0644))
+
+	mem := fstest.MapFS{
+		"tex/wood.png":  {Data: []byte("memory")},
+		"tex/stone.png": {Data: []byte("stone")},
+	}
+
+	r := NewResolver(dir).AddFS(mem).Mount("test", mem)
+	t.Setenv("GREMLIN_TEST_DIR", dir)
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"tex/wood.png", "disk"},
+		{"tex/stone.png", "stone"},
+		{"test://tex/wood.png", "memory"},
+		{"$GREMLIN_TEST_DIR/tex/wood.png", "disk"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := r.ReadFile(tt.name)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, string(data))
+		})
+	}
+
+	_, err := r.Open("tex/missing.png")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = r.Open("nope://tex/wood.png")
+	assert.Error(t, err)
+}
+
+func TestResolver_Relative(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.obj"), []byte("relative"), 0644))
+
+	r := NewResolver().AddFS(fstest.MapFS{"a.obj": {Data: []byte("base")}})
+
+	data, err := r.Relative(dir).ReadFile("a.obj")
+	assert.NoError(t, err)
+	assert.Equal(t, "relative", string(data))
+
+	data, err = r.ReadFile("a.obj")
+	assert.NoError(t, err)
+	assert.Equal(t, "base", string(data))
+}
+
+func TestFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.obj"), []byte("env"), 0644))
+	t.Setenv(PathEnv, string(filepath.ListSeparator)+dir)
+
+	data, err := FromEnv().ReadFile("b.obj")
+	assert.NoError(t, err)
+	assert.Equal(t, "env", string(data))
+}
@@ -0,0 +1,175 @@
+package camera
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+	"github.com/gmhorn/gremlin/archive/pkg/imageio"
+)
+
+// AOV (arbitrary output variable) is an auxiliary per-pixel channel that a
+// Film can record alongside the rendered image, e.g. as inputs for external
+// denoisers or for debugging shading. All of them describe the primary hit,
+// i.e. the first surface seen by a camera ray; rays that miss the scene
+// contribute zeros.
+//
+// Except for object IDs, AOVs are averaged over the samples taken in each
+// pixel (they aren't filtered).
+type AOV int
+
+const (
+	// AOVNormal is the world-space surface normal.
+	AOVNormal AOV = iota
+
+	// AOVDepth is the distance from the camera.
+	AOVDepth
+
+	// AOVAlbedo is the material's reflectance, as CIE XYZ (see
+	// colorspace.CIE1931Reflectance).
+	AOVAlbedo
+
+	// AOVObjectID identifies the object hit by one of the pixel's samples.
+	// Objects are numbered from 1, so 0 means nothing was hit.
+	AOVObjectID
+
+	// AOVVariance is the estimated variance of the pixel's luminance (the Y
+	// its film's Observer gives), as the squared standard error of the mean
+	// of its samples. Unlike the other AOVs it describes the rendered image,
+	// so renderers don't need to record it: FilmTile.AddSample does.
+	AOVVariance
+
+	// AOVLuminance is the luminance (Y) of the rendered radiance, relative to
+	// a flat spectrum of 1 (see colorspace.CIE1931Reflectance). Like variance
+	// it describes the rendered image, but renderers record it, since they
+	// have the radiance before the film's Observer sees it. FalseColor turns
+	// it into an image.
+	AOVLuminance
+
+	numAOVs
+)
+
+// String returns a short name for the AOV, e.g. for file names.
+func (a AOV) String() string {
+	switch a {
+	case AOVNormal:
+		return "normal"
+	case AOVDepth:
+		return "depth"
+	case AOVAlbedo:
+		return "albedo"
+	case AOVObjectID:
+		return "id"
+	case AOVVariance:
+		return "variance"
+	case AOVLuminance:
+		return "luminance"
+	}
+	return "unknown"
+}
+
+// ParseAOV returns the AOV with the given name, as returned by String.
+func ParseAOV(name string) (AOV, error) {
+	for aov := AOV(0); aov < numAOVs; aov++ {
+		if aov.String() == name {
+			return aov, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown AOV %q", name)
+}
+
+// EnableAOVs makes the film record the given AOVs.
+func (f *Film) EnableAOVs(aovs ...AOV) {
+	for _, aov := range aovs {
+		if f.aovs[aov] == nil {
+			f.aovs[aov] = make([]colorspace.Point, len(f.Pixels))
+		}
+	}
+}
+
+// AOVs returns the AOVs the film records.
+func (f *Film) AOVs() []AOV {
+	var aovs []AOV
+	for aov := AOV(0); aov < numAOVs; aov++ {
+		if f.aovs[aov] != nil {
+			aovs = append(aovs, aov)
+		}
+	}
+	return aovs
+}
+
+// AOV returns the recorded AOV as an image, or nil if the film doesn't record
+// it. Albedo is converted to linear RGB in the given color space; other AOVs
+// are written as-is, with scalars (depth, object ID, variance and luminance)
+// repeated in all three channels.
+func (f *Film) AOV(aov AOV, cs colorspace.RGB) *imageio.RGB {
+	buf := f.aovs[aov]
+	if buf == nil {
+		return nil
+	}
+
+	img := imageio.NewRGB(f.Width, f.Height)
+	for i, v := range buf {
+		n := float64(f.Pixels[i].Samples)
+		switch {
+		case aov == AOVVariance:
+			// v holds the sums of squares and of values
+			variance := 0.0
+			if n > 1 {
+				variance = math.Max(0, (v[0]-v[1]*v[1]/n)/(n*(n-1)))
+			}
+			v = colorspace.Point{variance, variance, variance}
+		case aov != AOVObjectID && n > 0:
+			v = v.Scale(1 / n)
+		}
+		if aov == AOVAlbedo {
+			v = cs.Linear(v)
+		}
+		copy(img.Pix[3*i:3*i+3], v[:])
+	}
+	return img
+}
+
+// AddAOV records the value of an AOV for a sample taken at raster coordinates
+// (x, y). Values are summed, except for object IDs, which replace the previous
+// value.
+func (t *FilmTile) AddAOV(x, y float64, aov AOV, v colorspace.Point) {
+	buf := t.aovs[aov]
+	home := image.Pt(int(x), int(y))
+	if buf == nil || !home.In(t.Bounds) {
+		return
+	}
+
+	i := t.index(home.X, home.Y)
+	if aov == AOVObjectID {
+		buf[i] = v
+		return
+	}
+	buf[i] = colorspace.Point{buf[i][0] + v[0], buf[i][1] + v[1], buf[i][2] + v[2]}
+}
+
+// mergeAOVs merges a tile's AOVs into the film. Object IDs only overwrite the
+// film's where the tile recorded one.
+func (f *Film) mergeAOVs(tile *FilmTile) {
+	for aov, src := range tile.aovs {
+		dst := f.aovs[aov]
+		if src == nil || dst == nil {
+			continue
+		}
+		for y := tile.Bounds.Min.Y; y < tile.Bounds.Max.Y; y++ {
+			for x := tile.Bounds.Min.X; x < tile.Bounds.Max.X; x++ {
+				s, d := src[tile.index(x, y)], &dst[y*f.Width+x]
+				if AOV(aov) == AOVObjectID {
+					if s[0] != 0 {
+						*d = s
+					}
+					continue
+				}
+				d[0] += s[0]
+				d[1] += s[1]
+				d[2] += s[2]
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package camera
+
+import (
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilm_AOV(t *testing.T) {
+	film := NewFilm(2, 1)
+	assert.Nil(t, film.AOVs())
+	assert.Nil(t, film.AOV(AOVDepth, colorspace.SRGB))
+
+	film.EnableAOVs(AOVDepth, AOVObjectID, AOVDepth)
+	assert.Equal(t, []AOV{AOVDepth, AOVObjectID}, film.AOVs())
+
+	tile := film.NewTile(0, 2)
+	tile.AddSample(0.5, 0.5, colorspace.Point{})
+	tile.AddSample(0.5, 0.5, colorspace.Point{})
+	tile.AddAOV(0.5, 0.5, AOVDepth, colorspace.Point{1, 1, 1})
+	tile.AddAOV(0.5, 0.5, AOVDepth, colorspace.Point{3, 3, 3})
+	tile.AddAOV(0.5, 0.5, AOVObjectID, colorspace.Point{2, 2, 2})
+	tile.AddAOV(0.5, 0.5, AOVNormal, colorspace.Point{1, 0, 0}) // not recorded
+	film.Merge(tile)
+
+	// a second pass with a miss keeps the object ID
+	tile = film.NewTile(0, 2)
+	tile.AddSample(0.5, 0.5, colorspace.Point{})
+	tile.AddAOV(0.5, 0.5, AOVDepth, colorspace.Point{5, 5, 5})
+	film.Merge(tile)
+
+	depth := film.AOV(AOVDepth, colorspace.SRGB)
+	assert.Equal(t, []float64{3, 3, 3, 0, 0, 0}, depth.Pix)
+	id := film.AOV(AOVObjectID, colorspace.SRGB)
+	assert.Equal(t, []float64{2, 2, 2, 0, 0, 0}, id.Pix)
+
+	assert.Equal(t, "albedo", AOVAlbedo.String())
+}
+
+func TestFilm_AOVVariance(t *testing.T) {
+	film := NewFilm(2, 1)
+	film.EnableAOVs(AOVVariance)
+
+	// luminances 1, 2, 3 and 4 in two passes: variance 5/3, over 4 samples
+	for _, ys := range [][]float64{{1, 2}, {3, 4}} {
+		tile := film.NewTile(0, 2)
+		for _, y := range ys {
+			tile.AddSample(0.5, 0.5, colorspace.Point{0, y, 0})
+		}
+		tile.AddSample(1.5, 0.5, colorspace.Point{0, 1, 0})
+		film.Merge(tile)
+	}
+
+	variance := film.AOV(AOVVariance, colorspace.SRGB)
+	r, g, b := variance.At(0, 0)
+	assert.InDelta(t, 5.0/12, r, 1e-12)
+	assert.Equal(t, r, g)
+	assert.Equal(t, r, b)
+
+	// constant samples have no variance
+	r, _, _ = variance.At(1, 0)
+	assert.InDelta(t, 0, r, 1e-12)
+}
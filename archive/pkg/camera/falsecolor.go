@@ -0,0 +1,99 @@
+package camera
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+)
+
+// falseColors are the stops of the false color scale, from its minimum to its
+// maximum.
+var falseColors = [...][3]float64{
+	{0, 0, 1},
+	{0, 1, 1},
+	{0, 1, 0},
+	{1, 1, 0},
+	{1, 0, 0},
+	{1, 0, 1},
+}
+
+// FalseColorScale maps luminance, in cd/m², to colors running from blue
+// through cyan, green, yellow and red to magenta, as lighting designers use to
+// check light levels at a glance. Luminance below Min is black and above Max
+// white, so out-of-range areas stand out.
+//
+// Log spaces the scale logarithmically, which suits the wide range of real
+// light levels. Steps splits it into that many flat bands, like contour lines,
+// or leaves it continuous if 0.
+//
+// Units is the luminance in cd/m² of a flat spectrum of radiance 1 in the
+// scene, e.g. 1000 for sun and sky lights (see light.NewSunSky), whose
+// radiance is in kcd/m². 0 means 1.
+type FalseColorScale struct {
+	Min, Max float64
+	Log      bool
+	Steps    int
+	Units    float64
+}
+
+// NewFalseColorScale creates a scale from min to max cd/m².
+func NewFalseColorScale(min, max float64, log bool) *FalseColorScale {
+	if min >= max || (log && min <= 0) {
+		panic("invalid false color range")
+	}
+	return &FalseColorScale{Min: min, Max: max, Log: log}
+}
+
+// Color returns the color of the luminance l, in cd/m².
+func (s *FalseColorScale) Color(l float64) color.RGBA {
+	if l < s.Min || math.IsNaN(l) {
+		return color.RGBA{A: 255}
+	}
+	if l > s.Max {
+		return color.RGBA{255, 255, 255, 255}
+	}
+
+	t := (l - s.Min) / (s.Max - s.Min)
+	if s.Log {
+		t = math.Log(l/s.Min) / math.Log(s.Max/s.Min)
+	}
+	if s.Steps > 0 {
+		// the middle of the band, so the first and last aren't the ends
+		band := math.Min(math.Floor(t*float64(s.Steps)), float64(s.Steps-1))
+		t = (band + 0.5) / float64(s.Steps)
+	}
+
+	x := t * float64(len(falseColors)-1)
+	i := int(math.Min(x, float64(len(falseColors)-2)))
+	f := x - float64(i)
+	var c [3]uint8
+	for ch := range c {
+		v := falseColors[i][ch] + f*(falseColors[i+1][ch]-falseColors[i][ch])
+		c[ch] = uint8(math.Round(v * 255))
+	}
+	return color.RGBA{c[0], c[1], c[2], 255}
+}
+
+// FalseColor returns an image of the film's luminance through the scale, or
+// nil if the film doesn't record AOVLuminance.
+func (f *Film) FalseColor(s *FalseColorScale) *image.RGBA {
+	lum := f.AOV(AOVLuminance, colorspace.SRGB)
+	if lum == nil {
+		return nil
+	}
+	units := s.Units
+	if units == 0 {
+		units = 1
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, f.Width, f.Height))
+	for y := 0; y < f.Height; y++ {
+		for x := 0; x < f.Width; x++ {
+			l, _, _ := lum.At(x, y)
+			img.SetRGBA(x, y, s.Color(l*units))
+		}
+	}
+	return img
+}
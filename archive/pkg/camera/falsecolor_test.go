@@ -0,0 +1,59 @@
+package camera
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFalseColorScale_Color(t *testing.T) {
+	linear := NewFalseColorScale(0, 500, false)
+	log := NewFalseColorScale(1, 10000, true)
+	stepped := &FalseColorScale{Min: 0, Max: 100, Steps: 5}
+
+	tests := []struct {
+		name     string
+		scale    *FalseColorScale
+		l        float64
+		expected color.RGBA
+	}{
+		{"Min", linear, 0, color.RGBA{0, 0, 255, 255}},
+		{"Middle", linear, 250, color.RGBA{128, 255, 0, 255}},
+		{"Max", linear, 500, color.RGBA{255, 0, 255, 255}},
+		{"Over", linear, 501, color.RGBA{255, 255, 255, 255}},
+		{"Under", linear, -1, color.RGBA{0, 0, 0, 255}},
+		{"LogMiddle", log, 100, color.RGBA{128, 255, 0, 255}},
+		{"LogStop", log, 10000, color.RGBA{255, 0, 255, 255}},
+		{"FirstBand", stepped, 3, color.RGBA{0, 128, 255, 255}},
+		{"SameBand", stepped, 19, color.RGBA{0, 128, 255, 255}},
+		{"LastBand", stepped, 100, color.RGBA{255, 0, 128, 255}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.scale.Color(test.l))
+		})
+	}
+
+	assert.Panics(t, func() { NewFalseColorScale(0, 10, true) })
+}
+
+func TestFilm_FalseColor(t *testing.T) {
+	film := NewFilm(2, 1)
+	scale := NewFalseColorScale(0, 500, false)
+	scale.Units = 1000
+	assert.Nil(t, film.FalseColor(scale))
+
+	film.EnableAOVs(AOVLuminance)
+	tile := film.NewTile(0, 2)
+	tile.AddSample(0.5, 0.5, colorspace.Point{})
+	tile.AddAOV(0.5, 0.5, AOVLuminance, colorspace.Point{0.5, 0.5, 0.5})
+	tile.AddSample(1.5, 0.5, colorspace.Point{})
+	tile.AddAOV(1.5, 0.5, AOVLuminance, colorspace.Point{0.25, 0.25, 0.25})
+	film.Merge(tile)
+
+	img := film.FalseColor(scale)
+	assert.Equal(t, color.RGBA{255, 0, 255, 255}, img.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{128, 255, 0, 255}, img.RGBAAt(1, 0))
+}
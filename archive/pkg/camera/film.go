@@ -3,17 +3,23 @@ package camera
 import (
 	"image"
 	"image/color"
+	"math"
 	"math/rand"
+	"sync/atomic"
 
 	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+	"github.com/gmhorn/gremlin/archive/pkg/imageio"
 )
 
-// Pixel is an individual film pixel. Its Color field stores the running sum of
-// the spectral sample contributions to the final pixel color, and the Samples
-// field stores the number of samples. The final pixel color can be easily
-// determined by taking the average
+// Pixel is an individual film pixel. Its Color field stores the running,
+// filter-weighted sum of the spectral sample contributions to the final pixel
+// color, and the Weight field stores the sum of the filter weights. The final
+// pixel color can be easily determined by taking the weighted average
 //
-//	pixel.Color / pixel.Samples
+//	pixel.Color / pixel.Weight
+//
+// Samples counts the samples taken within the pixel itself (which, depending
+// on the Film's Filter, may also contribute to neighboring pixels).
 //
 // A natural alternative would be to have each pixel store a running sum of the
 // full spectral distributions (say spectrum.Discrete). However, this results
@@ -28,16 +34,25 @@ import (
 // https://computergraphics.stackexchange.com/a/11000
 type Pixel struct {
 	Color   colorspace.Point
+	Weight  float64
 	Samples uint64
 }
 
+// AddColor adds a sample taken in this pixel, with weight 1.
 func (p *Pixel) AddColor(c colorspace.Point) {
-	p.Color[0] += c[0]
-	p.Color[1] += c[1]
-	p.Color[2] += c[2]
+	p.AddWeighted(c, 1)
 	p.Samples++
 }
 
+// AddWeighted adds a sample contribution with the given filter weight. It
+// doesn't count as a sample taken in this pixel.
+func (p *Pixel) AddWeighted(c colorspace.Point, w float64) {
+	p.Color[0] += w * c[0]
+	p.Color[1] += w * c[1]
+	p.Color[2] += w * c[2]
+	p.Weight += w
+}
+
 // Film is a rectagular grid of pixels.
 //
 // It stores the pixels in a linear slice, since the most frequent operations
@@ -60,16 +75,117 @@ func (p *Pixel) AddColor(c colorspace.Point) {
 // The tradeoff here is we have to range over the index then use that to mutate
 // the underlying slice. The benefit is much better data locality and cache
 // performance.
+//
+// Separately from the averaged Pixels, a Film keeps a splat buffer. Splats are
+// contributions deposited at arbitrary pixels (e.g. by light tracing or lens
+// flares) that are summed rather than averaged. SplatScale is applied to the
+// splat buffer when the final image is produced; for light tracing this is
+// typically 1/(samples per pixel).
+//
+// Observer is the Colorspace renderers should use to turn spectral samples
+// into pixel colors. It must produce CIE XYZ values that keep the radiance's
+// intensity, rather than chromaticities; by default it's
+// colorspace.CIE1931XYZ, but e.g. a colorspace.Sensor can be used to render as
+// a specific camera would see.
+//
+// Filter is the pixel reconstruction filter used by FilmTile.AddSample. By
+// default it's a box filter of radius 0.5, so each sample only lands in its
+// own pixel.
+//
+// LUT, if set, is applied to the display colors Image produces, to give
+// renders a grading look or film stock response (see colorspace.LoadCube). It
+// doesn't touch the linear colors of RGB.
 type Film struct {
 	Width, Height int
 	AspectRatio   float64
 	Pixels        []Pixel
+	SplatScale    float64
+	Observer      colorspace.Colorspace
+	Filter        Filter
+	LUT           *colorspace.LUT
+
+	splats []splat
+	aovs   [numAOVs][]colorspace.Point
 }
 
-// FilmTile is a slice of Pixels with a set Offset.
+// splat is an accumulator for splatted color contributions. Components are
+// float64 bit patterns so that they can be updated atomically; splats can
+// land on any pixel, so unlike Pixels they can't be partitioned into tiles.
+type splat [3]uint64
+
+// FilmTile is a rectangular block of Pixels, stored row by row, that covers
+// Bounds in the film's raster space. Tiles let renderers accumulate samples
+// without synchronization and merge them into the film afterwards.
 type FilmTile struct {
+	Bounds image.Rectangle
 	Pixels []Pixel
-	Offset int
+
+	filter Filter
+	aovs   [numAOVs][]colorspace.Point
+}
+
+// NewTile creates an empty tile for rendering the size pixels starting at the
+// given pixel index. The tile is big enough to hold all the filtered
+// contributions of samples taken in those pixels.
+func (f *Film) NewTile(offset, size int) *FilmTile {
+	x0, y0 := f.RasterCoords(offset)
+	x1, y1 := f.RasterCoords(offset + size - 1)
+	if y1 > y0 {
+		x0, x1 = 0, f.Width-1
+	}
+
+	// samples can reach pixels whose centers are within the filter radius
+	ext := int(math.Ceil(f.Filter.Radius() - 0.5))
+	bounds := image.Rect(x0-ext, y0-ext, x1+ext+1, y1+ext+1).Intersect(image.Rect(0, 0, f.Width, f.Height))
+
+	tile := &FilmTile{
+		Bounds: bounds,
+		Pixels: make([]Pixel, bounds.Dx()*bounds.Dy()),
+		filter: f.Filter,
+	}
+	for aov, buf := range f.aovs {
+		if buf != nil {
+			tile.aovs[aov] = make([]colorspace.Point, len(tile.Pixels))
+		}
+	}
+	return tile
+}
+
+// AddSample adds a sample taken at raster coordinates (x, y) to every pixel
+// in the tile whose center is within the filter radius, and counts it as a
+// sample of the pixel it's in (which is also where it's recorded for
+// AOVVariance).
+//
+// https://www.pbr-book.org/3ed-2018/Sampling_and_Reconstruction/Film_and_the_Imaging_Pipeline#AddingSampleContributions
+func (t *FilmTile) AddSample(x, y float64, c colorspace.Point) {
+	// discrete coordinates, so pixel centers are at integers
+	dx, dy := x-0.5, y-0.5
+	r := t.filter.Radius()
+
+	// pixels in (d - r, d + r], within the tile
+	footprint := image.Rect(
+		int(math.Floor(dx-r))+1, int(math.Floor(dy-r))+1,
+		int(math.Floor(dx+r))+1, int(math.Floor(dy+r))+1,
+	).Intersect(t.Bounds)
+
+	for py := footprint.Min.Y; py < footprint.Max.Y; py++ {
+		for px := footprint.Min.X; px < footprint.Max.X; px++ {
+			w := t.filter.Eval(float64(px)-dx, float64(py)-dy)
+			if w != 0 {
+				t.Pixels[t.index(px, py)].AddWeighted(c, w)
+			}
+		}
+	}
+
+	if home := image.Pt(int(x), int(y)); home.In(t.Bounds) {
+		t.Pixels[t.index(home.X, home.Y)].Samples++
+	}
+	t.AddAOV(x, y, AOVVariance, colorspace.Point{c[1] * c[1], c[1], 0})
+}
+
+// index returns the index into Pixels of raster coordinates (x, y).
+func (t *FilmTile) index(x, y int) int {
+	return (y-t.Bounds.Min.Y)*t.Bounds.Dx() + x - t.Bounds.Min.X
 }
 
 // NewFilm creates a new film with the given width and height (in pixels).
@@ -85,6 +201,10 @@ func NewFilm(width, height int) *Film {
 		Height:      height,
 		AspectRatio: float64(width) / float64(height),
 		Pixels:      make([]Pixel, width*height),
+		SplatScale:  1,
+		Observer:    colorspace.CIE1931XYZ,
+		Filter:      NewBoxFilter(0.5),
+		splats:      make([]splat, width*height),
 	}
 }
 
@@ -110,27 +230,82 @@ func (f *Film) PixelAt(x, y int) (int, *Pixel) {
 	return pxIdx, &f.Pixels[pxIdx]
 }
 
-// Merge merges a slice of pixels into this film's pixel buffer at the given
-// offset.
+// Merge merges a tile's pixels into this film's pixel buffer. Colors, weights
+// and sample counts are added to what's already there, so overlapping tiles
+// and tiles from repeated passes over the film accumulate.
 func (f *Film) Merge(tile *FilmTile) {
-	for idx := range tile.Pixels {
-		filmIdx := tile.Offset + idx
-		f.Pixels[filmIdx].Color[0] = tile.Pixels[idx].Color[0]
-		f.Pixels[filmIdx].Color[1] = tile.Pixels[idx].Color[1]
-		f.Pixels[filmIdx].Color[2] = tile.Pixels[idx].Color[2]
-		f.Pixels[filmIdx].Samples += tile.Pixels[idx].Samples
+	for y := tile.Bounds.Min.Y; y < tile.Bounds.Max.Y; y++ {
+		for x := tile.Bounds.Min.X; x < tile.Bounds.Max.X; x++ {
+			src := &tile.Pixels[tile.index(x, y)]
+			dst := &f.Pixels[y*f.Width+x]
+			dst.Color[0] += src.Color[0]
+			dst.Color[1] += src.Color[1]
+			dst.Color[2] += src.Color[2]
+			dst.Weight += src.Weight
+			dst.Samples += src.Samples
+		}
 	}
+	f.mergeAOVs(tile)
 }
 
+// Splat adds the color contribution c to the pixel at raster coordinates
+// (x, y). Splats outside the film are silently dropped, since light paths can
+// easily land off-screen.
+//
+// It is safe to call Splat concurrently from multiple goroutines.
+//
+// https://www.pbr-book.org/3ed-2018/Light_Transport_III_Bidirectional_Methods/Bidirectional_Path_Tracing#x3-Splatting
+func (f *Film) Splat(x, y int, c colorspace.Point) {
+	if x < 0 || x >= f.Width || y < 0 || y >= f.Height {
+		return
+	}
+
+	s := &f.splats[y*f.Width+x]
+	for i := range s {
+		atomicAdd(&s[i], c[i])
+	}
+}
+
+// SplatAt returns the accumulated (unscaled) splat contribution for the given
+// pixel index.
+func (f *Film) SplatAt(pxIdx int) colorspace.Point {
+	s := &f.splats[pxIdx]
+	return colorspace.Point{
+		math.Float64frombits(atomic.LoadUint64(&s[0])),
+		math.Float64frombits(atomic.LoadUint64(&s[1])),
+		math.Float64frombits(atomic.LoadUint64(&s[2])),
+	}
+}
+
+// Color returns the final (linear) color of the pixel at the given index: the
+// average of its samples plus its scaled splat contribution.
+func (f *Film) Color(pxIdx int) colorspace.Point {
+	px := &f.Pixels[pxIdx]
+
+	xyz := colorspace.Point{}
+	if px.Weight != 0 {
+		xyz = px.Color.Scale(1 / px.Weight)
+	}
+
+	s := f.SplatAt(pxIdx).Scale(f.SplatScale)
+	return colorspace.Point{xyz[0] + s[0], xyz[1] + s[1], xyz[2] + s[2]}
+}
+
+// Image returns the film as an 8-bit image for display, in the given color
+// space and through the LUT if there is one.
 func (f *Film) Image(cs colorspace.RGB) *image.RGBA {
 	img := image.NewRGBA(image.Rect(0, 0, f.Width, f.Height))
-	for i, px := range f.Pixels {
+	for i := range f.Pixels {
 		x, y := f.RasterCoords(i)
-
-		n := 1 / float64(px.Samples)
-		xyz := px.Color.Scale(n)
+		xyz := f.Color(i)
 
 		rgb := cs.ConvertXYZ(xyz)
+		if f.LUT != nil {
+			rgb = f.LUT.Apply(rgb)
+			for c := range rgb {
+				rgb[c] = math.Max(0, math.Min(1, rgb[c]))
+			}
+		}
 		img.Set(x, y, color.RGBA{
 			R: uint8(rgb[0] * 255),
 			G: uint8(rgb[1] * 255),
@@ -140,3 +315,28 @@ func (f *Film) Image(cs colorspace.RGB) *image.RGBA {
 	}
 	return img
 }
+
+// RGB returns the film as a linear, floating-point image in the given color
+// space, without any tone mapping or clamping. Use it with imageio.WriteEXR to
+// keep the full dynamic range for post-processing.
+func (f *Film) RGB(cs colorspace.RGB) *imageio.RGB {
+	img := imageio.NewRGB(f.Width, f.Height)
+	for i := range f.Pixels {
+		rgb := cs.Linear(f.Color(i))
+		copy(img.Pix[3*i:3*i+3], rgb[:])
+	}
+	return img
+}
+
+// atomicAdd adds v to the float64 stored (as bits) at addr.
+//
+// Same compare-and-swap loop as metrics.Quantity64.
+func atomicAdd(addr *uint64, v float64) {
+	for {
+		oldBits := atomic.LoadUint64(addr)
+		newBits := math.Float64bits(math.Float64frombits(oldBits) + v)
+		if atomic.CompareAndSwapUint64(addr, oldBits, newBits) {
+			return
+		}
+	}
+}
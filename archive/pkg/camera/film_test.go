@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/stretchr/testify/assert"
 )
 
 var img *image.RGBA
@@ -21,10 +23,129 @@ func TestPixel_AddColor(t *testing.T) {
 func BenchmarkFilm_Image(b *testing.B) {
 	film := NewFilm(360, 240)
 	for idx := range film.Pixels {
-		film.Pixels[idx].Samples++
+		film.Pixels[idx].AddColor(colorspace.Point{})
 	}
 
 	for i := 0; i < b.N; i++ {
 		img = film.Image(colorspace.SRGB)
 	}
 }
+
+func TestFilm_Splat(t *testing.T) {
+	film := NewFilm(4, 2)
+	film.Splat(1, 1, colorspace.Point{1, 2, 3})
+	film.Splat(1, 1, colorspace.Point{1, 2, 3})
+	film.Splat(-1, 0, colorspace.Point{1, 1, 1})
+	film.Splat(4, 0, colorspace.Point{1, 1, 1})
+
+	assert.Equal(t, colorspace.Point{2, 4, 6}, film.SplatAt(5))
+
+	film.SplatScale = 0.5
+	assert.Equal(t, colorspace.Point{1, 2, 3}, film.Color(5))
+	assert.Equal(t, colorspace.Point{}, film.Color(0))
+}
+
+func TestFilm_Merge(t *testing.T) {
+	film := NewFilm(2, 2)
+	tile := film.NewTile(1, 2)
+	assert.Equal(t, image.Rect(0, 0, 2, 2), tile.Bounds)
+	tile.AddSample(1.5, 0.5, colorspace.Point{1, 2, 3})
+	tile.AddSample(0.25, 1.75, colorspace.Point{2, 2, 2})
+
+	// merging twice accumulates colors, weights and samples
+	film.Merge(tile)
+	film.Merge(tile)
+
+	assert.Equal(t, Pixel{}, film.Pixels[0])
+	assert.Equal(t, Pixel{Color: colorspace.Point{2, 4, 6}, Weight: 2, Samples: 2}, film.Pixels[1])
+	assert.Equal(t, colorspace.Point{1, 2, 3}, film.Color(1))
+	assert.Equal(t, colorspace.Point{2, 2, 2}, film.Color(2))
+}
+
+func TestFilm_NewTile(t *testing.T) {
+	film := NewFilm(10, 5)
+
+	// box filter of radius 0.5 stays within the pixels
+	assert.Equal(t, image.Rect(2, 1, 6, 2), film.NewTile(12, 4).Bounds)
+	// wrapping to the next row covers whole rows
+	assert.Equal(t, image.Rect(0, 1, 10, 3), film.NewTile(18, 4).Bounds)
+
+	// wider filters reach neighboring pixels, clipped to the film
+	film.Filter = NewTentFilter(1)
+	assert.Equal(t, image.Rect(1, 0, 7, 3), film.NewTile(12, 4).Bounds)
+	assert.Equal(t, image.Rect(0, 0, 2, 2), film.NewTile(0, 1).Bounds)
+}
+
+func TestFilmTile_AddSample(t *testing.T) {
+	film := NewFilm(3, 3)
+	film.Filter = NewTentFilter(1)
+	tile := film.NewTile(4, 1)
+	assert.Equal(t, image.Rect(0, 0, 3, 3), tile.Bounds)
+
+	// a sample at the center of pixel (1, 1) only lands there
+	tile.AddSample(1.5, 1.5, colorspace.Point{1, 1, 1})
+	film.Merge(tile)
+	assert.Equal(t, Pixel{Color: colorspace.Point{1, 1, 1}, Weight: 1, Samples: 1}, film.Pixels[4])
+	assert.Equal(t, Pixel{}, film.Pixels[5])
+
+	// a sample at its corner is shared by the four pixels around it
+	tile = film.NewTile(4, 1)
+	tile.AddSample(2, 2, colorspace.Point{4, 4, 4})
+	film.Merge(tile)
+	for _, i := range []int{5, 7, 8} {
+		assert.Equal(t, 0.25, film.Pixels[i].Weight)
+		assert.Equal(t, colorspace.Point{4, 4, 4}, film.Color(i))
+	}
+	assert.Equal(t, 1.25, film.Pixels[4].Weight)
+	assert.Equal(t, colorspace.Point{1.6, 1.6, 1.6}, film.Color(4))
+
+	// it was taken in pixel (2, 2)
+	assert.Equal(t, uint64(1), film.Pixels[8].Samples)
+	assert.Equal(t, uint64(0), film.Pixels[5].Samples)
+}
+func TestFilm_Observer(t *testing.T) {
+	film := NewFilm(1, 1)
+
+	// no light is black, not a chromaticity of 0/0
+	assert.Equal(t, colorspace.Point{}, film.Observer.Convert(spectrum.Flat(0)))
+
+	// and twice the light is twice as bright
+	once := film.Observer.Convert(spectrum.Flat(1))
+	twice := film.Observer.Convert(spectrum.Flat(2))
+	assert.InDelta(t, 1, once[1], 1e-9)
+	assert.InDelta(t, 2*once[1], twice[1], 1e-9)
+}
+
+func TestFilm_RGB(t *testing.T) {
+	film := NewFilm(2, 1)
+	film.Pixels[1].AddColor(colorspace.Point{0.95047 * 4, 4, 1.08883 * 4})
+
+	img := film.RGB(colorspace.SRGB)
+	assert.Equal(t, 2, img.Width)
+	assert.Equal(t, 1, img.Height)
+
+	r, g, b := img.At(0, 0)
+	assert.Equal(t, []float64{0, 0, 0}, []float64{r, g, b})
+
+	// D65 white, 4 times brighter than display white: not clamped
+	r, g, b = img.At(1, 0)
+	assert.InDelta(t, 4, r, 1e-3)
+	assert.InDelta(t, 4, g, 1e-3)
+	assert.InDelta(t, 4, b, 1e-3)
+}
+
+func TestFilm_Image_LUT(t *testing.T) {
+	film := NewFilm(1, 1)
+	film.Pixels[0].AddColor(colorspace.Point{0.95047 * 0.2, 0.2, 1.08883 * 0.2})
+	gray := colorspace.SRGB.ConvertXYZ(film.Color(0))[1]
+
+	// a 1D LUT that inverts, and pushes red past white
+	film.LUT = &colorspace.LUT{
+		Size:      2,
+		Table:     []colorspace.Point{{2, 1, 1}, {0, 0, 0}},
+		DomainMax: colorspace.Point{1, 1, 1},
+	}
+	c := film.Image(colorspace.SRGB).RGBAAt(0, 0)
+	assert.Equal(t, uint8((1-gray)*255), c.G)
+	assert.Equal(t, uint8(255), c.R)
+}
@@ -0,0 +1,155 @@
+package camera
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"sync/atomic"
+
+	"github.com/gmhorn/gremlin/archive/pkg/asset"
+)
+
+// filmMagic starts every film file.
+var filmMagic = [8]byte{'G', 'R', 'M', 'F', 'I', 'L', 'M', '1'}
+
+// filmHeader is the fixed-size start of a film file. AOVs is a bit mask of the
+// AOVs that follow the pixels and splats.
+type filmHeader struct {
+	Magic         [8]byte
+	Width, Height uint32
+	SplatScale    float64
+	AOVs          uint32
+}
+
+// Write writes the film's raw accumulated state (pixel sums, weights and
+// sample counts, splats and AOVs) in a simple binary format, so it can be read
+// back with ReadFilm and merged with other renders of the same scene. The
+// Observer and Filter aren't saved.
+func (f *Film) Write(w io.Writer) error {
+	hdr := filmHeader{
+		Magic:      filmMagic,
+		Width:      uint32(f.Width),
+		Height:     uint32(f.Height),
+		SplatScale: f.SplatScale,
+	}
+	for _, aov := range f.AOVs() {
+		hdr.AOVs |= 1 << aov
+	}
+
+	data := []any{hdr, f.Pixels, f.splats}
+	for _, aov := range f.AOVs() {
+		data = append(data, f.aovs[aov])
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, d := range data {
+		if err := binary.Write(bw, binary.LittleEndian, d); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// LoadFilm opens the named film file with the resolver and reads it.
+func LoadFilm(res *asset.Resolver, name string) (*Film, error) {
+	file, err := res.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	film, err := ReadFilm(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return film, nil
+}
+
+// ReadFilm reads a film written by Film.Write. It gets the default Observer
+// and Filter.
+func ReadFilm(r io.Reader) (*Film, error) {
+	br := bufio.NewReader(r)
+
+	var hdr filmHeader
+	if err := binary.Read(br, binary.LittleEndian, &hdr); err != nil || hdr.Magic != filmMagic {
+		return nil, errors.New("not a film file")
+	}
+	if hdr.Width == 0 || hdr.Height == 0 || hdr.Width > math.MaxInt32/hdr.Height {
+		return nil, fmt.Errorf("bad film size %dx%d", hdr.Width, hdr.Height)
+	}
+
+	film := NewFilm(int(hdr.Width), int(hdr.Height))
+	film.SplatScale = hdr.SplatScale
+	data := []any{film.Pixels, film.splats}
+	for aov := AOV(0); aov < numAOVs; aov++ {
+		if hdr.AOVs&(1<<aov) != 0 {
+			film.EnableAOVs(aov)
+			data = append(data, film.aovs[aov])
+		}
+	}
+
+	for _, d := range data {
+		if err := binary.Read(br, binary.LittleEndian, d); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return film, nil
+}
+
+// Add merges another film, e.g. an independent render of the same scene with a
+// different seed, into this one. Pixel sums, weights and sample counts are
+// added, so the result is the same as if all the samples had been taken in a
+// single render. AOVs are merged like tiles, and only those recorded by both
+// films are kept.
+//
+// Splats are converted to final (scaled) values and averaged, weighted by each
+// film's total number of samples; afterwards SplatScale is 1.
+func (f *Film) Add(other *Film) error {
+	if f.Width != other.Width || f.Height != other.Height {
+		return fmt.Errorf("film sizes differ: %dx%d vs %dx%d", f.Width, f.Height, other.Width, other.Height)
+	}
+
+	n, m := float64(f.totalSamples()), float64(other.totalSamples())
+	wf, wo := 0.5, 0.5
+	if n+m > 0 {
+		wf, wo = n/(n+m), m/(n+m)
+	}
+	for i := range f.splats {
+		a := f.SplatAt(i).Scale(f.SplatScale * wf)
+		b := other.SplatAt(i).Scale(other.SplatScale * wo)
+		for c := range f.splats[i] {
+			atomic.StoreUint64(&f.splats[i][c], math.Float64bits(a[c]+b[c]))
+		}
+	}
+	f.SplatScale = 1
+
+	for aov := range f.aovs {
+		if other.aovs[aov] == nil {
+			f.aovs[aov] = nil
+		}
+	}
+	tile := &FilmTile{
+		Bounds: image.Rect(0, 0, f.Width, f.Height),
+		Pixels: other.Pixels,
+		filter: other.Filter,
+		aovs:   other.aovs,
+	}
+	f.Merge(tile)
+	return nil
+}
+
+// totalSamples returns the number of samples taken over the whole film.
+func (f *Film) totalSamples() uint64 {
+	total := uint64(0)
+	for i := range f.Pixels {
+		total += f.Pixels[i].Samples
+	}
+	return total
+}
@@ -0,0 +1,66 @@
+package camera
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilm_WriteRead(t *testing.T) {
+	film := NewFilm(3, 2)
+	film.EnableAOVs(AOVDepth)
+	film.SplatScale = 0.25
+	film.Pixels[4].AddColor(colorspace.Point{1, 2, 3})
+	film.Splat(2, 1, colorspace.Point{4, 4, 4})
+	film.aovs[AOVDepth][4] = colorspace.Point{7, 7, 7}
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, film.Write(buf))
+
+	read, err := ReadFilm(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, film.Pixels, read.Pixels)
+	assert.Equal(t, 0.25, read.SplatScale)
+	assert.Equal(t, colorspace.Point{4, 4, 4}, read.SplatAt(5))
+	assert.Equal(t, []AOV{AOVDepth}, read.AOVs())
+	assert.Equal(t, film.AOV(AOVDepth, colorspace.SRGB), read.AOV(AOVDepth, colorspace.SRGB))
+
+	_, err = ReadFilm(bytes.NewReader([]byte("GRMFILM0")))
+	assert.Error(t, err)
+
+	// truncated
+	buf.Reset()
+	film.Write(buf)
+	_, err = ReadFilm(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	assert.Error(t, err)
+}
+
+func TestFilm_Add(t *testing.T) {
+	a := NewFilm(2, 1)
+	a.EnableAOVs(AOVDepth, AOVNormal)
+	a.Pixels[0].AddColor(colorspace.Point{1, 1, 1})
+	a.Pixels[0].AddColor(colorspace.Point{1, 1, 1})
+	a.Pixels[1].AddColor(colorspace.Point{1, 1, 1})
+	a.Pixels[1].AddColor(colorspace.Point{1, 1, 1})
+	a.aovs[AOVDepth][0] = colorspace.Point{2, 2, 2}
+	a.SplatScale = 0.5
+	a.Splat(0, 0, colorspace.Point{2, 2, 2})
+
+	b := NewFilm(2, 1)
+	b.EnableAOVs(AOVDepth)
+	b.Pixels[0].AddColor(colorspace.Point{4, 4, 4})
+	b.Pixels[0].AddColor(colorspace.Point{4, 4, 4})
+	b.aovs[AOVDepth][0] = colorspace.Point{4, 4, 4}
+
+	assert.NoError(t, a.Add(b))
+	assert.Equal(t, uint64(4), a.Pixels[0].Samples)
+	assert.Equal(t, colorspace.Point{2.5 + 2.0/3, 2.5 + 2.0/3, 2.5 + 2.0/3}, a.Color(0))
+	assert.Equal(t, 1.0, a.SplatScale)
+	assert.Equal(t, []AOV{AOVDepth}, a.AOVs())
+	depth, _, _ := a.AOV(AOVDepth, colorspace.SRGB).At(0, 0)
+	assert.Equal(t, 1.5, depth)
+
+	assert.Error(t, a.Add(NewFilm(1, 2)))
+}
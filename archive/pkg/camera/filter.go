@@ -0,0 +1,138 @@
+package camera
+
+import "math"
+
+// Filter is a pixel reconstruction filter. Each sample contributes to every
+// pixel whose center is within the filter's radius, weighted by the filter
+// evaluated at the offset from the sample to the pixel center. Final pixel
+// colors are the weighted averages.
+//
+// All the filters here are separable, i.e. products of a 1D filter in x and y.
+//
+// https://www.pbr-book.org/3ed-2018/Sampling_and_Reconstruction/Image_Reconstruction
+type Filter interface {
+	// Radius is how far, in pixels, the filter extends from its center in x
+	// and y.
+	Radius() float64
+
+	// Eval returns the filter's weight at offset (x, y) from its center.
+	Eval(x, y float64) float64
+}
+
+// BoxFilter weighs all samples within its radius equally. With a radius of
+// 0.5, each sample only contributes to the pixel it's in.
+type BoxFilter struct {
+	radius float64
+}
+
+// NewBoxFilter creates a box filter. Panics if radius isn't positive.
+func NewBoxFilter(radius float64) *BoxFilter {
+	checkRadius(radius)
+	return &BoxFilter{radius}
+}
+
+func (f *BoxFilter) Radius() float64 {
+	return f.radius
+}
+
+func (f *BoxFilter) Eval(x, y float64) float64 {
+	if math.Abs(x) > f.radius || math.Abs(y) > f.radius {
+		return 0
+	}
+	return 1
+}
+
+// TentFilter (or triangle filter) falls off linearly from its center.
+type TentFilter struct {
+	radius float64
+}
+
+// NewTentFilter creates a tent filter. Panics if radius isn't positive.
+func NewTentFilter(radius float64) *TentFilter {
+	checkRadius(radius)
+	return &TentFilter{radius}
+}
+
+func (f *TentFilter) Radius() float64 {
+	return f.radius
+}
+
+func (f *TentFilter) Eval(x, y float64) float64 {
+	return math.Max(0, f.radius-math.Abs(x)) * math.Max(0, f.radius-math.Abs(y))
+}
+
+// GaussianFilter is a Gaussian bump with falloff rate alpha, shifted down so
+// it goes to 0 at its radius.
+//
+// https://www.pbr-book.org/3ed-2018/Sampling_and_Reconstruction/Image_Reconstruction#GaussianFilter
+type GaussianFilter struct {
+	radius, alpha float64
+	edge          float64
+}
+
+// NewGaussianFilter creates a Gaussian filter. Panics if radius isn't
+// positive.
+func NewGaussianFilter(radius, alpha float64) *GaussianFilter {
+	checkRadius(radius)
+	return &GaussianFilter{
+		radius: radius,
+		alpha:  alpha,
+		edge:   math.Exp(-alpha * radius * radius),
+	}
+}
+
+func (f *GaussianFilter) Radius() float64 {
+	return f.radius
+}
+
+func (f *GaussianFilter) Eval(x, y float64) float64 {
+	return f.gaussian(x) * f.gaussian(y)
+}
+
+func (f *GaussianFilter) gaussian(d float64) float64 {
+	return math.Max(0, math.Exp(-f.alpha*d*d)-f.edge)
+}
+
+// MitchellFilter is the Mitchell-Netravali cubic filter. It has negative lobes,
+// which sharpen edges; B = C = 1/3 is the recommended tradeoff between ringing
+// and blurring.
+//
+// https://www.pbr-book.org/3ed-2018/Sampling_and_Reconstruction/Image_Reconstruction#MitchellFilter
+type MitchellFilter struct {
+	radius, b, c float64
+}
+
+// NewMitchellFilter creates a Mitchell-Netravali filter. Panics if radius
+// isn't positive.
+func NewMitchellFilter(radius, b, c float64) *MitchellFilter {
+	checkRadius(radius)
+	return &MitchellFilter{radius, b, c}
+}
+
+func (f *MitchellFilter) Radius() float64 {
+	return f.radius
+}
+
+func (f *MitchellFilter) Eval(x, y float64) float64 {
+	return f.mitchell(x/f.radius) * f.mitchell(y/f.radius)
+}
+
+// mitchell evaluates the 1D filter, which spans [-2, 2], at 2x.
+func (f *MitchellFilter) mitchell(x float64) float64 {
+	b, c := f.b, f.c
+	x = math.Abs(2 * x)
+	switch {
+	case x > 2:
+		return 0
+	case x > 1:
+		return ((-b-6*c)*x*x*x + (6*b+30*c)*x*x + (-12*b-48*c)*x + (8*b + 24*c)) / 6
+	default:
+		return ((12-9*b-6*c)*x*x*x + (-18+12*b+6*c)*x*x + (6 - 2*b)) / 6
+	}
+}
+
+func checkRadius(radius float64) {
+	if !(radius > 0) {
+		panic("Filter radius must be positive")
+	}
+}
@@ -0,0 +1,38 @@
+package camera
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		center float64
+	}{
+		{"box", NewBoxFilter(0.5), 1},
+		{"tent", NewTentFilter(2), 4},
+		{"gaussian", NewGaussianFilter(1.5, 2), 0.9779054167276021},
+		{"mitchell", NewMitchellFilter(2, 1.0/3, 1.0/3), 0.7901234567901234},
+	}
+	for _, test := range tests {
+		f := test.filter
+		r := f.Radius()
+		assert.InDelta(t, test.center, f.Eval(0, 0), 1e-6, test.name)
+
+		// symmetric
+		assert.InDelta(t, f.Eval(0.3, -0.2), f.Eval(-0.3, 0.2), 1e-12, test.name)
+		assert.InDelta(t, f.Eval(0.3, 0.2), f.Eval(0.2, 0.3), 1e-12, test.name)
+
+		// nothing outside the radius
+		assert.Equal(t, 0.0, f.Eval(r+0.01, 0), test.name)
+		assert.Equal(t, 0.0, f.Eval(0, -r-0.01), test.name)
+	}
+
+	// Mitchell's negative lobes
+	assert.Less(t, NewMitchellFilter(2, 1.0/3, 1.0/3).Eval(1.5, 0), 0.0)
+
+	assert.Panics(t, func() { NewBoxFilter(0) })
+}
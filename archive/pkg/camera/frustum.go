@@ -0,0 +1,60 @@
+package camera
+
+import "github.com/gmhorn/gremlin/archive/pkg/geo"
+
+// Frustum is the (infinite) viewing pyramid of a camera: the region of space
+// that primary rays can reach. It's bounded by four planes through the eye,
+// with normals pointing inwards.
+type Frustum struct {
+	Eye    geo.Vec
+	Planes [4]geo.Vec
+}
+
+// Frustum returns the camera's viewing frustum in world space.
+func (c *Perspective) Frustum() *Frustum {
+	w := c.aspectRatio * c.tanHalfFOV
+	h := c.tanHalfFOV
+
+	// screen corners in camera space, in order around the screen
+	corners := [4]geo.Vec{
+		c.camToWorld.MultVec(geo.V(-w, h, -1)),
+		c.camToWorld.MultVec(geo.V(w, h, -1)),
+		c.camToWorld.MultVec(geo.V(w, -h, -1)),
+		c.camToWorld.MultVec(geo.V(-w, -h, -1)),
+	}
+	forward := c.camToWorld.MultVec(geo.V(0, 0, -1))
+
+	f := &Frustum{Eye: c.eye}
+	for i := range corners {
+		n := corners[i].Cross(corners[(i+1)%4])
+		if n.Dot(forward) < 0 {
+			n = n.Reverse()
+		}
+		f.Planes[i] = n
+	}
+	return f
+}
+
+// Overlaps returns true if the bounds are at least partially inside the
+// frustum. It's conservative: some bounds near the frustum's edges may be
+// reported as overlapping when they're actually just outside.
+func (f *Frustum) Overlaps(b *geo.Bounds) bool {
+	for _, n := range f.Planes {
+		// the corner of the box furthest along the plane normal
+		p := geo.Vec{X: b[0].X, Y: b[0].Y, Z: b[0].Z}
+		if n.X >= 0 {
+			p.X = b[1].X
+		}
+		if n.Y >= 0 {
+			p.Y = b[1].Y
+		}
+		if n.Z >= 0 {
+			p.Z = b[1].Z
+		}
+
+		if n.Dot(p.Minus(f.Eye)) < 0 {
+			return false
+		}
+	}
+	return true
+}
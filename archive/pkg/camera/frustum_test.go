@@ -0,0 +1,23 @@
+package camera
+
+import (
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrustum_Overlaps(t *testing.T) {
+	cam := NewPerspective(2, 90).MoveTo(geo.V(0, 0, 5)).PointAt(geo.Origin)
+	frustum := cam.Frustum()
+
+	box := func(center geo.Vec) *geo.Bounds {
+		return geo.NewBounds(center.Minus(geo.V(0.5, 0.5, 0.5)), center.Plus(geo.V(0.5, 0.5, 0.5)))
+	}
+
+	assert.True(t, frustum.Overlaps(box(geo.Origin)), "in front")
+	assert.True(t, frustum.Overlaps(box(geo.V(10, 0, 0))), "straddling right edge")
+	assert.False(t, frustum.Overlaps(box(geo.V(0, 0, 10))), "behind")
+	assert.False(t, frustum.Overlaps(box(geo.V(0, 8, 0))), "above")
+	assert.False(t, frustum.Overlaps(box(geo.V(-20, 0, 0))), "left")
+}
@@ -6,17 +6,25 @@ import (
 	"github.com/gmhorn/gremlin/archive/pkg/geo"
 )
 
-// Perspective is one of the most basic camera models. It simulates a camera
-// with vanishingly small aperture and no lense effects (e.g. all points in
-// space are in focus).
+// Perspective is one of the most basic camera models. By default it simulates
+// a camera with vanishingly small aperture and no lense effects (e.g. all
+// points in space are in focus). Setting a Lens turns it into a thin-lens
+// camera with depth of field.
 //
 // https://www.scratchapixel.com/lessons/3d-basic-rendering/ray-tracing-generating-camera-rays/generating-camera-rays
+// https://www.pbr-book.org/3ed-2018/Camera_Models/Projective_Camera_Models#TheThinLensModelandDepthofField
 type Perspective struct {
 	aspectRatio float64
 	tanHalfFOV  float64
 
 	eye, target geo.Vec
 	camToWorld  *geo.Mtx
+	motion      *geo.AnimatedTransform
+
+	shutterOpen, shutterClose float64
+
+	lensRadius, focusDistance float64
+	lensSampling              bool
 }
 
 // NewPerspective generates a new perspective camera. It is initialized at the
@@ -28,6 +36,9 @@ func NewPerspective(aspectRatio, fov float64) *Perspective {
 		tanHalfFOV:  math.Tan(fov * 0.5),
 		eye:         geo.Origin,
 		target:      geo.V(0, 0, -1),
+
+		focusDistance: 1,
+		lensSampling:  true,
 	}
 
 	c.recalculateLookMatrix()
@@ -48,6 +59,47 @@ func (c *Perspective) PointAt(location geo.Vec) *Perspective {
 	return c
 }
 
+// Shutter sets the interval the camera's shutter is open for. Rays generated
+// by TimedRay are distributed across this interval. Defaults to [0, 0], i.e.
+// an instantaneous exposure at time 0.
+func (c *Perspective) Shutter(open, close float64) *Perspective {
+	c.shutterOpen = open
+	c.shutterClose = close
+	return c
+}
+
+// Motion makes the camera move while the shutter is open, for motion blur:
+// rays are generated from the camera-to-world transform at their time, in
+// place of the one set up by MoveTo and PointAt. The keyframes are typically
+// built with geo.LookAt, e.g.
+//
+//	geo.NewAnimatedTransform(geo.LookAt(eye1, target1, geo.YAxis), 0, geo.LookAt(eye2, target2, geo.YAxis), 1)
+//
+// Nil turns motion off again. Frustum still uses the static transform.
+func (c *Perspective) Motion(camToWorld *geo.AnimatedTransform) *Perspective {
+	c.motion = camToWorld
+	return c
+}
+
+// Lens gives the camera a thin lens with the given aperture radius, focused at
+// the given distance from the camera. Points at that distance are sharp, and
+// others are blurred more the further they are from it, and the larger the
+// aperture. An aperture radius of 0 (the default) is a pinhole camera.
+func (c *Perspective) Lens(apertureRadius, focusDistance float64) *Perspective {
+	c.lensRadius = apertureRadius
+	c.focusDistance = focusDistance
+	return c
+}
+
+// LensSampling turns sampling of the lens on or off, without changing the
+// lens. With it off, the camera behaves like a pinhole: everything is in
+// focus, and the image is otherwise identical (the renderer doesn't scale
+// exposure by aperture), which is handy for A/B comparisons.
+func (c *Perspective) LensSampling(enabled bool) *Perspective {
+	c.lensSampling = enabled
+	return c
+}
+
 // Ray generates a ray from the normalized device coordinates (NDC) u and v.
 //
 // The NDC (u, v) of a specific pixel (x, y) is a function of the overall film
@@ -65,8 +117,24 @@ func (c *Perspective) PointAt(location geo.Vec) *Perspective {
 //
 //	u, v := (x+rand.Float64())/W, (y+rand(Float64())/H
 //
+// The ray's time is the moment the shutter opens, and it starts from the
+// center of the lens.
+//
 // https://www.scratchapixel.com/lessons/3d-basic-rendering/ray-tracing-generating-camera-rays/generating-camera-rays
 func (c *Perspective) Ray(u, v float64) *geo.Ray {
+	return c.TimedRay(u, v, 0)
+}
+
+// TimedRay is like Ray, but the ray's time is chosen within the shutter
+// interval by s, which should be in the range [0, 1). Passing a uniform random
+// value for s gives a box-shaped shutter.
+func (c *Perspective) TimedRay(u, v, s float64) *geo.Ray {
+	return c.LensRay(u, v, s, 0.5, 0.5)
+}
+
+// LensRay is like TimedRay, but also chooses where on the lens the ray starts
+// from by lu and lv, which should be uniform values in the range [0, 1).
+func (c *Perspective) LensRay(u, v, s, lu, lv float64) *geo.Ray {
 	// In camera space, the camera is centered a the origin and facing down
 	// the negative-z axis ("into the page"). The screen is centered one
 	// unit down the z-axis at (0, 0, -1)
@@ -82,9 +150,45 @@ func (c *Perspective) Ray(u, v float64) *geo.Ray {
 	// ...and the direction is given by (p-camera_origin) == p-{0, 0, 0} == p
 	//
 	// All that remains is to convert that direction to world space.
-	dir := c.camToWorld.MultVec(p)
+	time := c.shutterOpen + s*(c.shutterClose-c.shutterOpen)
+	camToWorld, eye := c.camToWorld, c.eye
+	if c.motion != nil {
+		camToWorld = c.motion.At(time)
+		eye = camToWorld.MultPoint(geo.Origin)
+	}
+	if c.lensRadius == 0 || !c.lensSampling {
+		return geo.NewRayAt(eye, camToWorld.MultVec(p), time)
+	}
 
-	return geo.NewRay(c.eye, dir)
+	// For a thin lens, all rays through the lens from p meet again on the
+	// plane of focus at z == -focusDistance. So pick a point on the lens
+	// and aim it at the point where the pinhole ray meets that plane.
+	dx, dy := concentricDisk(lu, lv)
+	lens := geo.V(dx*c.lensRadius, dy*c.lensRadius, 0)
+	focus := p.Scale(c.focusDistance)
+
+	origin := camToWorld.MultPoint(lens)
+	dir := camToWorld.MultVec(focus.Minus(lens))
+	return geo.NewRayAt(origin, dir, time)
+}
+
+// concentricDisk maps uniform values in [0, 1)^2 to the unit disk, preserving
+// area and keeping nearby points nearby (so stratification carries over).
+//
+// https://www.pbr-book.org/3ed-2018/Monte_Carlo_Integration/2D_Sampling_with_Multidimensional_Transformations#SamplingaUnitDisk
+func concentricDisk(u1, u2 float64) (x, y float64) {
+	ox, oy := 2*u1-1, 2*u2-1
+	if ox == 0 && oy == 0 {
+		return 0, 0
+	}
+
+	var r, theta float64
+	if math.Abs(ox) > math.Abs(oy) {
+		r, theta = ox, (math.Pi/4)*(oy/ox)
+	} else {
+		r, theta = oy, math.Pi/2-(math.Pi/4)*(ox/oy)
+	}
+	return r * math.Cos(theta), r * math.Sin(theta)
 }
 
 func (c *Perspective) recalculateLookMatrix() {
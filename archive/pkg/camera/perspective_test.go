@@ -0,0 +1,77 @@
+package camera
+
+import (
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPerspective_LensRay(t *testing.T) {
+	cam := NewPerspective(1, 90).MoveTo(geo.V(1, 2, 3)).PointAt(geo.V(1, 2, 0))
+	pinhole := cam.Ray(0.3, 0.6)
+
+	// camera looks down -z, so the plane of focus is at z == -1
+	cam.Lens(0.5, 4)
+	focus := pinhole.At(-4 / pinhole.Dir.Z)
+
+	for _, lens := range [][2]float64{{0, 0}, {0.5, 0.5}, {0.9, 0.1}, {0.25, 0.75}} {
+		ray := cam.LensRay(0.3, 0.6, 0, lens[0], lens[1])
+
+		// starts on the lens...
+		assert.InDelta(t, 3, ray.Origin.Z, 1e-12)
+		assert.LessOrEqual(t, ray.Origin.Minus(geo.V(1, 2, 3)).Len(), 0.5+1e-12)
+
+		// ...and passes through the same point on the plane of focus
+		hit := ray.At((focus.Z - ray.Origin.Z) / ray.Dir.Z)
+		assert.InDelta(t, focus.X, hit.X, 1e-12)
+		assert.InDelta(t, focus.Y, hit.Y, 1e-12)
+	}
+
+	// center of the lens is the pinhole ray
+	assert.Equal(t, pinhole.Origin, cam.TimedRay(0.3, 0.6, 0).Origin)
+
+	// turning lens sampling off gives pinhole rays back
+	cam.LensSampling(false)
+	assert.Equal(t, pinhole, cam.LensRay(0.3, 0.6, 0, 0.9, 0.1))
+}
+
+func TestPerspective_Motion(t *testing.T) {
+	start := geo.LookAt(geo.V(0, 0, 0), geo.V(0, 0, -1), geo.YAxis)
+	end := geo.LookAt(geo.V(2, 0, 0), geo.V(2, 0, -1), geo.YAxis)
+	cam := NewPerspective(1, 90).Shutter(0, 1).Motion(geo.NewAnimatedTransform(start, 0, end, 1))
+
+	ray := cam.TimedRay(0.5, 0.5, 0.5)
+	assertVecEqual(t, geo.V(1, 0, 0), ray.Origin, 1e-9)
+	assertVecEqual(t, geo.V(0, 0, -1), ray.Dir, 1e-9)
+	assert.Equal(t, 0.5, ray.Time)
+
+	// the lens moves with the camera
+	cam.Lens(0.5, 2)
+	ray = cam.LensRay(0.5, 0.5, 1, 0.5, 0.5)
+	assertVecEqual(t, geo.V(2, 0, 0), ray.Origin, 1e-9)
+
+	// without motion, the camera stays put
+	cam.Motion(nil)
+	assertVecEqual(t, geo.V(0, 0, 0), cam.TimedRay(0.5, 0.5, 1).Origin, 1e-9)
+}
+
+func assertVecEqual(t *testing.T, expected, actual geo.Vec, epsilon float64) {
+	dist := expected.Minus(actual).Len()
+	assert.LessOrEqualf(t, dist, epsilon,
+		"Expected close to %s, got %s (distance %g)", expected, actual, dist)
+}
+
+func TestConcentricDisk(t *testing.T) {
+	x, y := concentricDisk(0.5, 0.5)
+	assert.Equal(t, 0.0, x)
+	assert.Equal(t, 0.0, y)
+
+	for _, u := range [][2]float64{{0, 0}, {1, 0.5}, {0.5, 0}, {0.9, 0.7}} {
+		x, y := concentricDisk(u[0], u[1])
+		assert.LessOrEqual(t, x*x+y*y, 1+1e-12)
+	}
+	x, y = concentricDisk(1, 0.5)
+	assert.InDelta(t, 1, x, 1e-12)
+	assert.InDelta(t, 0, y, 1e-12)
+}
@@ -0,0 +1,91 @@
+package camera
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// Histogram counts the film's pixels by luminance (the Y of their colors), in
+// equal bins between Min and Max. Pixels outside the range are counted in
+// Below and Above, so clipping shows up. With Log set the bins are equal in
+// log2 luminance instead, i.e. in stops, which suits exposure.
+type Histogram struct {
+	Min, Max     float64
+	Log          bool
+	Counts       []int
+	Below, Above int
+}
+
+// Histogram returns the histogram of the film's luminance in the given number
+// of bins from min to max. Log needs min to be positive.
+func (f *Film) Histogram(bins int, min, max float64, log bool) *Histogram {
+	if bins < 1 || min >= max || (log && min <= 0) {
+		panic("invalid histogram range")
+	}
+	h := &Histogram{Min: min, Max: max, Log: log, Counts: make([]int, bins)}
+	for i := range f.Pixels {
+		l := f.Color(i)[1]
+		switch {
+		case l < min || math.IsNaN(l):
+			h.Below++
+		case l > max:
+			h.Above++
+		default:
+			t := (l - min) / (max - min)
+			if log {
+				t = math.Log2(l/min) / math.Log2(max/min)
+			}
+			h.Counts[int(math.Min(t*float64(bins), float64(bins-1)))]++
+		}
+	}
+	return h
+}
+
+// Percentiles returns the luminance below which the fraction p (in [0, 1]) of
+// the film's pixels fall, for each p: e.g. 0.5 for the median, or 0.99 for a
+// highlight level to expose for. Values are interpolated between pixels.
+func (f *Film) Percentiles(ps ...float64) []float64 {
+	lum := make([]float64, len(f.Pixels))
+	for i := range f.Pixels {
+		lum[i] = f.Color(i)[1]
+	}
+	sort.Float64s(lum)
+
+	out := make([]float64, len(ps))
+	for i, p := range ps {
+		x := math.Max(0, math.Min(1, p)) * float64(len(lum)-1)
+		lo := int(x)
+		hi := lo
+		if lo+1 < len(lum) {
+			hi = lo + 1
+		}
+		out[i] = lum[lo] + (x-float64(lo))*(lum[hi]-lum[lo])
+	}
+	return out
+}
+
+// Waveform returns a waveform monitor view of the film: an image as wide as the
+// film and height pixels tall, where each column plots the luminance of the
+// film's column from 0 at the bottom to max at the top. Brightness shows how
+// many pixels have that luminance, on a log scale so single pixels show.
+// Luminance above max is drawn in the top row.
+func (f *Film) Waveform(height int, max float64) *image.Gray {
+	if height < 1 || max <= 0 {
+		panic("invalid waveform size")
+	}
+	counts := make([]int, f.Width*height)
+	for i := range f.Pixels {
+		x, _ := f.RasterCoords(i)
+		t := math.Max(0, math.Min(1, f.Color(i)[1]/max))
+		row := height - 1 - int(math.Min(t*float64(height), float64(height-1)))
+		counts[row*f.Width+x]++
+	}
+
+	img := image.NewGray(image.Rect(0, 0, f.Width, height))
+	norm := math.Log1p(float64(f.Height))
+	for i, c := range counts {
+		img.Pix[i] = uint8(math.Round(255 * math.Log1p(float64(c)) / norm))
+	}
+	return img
+}
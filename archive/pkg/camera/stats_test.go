@@ -0,0 +1,54 @@
+package camera
+
+import (
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+	"github.com/stretchr/testify/assert"
+)
+
+// rampFilm returns a film whose pixels' luminance goes 0, 1, 2, ... in raster
+// order.
+func rampFilm(width, height int) *Film {
+	film := NewFilm(width, height)
+	for i := range film.Pixels {
+		film.Pixels[i].AddColor(colorspace.Point{0, float64(i), 0})
+	}
+	return film
+}
+
+func TestFilm_Histogram(t *testing.T) {
+	film := rampFilm(5, 2)
+
+	h := film.Histogram(4, 1, 8, false)
+	assert.Equal(t, []int{2, 2, 2, 2}, h.Counts)
+	assert.Equal(t, 1, h.Below)
+	assert.Equal(t, 1, h.Above)
+
+	// in stops: [1, 2), [2, 4), [4, 8]
+	h = film.Histogram(3, 1, 8, true)
+	assert.Equal(t, []int{1, 2, 5}, h.Counts)
+
+	assert.Panics(t, func() { film.Histogram(3, 0, 8, true) })
+}
+
+func TestFilm_Percentiles(t *testing.T) {
+	film := rampFilm(4, 3)
+	assert.Equal(t, []float64{0, 5.5, 11, 1.1}, film.Percentiles(0, 0.5, 1, 0.1))
+}
+
+func TestFilm_Waveform(t *testing.T) {
+	// every pixel in a column has the same luminance
+	film := NewFilm(3, 4)
+	for i := range film.Pixels {
+		x, _ := film.RasterCoords(i)
+		film.Pixels[i].AddColor(colorspace.Point{0, float64(x), 0})
+	}
+
+	img := film.Waveform(2, 2)
+	assert.Equal(t, 3, img.Bounds().Dx())
+	assert.Equal(t, []uint8{
+		0, 255, 255,
+		255, 0, 0,
+	}, img.Pix)
+}
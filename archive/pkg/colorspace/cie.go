@@ -36,6 +36,41 @@ var CIE1931 = ColorspaceFunc(func(dist spectrum.Distribution) Point {
 	return Point{X / XYZ, Y / XYZ, Z / XYZ}
 })
 
+// CIE1931Reflectance is a Colorspace for reflectance spectra, e.g. surface
+// albedos. It returns CIE 1931 X, Y and Z coordinates of the reflected light
+// under an equal-energy illuminant. Unlike CIE1931 they aren't normalized to
+// chromaticities: they're scaled so that a perfect white reflector (a flat
+// spectrum of 1) has Y = 1, so darker surfaces give smaller values.
+var CIE1931Reflectance = ColorspaceFunc(func(dist spectrum.Distribution) Point {
+	X := 0.0
+	Y := 0.0
+	Z := 0.0
+
+	for i, refl := range spectrum.Sample(dist) {
+		X += refl * cieX[i]
+		Y += refl * cieY[i]
+		Z += refl * cieZ[i]
+	}
+
+	return Point{X / cieYSum, Y / cieYSum, Z / cieYSum}
+})
+
+// CIE1931XYZ is a Colorspace for radiance, e.g. the spectra a renderer
+// traces. It's the same conversion as CIE1931Reflectance, so the values keep
+// the spectrum's intensity: radiance of a flat spectrum of 1 has Y = 1, twice
+// that has Y = 2 and none has Y = 0. This is what a Film's Observer should
+// produce, as opposed to the chromaticities of CIE1931.
+var CIE1931XYZ = CIE1931Reflectance
+
+// cieYSum is the sum of the Y color matching function's samples.
+var cieYSum = func() float64 {
+	sum := 0.0
+	for _, y := range cieY {
+		sum += y
+	}
+	return sum
+}()
+
 var cieX = spectrum.Sampled{
 	0.001368, 0.002236, 0.004243, 0.007650, 0.014310, 0.023190, 0.043510,
 	0.077630, 0.134380, 0.214770, 0.283900, 0.328500, 0.348280, 0.348060,
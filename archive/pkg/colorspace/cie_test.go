@@ -59,3 +59,61 @@ func BenchmarkCIE1931_Convert(b *testing.B) {
 		result = CIE1931.Convert(spectra[i%numSpectra])
 	}
 }
+
+func TestCIE1931Reflectance(t *testing.T) {
+	white := CIE1931Reflectance.Convert(spectrum.Flat(1))
+	assert.InDelta(t, 1, white[1], 1e-9)
+
+	// keeps intensity, unlike CIE1931
+	gray := CIE1931Reflectance.Convert(spectrum.Flat(0.5))
+	assert.InDelta(t, 0.5, gray[1], 1e-9)
+	assert.InDelta(t, white[0]/2, gray[0], 1e-9)
+}
+
+func TestCIE1931XYZ(t *testing.T) {
+	black := CIE1931XYZ.Convert(spectrum.Flat(0))
+	assert.Equal(t, Point{0, 0, 0}, black)
+
+	// keeps intensity, unlike CIE1931
+	bb := spectrum.Sample(spectrum.Blackbody(5000))
+	once := CIE1931XYZ.Convert(bb)
+	twice := CIE1931XYZ.Convert(bb.Scale(2))
+	assert.InEpsilon(t, 2*once[1], twice[1], 1e-12)
+	assert.InEpsilon(t, 2*once[0], twice[0], 1e-12)
+}
+
+func TestStandardIlluminants(t *testing.T) {
+	tests := []struct {
+		name       string
+		spectrum   *spectrum.Sampled
+		whitePoint Illuminant
+	}{
+		{"D65", spectrum.IlluminantD65, IlluminantD65},
+		{"D50", spectrum.IlluminantD50, Illuminant{0.34567, 0.35850}},
+		{"A", spectrum.IlluminantA, Illuminant{0.44757, 0.40745}},
+		{"F1", spectrum.Sample(spectrum.IlluminantF1), Illuminant{0.31310, 0.33727}},
+		{"F2", spectrum.Sample(spectrum.IlluminantF2), Illuminant{0.37208, 0.37529}},
+		{"F3", spectrum.Sample(spectrum.IlluminantF3), Illuminant{0.40910, 0.39430}},
+		{"F4", spectrum.Sample(spectrum.IlluminantF4), Illuminant{0.44018, 0.40329}},
+		{"F5", spectrum.Sample(spectrum.IlluminantF5), Illuminant{0.31379, 0.34531}},
+		{"F6", spectrum.Sample(spectrum.IlluminantF6), Illuminant{0.37790, 0.38835}},
+		{"F7", spectrum.Sample(spectrum.IlluminantF7), Illuminant{0.31292, 0.32933}},
+		{"F8", spectrum.Sample(spectrum.IlluminantF8), Illuminant{0.34588, 0.35875}},
+		{"F9", spectrum.Sample(spectrum.IlluminantF9), Illuminant{0.37417, 0.37281}},
+		{"F11", spectrum.Sample(spectrum.IlluminantF11), Illuminant{0.38052, 0.37713}},
+		{"F12", spectrum.Sample(spectrum.IlluminantF12), Illuminant{0.43695, 0.40441}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			xyz := CIE1931.Convert(test.spectrum)
+			assert.InDelta(t, test.whitePoint.X, xyz[0], 0.001)
+			assert.InDelta(t, test.whitePoint.Y, xyz[1], 0.001)
+		})
+	}
+
+	// F10's table integrates to a white point further from the published
+	// one, mostly in y (see spectrum.IlluminantF10)
+	xyz := CIE1931.Convert(spectrum.Sample(spectrum.IlluminantF10))
+	assert.InDelta(t, 0.34609, xyz[0], 0.0005)
+	assert.InDelta(t, 0.35986, xyz[1], 0.0015)
+}
@@ -0,0 +1,200 @@
+package colorspace
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gmhorn/gremlin/archive/pkg/asset"
+)
+
+// LUT is a color lookup table, the usual way of packaging a grading look or
+// the response of a film stock. A 1D LUT maps each channel separately through
+// a curve of Size entries; a 3D LUT maps whole colors through a Size^3 lattice,
+// so it can also shift hues and saturation. Both interpolate linearly between
+// entries.
+//
+// Inputs are mapped from [DomainMin, DomainMax] onto the table, and clamped to
+// it.
+type LUT struct {
+	Size                 int
+	ThreeD               bool
+	Table                []Point
+	DomainMin, DomainMax Point
+}
+
+// LoadCube loads a LUT from a .cube file.
+func LoadCube(res *asset.Resolver, name string) (*LUT, error) {
+	f, err := res.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lut, err := ReadCube(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return lut, nil
+}
+
+// ReadCube reads a 1D or 3D LUT in the .cube format used by Resolve and most
+// grading tools, as in Adobe's "Cube LUT Specification" (version 1.0). In 3D
+// tables the red index changes fastest.
+func ReadCube(r io.Reader) (*LUT, error) {
+	lut := &LUT{DomainMax: Point{1, 1, 1}}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if err := lut.parseLine(fields); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if lut.Size == 0 {
+		return nil, errors.New("missing LUT_1D_SIZE or LUT_3D_SIZE")
+	}
+	if n := lut.entries(); len(lut.Table) != n {
+		return nil, fmt.Errorf("expected %d entries, got %d", n, len(lut.Table))
+	}
+	for i := range lut.DomainMin {
+		if lut.DomainMax[i] <= lut.DomainMin[i] {
+			return nil, errors.New("empty domain")
+		}
+	}
+	return lut, nil
+}
+
+func (lut *LUT) parseLine(fields []string) error {
+	switch fields[0] {
+	case "TITLE":
+		return nil
+	case "LUT_1D_SIZE", "LUT_3D_SIZE":
+		if lut.Size != 0 {
+			return errors.New("more than one size")
+		}
+		if len(fields) != 2 {
+			return fmt.Errorf("%s needs a size", fields[0])
+		}
+		size, err := strconv.Atoi(fields[1])
+		if err != nil || size < 2 {
+			return fmt.Errorf("bad size %q", fields[1])
+		}
+		lut.Size, lut.ThreeD = size, fields[0] == "LUT_3D_SIZE"
+		return nil
+	case "DOMAIN_MIN":
+		return parsePoint(fields[1:], &lut.DomainMin)
+	case "DOMAIN_MAX":
+		return parsePoint(fields[1:], &lut.DomainMax)
+	case "LUT_1D_INPUT_RANGE", "LUT_3D_INPUT_RANGE":
+		// Resolve's older spelling of the domain, the same for all channels
+		if len(fields) != 3 {
+			return fmt.Errorf("%s needs 2 values", fields[0])
+		}
+		var r [2]float64
+		for i := range r {
+			v, err := strconv.ParseFloat(fields[i+1], 64)
+			if err != nil {
+				return err
+			}
+			r[i] = v
+		}
+		lut.DomainMin, lut.DomainMax = Point{r[0], r[0], r[0]}, Point{r[1], r[1], r[1]}
+		return nil
+	}
+
+	if lut.Size == 0 {
+		return fmt.Errorf("unexpected %q before the size", fields[0])
+	}
+	if len(lut.Table) == lut.entries() {
+		return errors.New("too many entries")
+	}
+	var p Point
+	if err := parsePoint(fields, &p); err != nil {
+		return err
+	}
+	lut.Table = append(lut.Table, p)
+	return nil
+}
+
+// parsePoint parses exactly three numbers into p.
+func parsePoint(fields []string, p *Point) error {
+	if len(fields) != 3 {
+		return fmt.Errorf("expected 3 values, got %d", len(fields))
+	}
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return err
+		}
+		p[i] = v
+	}
+	return nil
+}
+
+func (lut *LUT) entries() int {
+	if lut.ThreeD {
+		return lut.Size * lut.Size * lut.Size
+	}
+	return lut.Size
+}
+
+// Apply maps a color through the table.
+func (lut *LUT) Apply(c Point) Point {
+	// continuous table coordinates, and the cell and position within it
+	var i [3]int
+	var t [3]float64
+	n := float64(lut.Size - 1)
+	for ch := range c {
+		x := (c[ch] - lut.DomainMin[ch]) / (lut.DomainMax[ch] - lut.DomainMin[ch]) * n
+		if math.IsNaN(x) {
+			x = 0
+		}
+		x = math.Max(0, math.Min(n, x))
+		i[ch] = int(math.Min(x, n-1))
+		t[ch] = x - float64(i[ch])
+	}
+
+	if !lut.ThreeD {
+		var out Point
+		for ch := range out {
+			a, b := lut.Table[i[ch]][ch], lut.Table[i[ch]+1][ch]
+			out[ch] = a + t[ch]*(b-a)
+		}
+		return out
+	}
+
+	// trilinear interpolation between the cell's corners
+	var out Point
+	for corner := 0; corner < 8; corner++ {
+		w := 1.0
+		var idx [3]int
+		for ch := 0; ch < 3; ch++ {
+			if corner&(1<<ch) != 0 {
+				w *= t[ch]
+				idx[ch] = i[ch] + 1
+			} else {
+				w *= 1 - t[ch]
+				idx[ch] = i[ch]
+			}
+		}
+		if w == 0 {
+			continue
+		}
+		p := lut.Table[idx[0]+lut.Size*(idx[1]+lut.Size*idx[2])]
+		out[0] += w * p[0]
+		out[1] += w * p[1]
+		out[2] += w * p[2]
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package colorspace
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// identity3D is the .cube file of an identity 3D LUT of the given size.
+func identity3D(size int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "TITLE \"identity\"\n# comment\nLUT_3D_SIZE %d\n", size)
+	n := float64(size - 1)
+	for bl := 0; bl < size; bl++ {
+		for g := 0; g < size; g++ {
+			for r := 0; r < size; r++ {
+				fmt.Fprintf(&b, "%g %g %g\n", float64(r)/n, float64(g)/n, float64(bl)/n)
+			}
+		}
+	}
+	return b.String()
+}
+
+func TestReadCube_3D(t *testing.T) {
+	lut, err := ReadCube(strings.NewReader(identity3D(5)))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, lut.ThreeD)
+	assert.Len(t, lut.Table, 125)
+
+	// interpolating the identity is exact, and clamps outside the domain
+	tests := []struct {
+		in, expected Point
+	}{
+		{Point{0, 0, 0}, Point{0, 0, 0}},
+		{Point{1, 1, 1}, Point{1, 1, 1}},
+		{Point{0.1, 0.55, 0.9}, Point{0.1, 0.55, 0.9}},
+		{Point{-1, 2, 0.3}, Point{0, 1, 0.3}},
+	}
+	for _, test := range tests {
+		out := lut.Apply(test.in)
+		assert.InDeltaSlice(t, test.expected[:], out[:], 1e-12, "%v", test.in)
+	}
+}
+
+func TestReadCube_1D(t *testing.T) {
+	// inverts each channel, over a domain of [0, 2]
+	lut, err := ReadCube(strings.NewReader(`
+LUT_1D_SIZE 3
+LUT_1D_INPUT_RANGE 0 2
+1 1 1
+0.5 0.5 0.5
+0 0 0
+`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.False(t, lut.ThreeD)
+	out := lut.Apply(Point{0, 0.5, 2})
+	assert.InDeltaSlice(t, []float64{1, 0.75, 0}, out[:], 1e-12)
+}
+
+func TestReadCube_Errors(t *testing.T) {
+	tests := []struct {
+		name, cube, err string
+	}{
+		{"NoSize", "0 0 0\n", "before the size"},
+		{"Short", "LUT_1D_SIZE 3\n0 0 0\n1 1 1\n", "expected 3 entries, got 2"},
+		{"Long", "LUT_1D_SIZE 2\n0 0 0\n1 1 1\n1 1 1\n", "line 4: too many entries"},
+		{"BadSize", "LUT_3D_SIZE 1\n", "bad size"},
+		{"BadValue", "LUT_1D_SIZE 2\n0 0 x\n1 1 1\n", "line 2"},
+		{"Domain", "LUT_1D_SIZE 2\nDOMAIN_MIN 1 1 1\n0 0 0\n1 1 1\n", "empty domain"},
+		{"Empty", "", "missing LUT_1D_SIZE"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ReadCube(strings.NewReader(test.cube))
+			assert.ErrorContains(t, err, test.err)
+		})
+	}
+}
@@ -44,12 +44,8 @@ func (cs *RGB) Convert(dist spectrum.Distribution) Point {
 //	https://www.fourmilab.ch/documents/specrend/
 //	https://www.fourmilab.ch/documents/specrend/specrend.c
 func (cs *RGB) ConvertXYZ(xyz Point) Point {
-	rgb := Point{}
-	for i := 0; i < 3; i++ {
-		for j := 0; j < 3; j++ {
-			rgb[i] += cs.m[i][j] * xyz[j]
-		}
-
+	rgb := cs.Linear(xyz)
+	for i := range rgb {
 		rgb[i] = cs.gamma(rgb[i])
 	}
 
@@ -71,6 +67,20 @@ func (cs *RGB) ConvertXYZ(xyz Point) Point {
 	return rgb
 }
 
+// Linear converts CIE 1931 X, Y, Z chromaticities to linear red, green, blue
+// values. Unlike ConvertXYZ, no gamma correction, gamut mapping or clamping is
+// done, so values can be negative or greater than 1. This is what HDR image
+// formats want.
+func (cs *RGB) Linear(xyz Point) Point {
+	rgb := Point{}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			rgb[i] += cs.m[i][j] * xyz[j]
+		}
+	}
+	return rgb
+}
+
 // SRGB is a standard color space widely useful for display on monitors. Note
 // that its name is properly rendered "sRGB" but Go naming conventions require
 // the initial "s" to be capitalized.
@@ -84,38 +94,177 @@ var SRGB = RGB{
 		{-0.9692660, +1.8760108, +0.0415560},
 		{+0.0556434, -0.2040259, +1.0572252},
 	},
-	gamma: func(v float64) float64 {
-		if v <= 0.0031308 {
-			return 12.92 * v
-		}
-		return 1.055*math.Pow(v, 0.41667) - 0.055
-	},
+	gamma: srgbGamma,
+}
+
+// Other standard RGB color spaces, built from their models. LinearSRGB is sRGB
+// without its transfer function, and ACEScg is linear too, as it's meant for
+// rendering and compositing rather than display.
+var (
+	LinearSRGB = ModelLinearSRGB.RGB()
+	ACEScg     = ModelACEScg.RGB()
+	Rec2020    = ModelRec2020.RGB()
+	DisplayP3  = ModelDisplayP3.RGB()
+	AdobeRGB   = ModelAdobeRGB.RGB()
+)
+
+// srgbGamma is the sRGB transfer function.
+// https://en.wikipedia.org/wiki/SRGB#Transfer_function_(%22gamma%22)
+func srgbGamma(v float64) float64 {
+	if v <= 0.0031308 {
+		return 12.92 * v
+	}
+	return 1.055*math.Pow(v, 0.41667) - 0.055
 }
 
-// Illuminant are the normalized chromaticity coordinates of an illuminant
-// white point.
+// rec2020Gamma is the Rec. 2020 transfer function, which is Rec. 709's with
+// more precise constants.
+// https://en.wikipedia.org/wiki/Rec._2020#Transfer_characteristics
+func rec2020Gamma(v float64) float64 {
+	const alpha, beta = 1.09929682680944, 0.018053968510807
+	if v < beta {
+		return 4.5 * v
+	}
+	return alpha*math.Pow(v, 0.45) - (alpha - 1)
+}
+
+// adobeRGBGamma is the Adobe RGB (1998) transfer function, a pure power of
+// 563/256, or about 2.2.
+func adobeRGBGamma(v float64) float64 {
+	if v <= 0 {
+		return v
+	}
+	return math.Pow(v, 256.0/563)
+}
+
+// Illuminant are the normalized (x, y) chromaticity coordinates of a color,
+// typically an illuminant white point or an RGB primary.
 // https://en.wikipedia.org/wiki/Standard_illuminant
-// type Illuminant struct {
-// 	X, Y float64
-// }
+type Illuminant struct {
+	X, Y float64
+}
 
 // White points of standard illuminants.
-// var IlluminantD65 = Illuminant{0.31271, 0.32902}
-// var IlluminantC   = Illuminant{0.31006, 0.31616}
-// var IlluminantE   = Illuminant{0.33333, 0.33333}
+var (
+	IlluminantD65 = Illuminant{0.31271, 0.32902}
+	IlluminantC   = Illuminant{0.31006, 0.31616}
+	IlluminantE   = Illuminant{0.33333, 0.33333}
+
+	// IlluminantACES is the white point of the ACES color spaces, close to
+	// D60.
+	IlluminantACES = Illuminant{0.32168, 0.33767}
+)
 
-// Model represents an instance of an RGB color model.
+// xyz returns the XYZ coordinates of the chromaticity, scaled to Y == 1.
+func (i Illuminant) xyz() [3]float64 {
+	return [3]float64{i.X / i.Y, 1, (1 - i.X - i.Y) / i.Y}
+}
+
+// Model represents an instance of an RGB color model: the chromaticities of
+// its red, green and blue primaries and its white point, plus the gamma
+// (transfer) function. Use RGB to get a usable Colorspace from it.
 // https://en.wikipedia.org/wiki/RGB_color_spaces
-// type Model struct {
-// 	Red, Green, Blue, White Illuminant
-// 	Gamma                   func(float64) float64
-// }
-
-// Standard color spaces
-// var (
-// 	ModelSRGB = Model{
-// 		Red:   Illuminant{0.64, 0.33},
-// 		Green: Illuminant{0.3, 0.6},
-// 		Blue:  Illuminant{0.15, 0.06},
-// 		White: IlluminantD65}
-// )
+type Model struct {
+	Red, Green, Blue, White Illuminant
+	Gamma                   func(float64) float64
+}
+
+// Standard color models. Custom color spaces can be defined the same way, and
+// built with Model.RGB.
+var (
+	ModelSRGB = Model{
+		Red:   Illuminant{0.64, 0.33},
+		Green: Illuminant{0.3, 0.6},
+		Blue:  Illuminant{0.15, 0.06},
+		White: IlluminantD65,
+		Gamma: srgbGamma,
+	}
+	ModelLinearSRGB = Model{
+		Red:   ModelSRGB.Red,
+		Green: ModelSRGB.Green,
+		Blue:  ModelSRGB.Blue,
+		White: IlluminantD65,
+	}
+	// https://en.wikipedia.org/wiki/Academy_Color_Encoding_System#Color_space
+	ModelACEScg = Model{
+		Red:   Illuminant{0.713, 0.293},
+		Green: Illuminant{0.165, 0.830},
+		Blue:  Illuminant{0.128, 0.044},
+		White: IlluminantACES,
+	}
+	// https://en.wikipedia.org/wiki/Rec._2020
+	ModelRec2020 = Model{
+		Red:   Illuminant{0.708, 0.292},
+		Green: Illuminant{0.170, 0.797},
+		Blue:  Illuminant{0.131, 0.046},
+		White: IlluminantD65,
+		Gamma: rec2020Gamma,
+	}
+	// https://en.wikipedia.org/wiki/DCI-P3#Display_P3
+	ModelDisplayP3 = Model{
+		Red:   Illuminant{0.680, 0.320},
+		Green: Illuminant{0.265, 0.690},
+		Blue:  Illuminant{0.150, 0.060},
+		White: IlluminantD65,
+		Gamma: srgbGamma,
+	}
+	// https://en.wikipedia.org/wiki/Adobe_RGB_color_space
+	ModelAdobeRGB = Model{
+		Red:   Illuminant{0.64, 0.33},
+		Green: Illuminant{0.21, 0.71},
+		Blue:  Illuminant{0.15, 0.06},
+		White: IlluminantD65,
+		Gamma: adobeRGBGamma,
+	}
+)
+
+// RGB builds the RGB colorspace for this model. The XYZ to RGB matrix is
+// computed from the primaries and white point: the primaries' XYZ coordinates
+// form the columns of the RGB to XYZ matrix, scaled so that RGB (1, 1, 1) maps
+// to the white point. That matrix is then inverted.
+//
+// http://www.brucelindbloom.com/index.html?Eqn_RGB_XYZ_Matrix.html
+func (m Model) RGB() RGB {
+	r, g, b := m.Red.xyz(), m.Green.xyz(), m.Blue.xyz()
+	prim := [3][3]float64{
+		{r[0], g[0], b[0]},
+		{r[1], g[1], b[1]},
+		{r[2], g[2], b[2]},
+	}
+
+	w := m.White.xyz()
+	primInv := inv3(prim)
+	var s [3]float64
+	for i := 0; i < 3; i++ {
+		s[i] = primInv[i][0]*w[0] + primInv[i][1]*w[1] + primInv[i][2]*w[2]
+	}
+
+	var rgbToXYZ [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			rgbToXYZ[i][j] = prim[i][j] * s[j]
+		}
+	}
+
+	gamma := m.Gamma
+	if gamma == nil {
+		gamma = func(v float64) float64 { return v }
+	}
+
+	return RGB{m: inv3(rgbToXYZ), gamma: gamma}
+}
+
+// inv3 returns the inverse of a 3x3 matrix via its adjugate.
+func inv3(m [3][3]float64) [3][3]float64 {
+	c00 := m[1][1]*m[2][2] - m[1][2]*m[2][1]
+	c01 := m[1][2]*m[2][0] - m[1][0]*m[2][2]
+	c02 := m[1][0]*m[2][1] - m[1][1]*m[2][0]
+	det := m[0][0]*c00 + m[0][1]*c01 + m[0][2]*c02
+	d := 1 / det
+
+	return [3][3]float64{
+		{c00 * d, (m[0][2]*m[2][1] - m[0][1]*m[2][2]) * d, (m[0][1]*m[1][2] - m[0][2]*m[1][1]) * d},
+		{c01 * d, (m[0][0]*m[2][2] - m[0][2]*m[2][0]) * d, (m[0][2]*m[1][0] - m[0][0]*m[1][2]) * d},
+		{c02 * d, (m[0][1]*m[2][0] - m[0][0]*m[2][1]) * d, (m[0][0]*m[1][1] - m[0][1]*m[1][0]) * d},
+	}
+}
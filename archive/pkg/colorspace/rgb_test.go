@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestSRGB_ConvertXYZ(t *testing.T) {
@@ -27,3 +28,69 @@ func TestColors(t *testing.T) {
 	srgb := SRGB.Convert(s)
 	fmt.Println(srgb)
 }
+
+func TestModel_RGB(t *testing.T) {
+	actual := ModelSRGB.RGB()
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			assert.InDelta(t, SRGB.m[i][j], actual.m[i][j], 1e-3)
+		}
+	}
+
+	// the white point should map to equal parts red, green and blue
+	cs := ModelSRGB.RGB()
+	rgb := cs.ConvertXYZ(Point{0.31271, 0.32902, 1 - 0.31271 - 0.32902})
+	assert.InDelta(t, rgb[0], rgb[1], 1e-6)
+	assert.InDelta(t, rgb[1], rgb[2], 1e-6)
+}
+
+func TestModel_RGB_Standard(t *testing.T) {
+	// the luminance of each primary, i.e. the middle row of the RGB to XYZ
+	// matrix
+	tests := []struct {
+		name string
+		cs   RGB
+		y    [3]float64
+	}{
+		{"LinearSRGB", LinearSRGB, [3]float64{0.2126, 0.7152, 0.0722}},
+		{"ACEScg", ACEScg, [3]float64{0.2722, 0.6741, 0.0537}},
+		{"Rec2020", Rec2020, [3]float64{0.2627, 0.6780, 0.0593}},
+		{"DisplayP3", DisplayP3, [3]float64{0.2290, 0.6917, 0.0793}},
+		{"AdobeRGB", AdobeRGB, [3]float64{0.2973, 0.6274, 0.0753}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			toXYZ := inv3(test.cs.m)
+			assert.InDeltaSlice(t, test.y[:], toXYZ[1][:], 1e-3)
+			assert.InDelta(t, 1.0, test.cs.gamma(1), 1e-9)
+		})
+	}
+
+	// Bruce Lindbloom's Adobe RGB matrix
+	adobe := [3][3]float64{
+		{+2.0413690, -0.5649464, -0.3446944},
+		{-0.9692660, +1.8760108, +0.0415560},
+		{+0.0134474, -0.1183897, +1.0154096},
+	}
+	for i := range adobe {
+		assert.InDeltaSlice(t, adobe[i][:], AdobeRGB.m[i][:], 1e-3)
+	}
+}
+
+func TestRec2020Gamma(t *testing.T) {
+	// the two pieces meet
+	const beta = 0.018053968510807
+	assert.InDelta(t, rec2020Gamma(beta-1e-12), rec2020Gamma(beta), 1e-9)
+	assert.InDelta(t, 0.5, rec2020Gamma(0.2599), 1e-3)
+}
+
+func TestFromRGB_RoundTrip(t *testing.T) {
+	// Relative to white, since equal-energy white isn't sRGB white
+	white := SRGB.Linear(CIE1931Reflectance.Convert(spectrum.FromRGB(1, 1, 1)))
+	for _, c := range [][3]float64{{0.8, 0.1, 0.1}, {0.1, 0.8, 0.1}, {0.1, 0.1, 0.8}, {0.5, 0.5, 0.2}, {0.2, 0.3, 0.4}} {
+		rgb := SRGB.Linear(CIE1931Reflectance.Convert(spectrum.FromRGB(c[0], c[1], c[2])))
+		for i := range rgb {
+			assert.InDelta(t, c[i], rgb[i]/white[i], 0.05, "%v", c)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package colorspace
+
+import "github.com/gmhorn/gremlin/archive/pkg/spectrum"
+
+// Sensor is a Colorspace that models a physical camera sensor. Instead of the
+// CIE standard observer, spectra are integrated against the sensor's measured
+// red, green and blue spectral sensitivities, giving "raw" sensor values. A
+// color correction matrix (as found in e.g. DNG metadata) then maps raw values
+// to XYZ, so the result can be used anywhere CIE1931XYZ is, such as a Film's
+// Observer.
+//
+// Rendering through a specific camera's curves reproduces its metamerism and
+// color rendition, which matters for synthetic training data.
+//
+// https://www.gujinwei.org/research/camspec/
+type Sensor struct {
+	red, green, blue *spectrum.Sampled
+	gains            Point
+	toXYZ            [3][3]float64
+}
+
+// NewSensor creates a Sensor from its red, green and blue spectral response
+// curves and the matrix that converts raw (white balanced) sensor values to
+// XYZ. A nil matrix means raw values are passed through as-is.
+func NewSensor(red, green, blue spectrum.Distribution, toXYZ *[3][3]float64) *Sensor {
+	s := &Sensor{
+		red:   spectrum.Sample(red),
+		green: spectrum.Sample(green),
+		blue:  spectrum.Sample(blue),
+		gains: Point{1, 1, 1},
+		toXYZ: [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}},
+	}
+	if toXYZ != nil {
+		s.toXYZ = *toXYZ
+	}
+	return s
+}
+
+// WhiteBalanced returns a copy of this Sensor with per-channel gains chosen so
+// that the given illuminant produces equal raw red, green and blue values.
+func (s *Sensor) WhiteBalanced(illuminant spectrum.Distribution) *Sensor {
+	wb := *s
+	wb.gains = Point{1, 1, 1}
+
+	raw := wb.Raw(illuminant)
+	wb.gains = Point{raw[1] / raw[0], 1, raw[1] / raw[2]}
+	return &wb
+}
+
+// Raw returns the (white balanced) sensor response to the given spectrum.
+func (s *Sensor) Raw(dist spectrum.Distribution) Point {
+	raw := Point{}
+	for i, power := range spectrum.Sample(dist) {
+		raw[0] += power * s.red[i]
+		raw[1] += power * s.green[i]
+		raw[2] += power * s.blue[i]
+	}
+	return Point{raw[0] * s.gains[0], raw[1] * s.gains[1], raw[2] * s.gains[2]}
+}
+
+// Convert returns the XYZ coordinates of the sensor's response to the given
+// spectrum. Like CIE1931XYZ they keep its intensity, with raw values scaled
+// by the same factor, so a sensor with the CIE observer's curves is
+// CIE1931XYZ.
+func (s *Sensor) Convert(dist spectrum.Distribution) Point {
+	raw := s.Raw(dist)
+
+	xyz := Point{}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			xyz[i] += s.toXYZ[i][j] * raw[j]
+		}
+	}
+
+	return xyz.Scale(1 / cieYSum)
+}
@@ -0,0 +1,28 @@
+package colorspace
+
+import (
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSensor_Convert(t *testing.T) {
+	// A "sensor" with the CIE observer's sensitivities is the CIE observer
+	cie := NewSensor(&cieX, &cieY, &cieZ, nil)
+
+	for _, temp := range []float64{2000, 5000, 9500} {
+		expected := CIE1931XYZ.Convert(spectrum.Blackbody(temp))
+		actual := cie.Convert(spectrum.Blackbody(temp))
+		assert.InEpsilonSlice(t, expected[:], actual[:], 1e-12)
+	}
+}
+
+func TestSensor_WhiteBalanced(t *testing.T) {
+	sensor := NewSensor(spectrum.Peak(600, 900), spectrum.Peak(540, 900), spectrum.Peak(460, 900), nil)
+	illum := spectrum.Blackbody(3200)
+
+	raw := sensor.WhiteBalanced(illum).Raw(illum)
+	assert.InEpsilon(t, raw[1], raw[0], 1e-9)
+	assert.InEpsilon(t, raw[1], raw[2], 1e-9)
+}
@@ -0,0 +1,109 @@
+package geo
+
+import "math"
+
+// motionSteps is how many times a moving box is sampled by
+// AnimatedTransform.MotionBounds.
+const motionSteps = 32
+
+// AnimatedTransform interpolates between two keyframed transforms, for motion
+// blur. Each keyframe is decomposed into translation, rotation and scale (see
+// Mtx.Decompose), which are interpolated separately: linearly for translation
+// and scale, and with Slerp for rotation. Interpolating the matrices directly
+// would squash and shear objects partway through a rotation.
+//
+// Before StartTime the transform is the start keyframe, and after EndTime the
+// end keyframe.
+//
+// https://www.pbr-book.org/3ed-2018/Geometry_and_Transformations/Animating_Transformations
+type AnimatedTransform struct {
+	StartTime, EndTime float64
+
+	start, end *Mtx
+	t          [2]Vec
+	r          [2]Quat
+	s          [2]*Mtx
+	animated   bool
+}
+
+// NewAnimatedTransform creates a transform that moves from start at startTime
+// to end at endTime. Panics if endTime is before startTime.
+func NewAnimatedTransform(start *Mtx, startTime float64, end *Mtx, endTime float64) *AnimatedTransform {
+	if endTime < startTime {
+		panic("AnimatedTransform must not end before it starts")
+	}
+
+	a := &AnimatedTransform{
+		StartTime: startTime,
+		EndTime:   endTime,
+		start:     start,
+		end:       end,
+		animated:  *start != *end && endTime > startTime,
+	}
+	a.t[0], a.r[0], a.s[0] = start.Decompose()
+	a.t[1], a.r[1], a.s[1] = end.Decompose()
+	return a
+}
+
+// IsAnimated returns false if the transform is the same at all times.
+func (a *AnimatedTransform) IsAnimated() bool {
+	return a.animated
+}
+
+// At returns the transform at the given time. The keyframe matrices are
+// returned as-is (outside the keyframes' interval, or if the transform isn't
+// animated), so the result must not be modified.
+func (a *AnimatedTransform) At(time float64) *Mtx {
+	if !a.animated || time <= a.StartTime {
+		return a.start
+	}
+	if time >= a.EndTime {
+		return a.end
+	}
+
+	dt := (time - a.StartTime) / (a.EndTime - a.StartTime)
+	t := a.t[0].Scale(1 - dt).Plus(a.t[1].Scale(dt))
+	r := Slerp(dt, a.r[0], a.r[1])
+	s := &Mtx{}
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			s[i][j] = (1-dt)*a.s[0][i][j] + dt*a.s[1][i][j]
+		}
+	}
+	return Compose(t, r, s)
+}
+
+// MotionBounds returns bounds containing b as it's transformed over the whole
+// interval between the keyframes.
+//
+// The transformed box is sampled at a number of times in between, and the
+// result padded by how far a rotating corner can bulge out between two
+// samples. PBRT instead solves for the extrema of each corner's path, which
+// gives tighter bounds; so far, these have been good enough.
+//
+// https://www.pbr-book.org/3ed-2018/Geometry_and_Transformations/Animating_Transformations#BoundingMovingBoundingBoxes
+func (a *AnimatedTransform) MotionBounds(b *Bounds) *Bounds {
+	if !a.animated {
+		return a.start.MultBounds(b)
+	}
+
+	bounds := a.start.MultBounds(b).Union(a.end.MultBounds(b))
+	for i := 1; i < motionSteps; i++ {
+		time := a.StartTime + (a.EndTime-a.StartTime)*float64(i)/motionSteps
+		bounds = bounds.Union(a.At(time).MultBounds(b))
+	}
+
+	// A corner at distance r from the center of rotation strays at most
+	// r(1 - cos(θ/2)) from the chord between two samples θ apart
+	theta := 2 * math.Acos(math.Min(1, math.Abs(a.r[0].Dot(a.r[1])))) / motionSteps
+	radius := 0.0
+	for _, s := range a.s {
+		for i := 0; i < 8; i++ {
+			corner := Vec{b[i&1].X, b[(i>>1)&1].Y, b[(i>>2)&1].Z}
+			radius = math.Max(radius, s.MultVec(corner).Len())
+		}
+	}
+	pad := radius * (1 - math.Cos(theta/2))
+	p := Vec{pad, pad, pad}
+	return &Bounds{bounds[0].Minus(p), bounds[1].Plus(p)}
+}
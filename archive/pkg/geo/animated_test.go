@@ -0,0 +1,57 @@
+package geo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnimatedTransform_At(t *testing.T) {
+	start := Shift(V(0, 0, 0))
+	end := Shift(V(4, 0, 0)).Mult(Rotate(math.Pi/2, YAxis)).Mult(Scale(V(3, 3, 3)))
+	a := NewAnimatedTransform(start, 1, end, 3)
+	assert.True(t, a.IsAnimated())
+
+	assert.Same(t, start, a.At(0))
+	assert.Same(t, start, a.At(1))
+	assert.Same(t, end, a.At(3))
+	assert.Same(t, end, a.At(5))
+
+	// halfway, each part is halfway
+	expected := Shift(V(2, 0, 0)).Mult(Rotate(math.Pi/4, YAxis)).Mult(Scale(V(2, 2, 2)))
+	assertMtxEqual(t, expected, a.At(2), 1e-9)
+
+	// no motion
+	still := NewAnimatedTransform(end, 0, end.Clone(), 1)
+	assert.False(t, still.IsAnimated())
+	assert.Same(t, end, still.At(0.5))
+
+	assert.Panics(t, func() { NewAnimatedTransform(start, 1, end, 0) })
+}
+
+func TestAnimatedTransform_MotionBounds(t *testing.T) {
+	// A box off to the side, swinging half way round the y-axis
+	box := NewBounds(V(2, -1, -1), V(4, 1, 1))
+	a := NewAnimatedTransform(Identity, 0, Shift(V(0, 3, 0)).Mult(Rotate(math.Pi, YAxis)), 1)
+	bounds := a.MotionBounds(box)
+
+	for i := 0; i <= 1000; i++ {
+		b := a.At(float64(i) / 1000).MultBounds(box)
+		for _, p := range b {
+			for axis := 0; axis < 3; axis++ {
+				assert.GreaterOrEqual(t, p.Axis(axis), bounds[0].Axis(axis)-1e-9)
+				assert.LessOrEqual(t, p.Axis(axis), bounds[1].Axis(axis)+1e-9)
+			}
+		}
+	}
+
+	// Rotating x towards -z, the far corners swing through z = -√17, and the
+	// padding only adds a little
+	assert.InDelta(t, -math.Sqrt(17), bounds[0].Z, 0.01)
+	assert.InDelta(t, 4.0, bounds[1].Y, 0.01)
+
+	// Without motion, it's just the transformed bounds
+	still := NewAnimatedTransform(Shift(V(1, 0, 0)), 0, Shift(V(1, 0, 0)), 1)
+	assert.Equal(t, NewBounds(V(3, -1, -1), V(5, 1, 1)), still.MotionBounds(box))
+}
@@ -11,15 +11,96 @@ func NewBounds(p1, p2 Vec) *Bounds {
 	return &Bounds{vecMin(p1, p2), vecMax(p1, p2)}
 }
 
+// Union returns the smallest bounds containing both b and c.
+func (b *Bounds) Union(c *Bounds) *Bounds {
+	return &Bounds{vecMin(b[0], c[0]), vecMax(b[1], c[1])}
+}
+
+// Extend returns the smallest bounds containing both b and the point p.
+func (b *Bounds) Extend(p Vec) *Bounds {
+	return &Bounds{vecMin(b[0], p), vecMax(b[1], p)}
+}
+
+// Centroid returns the center point of the bounds.
+func (b *Bounds) Centroid() Vec {
+	return b[0].Plus(b[1]).Scale(0.5)
+}
+
+// Diagonal returns the vector from the minimum to the maximum point.
+func (b *Bounds) Diagonal() Vec {
+	return b[1].Minus(b[0])
+}
+
+// SurfaceArea returns the total surface area of the six faces of the bounds.
+func (b *Bounds) SurfaceArea() float64 {
+	d := b.Diagonal()
+	return 2 * (d.X*d.Y + d.X*d.Z + d.Y*d.Z)
+}
+
+// MaxExtent returns the index of the longest axis of the bounds (0 for x, 1
+// for y and 2 for z).
+func (b *Bounds) MaxExtent() int {
+	d := b.Diagonal()
+	switch {
+	case d.X > d.Y && d.X > d.Z:
+		return 0
+	case d.Y > d.Z:
+		return 1
+	default:
+		return 2
+	}
+}
+
 // Intersect tests if the ray intersects the bounds. If it does, it returns the
 // two t values in ascending order and the value true. Otherwise it returns
 // false and garbage t values. Always check the returned boolean.
 //
+// Since a ray only extends forward, bounds entirely behind the ray's origin
+// are not intersected. If the origin is inside the bounds, t0 is negative.
+// The bounds are closed, so a ray lying in the plane of a face intersects
+// them.
+//
+// Uses the branchless slab method. The ray's precomputed inverse direction and
+// direction signs pick the near and far planes of each slab directly, and each
+// slab narrows [t0, t1] with a min and a max, without early outs. A ray whose
+// origin is on a slab's plane and which runs parallel to it gives 0 * Inf =
+// NaN for that plane; minf and maxf keep the running value when given a NaN,
+// so that plane doesn't narrow the interval.
+//
 // https://www.scratchapixel.com/lessons/3d-basic-rendering/minimal-ray-tracer-rendering-simple-shapes/ray-box-intersection
 func (b *Bounds) Intersect(ray *Ray) (t0, t1 float64, found bool) {
+	t0, t1 = math.Inf(-1), math.Inf(1)
+
+	t0 = maxf((b[ray.sign[0]].X-ray.Origin.X)*ray.invDir.X, t0)
+	t1 = minf((b[1-ray.sign[0]].X-ray.Origin.X)*ray.invDir.X, t1)
+
+	t0 = maxf((b[ray.sign[1]].Y-ray.Origin.Y)*ray.invDir.Y, t0)
+	t1 = minf((b[1-ray.sign[1]].Y-ray.Origin.Y)*ray.invDir.Y, t1)
+
+	t0 = maxf((b[ray.sign[2]].Z-ray.Origin.Z)*ray.invDir.Z, t0)
+	t1 = minf((b[1-ray.sign[2]].Z-ray.Origin.Z)*ray.invDir.Z, t1)
+
+	found = t0 <= t1 && t1 >= 0
 	return
 }
 
+// minf returns the smaller of a and b, or b if either is NaN. Unlike math.Min
+// it doesn't special-case signed zeros, so it compiles to a plain compare.
+func minf(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxf returns the larger of a and b, or b if either is NaN.
+func maxf(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 // return the vector that is the component-wise minimum of the two vectors
 func vecMin(a, b Vec) Vec {
 	return Vec{
@@ -0,0 +1,84 @@
+package geo
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var benchResultBool bool
+
+func TestBounds_Intersect(t *testing.T) {
+	b := NewBounds(V(-1, -1, -1), V(1, 1, 1))
+
+	tests := []struct {
+		ray    *Ray
+		found  bool
+		t0, t1 float64
+	}{{
+		ray:   NewRay(V(0, 0, 5), V(0, 0, -1)),
+		found: true, t0: 4, t1: 6,
+	}, {
+		ray:   NewRay(V(-5, 0.5, 0.5), V(2, 0, 0)),
+		found: true, t0: 2, t1: 3,
+	}, {
+		ray:   NewRay(V(0, 0, 0), V(0, 1, 0)),
+		found: true, t0: -1, t1: 1,
+	}, {
+		ray:   NewRay(V(-3, -3, 0), V(1, 1, 0)),
+		found: true, t0: 2, t1: 4,
+	}, {
+		ray:   NewRay(V(0, 0, 5), V(0, 0, 1)),
+		found: false,
+	}, {
+		ray:   NewRay(V(0, 3, 5), V(0, 0, -1)),
+		found: false,
+	}, {
+		ray:   NewRay(V(-3, 0, 0), V(1, 2, 0)),
+		found: false,
+	}, {
+		// origin on the x = 1 face, running along it: 0 * Inf is NaN
+		ray:   NewRay(V(1, 0, 5), V(0, 0, -1)),
+		found: true, t0: 4, t1: 6,
+	}, {
+		ray:   NewRay(V(-1, 0, 5), V(0, 0, -1)),
+		found: true, t0: 4, t1: 6,
+	}, {
+		// the same with a negative zero component
+		ray:   NewRay(V(1, 0, 5), V(math.Copysign(0, -1), 0, -1)),
+		found: true, t0: 4, t1: 6,
+	}, {
+		// on the plane of a face, but outside the box
+		ray:   NewRay(V(1, 3, 5), V(0, 0, -1)),
+		found: false,
+	}}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			t0, t1, found := b.Intersect(tt.ray)
+			assert.Equal(t, tt.found, found)
+			if tt.found {
+				assert.InDelta(t, tt.t0, t0, 1e-9)
+				assert.InDelta(t, tt.t1, t1, 1e-9)
+			}
+		})
+	}
+}
+
+func BenchmarkBounds_Intersect_Hit(b *testing.B) {
+	bounds := NewBounds(V(-1, -1, -1), V(1, 1, 1))
+	ray := NewRay(V(-3, 2, 5), V(1, -0.5, -2))
+	for i := 0; i < b.N; i++ {
+		_, _, benchResultBool = bounds.Intersect(ray)
+	}
+}
+
+func BenchmarkBounds_Intersect_Miss(b *testing.B) {
+	bounds := NewBounds(V(-1, -1, -1), V(1, 1, 1))
+	ray := NewRay(V(-3, 2, 5), V(1, 0.5, -2))
+	for i := 0; i < b.N; i++ {
+		_, _, benchResultBool = bounds.Intersect(ray)
+	}
+}
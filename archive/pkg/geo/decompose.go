@@ -0,0 +1,85 @@
+package geo
+
+import "math"
+
+// Decompose splits this (affine) transform into a translation t, rotation r
+// and scale s such that
+//
+//	a == Compose(t, r, s)
+//
+// The rotation is extracted by polar decomposition, so s is the symmetric
+// "stretch" matrix left over: a pure scale for well-behaved matrices, but it
+// may also contain shear. If the matrix contains a reflection, it's folded
+// into s (since a quaternion can't represent one).
+//
+// https://www.pbr-book.org/3ed-2018/Geometry_and_Transformations/Animating_Transformations#AnimatedTransformImplementation
+func (a *Mtx) Decompose() (t Vec, r Quat, s *Mtx) {
+	t = Vec{a[0][3], a[1][3], a[2][3]}
+
+	// Upper 3x3 portion, with the translation removed
+	m := a.Clone()
+	m[0][3], m[1][3], m[2][3] = 0, 0, 0
+	m[3] = [4]float64{0, 0, 0, 1}
+
+	rot := m.polar()
+
+	// a == R*S => S == R^-1 * a == R^T * a
+	s = rot.T().Mult(m)
+	r = QuatFromMtx(rot).Normalize()
+	return
+}
+
+// polar returns the rotation part of the polar decomposition of the upper 3x3
+// portion of the matrix (translation is ignored): the nearest orthonormal
+// matrix, found by repeatedly averaging with the inverse transpose until it
+// converges. Reflections are flipped out, so the result is always a proper
+// rotation.
+func (a *Mtx) polar() *Mtx {
+	rot := a.Clone()
+	rot[0][3], rot[1][3], rot[2][3] = 0, 0, 0
+	rot[3] = [4]float64{0, 0, 0, 1}
+
+	for i := 0; i < 100; i++ {
+		next := &Mtx{}
+		invT := rot.Inv().T()
+		for j := 0; j < 4; j++ {
+			for k := 0; k < 4; k++ {
+				next[j][k] = 0.5 * (rot[j][k] + invT[j][k])
+			}
+		}
+
+		norm := 0.0
+		for j := 0; j < 3; j++ {
+			n := math.Abs(rot[j][0]-next[j][0]) +
+				math.Abs(rot[j][1]-next[j][1]) +
+				math.Abs(rot[j][2]-next[j][2])
+			norm = math.Max(norm, n)
+		}
+		rot = next
+		if norm < 1e-12 {
+			break
+		}
+	}
+
+	if rot.det3() < 0 {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				rot[j][k] = -rot[j][k]
+			}
+		}
+	}
+	return rot
+}
+
+// Compose builds the transform that scales by s, then rotates by r, then
+// translates by t. It's the inverse of Decompose.
+func Compose(t Vec, r Quat, s *Mtx) *Mtx {
+	return Shift(t).Mult(r.Mtx()).Mult(s)
+}
+
+// det3 returns the determinant of the upper 3x3 portion of the matrix.
+func (a *Mtx) det3() float64 {
+	return a[0][0]*(a[1][1]*a[2][2]-a[1][2]*a[2][1]) -
+		a[0][1]*(a[1][0]*a[2][2]-a[1][2]*a[2][0]) +
+		a[0][2]*(a[1][0]*a[2][1]-a[1][1]*a[2][0])
+}
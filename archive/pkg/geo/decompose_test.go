@@ -0,0 +1,50 @@
+package geo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMtx_Decompose(t *testing.T) {
+	shift := V(1, -2, 3)
+	rot := Rotate(math.Pi/3, V(1, 1, 0).Unit())
+	scale := V(2, 3, 0.5)
+	m := Shift(shift).Mult(rot).Mult(Scale(scale))
+
+	tr, r, s := m.Decompose()
+
+	assertVecEqual(t, shift, tr, 1e-9)
+	assertMtxEqual(t, rot, r.Mtx(), 1e-9)
+	assertMtxEqual(t, Scale(scale), s, 1e-9)
+	assertMtxEqual(t, m, Compose(tr, r, s), 1e-9)
+}
+
+func TestMtx_Decompose_Reflection(t *testing.T) {
+	m := Rotate(0.5, ZAxis).Mult(Scale(V(-1, 2, 2)))
+
+	tr, r, s := m.Decompose()
+	assertMtxEqual(t, m, Compose(tr, r, s), 1e-9)
+}
+
+func TestQuatFromMtx(t *testing.T) {
+	// exercise each branch of Shepperd's method
+	for _, m := range []*Mtx{
+		Rotate(0.3, XAxis),
+		Rotate(math.Pi-0.1, XAxis),
+		Rotate(math.Pi-0.1, YAxis),
+		Rotate(math.Pi-0.1, ZAxis),
+	} {
+		assertMtxEqual(t, m, QuatFromMtx(m).Mtx(), 1e-9)
+	}
+}
+
+func assertMtxEqual(t *testing.T, expected, actual *Mtx, epsilon float64) {
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			assert.InDeltaf(t, expected[i][j], actual[i][j], epsilon,
+				"Expected %v, got %v (differ at [%d][%d])", expected, actual, i, j)
+		}
+	}
+}
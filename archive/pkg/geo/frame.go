@@ -0,0 +1,127 @@
+package geo
+
+import "math"
+
+// Frame is an orthonormal basis, typically a shading frame built around a
+// surface normal. In the frame's local coordinates N is the z-axis, and S and
+// T (tangent and bitangent) are the x- and y-axes.
+//
+// BSDFs are much simpler to write in local coordinates: the cosine of the
+// angle between a direction and the normal is just its z component, etc. See
+// CosTheta and friends.
+//
+// https://www.pbr-book.org/3ed-2018/Reflection_Models#GeometricSetting
+type Frame struct {
+	S, T, N Unit
+}
+
+// NewFrame builds a frame around the normal n. The tangent is projected into
+// the plane perpendicular to n, so it doesn't need to be exactly
+// perpendicular (e.g. dpdu from a shape). If the tangent is degenerate (zero,
+// or parallel to n), an arbitrary one is chosen as in FrameFromNormal.
+func NewFrame(n Unit, tangent Vec) Frame {
+	s := tangent.Minus(n.Scale(tangent.Dot(Vec(n))))
+	if s.LenSquared() < epsilon {
+		return FrameFromNormal(n)
+	}
+
+	su := s.Unit()
+	return Frame{S: su, T: n.Cross(su).Unit(), N: n}
+}
+
+// FrameFromNormal builds a frame around the normal n with an arbitrary (but
+// continuous and deterministic) choice of tangent.
+//
+// https://graphics.pixar.com/library/OrthonormalB/paper.pdf
+func FrameFromNormal(n Unit) Frame {
+	sign := math.Copysign(1, n.Z)
+	a := -1 / (sign + n.Z)
+	b := n.X * n.Y * a
+
+	return Frame{
+		S: Unit{1 + sign*n.X*n.X*a, sign * b, -sign * n.X},
+		T: Unit{b, sign + n.Y*n.Y*a, -n.Y},
+		N: n,
+	}
+}
+
+// ToLocal converts the world-space direction w to this frame's local
+// coordinates.
+func (f Frame) ToLocal(w Unit) Unit {
+	return Unit{w.Dot(f.S), w.Dot(f.T), w.Dot(f.N)}
+}
+
+// ToWorld converts the local direction w back to world space.
+func (f Frame) ToWorld(w Unit) Unit {
+	return Unit{
+		f.S.X*w.X + f.T.X*w.Y + f.N.X*w.Z,
+		f.S.Y*w.X + f.T.Y*w.Y + f.N.Y*w.Z,
+		f.S.Z*w.X + f.T.Z*w.Y + f.N.Z*w.Z,
+	}
+}
+
+// CosTheta returns the cosine of the angle between the local direction w and
+// the normal (the local z-axis).
+func CosTheta(w Unit) float64 {
+	return w.Z
+}
+
+// Cos2Theta returns the squared cosine of the angle between the local
+// direction w and the normal.
+func Cos2Theta(w Unit) float64 {
+	return w.Z * w.Z
+}
+
+// AbsCosTheta returns the absolute value of CosTheta.
+func AbsCosTheta(w Unit) float64 {
+	return math.Abs(w.Z)
+}
+
+// Sin2Theta returns the squared sine of the angle between the local direction
+// w and the normal.
+func Sin2Theta(w Unit) float64 {
+	return math.Max(0, 1-Cos2Theta(w))
+}
+
+// SinTheta returns the sine of the angle between the local direction w and the
+// normal.
+func SinTheta(w Unit) float64 {
+	return math.Sqrt(Sin2Theta(w))
+}
+
+// TanTheta returns the tangent of the angle between the local direction w and
+// the normal.
+func TanTheta(w Unit) float64 {
+	return SinTheta(w) / CosTheta(w)
+}
+
+// Tan2Theta returns the squared tangent of the angle between the local
+// direction w and the normal.
+func Tan2Theta(w Unit) float64 {
+	return Sin2Theta(w) / Cos2Theta(w)
+}
+
+// CosPhi returns the cosine of the azimuthal angle of the local direction w
+// (measured from the local x-axis).
+func CosPhi(w Unit) float64 {
+	sinTheta := SinTheta(w)
+	if sinTheta == 0 {
+		return 1
+	}
+	return math.Max(-1, math.Min(1, w.X/sinTheta))
+}
+
+// SinPhi returns the sine of the azimuthal angle of the local direction w.
+func SinPhi(w Unit) float64 {
+	sinTheta := SinTheta(w)
+	if sinTheta == 0 {
+		return 0
+	}
+	return math.Max(-1, math.Min(1, w.Y/sinTheta))
+}
+
+// SameHemisphere returns true if the two local directions are on the same side
+// of the surface.
+func SameHemisphere(w, wp Unit) bool {
+	return w.Z*wp.Z > 0
+}
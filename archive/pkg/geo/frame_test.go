@@ -0,0 +1,52 @@
+package geo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrame(t *testing.T) {
+	normals := []Unit{
+		ZAxis,
+		ZAxis.Reverse(),
+		XAxis,
+		V(1, -2, 3).Unit(),
+		V(-0.2, 0.1, -5).Unit(),
+	}
+
+	for _, n := range normals {
+		for _, f := range []Frame{FrameFromNormal(n), NewFrame(n, V(1, 1, 1))} {
+			// orthonormal and right-handed
+			assert.InDelta(t, 0, f.S.Dot(f.T), 1e-12)
+			assert.InDelta(t, 0, f.S.Dot(f.N), 1e-12)
+			assert.InDelta(t, 0, f.T.Dot(f.N), 1e-12)
+			assert.InDelta(t, 1, Vec(f.S).Len(), 1e-12)
+			assert.InDelta(t, 1, Vec(f.T).Len(), 1e-12)
+			assertVecEqual(t, Vec(f.N), f.S.Cross(f.T), 1e-12)
+
+			// normal maps to local z-axis, and directions round-trip
+			assertVecEqual(t, Vec(ZAxis), Vec(f.ToLocal(n)), 1e-12)
+			w := V(0.3, -0.4, 0.5).Unit()
+			assertVecEqual(t, Vec(w), Vec(f.ToWorld(f.ToLocal(w))), 1e-12)
+		}
+	}
+}
+
+func TestNewFrame_Tangent(t *testing.T) {
+	f := NewFrame(ZAxis, V(2, 0, 1))
+	assertVecEqual(t, Vec(XAxis), Vec(f.S), 1e-12)
+	assertVecEqual(t, Vec(YAxis), Vec(f.T), 1e-12)
+}
+
+func TestCosTheta(t *testing.T) {
+	w := V(1, 1, math.Sqrt(2)).Unit()
+	assert.InDelta(t, math.Cos(math.Pi/4), CosTheta(w), 1e-12)
+	assert.InDelta(t, math.Sin(math.Pi/4), SinTheta(w), 1e-12)
+	assert.InDelta(t, 1, TanTheta(w), 1e-12)
+	assert.InDelta(t, math.Cos(math.Pi/4), CosPhi(w), 1e-12)
+	assert.InDelta(t, math.Sin(math.Pi/4), SinPhi(w), 1e-12)
+	assert.True(t, SameHemisphere(w, ZAxis))
+	assert.False(t, SameHemisphere(w, ZAxis.Reverse()))
+}
@@ -102,11 +102,59 @@ func (a *Mtx) MultUnit(u Unit) Vec {
 	return a.MultVec(Vec(u))
 }
 
+// MultNormal transforms a surface normal by this matrix. Normals don't
+// transform like vectors: to stay perpendicular to the (transformed) surface
+// under non-uniform scaling or shear, they have to be multiplied by the
+// inverse-transpose of the upper 3x3 portion.
+//
+// Since the result is renormalized anyway, this uses the cofactor matrix
+// (which is the inverse-transpose scaled by the determinant) instead of a full
+// inverse, flipping the result if the determinant is negative.
+//
+// https://www.pbr-book.org/3ed-2018/Geometry_and_Transformations/Applying_Transformations#Normals
+// https://www.scratchapixel.com/lessons/mathematics-physics-for-computer-graphics/geometry/transforming-normals
+func (a *Mtx) MultNormal(n Unit) Unit {
+	c00 := a[1][1]*a[2][2] - a[1][2]*a[2][1]
+	c01 := a[1][2]*a[2][0] - a[1][0]*a[2][2]
+	c02 := a[1][0]*a[2][1] - a[1][1]*a[2][0]
+	c10 := a[0][2]*a[2][1] - a[0][1]*a[2][2]
+	c11 := a[0][0]*a[2][2] - a[0][2]*a[2][0]
+	c12 := a[0][1]*a[2][0] - a[0][0]*a[2][1]
+	c20 := a[0][1]*a[1][2] - a[0][2]*a[1][1]
+	c21 := a[0][2]*a[1][0] - a[0][0]*a[1][2]
+	c22 := a[0][0]*a[1][1] - a[0][1]*a[1][0]
+
+	v := Vec{
+		c00*n.X + c01*n.Y + c02*n.Z,
+		c10*n.X + c11*n.Y + c12*n.Z,
+		c20*n.X + c21*n.Y + c22*n.Z,
+	}
+	if a.det3() < 0 {
+		v = v.Scale(-1)
+	}
+	return v.Unit()
+}
+
+// MultBounds returns the bounds of the eight transformed corners of b. Under
+// rotation these are looser than the bounds of the transformed box itself,
+// but always contain it.
+func (a *Mtx) MultBounds(b *Bounds) *Bounds {
+	corner := func(i int) Vec {
+		return a.MultPoint(Vec{b[i&1].X, b[(i>>1)&1].Y, b[(i>>2)&1].Z})
+	}
+
+	bounds := NewBounds(corner(0), corner(0))
+	for i := 1; i < 8; i++ {
+		bounds = bounds.Extend(corner(i))
+	}
+	return bounds
+}
+
 // MultRay multiplies a ray by this matrix. Effectively, it does a point-like
 // multiplcation of the ray's origin, and a vector-like multiplication of the
-// ray's direction.
+// ray's direction. The ray's time is preserved.
 func (a *Mtx) MultRay(r *Ray) *Ray {
-	return NewRay(a.MultPoint(r.Origin), a.MultVec(r.Dir))
+	return NewRayAt(a.MultPoint(r.Origin), a.MultVec(r.Dir), r.Time)
 }
 
 // T returns a new matrix that is the transpose of this matrix.
@@ -54,3 +54,22 @@ func TestMtx_Inv(t *testing.T) {
 
 	fmt.Println(c)
 }
+
+func TestMtx_MultNormal(t *testing.T) {
+	// plane x + y = 0, squashed along x
+	n := V(1, 1, 0).Unit()
+	tangent := V(1, -1, 0)
+	m := Scale(V(2, 1, 1))
+
+	got := m.MultNormal(n)
+	assert.InDelta(t, 0, Vec(got).Dot(m.MultVec(tangent)), 1e-12)
+	assert.InDelta(t, 0, V(1, 2, 0).Unit().Dot(got)-1, 1e-12)
+
+	// general transform, with a reflection, matches the inverse-transpose
+	m = Shift(V(1, 2, 3)).Mult(Rotate(0.7, V(1, 2, 3).Unit())).Mult(Scale(V(-1, 3, 0.5)))
+	want := m.Inv().T().MultUnit(n).Unit()
+	got = m.MultNormal(n)
+	assert.InDelta(t, want.X, got.X, 1e-12)
+	assert.InDelta(t, want.Y, got.Y, 1e-12)
+	assert.InDelta(t, want.Z, got.Z, 1e-12)
+}
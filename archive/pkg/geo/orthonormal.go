@@ -0,0 +1,99 @@
+package geo
+
+import "math"
+
+// IsOrthonormal returns true if the upper 3x3 portion of the matrix is a
+// rotation (or reflection) to within the given tolerance: its columns are unit
+// length and mutually perpendicular.
+func (a *Mtx) IsOrthonormal(tol float64) bool {
+	cols := a.columns()
+	for i := 0; i < 3; i++ {
+		if math.Abs(cols[i].Dot(cols[i])-1) > tol {
+			return false
+		}
+		for j := i + 1; j < 3; j++ {
+			if math.Abs(cols[i].Dot(cols[j])) > tol {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Orthonormalize returns a copy of this matrix with its upper 3x3 portion
+// replaced by the nearest rotation, keeping the translation. Use it to clean
+// up "rotation" matrices that have drifted from orthonormal (e.g. from
+// limited-precision export formats). Any scale or shear is discarded.
+func (a *Mtx) Orthonormalize() *Mtx {
+	r := a.polar()
+	r[0][3], r[1][3], r[2][3] = a[0][3], a[1][3], a[2][3]
+	return r
+}
+
+// DecomposeShear splits this (affine) transform into translation, rotation,
+// scale and shear such that
+//
+//	a == Shift(t).Mult(r.Mtx()).Mult(Shear(shear)).Mult(Scale(scale))
+//
+// Unlike Decompose, it uses Gram-Schmidt on the matrix's columns (x-axis
+// first), which separates out shear explicitly. Shear components are the XY,
+// XZ and YZ shear factors as taken by Shear. A reflection, if present, is
+// folded into the scale.
+//
+// Based on "Decomposing a Matrix into Simple Transformations" by Spencer W.
+// Thomas, in Graphics Gems II.
+func (a *Mtx) DecomposeShear() (t Vec, r Quat, scale, shear Vec) {
+	t = Vec{a[0][3], a[1][3], a[2][3]}
+	cols := a.columns()
+
+	scale.X = cols[0].Len()
+	c0 := cols[0].Scale(1 / scale.X)
+
+	shear.X = c0.Dot(cols[1])
+	c1 := cols[1].Minus(c0.Scale(shear.X))
+	scale.Y = c1.Len()
+	c1 = c1.Scale(1 / scale.Y)
+	shear.X /= scale.Y
+
+	shear.Y = c0.Dot(cols[2])
+	c2 := cols[2].Minus(c0.Scale(shear.Y))
+	shear.Z = c1.Dot(c2)
+	c2 = c2.Minus(c1.Scale(shear.Z))
+	scale.Z = c2.Len()
+	c2 = c2.Scale(1 / scale.Z)
+	shear.Y /= scale.Z
+	shear.Z /= scale.Z
+
+	if c0.Dot(c1.Cross(c2)) < 0 {
+		c0, c1, c2 = c0.Reverse(), c1.Reverse(), c2.Reverse()
+		scale = scale.Reverse()
+	}
+
+	rot := &Mtx{
+		{c0.X, c1.X, c2.X, 0},
+		{c0.Y, c1.Y, c2.Y, 0},
+		{c0.Z, c1.Z, c2.Z, 0},
+		{0, 0, 0, 1},
+	}
+	r = QuatFromMtx(rot).Normalize()
+	return
+}
+
+// RemoveShear returns a copy of this transform with any shear removed, keeping
+// its translation, rotation and (per-axis) scale. Shear in imported transforms
+// is almost always an export artifact, and it skews normals and breaks
+// instancing.
+func (a *Mtx) RemoveShear() *Mtx {
+	t, r, scale, _ := a.DecomposeShear()
+	return Shift(t).Mult(r.Mtx()).Mult(Scale(scale))
+}
+
+// columns returns the first three columns of the matrix (i.e. the images of
+// the x, y and z axes).
+func (a *Mtx) columns() [3]Vec {
+	return [3]Vec{
+		{a[0][0], a[1][0], a[2][0]},
+		{a[0][1], a[1][1], a[2][1]},
+		{a[0][2], a[1][2], a[2][2]},
+	}
+}
@@ -0,0 +1,50 @@
+package geo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMtx_IsOrthonormal(t *testing.T) {
+	assert.True(t, Identity.IsOrthonormal(1e-9))
+	assert.True(t, Shift(V(1, 2, 3)).Mult(Rotate(0.7, YAxis)).IsOrthonormal(1e-9))
+	assert.False(t, Scale(V(1, 2, 1)).IsOrthonormal(1e-9))
+	assert.False(t, Shear(V(0.1, 0, 0)).IsOrthonormal(1e-9))
+}
+
+func TestMtx_Orthonormalize(t *testing.T) {
+	rot := Rotate(0.7, V(1, 2, 3).Unit())
+
+	// perturb it, like a rotation written out with 4 decimal places
+	sloppy := rot.Clone()
+	sloppy[0][1] += 3e-4
+	sloppy[2][0] -= 2e-4
+	sloppy[1][3] = 5
+	assert.False(t, sloppy.IsOrthonormal(1e-6))
+
+	fixed := sloppy.Orthonormalize()
+	assert.True(t, fixed.IsOrthonormal(1e-9))
+	assert.Equal(t, 5.0, fixed[1][3])
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			assert.InDelta(t, rot[i][j], fixed[i][j], 1e-3)
+		}
+	}
+}
+
+func TestMtx_DecomposeShear(t *testing.T) {
+	shift := V(1, 2, 3)
+	rot := Rotate(1.1, V(-1, 2, 0.5).Unit())
+	shear := V(0.3, -0.2, 0.1)
+	scale := V(2, 0.5, 3)
+	m := Shift(shift).Mult(rot).Mult(Shear(shear)).Mult(Scale(scale))
+
+	tr, r, sc, sh := m.DecomposeShear()
+	assertVecEqual(t, shift, tr, 1e-9)
+	assertMtxEqual(t, rot, r.Mtx(), 1e-9)
+	assertVecEqual(t, scale, sc, 1e-9)
+	assertVecEqual(t, shear, sh, 1e-9)
+
+	assertMtxEqual(t, Shift(shift).Mult(rot).Mult(Scale(scale)), m.RemoveShear(), 1e-9)
+}
@@ -0,0 +1,151 @@
+package geo
+
+import (
+	"fmt"
+	"math"
+)
+
+// Quat is a quaternion, with vector (imaginary) part V and scalar (real) part
+// W. Unit quaternions represent rotations; most of the time that's all we use
+// them for.
+//
+// https://www.pbr-book.org/3ed-2018/Geometry_and_Transformations/Animating_Transformations#Quaternions
+type Quat struct {
+	V Vec
+	W float64
+}
+
+// IdentityQuat is the quaternion representing no rotation.
+var IdentityQuat = Quat{W: 1}
+
+// QuatFromAxisAngle returns the quaternion for a rotation by theta radians
+// around the axis, like Rotate.
+func QuatFromAxisAngle(theta float64, axis Unit) Quat {
+	sin, cos := math.Sincos(theta / 2)
+	return Quat{V: axis.Scale(sin), W: cos}
+}
+
+// QuatFromEuler returns the quaternion for rotating by x radians around the
+// x-axis, then y around the y-axis, then z around the z-axis (all fixed
+// world axes), i.e. for the matrix
+//
+//	Rotate(z, ZAxis).Mult(Rotate(y, YAxis)).Mult(Rotate(x, XAxis))
+//
+// Euler angles are convenient to write down, but interpolating them directly
+// can lock axes together; convert to quaternions and use Slerp instead.
+//
+// https://en.wikipedia.org/wiki/Conversion_between_quaternions_and_Euler_angles
+func QuatFromEuler(x, y, z float64) Quat {
+	qx := QuatFromAxisAngle(x, XAxis)
+	qy := QuatFromAxisAngle(y, YAxis)
+	qz := QuatFromAxisAngle(z, ZAxis)
+	return qz.Mult(qy).Mult(qx)
+}
+
+// QuatFromMtx returns the quaternion for the rotation represented by the upper
+// 3x3 portion of the given matrix. The matrix should be a pure rotation
+// (orthonormal with determinant 1); any translation is ignored.
+//
+// Uses Shepperd's method, which picks the numerically largest component to
+// divide by.
+//
+// https://www.euclideanspace.com/maths/geometry/rotations/conversions/matrixToQuaternion/
+func QuatFromMtx(m *Mtx) Quat {
+	trace := m[0][0] + m[1][1] + m[2][2]
+
+	switch {
+	case trace > 0:
+		s := 2 * math.Sqrt(trace+1)
+		return Quat{
+			V: Vec{(m[2][1] - m[1][2]) / s, (m[0][2] - m[2][0]) / s, (m[1][0] - m[0][1]) / s},
+			W: 0.25 * s,
+		}
+	case m[0][0] > m[1][1] && m[0][0] > m[2][2]:
+		s := 2 * math.Sqrt(1+m[0][0]-m[1][1]-m[2][2])
+		return Quat{
+			V: Vec{0.25 * s, (m[0][1] + m[1][0]) / s, (m[0][2] + m[2][0]) / s},
+			W: (m[2][1] - m[1][2]) / s,
+		}
+	case m[1][1] > m[2][2]:
+		s := 2 * math.Sqrt(1+m[1][1]-m[0][0]-m[2][2])
+		return Quat{
+			V: Vec{(m[0][1] + m[1][0]) / s, 0.25 * s, (m[1][2] + m[2][1]) / s},
+			W: (m[0][2] - m[2][0]) / s,
+		}
+	default:
+		s := 2 * math.Sqrt(1+m[2][2]-m[0][0]-m[1][1])
+		return Quat{
+			V: Vec{(m[0][2] + m[2][0]) / s, (m[1][2] + m[2][1]) / s, 0.25 * s},
+			W: (m[1][0] - m[0][1]) / s,
+		}
+	}
+}
+
+// Mtx returns the rotation matrix for this quaternion, which is assumed to be
+// normalized.
+func (q Quat) Mtx() *Mtx {
+	x, y, z, w := q.V.X, q.V.Y, q.V.Z, q.W
+	return &Mtx{
+		{1 - 2*(y*y+z*z), 2 * (x*y - w*z), 2 * (x*z + w*y), 0},
+		{2 * (x*y + w*z), 1 - 2*(x*x+z*z), 2 * (y*z - w*x), 0},
+		{2 * (x*z - w*y), 2 * (y*z + w*x), 1 - 2*(x*x+y*y), 0},
+		{0, 0, 0, 1},
+	}
+}
+
+// Mult returns the product q*r (the Hamilton product). For rotations, this is
+// the rotation by r followed by the rotation by q, like Mtx.Mult.
+func (q Quat) Mult(r Quat) Quat {
+	return Quat{
+		V: r.V.Scale(q.W).Plus(q.V.Scale(r.W)).Plus(q.V.Cross(r.V)),
+		W: q.W*r.W - q.V.Dot(r.V),
+	}
+}
+
+// Dot returns the dot product of the two quaternions (treated as 4-vectors).
+func (q Quat) Dot(r Quat) float64 {
+	return q.V.Dot(r.V) + q.W*r.W
+}
+
+// Normalize returns this quaternion scaled to unit length.
+func (q Quat) Normalize() Quat {
+	n := 1 / math.Sqrt(q.Dot(q))
+	return Quat{V: q.V.Scale(n), W: q.W * n}
+}
+
+// Slerp spherically interpolates between the rotations q1 (at t = 0) and q2
+// (at t = 1), at constant angular velocity. It takes the shorter way around,
+// and falls back to linear interpolation when the rotations are nearly the
+// same. Both quaternions should be normalized.
+//
+// https://www.pbr-book.org/3ed-2018/Geometry_and_Transformations/Animating_Transformations#QuaternionInterpolation
+func Slerp(t float64, q1, q2 Quat) Quat {
+	cosTheta := q1.Dot(q2)
+
+	// q and -q are the same rotation, but only one is the short way round
+	if cosTheta < 0 {
+		q2 = Quat{V: q2.V.Reverse(), W: -q2.W}
+		cosTheta = -cosTheta
+	}
+
+	if cosTheta > 0.9995 {
+		return Quat{
+			V: q1.V.Scale(1 - t).Plus(q2.V.Scale(t)),
+			W: q1.W*(1-t) + q2.W*t,
+		}.Normalize()
+	}
+
+	theta := math.Acos(math.Min(1, cosTheta))
+	sinTheta := math.Sin(theta)
+	a := math.Sin((1-t)*theta) / sinTheta
+	b := math.Sin(t*theta) / sinTheta
+	return Quat{
+		V: q1.V.Scale(a).Plus(q2.V.Scale(b)),
+		W: q1.W*a + q2.W*b,
+	}
+}
+
+// String returns a string representation of this quaternion.
+func (q Quat) String() string {
+	return fmt.Sprintf("Quat(%5f, %5f, %5f, %5f)", q.V.X, q.V.Y, q.V.Z, q.W)
+}
@@ -0,0 +1,56 @@
+package geo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuatFromAxisAngle(t *testing.T) {
+	axis := V(1, 2, -1).Unit()
+	assertMtxEqual(t, Rotate(0.7, axis), QuatFromAxisAngle(0.7, axis).Mtx(), 1e-9)
+	assertMtxEqual(t, Identity, QuatFromAxisAngle(0, axis).Mtx(), 1e-9)
+}
+
+func TestQuatFromEuler(t *testing.T) {
+	x, y, z := 0.3, -1.2, 2.5
+	expected := Rotate(z, ZAxis).Mult(Rotate(y, YAxis)).Mult(Rotate(x, XAxis))
+	assertMtxEqual(t, expected, QuatFromEuler(x, y, z).Mtx(), 1e-9)
+}
+
+func TestQuat_Mult(t *testing.T) {
+	q := QuatFromAxisAngle(0.4, XAxis)
+	r := QuatFromAxisAngle(-1.1, V(0, 1, 1).Unit())
+	assertMtxEqual(t, q.Mtx().Mult(r.Mtx()), q.Mult(r).Mtx(), 1e-9)
+	assert.Equal(t, q, q.Mult(IdentityQuat))
+}
+
+func TestSlerp(t *testing.T) {
+	q1 := QuatFromAxisAngle(0.2, ZAxis)
+	q2 := QuatFromAxisAngle(1.4, ZAxis)
+
+	tests := []struct {
+		t     float64
+		angle float64
+	}{
+		{0, 0.2},
+		{0.25, 0.5},
+		{0.5, 0.8},
+		{1, 1.4},
+	}
+	for _, tt := range tests {
+		assertMtxEqual(t, Rotate(tt.angle, ZAxis), Slerp(tt.t, q1, q2).Mtx(), 1e-9)
+	}
+
+	// The short way round: from -170° to 170° passes through 180°
+	q1 = QuatFromAxisAngle(-170*math.Pi/180, YAxis)
+	q2 = QuatFromAxisAngle(170*math.Pi/180, YAxis)
+	assertMtxEqual(t, Rotate(math.Pi, YAxis), Slerp(0.5, q1, q2).Mtx(), 1e-9)
+
+	// Nearly identical rotations are still interpolated sensibly
+	q2 = QuatFromAxisAngle(0.2+1e-6, ZAxis)
+	q := Slerp(0.5, QuatFromAxisAngle(0.2, ZAxis), q2)
+	assert.InDelta(t, 1.0, q.Dot(q), 1e-12)
+	assertMtxEqual(t, Rotate(0.2+5e-7, ZAxis), q.Mtx(), 1e-9)
+}
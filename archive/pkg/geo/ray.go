@@ -3,7 +3,9 @@ package geo
 // Ray is a geometric ray.
 //
 // Origin is a vector defining the point the ray originates from. Dir is the
-// vector (not necessarily normalized!) that defines the ray's direction.
+// vector (not necessarily normalized!) that defines the ray's direction. Time
+// is the instant (within the camera's shutter interval) the ray exists at, for
+// anything in the scene that is animated.
 
 // Ray structs also contain non-public members that are mostly used for
 // accelerating intersection tests with Bounds struct. As result:
@@ -12,13 +14,19 @@ package geo
 type Ray struct {
 	Origin Vec
 	Dir    Vec
+	Time   float64
 
 	invDir Vec
 	sign   [3]int
 }
 
-// NewRay creates a new Ray at the given origin and direction
+// NewRay creates a new Ray at the given origin and direction, at time 0.
 func NewRay(origin, dir Vec) *Ray {
+	return NewRayAt(origin, dir, 0)
+}
+
+// NewRayAt creates a new Ray at the given origin, direction and time.
+func NewRayAt(origin, dir Vec, time float64) *Ray {
 	if dir.NearZero() {
 		panic("Cannot create Ray with 0-direction")
 	}
@@ -26,6 +34,7 @@ func NewRay(origin, dir Vec) *Ray {
 	ray := &Ray{
 		Origin: origin,
 		Dir:    dir,
+		Time:   time,
 		invDir: Vec{1 / dir.X, 1 / dir.Y, 1 / dir.Z},
 	}
 
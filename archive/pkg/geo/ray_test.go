@@ -3,6 +3,8 @@ package geo
 import (
 	"fmt"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 var benchResultRay *Ray
@@ -38,3 +40,11 @@ func BenchmarkRay_At(b *testing.B) {
 		benchResultVec = ray.At(float64(i))
 	}
 }
+
+func TestMtx_MultRay_PreservesTime(t *testing.T) {
+	ray := NewRayAt(Origin, Vec(ZAxis), 0.25)
+	moved := Shift(V(1, 2, 3)).MultRay(ray)
+
+	assert.Equal(t, 0.25, moved.Time)
+	assert.Equal(t, V(1, 2, 3), moved.Origin)
+}
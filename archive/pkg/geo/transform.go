@@ -39,6 +39,23 @@ func Scale(v Vec) *Mtx {
 	}
 }
 
+// Shear returns a shearing transform matrix. The components of v are the XY,
+// XZ and YZ shear factors: how much x shifts per unit y, x per unit z and y per
+// unit z, respectively.
+//
+//	1  v.X v.Y 0
+//	0  1   v.Z 0
+//	0  0   1   0
+//	0  0   0   1
+func Shear(v Vec) *Mtx {
+	return &Mtx{
+		{1, v.X, v.Y, 0},
+		{0, 1, v.Z, 0},
+		{0, 0, 1, 0},
+		{0, 0, 0, 1},
+	}
+}
+
 // Rotate returns a transform matrix representing rotation about the given axis.
 //
 // Note that the inverse of a rotation transform is equal to its transpose:
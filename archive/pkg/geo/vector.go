@@ -50,6 +50,19 @@ func VecMax(a, b Vec) Vec {
 	}
 }
 
+// Axis returns the component of the vector along the given axis (0 for x, 1
+// for y and 2 for z).
+func (a Vec) Axis(i int) float64 {
+	switch i {
+	case 0:
+		return a.X
+	case 1:
+		return a.Y
+	default:
+		return a.Z
+	}
+}
+
 // Plus returns the vector a + b.
 func (a Vec) Plus(b Vec) Vec {
 	return Vec{a.X + b.X, a.Y + b.Y, a.Z + b.Z}
@@ -0,0 +1,100 @@
+package imageio
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Diff holds per-pixel difference statistics between two images of the same
+// size, as computed by Compare.
+type Diff struct {
+	Width, Height int
+
+	// RMSE is the root mean squared error over all pixel components.
+	RMSE float64
+
+	// MeanDeltaE and MaxDeltaE summarize DeltaE, the per-pixel CIE76 color
+	// difference (Euclidean distance in CIELAB), row by row from the top left.
+	MeanDeltaE, MaxDeltaE float64
+	DeltaE                []float64
+}
+
+// Compare computes difference statistics between two images. Both are taken
+// to hold linear sRGB values, with (1, 1, 1) being reference white for the
+// CIELAB conversion.
+//
+// https://en.wikipedia.org/wiki/Color_difference#CIE76
+func Compare(a, b *RGB) (*Diff, error) {
+	if a.Width != b.Width || a.Height != b.Height {
+		return nil, fmt.Errorf("image sizes differ: %dx%d vs %dx%d", a.Width, a.Height, b.Width, b.Height)
+	}
+
+	d := &Diff{
+		Width:  a.Width,
+		Height: a.Height,
+		DeltaE: make([]float64, a.Width*a.Height),
+	}
+	if len(d.DeltaE) == 0 {
+		return d, nil
+	}
+
+	sumSq, sumDE := 0.0, 0.0
+	for i := range d.DeltaE {
+		pa, pb := a.Pix[3*i:3*i+3], b.Pix[3*i:3*i+3]
+		for c := range pa {
+			sumSq += (pa[c] - pb[c]) * (pa[c] - pb[c])
+		}
+
+		la, lb := srgbToLab(pa), srgbToLab(pb)
+		de := math.Sqrt((la[0]-lb[0])*(la[0]-lb[0]) + (la[1]-lb[1])*(la[1]-lb[1]) + (la[2]-lb[2])*(la[2]-lb[2]))
+		d.DeltaE[i] = de
+		sumDE += de
+		d.MaxDeltaE = math.Max(d.MaxDeltaE, de)
+	}
+	d.RMSE = math.Sqrt(sumSq / float64(len(a.Pix)))
+	d.MeanDeltaE = sumDE / float64(len(d.DeltaE))
+	return d, nil
+}
+
+// Heatmap renders DeltaE as an image, going from black (no difference)
+// through red and yellow to white (MaxDeltaE).
+func (d *Diff) Heatmap() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, d.Width, d.Height))
+	for i, de := range d.DeltaE {
+		t := 0.0
+		if d.MaxDeltaE > 0 {
+			t = 3 * (de / d.MaxDeltaE)
+		}
+		img.Set(i%d.Width, i/d.Width, color.RGBA{
+			R: uint8(255 * math.Min(t, 1)),
+			G: uint8(255 * math.Max(0, math.Min(t-1, 1))),
+			B: uint8(255 * math.Max(0, math.Min(t-2, 1))),
+			A: 255,
+		})
+	}
+	return img
+}
+
+// srgbToLab converts a linear sRGB color to CIELAB, relative to the D65 white
+// point.
+//
+// http://www.brucelindbloom.com/index.html?Eqn_XYZ_to_Lab.html
+func srgbToLab(rgb []float64) [3]float64 {
+	x := (0.4124564*rgb[0] + 0.3575761*rgb[1] + 0.1804375*rgb[2]) / 0.95047
+	y := 0.2126729*rgb[0] + 0.7151522*rgb[1] + 0.0721750*rgb[2]
+	z := (0.0193339*rgb[0] + 0.1191920*rgb[1] + 0.9503041*rgb[2]) / 1.08883
+
+	fx, fy, fz := labF(x), labF(y), labF(z)
+	return [3]float64{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+func labF(t float64) float64 {
+	const eps = 216.0 / 24389
+	const kappa = 24389.0 / 27
+	if t > eps {
+		return math.Cbrt(t)
+	}
+	return (kappa*t + 16) / 116
+}
@@ -0,0 +1,34 @@
+package imageio
+
+import (
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	a := NewRGB(2, 1)
+	b := NewRGB(2, 1)
+	a.Set(0, 0, 1, 1, 1)
+	b.Set(0, 0, 1, 1, 1)
+	b.Set(1, 0, 1, 1, 1)
+
+	d, err := Compare(a, b)
+	assert.NoError(t, err)
+	assert.InDelta(t, math.Sqrt(0.5), d.RMSE, 1e-9)
+
+	// black vs white is a lightness difference of 100
+	assert.InDelta(t, 0, d.DeltaE[0], 1e-9)
+	assert.InDelta(t, 100, d.DeltaE[1], 1e-3)
+	assert.InDelta(t, 100, d.MaxDeltaE, 1e-3)
+	assert.InDelta(t, 50, d.MeanDeltaE, 1e-3)
+
+	heat := d.Heatmap()
+	assert.Equal(t, color.RGBA{0, 0, 0, 255}, heat.At(0, 0))
+	assert.Equal(t, color.RGBA{255, 255, 255, 255}, heat.At(1, 0))
+
+	_, err = Compare(a, NewRGB(1, 2))
+	assert.Error(t, err)
+}
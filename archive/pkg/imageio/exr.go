@@ -0,0 +1,381 @@
+package imageio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/gmhorn/gremlin/archive/pkg/asset"
+)
+
+// OpenEXR constants used by ReadEXR and WriteEXR.
+const (
+	exrMagic   = 20000630
+	exrVersion = 2 // single-part scanline file
+	exrTiled   = 0x200
+	exrDeep    = 0x800
+	exrMulti   = 0x1000
+
+	// channel pixel types
+	exrUint  = 0
+	exrHalf  = 1
+	exrFloat = 2
+)
+
+// exrChannels are the channels WriteEXR writes, in the (alphabetical) order
+// OpenEXR stores them, along with their offset into an RGB pixel.
+var exrChannels = []struct {
+	name   string
+	offset int
+}{
+	{"B", 2},
+	{"G", 1},
+	{"R", 0},
+}
+
+// WriteEXR writes the image as an uncompressed, single-part scanline OpenEXR
+// file with 32-bit float R, G and B channels. Pixel values are written as-is,
+// so the image should hold linear radiance.
+//
+// https://openexr.com/en/latest/OpenEXRFileLayout.html
+func WriteEXR(w io.Writer, img *RGB) error {
+	header := exrHeader(img.Width, img.Height)
+
+	// One chunk per scanline: its y coordinate, its data size and then each
+	// channel's row of floats.
+	rowSize := 4 * len(exrChannels) * img.Width
+	chunkSize := 8 + rowSize
+
+	bw := bufio.NewWriter(w)
+	bw.Write(header)
+
+	// offset table
+	offset := uint64(len(header) + 8*img.Height)
+	for y := 0; y < img.Height; y++ {
+		writeLE(bw, offset)
+		offset += uint64(chunkSize)
+	}
+
+	row := make([]byte, rowSize)
+	for y := 0; y < img.Height; y++ {
+		writeLE(bw, int32(y))
+		writeLE(bw, int32(rowSize))
+
+		i := 0
+		for _, ch := range exrChannels {
+			for x := 0; x < img.Width; x++ {
+				v := float32(img.Pix[3*(y*img.Width+x)+ch.offset])
+				binary.LittleEndian.PutUint32(row[i:], math.Float32bits(v))
+				i += 4
+			}
+		}
+		bw.Write(row)
+	}
+
+	return bw.Flush()
+}
+
+// exrHeader returns the magic number, version field and header attributes for
+// an image of the given size.
+func exrHeader(width, height int) []byte {
+	buf := &bytes.Buffer{}
+	writeLE(buf, int32(exrMagic))
+	writeLE(buf, int32(exrVersion))
+
+	chlist := &bytes.Buffer{}
+	for _, ch := range exrChannels {
+		chlist.WriteString(ch.name)
+		chlist.WriteByte(0)
+		writeLE(chlist, int32(exrFloat))
+		chlist.Write([]byte{0, 0, 0, 0}) // pLinear and reserved
+		writeLE(chlist, [2]int32{1, 1})  // x and y sampling
+	}
+	chlist.WriteByte(0)
+
+	box := [4]int32{0, 0, int32(width - 1), int32(height - 1)}
+	exrAttr(buf, "channels", "chlist", chlist.Bytes())
+	exrAttr(buf, "compression", "compression", []byte{0})
+	exrAttr(buf, "dataWindow", "box2i", leBytes(box))
+	exrAttr(buf, "displayWindow", "box2i", leBytes(box))
+	exrAttr(buf, "lineOrder", "lineOrder", []byte{0})
+	exrAttr(buf, "pixelAspectRatio", "float", leBytes(float32(1)))
+	exrAttr(buf, "screenWindowCenter", "v2f", leBytes([2]float32{0, 0}))
+	exrAttr(buf, "screenWindowWidth", "float", leBytes(float32(1)))
+	buf.WriteByte(0)
+
+	return buf.Bytes()
+}
+
+// exrAttr writes a header attribute: its name, type name, size and value.
+func exrAttr(buf *bytes.Buffer, name, typ string, value []byte) {
+	buf.WriteString(name)
+	buf.WriteByte(0)
+	buf.WriteString(typ)
+	buf.WriteByte(0)
+	writeLE(buf, int32(len(value)))
+	buf.Write(value)
+}
+
+// writeLE writes fixed-size data in little-endian byte order. Write errors are
+// left to the caller to pick up (from bufio.Writer.Flush).
+func writeLE(w io.Writer, data any) {
+	binary.Write(w, binary.LittleEndian, data)
+}
+
+// leBytes returns the little-endian encoding of fixed-size data.
+func leBytes(data any) []byte {
+	buf := &bytes.Buffer{}
+	writeLE(buf, data)
+	return buf.Bytes()
+}
+
+// LoadEXR opens the named OpenEXR file with the resolver and reads it.
+func LoadEXR(res *asset.Resolver, name string) (*RGB, error) {
+	f, err := res.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, err := ReadEXR(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return img, nil
+}
+
+// exrChannel is a channel as described in an OpenEXR header.
+type exrChannel struct {
+	name      string
+	pixelType int32
+}
+
+// ReadEXR reads an uncompressed, single-part scanline OpenEXR file, such as
+// the ones WriteEXR writes. The image is built from the R, G and B channels
+// (or the Y channel, for grayscale images), which may be half or full floats;
+// other channels are skipped. Compressed, tiled, deep and multi-part files are
+// not supported.
+//
+// https://openexr.com/en/latest/OpenEXRFileLayout.html
+func ReadEXR(r io.Reader) (*RGB, error) {
+	br := bufio.NewReader(r)
+
+	var magic, version int32
+	if err := binary.Read(br, binary.LittleEndian, &magic); err != nil || magic != exrMagic {
+		return nil, errors.New("not an OpenEXR file")
+	}
+	if err := binary.Read(br, binary.LittleEndian, &version); err != nil {
+		return nil, fmt.Errorf("reading version: %w", err)
+	}
+	if version&0xff != exrVersion || version&(exrTiled|exrDeep|exrMulti) != 0 {
+		return nil, fmt.Errorf("unsupported version field %#x (only single-part scanline files)", version)
+	}
+
+	channels, window, err := readEXRHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	width := int(window[2] - window[0] + 1)
+	height := int(window[3] - window[1] + 1)
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("bad data window %v", window)
+	}
+
+	// where each channel goes in an RGB pixel (-1 to skip it, 3 for all of
+	// them)
+	targets := make([]int, len(channels))
+	found := false
+	for i, ch := range channels {
+		targets[i] = strings.Index("RGB", ch.name)
+		if len(ch.name) != 1 {
+			targets[i] = -1
+		}
+		found = found || targets[i] >= 0
+	}
+	if !found {
+		for i, ch := range channels {
+			if ch.name == "Y" {
+				targets[i] = 3
+				found = true
+			}
+		}
+	}
+	if !found {
+		return nil, errors.New("no R, G, B or Y channels")
+	}
+
+	// offset table, not needed when reading the chunks in order
+	if _, err := br.Discard(8 * height); err != nil {
+		return nil, fmt.Errorf("reading offset table: %w", err)
+	}
+
+	img := NewRGB(width, height)
+	for i := 0; i < height; i++ {
+		var chunk [2]int32
+		if err := binary.Read(br, binary.LittleEndian, &chunk); err != nil {
+			return nil, fmt.Errorf("reading scanline %d: %w", i, err)
+		}
+		y := int(chunk[0] - window[1])
+		if y < 0 || y >= height {
+			return nil, fmt.Errorf("scanline y %d outside data window", chunk[0])
+		}
+
+		data := make([]byte, chunk[1])
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, fmt.Errorf("reading scanline %d: %w", chunk[0], err)
+		}
+		if err := readEXRScanline(img, y, data, channels, targets); err != nil {
+			return nil, fmt.Errorf("scanline %d: %w", chunk[0], err)
+		}
+	}
+	return img, nil
+}
+
+// readEXRHeader reads header attributes up to the terminating null byte,
+// returning the channel list and data window.
+func readEXRHeader(br *bufio.Reader) (channels []exrChannel, window [4]int32, err error) {
+	hasWindow := false
+	for {
+		name, err := readCString(br)
+		if err != nil {
+			return nil, window, fmt.Errorf("reading header: %w", err)
+		}
+		if name == "" {
+			break
+		}
+		typ, err := readCString(br)
+		if err != nil {
+			return nil, window, fmt.Errorf("reading header: %w", err)
+		}
+		var size int32
+		if err := binary.Read(br, binary.LittleEndian, &size); err != nil {
+			return nil, window, fmt.Errorf("reading header: %w", err)
+		}
+		if size < 0 {
+			return nil, window, fmt.Errorf("attribute %s: bad size %d", name, size)
+		}
+		value := make([]byte, size)
+		if _, err := io.ReadFull(br, value); err != nil {
+			return nil, window, fmt.Errorf("attribute %s: %w", name, err)
+		}
+
+		switch {
+		case name == "channels" && typ == "chlist":
+			if channels, err = parseEXRChannels(value); err != nil {
+				return nil, window, err
+			}
+		case name == "compression" && typ == "compression":
+			if size != 1 || value[0] != 0 {
+				return nil, window, errors.New("compressed files are not supported")
+			}
+		case name == "dataWindow" && typ == "box2i" && size == 16:
+			binary.Read(bytes.NewReader(value), binary.LittleEndian, &window)
+			hasWindow = true
+		}
+	}
+
+	if channels == nil || !hasWindow {
+		return nil, window, errors.New("header is missing channels or dataWindow")
+	}
+	return channels, window, nil
+}
+
+// parseEXRChannels parses a chlist attribute value.
+func parseEXRChannels(value []byte) ([]exrChannel, error) {
+	var channels []exrChannel
+	for len(value) > 0 && value[0] != 0 {
+		end := bytes.IndexByte(value, 0)
+		if end < 0 || len(value) < end+17 {
+			return nil, errors.New("truncated channel list")
+		}
+		ch := exrChannel{
+			name:      string(value[:end]),
+			pixelType: int32(binary.LittleEndian.Uint32(value[end+1:])),
+		}
+		xs := binary.LittleEndian.Uint32(value[end+9:])
+		ys := binary.LittleEndian.Uint32(value[end+13:])
+		if ch.pixelType < exrUint || ch.pixelType > exrFloat {
+			return nil, fmt.Errorf("channel %s: unknown pixel type %d", ch.name, ch.pixelType)
+		}
+		if xs != 1 || ys != 1 {
+			return nil, fmt.Errorf("channel %s: subsampled channels are not supported", ch.name)
+		}
+		channels = append(channels, ch)
+		value = value[end+17:]
+	}
+	return channels, nil
+}
+
+// readEXRScanline decodes one scanline of channel data into row y of img,
+// writing each channel to its target pixel component.
+func readEXRScanline(img *RGB, y int, data []byte, channels []exrChannel, targets []int) error {
+	for i, ch := range channels {
+		size := 4
+		if ch.pixelType == exrHalf {
+			size = 2
+		}
+		if len(data) < size*img.Width {
+			return errors.New("truncated scanline")
+		}
+
+		for x := 0; x < img.Width && targets[i] >= 0; x++ {
+			var v float64
+			switch ch.pixelType {
+			case exrHalf:
+				v = halfToFloat(binary.LittleEndian.Uint16(data[2*x:]))
+			case exrFloat:
+				v = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[4*x:])))
+			default:
+				v = float64(binary.LittleEndian.Uint32(data[4*x:]))
+			}
+
+			px := img.Pix[3*(y*img.Width+x):]
+			if targets[i] == 3 {
+				px[0], px[1], px[2] = v, v, v
+			} else {
+				px[targets[i]] = v
+			}
+		}
+		data = data[size*img.Width:]
+	}
+	return nil
+}
+
+// readCString reads a null-terminated string.
+func readCString(br *bufio.Reader) (string, error) {
+	s, err := br.ReadString(0)
+	if err != nil {
+		return "", err
+	}
+	return s[:len(s)-1], nil
+}
+
+// halfToFloat converts an IEEE 754 half-precision float to a float64.
+//
+// https://en.wikipedia.org/wiki/Half-precision_floating-point_format
+func halfToFloat(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(h>>10) & 0x1f
+	frac := float64(h & 0x3ff)
+
+	switch exp {
+	case 0:
+		// subnormal
+		return sign * math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	}
+	return sign * math.Ldexp(1+frac/1024, exp-15)
+}
@@ -0,0 +1,90 @@
+package imageio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteEXR(t *testing.T) {
+	img := NewRGB(2, 2)
+	img.Set(0, 0, 1, 2, 3)
+	img.Set(1, 0, 0.5, 0, 100)
+	img.Set(1, 1, -1, 0, 0)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, WriteEXR(buf, img))
+	data := buf.Bytes()
+	le := binary.LittleEndian
+
+	assert.Equal(t, []byte{0x76, 0x2f, 0x31, 0x01, 2, 0, 0, 0}, data[:8])
+	assert.Contains(t, string(data), "dataWindow\x00box2i\x00")
+
+	// header ends with a null byte, then the offset table
+	header := exrHeader(2, 2)
+	assert.Equal(t, header, data[:len(header)])
+	assert.Equal(t, byte(0), header[len(header)-1])
+
+	first := le.Uint64(data[len(header):])
+	second := le.Uint64(data[len(header)+8:])
+	assert.Equal(t, uint64(len(header)+16), first)
+	assert.Equal(t, uint64(8+2*3*4), second-first)
+	assert.Equal(t, int(second-first)*2, len(data)-int(first))
+
+	// scanlines hold B, G, R rows
+	float := func(off uint64) float32 {
+		return math.Float32frombits(le.Uint32(data[off:]))
+	}
+	assert.Equal(t, uint32(0), le.Uint32(data[first:]))
+	assert.Equal(t, uint32(24), le.Uint32(data[first+4:]))
+	row := first + 8
+	assert.Equal(t, []float32{3, 100, 2, 0, 1, 0.5}, []float32{
+		float(row), float(row + 4), float(row + 8), float(row + 12), float(row + 16), float(row + 20),
+	})
+	assert.Equal(t, uint32(1), le.Uint32(data[second:]))
+	assert.Equal(t, float32(-1), float(second+8+20))
+}
+
+func TestReadEXR(t *testing.T) {
+	img := NewRGB(3, 2)
+	img.Set(0, 0, 1, 2, 3)
+	img.Set(2, 1, 0.25, -4, 1e6)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, WriteEXR(buf, img))
+
+	read, err := ReadEXR(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, img, read)
+
+	_, err = ReadEXR(bytes.NewReader([]byte("#?RADIANCE\n")))
+	assert.Error(t, err)
+
+	// compressed files are rejected
+	data := exrHeader(1, 1)
+	data = bytes.Replace(data, []byte("compression\x00compression\x00\x01\x00\x00\x00\x00"), []byte("compression\x00compression\x00\x01\x00\x00\x00\x03"), 1)
+	_, err = ReadEXR(bytes.NewReader(data))
+	assert.ErrorContains(t, err, "compressed")
+}
+
+func TestHalfToFloat(t *testing.T) {
+	tests := []struct {
+		half uint16
+		want float64
+	}{
+		{0x0000, 0},
+		{0x3c00, 1},
+		{0xc000, -2},
+		{0x3555, 0.333251953125},
+		{0x7bff, 65504},
+		{0x0001, math.Ldexp(1, -24)},
+		{0x7c00, math.Inf(1)},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, halfToFloat(test.half), "%#04x", test.half)
+	}
+	assert.True(t, math.IsNaN(halfToFloat(0x7e00)))
+}
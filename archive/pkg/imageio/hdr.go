@@ -0,0 +1,157 @@
+package imageio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/gmhorn/gremlin/archive/pkg/asset"
+)
+
+// LoadHDR opens the named Radiance .hdr file with the resolver and reads it.
+func LoadHDR(res *asset.Resolver, name string) (*RGB, error) {
+	f, err := res.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, err := ReadHDR(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return img, nil
+}
+
+// ReadHDR reads an image in Radiance's RGBE (.hdr, .pic) format, with either
+// flat or run-length encoded scanlines. Only the standard orientation
+// ("-Y height +X width") is supported.
+//
+// https://www.graphics.cornell.edu/~bjw/rgbe.html
+// https://radsite.lbl.gov/radiance/refer/filefmts.pdf
+func ReadHDR(r io.Reader) (*RGB, error) {
+	br := bufio.NewReader(r)
+
+	width, height, err := readHDRHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	img := NewRGB(width, height)
+	scanline := make([]byte, 4*width)
+	for y := 0; y < height; y++ {
+		if err := readHDRScanline(br, scanline); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("scanline %d: %w", y, err)
+		}
+		for x := 0; x < width; x++ {
+			rgbe := scanline[4*x : 4*x+4]
+			img.Set(x, y, rgbeToFloat(rgbe[0], rgbe[3]), rgbeToFloat(rgbe[1], rgbe[3]), rgbeToFloat(rgbe[2], rgbe[3]))
+		}
+	}
+	return img, nil
+}
+
+func readHDRHeader(br *bufio.Reader) (width, height int, err error) {
+	magic, err := br.ReadString('\n')
+	if err != nil || !strings.HasPrefix(magic, "#?") {
+		return 0, 0, errors.New("not a Radiance HDR file")
+	}
+
+	// header variables, up to a blank line
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return 0, 0, fmt.Errorf("reading header: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "FORMAT=") && line != "FORMAT=32-bit_rle_rgbe" {
+			return 0, 0, fmt.Errorf("unsupported format %q", strings.TrimPrefix(line, "FORMAT="))
+		}
+	}
+
+	res, err := br.ReadString('\n')
+	if err != nil {
+		return 0, 0, fmt.Errorf("reading resolution: %w", err)
+	}
+	if _, err := fmt.Sscanf(res, "-Y %d +X %d", &height, &width); err != nil {
+		return 0, 0, fmt.Errorf("unsupported resolution line %q", strings.TrimSpace(res))
+	}
+	if width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("bad image size %dx%d", width, height)
+	}
+	return width, height, nil
+}
+
+// readHDRScanline reads one scanline of RGBE values into buf.
+func readHDRScanline(br *bufio.Reader, buf []byte) error {
+	width := len(buf) / 4
+
+	// Run-length encoded scanlines start with 2, 2 and the width; anything
+	// else is a flat scanline.
+	head, err := br.Peek(4)
+	if err != nil {
+		return err
+	}
+	if width < 8 || width > 0x7fff || head[0] != 2 || head[1] != 2 || int(head[2])<<8|int(head[3]) != width {
+		_, err := io.ReadFull(br, buf)
+		return err
+	}
+	br.Discard(4)
+
+	// each channel is encoded separately
+	for c := 0; c < 4; c++ {
+		for x := 0; x < width; {
+			count, err := br.ReadByte()
+			if err != nil {
+				return err
+			}
+
+			if count > 128 {
+				// run of one value
+				n := int(count - 128)
+				if x+n > width {
+					return errors.New("run overflows scanline")
+				}
+				v, err := br.ReadByte()
+				if err != nil {
+					return err
+				}
+				for ; n > 0; n-- {
+					buf[4*x+c] = v
+					x++
+				}
+			} else {
+				// literal values
+				n := int(count)
+				if n == 0 || x+n > width {
+					return errors.New("bad literal run")
+				}
+				for ; n > 0; n-- {
+					v, err := br.ReadByte()
+					if err != nil {
+						return err
+					}
+					buf[4*x+c] = v
+					x++
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func rgbeToFloat(mantissa, exponent byte) float64 {
+	if exponent == 0 {
+		return 0
+	}
+	return (float64(mantissa) + 0.5) * math.Ldexp(1, int(exponent)-(128+8))
+}
@@ -0,0 +1,75 @@
+package imageio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const hdrHeader = "#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n\n"
+
+func TestReadHDR_Flat(t *testing.T) {
+	data := []byte(hdrHeader + "-Y 1 +X 2\n")
+	// 1.0 is mantissa 128, exponent 129; 0.5 is 128, 128
+	data = append(data, 128, 128, 128, 129, 128, 0, 64, 128)
+
+	img, err := ReadHDR(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, img.Width)
+	assert.Equal(t, 1, img.Height)
+
+	r, g, b := img.At(0, 0)
+	assert.InDelta(t, 1, r, 0.01)
+	assert.InDelta(t, 1, g, 0.01)
+	assert.InDelta(t, 1, b, 0.01)
+	r, g, b = img.At(1, 0)
+	assert.InDelta(t, 0.5, r, 0.01)
+	assert.InDelta(t, 0, g, 0.01)
+	assert.InDelta(t, 0.25, b, 0.01)
+}
+
+func TestReadHDR_RLE(t *testing.T) {
+	data := []byte(hdrHeader + "-Y 1 +X 8\n")
+	data = append(data, 2, 2, 0, 8)
+	// red: literal run of 8
+	data = append(data, 8, 10, 20, 30, 40, 50, 60, 70, 80)
+	// green: run of 8 zeros
+	data = append(data, 128+8, 0)
+	// blue: run of 4, then 4 literals
+	data = append(data, 128+4, 128, 4, 1, 2, 3, 4)
+	// exponent: all 129
+	data = append(data, 128+8, 129)
+
+	img, err := ReadHDR(bytes.NewReader(data))
+	assert.NoError(t, err)
+
+	r, g, b := img.At(2, 0)
+	assert.InDelta(t, 30.5/128, r, 1e-9)
+	assert.Equal(t, 0.5/128, g)
+	assert.InDelta(t, 128.5/128, b, 1e-9)
+	_, _, b = img.At(7, 0)
+	assert.InDelta(t, 4.5/128, b, 1e-9)
+}
+
+func TestReadHDR_Errors(t *testing.T) {
+	tests := map[string]string{
+		"magic":       "P6\n",
+		"format":      "#?RADIANCE\nFORMAT=32-bit_rle_xyze\n\n-Y 1 +X 1\n",
+		"orientation": hdrHeader + "+Y 1 +X 1\n",
+		"size":        hdrHeader + "-Y 0 +X 1\n",
+		"truncated":   hdrHeader + "-Y 2 +X 1\n\x80\x80\x80\x81",
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadHDR(bytes.NewReader([]byte(data)))
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := ReadHDR(bytes.NewReader([]byte(tests["truncated"])))
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+}
@@ -0,0 +1,31 @@
+// Package imageio reads and writes high dynamic range images, and reads
+// ordinary PNG and JPEG images for textures.
+package imageio
+
+// RGB is a linear, floating-point RGB image. Pixels are stored row by row from
+// the top left, three values (red, green, blue) per pixel.
+type RGB struct {
+	Width, Height int
+	Pix           []float64
+}
+
+// NewRGB creates a black image of the given size.
+func NewRGB(width, height int) *RGB {
+	return &RGB{
+		Width:  width,
+		Height: height,
+		Pix:    make([]float64, 3*width*height),
+	}
+}
+
+// At returns the color of the pixel at (x, y).
+func (img *RGB) At(x, y int) (r, g, b float64) {
+	i := 3 * (y*img.Width + x)
+	return img.Pix[i], img.Pix[i+1], img.Pix[i+2]
+}
+
+// Set sets the color of the pixel at (x, y).
+func (img *RGB) Set(x, y int, r, g, b float64) {
+	i := 3 * (y*img.Width + x)
+	img.Pix[i], img.Pix[i+1], img.Pix[i+2] = r, g, b
+}
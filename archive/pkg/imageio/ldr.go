@@ -0,0 +1,83 @@
+package imageio
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"math"
+
+	// Register the formats ReadImage understands.
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/gmhorn/gremlin/archive/pkg/asset"
+)
+
+// LoadImage opens the named PNG or JPEG file with the resolver and reads it.
+func LoadImage(res *asset.Resolver, name string) (*RGB, error) {
+	return loadImage(res, name, ReadImage)
+}
+
+// LoadImageData opens the named PNG or JPEG file with the resolver and reads
+// it with ReadImageData.
+func LoadImageData(res *asset.Resolver, name string) (*RGB, error) {
+	return loadImage(res, name, ReadImageData)
+}
+
+func loadImage(res *asset.Resolver, name string, read func(io.Reader) (*RGB, error)) (*RGB, error) {
+	f, err := res.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, err := read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return img, nil
+}
+
+// ReadImage reads an 8 or 16 bit PNG or JPEG image, like a texture. Pixels are
+// taken to be sRGB encoded and are converted to linear values in [0, 1]. Alpha
+// isn't kept, and colors come out premultiplied by it, so textures should be
+// opaque.
+func ReadImage(r io.Reader) (*RGB, error) {
+	return readImage(r, srgbToLinear)
+}
+
+// ReadImageData is like ReadImage, but for images that hold data rather than
+// colors, like normal maps: values are scaled to [0, 1] without decoding
+// sRGB.
+func ReadImageData(r io.Reader) (*RGB, error) {
+	return readImage(r, func(v uint32) float64 { return float64(v) / 0xffff })
+}
+
+func readImage(r io.Reader, decode func(uint32) float64) (*RGB, error) {
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	img := NewRGB(bounds.Dx(), bounds.Dy())
+	for y := 0; y < img.Height; y++ {
+		for x := 0; x < img.Width; x++ {
+			r, g, b, _ := src.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			img.Set(x, y, decode(r), decode(g), decode(b))
+		}
+	}
+	return img, nil
+}
+
+// srgbToLinear decodes a 16 bit sRGB value, as returned by color.Color, to a
+// linear value in [0, 1].
+//
+// https://en.wikipedia.org/wiki/SRGB#From_sRGB_to_CIE_XYZ
+func srgbToLinear(v uint32) float64 {
+	c := float64(v) / 0xffff
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
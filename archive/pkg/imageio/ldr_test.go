@@ -0,0 +1,46 @@
+package imageio
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadImage(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, color.NRGBA{255, 128, 0, 255})
+	src.Set(1, 0, color.NRGBA{255, 255, 255, 0})
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, src))
+	data := buf.Bytes()
+
+	img, err := ReadImage(bytes.NewReader(data))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 2, img.Width)
+	assert.Equal(t, 1, img.Height)
+
+	// sRGB 128 is about 0.216 linear
+	r, g, b := img.At(0, 0)
+	assert.InDelta(t, 1, r, 1e-9)
+	assert.InDelta(t, 0.216, g, 0.001)
+	assert.InDelta(t, 0, b, 1e-9)
+
+	// transparent pixels are premultiplied to black
+	r, _, _ = img.At(1, 0)
+	assert.Zero(t, r)
+
+	// data isn't decoded
+	img, err = ReadImageData(bytes.NewReader(data))
+	assert.NoError(t, err)
+	_, g, _ = img.At(0, 0)
+	assert.InDelta(t, 128.0/255, g, 1e-9)
+
+	_, err = ReadImage(bytes.NewReader([]byte("not an image")))
+	assert.Error(t, err)
+}
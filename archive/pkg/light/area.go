@@ -0,0 +1,120 @@
+package light
+
+import (
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+)
+
+// Rect is a one-sided, rectangular area light with uniform radiance. It's the
+// parallelogram spanned by Edge1 and Edge2 from Corner, and emits on the side
+// Edge1 x Edge2 points to.
+type Rect struct {
+	Corner       geo.Vec
+	Edge1, Edge2 geo.Vec
+	Radiance     *spectrum.Sampled
+	normal       geo.Unit
+	area         float64
+}
+
+// NewRect creates a rectangular area light.
+func NewRect(corner, edge1, edge2 geo.Vec, radiance spectrum.Distribution) *Rect {
+	n := edge1.Cross(edge2)
+	return &Rect{
+		Corner:   corner,
+		Edge1:    edge1,
+		Edge2:    edge2,
+		Radiance: spectrum.Sample(radiance),
+		normal:   n.Unit(),
+		area:     n.Len(),
+	}
+}
+
+// SampleLi implements Light. Positions are chosen uniformly over the
+// rectangle's area, and the pdf converted to solid angle.
+//
+// https://www.pbr-book.org/3ed-2018/Light_Transport_I_Surface_Reflection/Sampling_Light_Sources#ShapeSampling
+func (r *Rect) SampleLi(point geo.Vec, u1, u2 float64) (Sample, bool) {
+	pos := r.Corner.Plus(r.Edge1.Scale(u1)).Plus(r.Edge2.Scale(u2))
+	d := pos.Minus(point)
+	dist2 := d.LenSquared()
+	if dist2 == 0 {
+		return Sample{}, false
+	}
+	dist := math.Sqrt(dist2)
+	wi := d.Scale(1 / dist).Unit()
+
+	// only the front side emits
+	cos := -wi.Dot(r.normal)
+	if cos <= 0 {
+		return Sample{}, false
+	}
+
+	return Sample{
+		Wi:   wi,
+		Li:   r.Radiance,
+		Dist: dist,
+		PDF:  dist2 / (cos * r.area),
+	}, true
+}
+
+// Power implements Light.
+func (r *Rect) Power() *spectrum.Sampled {
+	return r.Radiance.Scale(math.Pi * r.area)
+}
+
+// Sphere is a spherical area light with uniform radiance.
+type Sphere struct {
+	Center   geo.Vec
+	Radius   float64
+	Radiance *spectrum.Sampled
+}
+
+// NewSphere creates a spherical area light.
+func NewSphere(center geo.Vec, radius float64, radiance spectrum.Distribution) *Sphere {
+	return &Sphere{Center: center, Radius: radius, Radiance: spectrum.Sample(radiance)}
+}
+
+// SampleLi implements Light. Directions are chosen uniformly within the cone
+// the sphere subtends from the point, so every sample hits the visible part of
+// the sphere. Points inside the sphere can't see it.
+//
+// https://www.pbr-book.org/3ed-2018/Light_Transport_I_Surface_Reflection/Sampling_Light_Sources#SamplingSpheres
+func (s *Sphere) SampleLi(point geo.Vec, u1, u2 float64) (Sample, bool) {
+	d := s.Center.Minus(point)
+	dist2 := d.LenSquared()
+	r2 := s.Radius * s.Radius
+	if dist2 <= r2 {
+		return Sample{}, false
+	}
+
+	sin2ThetaMax := r2 / dist2
+	cosThetaMax := math.Sqrt(math.Max(0, 1-sin2ThetaMax))
+	cosTheta := 1 - u1*(1-cosThetaMax)
+	sinTheta := math.Sqrt(math.Max(0, 1-cosTheta*cosTheta))
+	phi := 2 * math.Pi * u2
+
+	frame := geo.FrameFromNormal(d.Unit())
+	wi := frame.ToWorld(geo.Unit{
+		X: sinTheta * math.Cos(phi),
+		Y: sinTheta * math.Sin(phi),
+		Z: cosTheta,
+	})
+
+	// distance to the near side of the sphere along wi
+	dc := math.Sqrt(dist2)
+	dist := dc*cosTheta - math.Sqrt(math.Max(0, r2-dist2*sinTheta*sinTheta))
+
+	return Sample{
+		Wi:   wi,
+		Li:   s.Radiance,
+		Dist: dist,
+		PDF:  1 / (2 * math.Pi * (1 - cosThetaMax)),
+	}, true
+}
+
+// Power implements Light.
+func (s *Sphere) Power() *spectrum.Sampled {
+	return s.Radiance.Scale(4 * math.Pi * math.Pi * s.Radius * s.Radius)
+}
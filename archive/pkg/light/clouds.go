@@ -0,0 +1,208 @@
+package light
+
+import (
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/gmhorn/gremlin/archive/pkg/texture"
+	"github.com/gmhorn/gremlin/archive/pkg/util"
+)
+
+// Clouds is an infinite light that puts a layer of procedural clouds in front
+// of a sky, lit by the sun and by the sky itself. Le ray marches through a
+// slab of noise between the heights Bottom and Top above the viewer (in scene
+// units, which for a sun and sky are best thought of as meters), so clouds
+// are bright and thin at the edges, dark underneath and glow around the sun.
+//
+// Sampling is left to the sky: SampleLi and PDF are the sky's, and the clouds
+// only change the radiance, so pair it with the path tracer's multiple
+// importance sampling (it's a light.Infinite) rather than relying on light
+// samples alone. The clouds don't shadow the sun's direct light either.
+//
+// Scattering is single scattering with a Henyey-Greenstein phase function,
+// plus a cheap stand-in for multiple scattering so thick clouds aren't black
+// inside.
+//
+// https://www.ea.com/frostbite/news/physically-based-sky-atmosphere-and-cloud-rendering
+type Clouds struct {
+	Sky Infinite
+	Sun *Directional
+
+	// Coverage is roughly the fraction of the sky covered, from 0 (clear) to 1
+	// (overcast). It assumes Noise is fractal noise around 0.5, like
+	// texture.Noise.
+	Coverage float64
+
+	// Bottom and Top are the heights of the cloud layer, Extinction the
+	// extinction coefficient (per unit length) of its densest parts and Steps
+	// the number of ray marching steps through it.
+	Bottom, Top float64
+	Extinction  float64
+	Steps       int
+
+	// Noise is sampled at points in the layer for the cloud density.
+	Noise texture.Scalar
+}
+
+// Defaults for NewClouds.
+const (
+	cloudBottom     = 1000
+	cloudTop        = 1600
+	cloudExtinction = 0.02
+	cloudSteps      = 32
+	cloudFrequency  = 1 / 800.
+	cloudOctaves    = 5
+)
+
+// Cloud shading constants: the Henyey-Greenstein asymmetry (clouds scatter
+// strongly forward), steps towards the sun for its transmittance, the height
+// of the view direction below which clouds fade out, the transmittance at
+// which marching stops, and the longest march, in layer thicknesses.
+const (
+	cloudG         = 0.6
+	cloudSunSteps  = 4
+	cloudHorizon   = 0.1
+	cloudCutoff    = 0.01
+	cloudMaxLength = 4
+)
+
+// NewClouds creates a cloud layer in front of the sky, lit by sun (which may
+// be nil, for an environment map), with the given coverage in [0, 1].
+func NewClouds(sky Infinite, sun *Directional, coverage float64) *Clouds {
+	if coverage < 0 || coverage > 1 {
+		panic("cloud coverage must be in [0, 1]")
+	}
+	return &Clouds{
+		Sky:        sky,
+		Sun:        sun,
+		Coverage:   coverage,
+		Bottom:     cloudBottom,
+		Top:        cloudTop,
+		Extinction: cloudExtinction,
+		Steps:      cloudSteps,
+		Noise:      texture.NewNoise(nil, nil, cloudFrequency, cloudOctaves),
+	}
+}
+
+// Le implements Infinite.
+func (c *Clouds) Le(dir geo.Unit) *spectrum.Sampled {
+	sky := c.Sky.Le(dir)
+	if dir.Y <= 0 || c.Coverage == 0 {
+		return sky
+	}
+
+	// march from the bottom of the layer to the top, but not forever near
+	// the horizon, where the clouds fade out anyway
+	t0 := c.Bottom / dir.Y
+	t1 := math.Min(c.Top/dir.Y, t0+cloudMaxLength*(c.Top-c.Bottom))
+	dt := (t1 - t0) / float64(c.Steps)
+
+	// jitter the start per direction, trading banding for noise
+	jitter := float64(util.Hash(math.Float64bits(dir.X), math.Float64bits(dir.Y), math.Float64bits(dir.Z))>>11) / (1 << 53)
+
+	var toSun geo.Unit
+	phase := 0.0
+	if c.Sun != nil {
+		toSun = c.Sun.Dir.Reverse()
+		phase = henyeyGreenstein(toSun.Dot(dir), cloudG)
+	}
+
+	// transmittance, and the shares of the sun and the ambient sky light
+	// scattered towards the viewer
+	tr, sun, ambient := 1.0, 0.0, 0.0
+	for i := 0; i < c.Steps && tr > cloudCutoff; i++ {
+		p := dir.Scale(t0 + (float64(i)+jitter)*dt)
+		sigma := c.Extinction * c.density(p)
+		if sigma == 0 {
+			continue
+		}
+
+		// integrate the in-scattering over the step analytically, so thick
+		// steps don't add energy
+		step := math.Exp(-sigma * dt)
+		scattered := tr * (1 - step)
+		if phase > 0 && toSun.Y > 0 {
+			sun += scattered * phase * c.sunTransmittance(p, toSun)
+		}
+		ambient += scattered
+		tr *= step
+	}
+
+	// fade the clouds out towards the horizon
+	fade := math.Min(1, dir.Y/cloudHorizon)
+	fade *= fade * (3 - 2*fade)
+	tr = 1 - fade*(1-tr)
+
+	// the sky above lights the clouds from all around; half of it is a fair
+	// guess, with the ground below
+	out := sky.Scale(tr).Plus(c.Sky.Le(geo.YAxis).Scale(0.5 * fade * ambient))
+	if c.Sun != nil && sun > 0 {
+		out = out.Plus(c.Sun.Radiance.Scale(fade * sun))
+	}
+	return out
+}
+
+// density returns the cloud density, in [0, 1], at the point p.
+func (c *Clouds) density(p geo.Vec) float64 {
+	h := (p.Y - c.Bottom) / (c.Top - c.Bottom)
+	if h <= 0 || h >= 1 {
+		return 0
+	}
+
+	// the noise is mostly within [0.3, 0.7], so coverage moves the threshold
+	// across that, with a soft edge
+	threshold := 0.7 - 0.4*c.Coverage
+	d := (c.Noise.Value(texture.Coords{P: p}) - threshold) / 0.1
+	if d <= 0 {
+		return 0
+	}
+
+	// rounded tops and bottoms
+	return math.Min(1, d) * 4 * h * (1 - h)
+}
+
+// sunTransmittance returns the transmittance from the point p to the top of
+// the layer towards the sun.
+func (c *Clouds) sunTransmittance(p geo.Vec, toSun geo.Unit) float64 {
+	length := math.Min((c.Top-p.Y)/toSun.Y, c.Top-c.Bottom)
+	dt := length / cloudSunSteps
+	tau := 0.0
+	for i := 0; i < cloudSunSteps; i++ {
+		q := p.Plus(toSun.Scale((float64(i) + 0.5) * dt))
+		tau += c.Extinction * c.density(q) * dt
+	}
+
+	// multiple scattering lets light further into a cloud than Beer-Lambert
+	// alone would, which a second, softer extinction makes up for
+	return math.Max(math.Exp(-tau), 0.3*math.Exp(-0.25*tau))
+}
+
+// SampleLi implements Light, with the sky's sampling.
+func (c *Clouds) SampleLi(point geo.Vec, u1, u2 float64) (Sample, bool) {
+	s, ok := c.Sky.SampleLi(point, u1, u2)
+	if !ok {
+		return s, false
+	}
+	s.Li = c.Le(s.Wi)
+	return s, true
+}
+
+// PDF implements Infinite.
+func (c *Clouds) PDF(dir geo.Unit) float64 {
+	return c.Sky.PDF(dir)
+}
+
+// Power implements Light. It's the sky's: the clouds mostly move light
+// around.
+func (c *Clouds) Power() *spectrum.Sampled {
+	return c.Sky.Power()
+}
+
+// henyeyGreenstein is the Henyey-Greenstein phase function for the cosine of
+// the angle between the directions towards the light and the viewer, with
+// asymmetry g.
+func henyeyGreenstein(cos, g float64) float64 {
+	denom := 1 + g*g - 2*g*cos
+	return (1 - g*g) / (4 * math.Pi * denom * math.Sqrt(denom))
+}
@@ -0,0 +1,102 @@
+package light
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/imageio"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/gmhorn/gremlin/archive/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClouds_Coverage(t *testing.T) {
+	img := imageio.NewRGB(16, 8)
+	for i := range img.Pix {
+		img.Pix[i] = 1
+	}
+	sky := NewEnvironment(img, 1)
+
+	// with a uniform sky and no sun, clouds only dim it, by absorbing half of
+	// what they block (the ground's half of the ambient light)
+	brightness := func(coverage float64) float64 {
+		c := NewClouds(sky, nil, coverage)
+		rnd := util.NewRand(0)
+		sum := 0.0
+		for i := 0; i < 200; i++ {
+			dir := geo.V(rnd.Float64()-0.5, 1, rnd.Float64()-0.5).Unit()
+			sum += c.Le(dir)[10] / sky.Le(dir)[10]
+		}
+		return sum / 200
+	}
+	clear, half, overcast := brightness(0), brightness(0.5), brightness(1)
+	assert.Equal(t, 1.0, clear)
+	assert.Less(t, half, 0.95)
+	assert.Greater(t, half, 0.55)
+	assert.InDelta(t, 0.5, overcast, 0.02)
+
+	// below the horizon there are no clouds
+	c := NewClouds(sky, nil, 1)
+	down := geo.V(0.3, -1, 0).Unit()
+	assert.Equal(t, sky.Le(down), c.Le(down))
+
+	assert.Panics(t, func() { NewClouds(sky, nil, 1.5) })
+}
+
+func TestClouds_SunSky(t *testing.T) {
+	sun, sky := NewSunSky(40, 0, time.Date(2023, 6, 21, 16, 0, 0, 0, time.UTC), 3)
+	c := NewClouds(sky, sun, 1)
+	toSun := sun.Dir.Reverse()
+
+	// an overcast sky is whiter and darker than a clear one
+	up := geo.YAxis
+	last := len(sky.Le(up)) - 1
+	assert.Greater(t, c.Le(up)[last]/c.Le(up)[0], sky.Le(up)[last]/sky.Le(up)[0])
+	assert.Less(t, c.Le(up).Max(), sky.Le(up).Max())
+	assert.False(t, math.IsNaN(c.Le(toSun)[0]))
+
+	// sampling is the sky's, with the clouds' radiance
+	rnd := util.NewRand(0)
+	for i := 0; i < 20; i++ {
+		s, ok := c.SampleLi(geo.Origin, rnd.Float64(), rnd.Float64())
+		if !ok {
+			continue
+		}
+		assert.Equal(t, c.Le(s.Wi), s.Li)
+		assert.Equal(t, sky.PDF(s.Wi), c.PDF(s.Wi))
+	}
+}
+
+func TestClouds_ForwardScattering(t *testing.T) {
+	img := imageio.NewRGB(16, 8)
+	for i := range img.Pix {
+		img.Pix[i] = 1
+	}
+	toSun := geo.V(1, 1, 0).Unit()
+	sun := NewDirectional(geo.V(-1, -1, 0), spectrum.Flat(50))
+	c := NewClouds(NewEnvironment(img, 1), sun, 0.5)
+
+	// broken clouds are brightest around the sun
+	rnd := util.NewRand(0)
+	near, far := 0.0, 0.0
+	for i := 0; i < 200; i++ {
+		jitter := geo.V(rnd.Float64()-0.5, rnd.Float64()-0.5, rnd.Float64()-0.5).Scale(0.2)
+		near += c.Le(geo.Vec(toSun).Plus(jitter).Unit())[10]
+		far += c.Le(geo.V(-1, 1, 0).Plus(jitter).Unit())[10]
+	}
+	assert.Greater(t, near, 2*far)
+}
+
+func TestHenyeyGreenstein(t *testing.T) {
+	// normalized over the sphere, and forward scattering for g > 0
+	sum, n := 0.0, 10000
+	for i := 0; i < n; i++ {
+		cos := -1 + 2*(float64(i)+0.5)/float64(n)
+		sum += henyeyGreenstein(cos, 0.6) * 2 * math.Pi * 2 / float64(n)
+	}
+	assert.InDelta(t, 1, sum, 1e-3)
+	assert.Greater(t, henyeyGreenstein(1, 0.6), henyeyGreenstein(-1, 0.6))
+	assert.InDelta(t, 1/(4*math.Pi), henyeyGreenstein(0.3, 0), 1e-12)
+}
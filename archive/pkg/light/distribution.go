@@ -0,0 +1,103 @@
+package light
+
+import "sort"
+
+// distribution1D is a piecewise-constant 1D distribution over [0, 1), for
+// sampling in proportion to a tabulated function.
+//
+// https://www.pbr-book.org/3ed-2018/Monte_Carlo_Integration/Sampling_Random_Variables#Example:Piecewise-Constant1DFunctions
+type distribution1D struct {
+	fn       []float64
+	cdf      []float64
+	integral float64
+}
+
+func newDistribution1D(fn []float64) *distribution1D {
+	n := len(fn)
+	cdf := make([]float64, n+1)
+	for i, f := range fn {
+		cdf[i+1] = cdf[i] + f/float64(n)
+	}
+
+	integral := cdf[n]
+	for i := 1; i <= n; i++ {
+		if integral == 0 {
+			// all zero: fall back to uniform
+			cdf[i] = float64(i) / float64(n)
+		} else {
+			cdf[i] /= integral
+		}
+	}
+	return &distribution1D{fn: fn, cdf: cdf, integral: integral}
+}
+
+// sample returns a value in [0, 1), its pdf, and the index of the segment it's
+// in.
+func (d *distribution1D) sample(u float64) (x, pdf float64, idx int) {
+	// last cdf entry <= u
+	idx = sort.Search(len(d.cdf), func(i int) bool { return d.cdf[i] > u }) - 1
+	if idx < 0 {
+		idx = 0
+	} else if idx >= len(d.fn) {
+		idx = len(d.fn) - 1
+	}
+
+	du := u - d.cdf[idx]
+	if width := d.cdf[idx+1] - d.cdf[idx]; width > 0 {
+		du /= width
+	}
+	return (float64(idx) + du) / float64(len(d.fn)), d.pdf(idx), idx
+}
+
+// pdf returns the density of the idx-th segment.
+func (d *distribution1D) pdf(idx int) float64 {
+	if d.integral == 0 {
+		return 1
+	}
+	return d.fn[idx] / d.integral
+}
+
+// distribution2D samples [0, 1)^2 in proportion to a tabulated function, by
+// first choosing a row from the marginal distribution, then a column within
+// it.
+//
+// https://www.pbr-book.org/3ed-2018/Monte_Carlo_Integration/2D_Sampling_with_Multidimensional_Transformations#Piecewise-Constant2DDistributions
+type distribution2D struct {
+	rows     []*distribution1D
+	marginal *distribution1D
+}
+
+// newDistribution2D creates a distribution from row-major function values.
+func newDistribution2D(fn []float64, width, height int) *distribution2D {
+	rows := make([]*distribution1D, height)
+	integrals := make([]float64, height)
+	for y := range rows {
+		rows[y] = newDistribution1D(fn[y*width : (y+1)*width])
+		integrals[y] = rows[y].integral
+	}
+	return &distribution2D{rows: rows, marginal: newDistribution1D(integrals)}
+}
+
+// sample returns a point in [0, 1)^2 and its pdf.
+func (d *distribution2D) sample(u1, u2 float64) (x, y, pdf float64) {
+	y, pdfY, row := d.marginal.sample(u2)
+	x, pdfX, _ := d.rows[row].sample(u1)
+	return x, y, pdfX * pdfY
+}
+
+// pdf returns the density at the point (x, y) in [0, 1)^2.
+func (d *distribution2D) pdf(x, y float64) float64 {
+	row := clampIndex(int(y*float64(len(d.rows))), len(d.rows))
+	col := clampIndex(int(x*float64(len(d.rows[row].fn))), len(d.rows[row].fn))
+	return d.marginal.pdf(row) * d.rows[row].pdf(col)
+}
+
+func clampIndex(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
@@ -0,0 +1,143 @@
+package light
+
+import (
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/imageio"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+)
+
+// Infinite is a light infinitely far away that surrounds the scene, so rays
+// that escape the scene see it. Le returns the radiance arriving from the
+// direction dir, and PDF the density with which SampleLi chooses dir, with
+// respect to solid angle, so paths that find the light by chance can be
+// weighted against sampling it directly.
+type Infinite interface {
+	Light
+	Le(dir geo.Unit) *spectrum.Sampled
+	PDF(dir geo.Unit) float64
+}
+
+// Environment is an infinite light whose radiance comes from an
+// equirectangular (latitude-longitude) image, like an HDRI of a real location.
+// The image's top row is straight up (+y), and its center looks down -z.
+//
+// Directions are importance sampled in proportion to the image's brightness,
+// so small bright regions like the sun are found quickly.
+//
+// Like Directional, its power depends on SceneRadius.
+//
+// https://www.pbr-book.org/3ed-2018/Light_Sources/Infinite_Area_Lights
+type Environment struct {
+	Image       *imageio.RGB
+	Scale       float64
+	SceneRadius float64
+	dist        *distribution2D
+}
+
+// NewEnvironment creates an environment light from the image, with its
+// radiance multiplied by scale.
+func NewEnvironment(img *imageio.RGB, scale float64) *Environment {
+	// weight by sin(theta), since rows near the poles cover less solid angle
+	fn := make([]float64, img.Width*img.Height)
+	for y := 0; y < img.Height; y++ {
+		sinTheta := math.Sin(math.Pi * (float64(y) + 0.5) / float64(img.Height))
+		for x := 0; x < img.Width; x++ {
+			r, g, b := img.At(x, y)
+			fn[y*img.Width+x] = luminance(r, g, b) * sinTheta
+		}
+	}
+
+	return &Environment{
+		Image: img,
+		Scale: scale,
+		dist:  newDistribution2D(fn, img.Width, img.Height),
+	}
+}
+
+// Le implements Infinite.
+func (e *Environment) Le(dir geo.Unit) *spectrum.Sampled {
+	u, v := e.uv(dir)
+	x := clampIndex(int(u*float64(e.Image.Width)), e.Image.Width)
+	y := clampIndex(int(v*float64(e.Image.Height)), e.Image.Height)
+
+	r, g, b := e.Image.At(x, y)
+	return spectrum.FromRGB(r, g, b).Scale(e.Scale)
+}
+
+// SampleLi implements Light.
+func (e *Environment) SampleLi(point geo.Vec, u1, u2 float64) (Sample, bool) {
+	u, v, pdf := e.dist.sample(u1, u2)
+	if pdf == 0 {
+		return Sample{}, false
+	}
+
+	wi := e.dir(u, v)
+	sinTheta := math.Sin(v * math.Pi)
+	if sinTheta == 0 {
+		return Sample{}, false
+	}
+
+	return Sample{
+		Wi:   wi,
+		Li:   e.Le(wi),
+		Dist: math.Inf(1),
+		// convert from the image's (u, v) to solid angle
+		PDF: pdf / (2 * math.Pi * math.Pi * sinTheta),
+	}, true
+}
+
+// PDF implements Infinite.
+func (e *Environment) PDF(dir geo.Unit) float64 {
+	u, v := e.uv(dir)
+	sinTheta := math.Sin(v * math.Pi)
+	if sinTheta == 0 {
+		return 0
+	}
+	return e.dist.pdf(u, v) / (2 * math.Pi * math.Pi * sinTheta)
+}
+
+// Power implements Light.
+func (e *Environment) Power() *spectrum.Sampled {
+	// average radiance over the sphere
+	sum := new(spectrum.Sampled)
+	total := 0.0
+	for y := 0; y < e.Image.Height; y++ {
+		sinTheta := math.Sin(math.Pi * (float64(y) + 0.5) / float64(e.Image.Height))
+		for x := 0; x < e.Image.Width; x++ {
+			r, g, b := e.Image.At(x, y)
+			sum = sum.Plus(spectrum.FromRGB(r, g, b).Scale(sinTheta))
+			total += sinTheta
+		}
+	}
+	if total == 0 {
+		return sum
+	}
+	return sum.Scale(e.Scale * math.Pi * e.SceneRadius * e.SceneRadius / total)
+}
+
+// uv returns the image coordinates, in [0, 1)^2, for the direction.
+func (e *Environment) uv(dir geo.Unit) (u, v float64) {
+	theta := math.Acos(math.Max(-1, math.Min(1, dir.Y)))
+	phi := math.Atan2(dir.X, -dir.Z)
+	return 0.5 + phi/(2*math.Pi), theta / math.Pi
+}
+
+// dir is the inverse of uv.
+func (e *Environment) dir(u, v float64) geo.Unit {
+	theta := v * math.Pi
+	phi := (u - 0.5) * 2 * math.Pi
+	sinTheta := math.Sin(theta)
+	return geo.Unit{
+		X: sinTheta * math.Sin(phi),
+		Y: math.Cos(theta),
+		Z: -sinTheta * math.Cos(phi),
+	}
+}
+
+// luminance returns the relative luminance (the Y of CIE XYZ) of a linear sRGB
+// color.
+func luminance(r, g, b float64) float64 {
+	return 0.2126*r + 0.7152*g + 0.0722*b
+}
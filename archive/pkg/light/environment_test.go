@@ -0,0 +1,93 @@
+package light
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/imageio"
+	"github.com/gmhorn/gremlin/archive/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistribution1D(t *testing.T) {
+	d := newDistribution1D([]float64{1, 3, 0, 0})
+	assert.Equal(t, 1.0, d.integral)
+
+	x, pdf, idx := d.sample(0.1)
+	assert.Equal(t, 0, idx)
+	assert.InDelta(t, 0.1, x, 1e-12)
+	assert.Equal(t, 1.0, pdf)
+
+	x, pdf, idx = d.sample(0.5)
+	assert.Equal(t, 1, idx)
+	assert.InDelta(t, 0.25+0.25/3, x, 1e-12)
+	assert.Equal(t, 3.0, pdf)
+
+	// never lands in a zero segment
+	_, _, idx = d.sample(0.9999)
+	assert.Equal(t, 1, idx)
+
+	// all zero falls back to uniform
+	x, _, _ = newDistribution1D([]float64{0, 0}).sample(0.75)
+	assert.InDelta(t, 0.75, x, 1e-12)
+}
+
+func TestEnvironment_Uniform(t *testing.T) {
+	img := imageio.NewRGB(16, 8)
+	for i := range img.Pix {
+		img.Pix[i] = 1
+	}
+	env := NewEnvironment(img, 2)
+	rnd := util.NewRand(0)
+
+	for i := 0; i < 100; i++ {
+		s, ok := env.SampleLi(geo.Origin, rnd.Float64(), rnd.Float64())
+		assert.True(t, ok)
+		assert.InDelta(t, 2, s.Li[0], 1e-12)
+		assert.InDelta(t, env.PDF(s.Wi), s.PDF, 1e-9)
+		assert.True(t, math.IsInf(s.Dist, 1))
+	}
+
+	// the sin(theta) weighting makes the pdf over solid angle (nearly)
+	// uniform, up to the resolution of the image
+	assert.InDelta(t, 1/(4*math.Pi), env.PDF(geo.V(1, 0.2, 0).Unit()), 0.01)
+}
+
+func TestEnvironment_Bright(t *testing.T) {
+	img := imageio.NewRGB(16, 8)
+	for i := range img.Pix {
+		img.Pix[i] = 0.01
+	}
+	img.Set(8, 2, 1000, 1000, 1000)
+	env := NewEnvironment(img, 1)
+	rnd := util.NewRand(0)
+
+	sun := env.dir((8+0.5)/16, (2+0.5)/8)
+	assert.InDelta(t, 1000, env.Le(sun)[0], 1e-9)
+
+	near := 0
+	for i := 0; i < 100; i++ {
+		s, _ := env.SampleLi(geo.Origin, rnd.Float64(), rnd.Float64())
+		if s.Wi.Dot(sun) > 0.9 {
+			near++
+		}
+	}
+	assert.Greater(t, near, 90)
+}
+
+func TestEnvironment_UV(t *testing.T) {
+	env := NewEnvironment(imageio.NewRGB(4, 2), 1)
+
+	u, v := env.uv(geo.Unit{Z: -1})
+	assert.InDelta(t, 0.5, u, 1e-12)
+	assert.InDelta(t, 0.5, v, 1e-12)
+
+	for _, d := range []geo.Vec{geo.V(1, 2, 3), geo.V(-1, -0.5, 0.2), geo.V(0, 0.1, 1)} {
+		w := d.Unit()
+		back := env.dir(env.uv(w))
+		assert.InDelta(t, w.X, back.X, 1e-12)
+		assert.InDelta(t, w.Y, back.Y, 1e-12)
+		assert.InDelta(t, w.Z, back.Z, 1e-12)
+	}
+}
@@ -0,0 +1,69 @@
+// Package light has light sources that can be sampled directly, for next-event
+// estimation.
+package light
+
+import (
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+)
+
+// Light is a source of light that can be sampled from any point in the scene.
+//
+// https://www.pbr-book.org/3ed-2018/Light_Sources/Light_Interface
+type Light interface {
+	// SampleLi samples a direction from point towards the light, using the
+	// uniform random numbers u1 and u2. Returns false if the light can't be
+	// seen from the point at all.
+	SampleLi(point geo.Vec, u1, u2 float64) (Sample, bool)
+
+	// Power returns the total power emitted by the light.
+	Power() *spectrum.Sampled
+}
+
+// Sample is a direction towards a light chosen by Light.SampleLi.
+//
+// Wi is the direction from the point to the light, and Dist the distance to
+// the sampled position on the light (infinite for lights at infinity), which
+// is needed to test for occluders. Li is the radiance arriving at the point
+// if it's not occluded, and PDF is the probability density of the sample with
+// respect to solid angle. For lights described by a delta distribution (point
+// and directional lights), PDF is 1.
+type Sample struct {
+	Wi   geo.Unit
+	Li   *spectrum.Sampled
+	Dist float64
+	PDF  float64
+}
+
+// SetLumens scales the emission of a point or area light so that it emits the
+// given luminous flux, e.g. 800 lumens for a household bulb. Luminance is
+// taken relative to a flat spectrum of 1, as by colorspace.CIE1931Reflectance,
+// so the units are those of NewSunSky: a flat radiance of 1 is 1 cd/m².
+//
+// Returns false for lights without a fixed power, like Directional and
+// Environment lights, and for lights that emit nothing.
+func SetLumens(l Light, lumens float64) bool {
+	flux := luminanceOf(l.Power())
+	if flux <= 0 {
+		return false
+	}
+	k := lumens / flux
+
+	switch l := l.(type) {
+	case *Point:
+		l.Intensity = l.Intensity.Scale(k)
+	case *Rect:
+		l.Radiance = l.Radiance.Scale(k)
+	case *Sphere:
+		l.Radiance = l.Radiance.Scale(k)
+	case *Panel:
+		l.Radiance = l.Radiance.Scale(k)
+	case *Ring:
+		l.Radiance = l.Radiance.Scale(k)
+	case *Mesh:
+		l.Scale *= k
+	default:
+		return false
+	}
+	return true
+}
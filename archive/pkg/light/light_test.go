@@ -0,0 +1,97 @@
+package light
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/gmhorn/gremlin/archive/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoint(t *testing.T) {
+	p := NewPoint(geo.V(0, 2, 0), spectrum.Flat(8))
+
+	s, ok := p.SampleLi(geo.V(0, 0, 0), 0.5, 0.5)
+	assert.True(t, ok)
+	assert.Equal(t, geo.Unit{Y: 1}, s.Wi)
+	assert.Equal(t, 2.0, s.Dist)
+	assert.Equal(t, 2.0, s.Li[0])
+	assert.InDelta(t, 32*math.Pi, p.Power()[0], 1e-12)
+}
+
+func TestDirectional(t *testing.T) {
+	d := NewDirectional(geo.V(0, -3, 0), spectrum.Flat(2))
+	d.SceneRadius = 10
+
+	s, ok := d.SampleLi(geo.V(5, 5, 5), 0.5, 0.5)
+	assert.True(t, ok)
+	assert.Equal(t, geo.Unit{Y: 1}, s.Wi)
+	assert.True(t, math.IsInf(s.Dist, 1))
+	assert.InDelta(t, 200*math.Pi, d.Power()[0], 1e-9)
+}
+
+func TestRect(t *testing.T) {
+	// small light facing down, 10 units above the origin
+	r := NewRect(geo.V(-0.05, 10, -0.05), geo.V(0.1, 0, 0), geo.V(0, 0, 0.1), spectrum.Flat(1))
+	rnd := util.NewRand(0)
+
+	// E[1/pdf] is the solid angle, which is about area/dist^2 for a small,
+	// distant light
+	sum := 0.0
+	n := 1000
+	for i := 0; i < n; i++ {
+		s, ok := r.SampleLi(geo.V(0, 0, 0), rnd.Float64(), rnd.Float64())
+		assert.True(t, ok)
+		assert.InDelta(t, 10, s.Dist, 0.01)
+		sum += 1 / s.PDF
+	}
+	assert.InDelta(t, 0.01/100, sum/float64(n), 1e-7)
+
+	// can't be seen from behind
+	_, ok := r.SampleLi(geo.V(0, 20, 0), 0.5, 0.5)
+	assert.False(t, ok)
+
+	assert.InDelta(t, 0.01*math.Pi, r.Power()[0], 1e-12)
+}
+
+func TestSphere(t *testing.T) {
+	s := NewSphere(geo.V(0, 0, 5), 1, spectrum.Flat(1))
+	rnd := util.NewRand(0)
+	point := geo.V(0, 0, 0)
+
+	for i := 0; i < 100; i++ {
+		ls, ok := s.SampleLi(point, rnd.Float64(), rnd.Float64())
+		assert.True(t, ok)
+
+		// sampled point is on the near side of the sphere
+		onSphere := point.Plus(ls.Wi.Scale(ls.Dist))
+		assert.InDelta(t, 1, onSphere.Minus(s.Center).Len(), 1e-9)
+		assert.Less(t, onSphere.Z, s.Center.Z)
+
+		// uniform over the cone
+		cosThetaMax := math.Sqrt(1 - 1.0/25)
+		assert.InDelta(t, 1/(2*math.Pi*(1-cosThetaMax)), ls.PDF, 1e-9)
+	}
+
+	_, ok := s.SampleLi(geo.V(0, 0, 5.5), 0.5, 0.5)
+	assert.False(t, ok)
+}
+
+func TestSetLumens(t *testing.T) {
+	p := NewPoint(geo.V(0, 0, 0), spectrum.IlluminantD65)
+	assert.True(t, SetLumens(p, 800))
+	assert.InDelta(t, 800, luminanceOf(p.Power()), 1e-9)
+	// still D65
+	assert.InDelta(t, spectrum.IlluminantD65[0]/spectrum.IlluminantD65[40], p.Intensity[0]/p.Intensity[40], 1e-12)
+
+	r := NewRect(geo.V(0, 0, 0), geo.V(1, 0, 0), geo.V(0, 1, 0), spectrum.Flat(1))
+	assert.True(t, SetLumens(r, 100))
+	assert.InDelta(t, 100/math.Pi, r.Radiance[0], 1e-9)
+
+	d := NewDirectional(geo.V(0, -1, 0), spectrum.Flat(1))
+	d.SceneRadius = 10
+	assert.False(t, SetLumens(d, 100))
+	assert.False(t, SetLumens(NewPoint(geo.V(0, 0, 0), spectrum.Flat(0)), 100))
+}
@@ -0,0 +1,223 @@
+package light
+
+import (
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/gmhorn/gremlin/archive/pkg/texture"
+)
+
+// maxMeshSubdivisions caps how finely each face of a Mesh light is split up
+// for sampling, so that huge textures on big faces don't cost unbounded
+// memory.
+const maxMeshSubdivisions = 32
+
+// Mesh is a one-sided area light in the shape of a triangle mesh, emitting
+// radiance from a texture, like a TV screen or a stained glass window lit from
+// behind. Faces emit on the side their vertices wind counter-clockwise around,
+// as for Rect, and the texture is looked up with the mesh's UVs, times Scale.
+//
+// Positions are sampled in proportion to the emitted power: each face is split
+// into smaller triangles about the size of the texture's pixels (for image
+// textures; other textures are taken to be smooth), weighted by their area and
+// brightness, so bright parts of the texture are sampled more. Every part of
+// the mesh keeps a small chance of being sampled, so that nothing the
+// weighting misses is lost.
+//
+// Like the other area lights, it isn't part of the scene geometry (see
+// render.PathTracer), so add the mesh to the scene too to see it.
+type Mesh struct {
+	Mesh     *shape.Mesh
+	Emission texture.Texture
+	Scale    float64
+	cells    []meshCell
+	dist     *distribution1D
+}
+
+// meshCell is one of the triangles the faces are split into: subdivision
+// level k of face, at (i, j) in the grid of barycentric coordinates, and
+// flipped if it's one of the upside-down triangles between upright ones.
+type meshCell struct {
+	face    int
+	k, i, j int32
+	flipped bool
+}
+
+// NewMesh creates a mesh light with emission from the texture, multiplied by
+// scale.
+func NewMesh(m *shape.Mesh, emission texture.Texture, scale float64) *Mesh {
+	ml := &Mesh{Mesh: m, Emission: emission, Scale: scale}
+
+	img, _ := emission.(*texture.Image)
+	for face := 0; face < m.NumFaces(); face++ {
+		k := 1
+		if img != nil {
+			// about one cell per texel the face covers
+			uv := ml.uvs(face)
+			uvArea := math.Abs((uv[1][0]-uv[0][0])*(uv[2][1]-uv[0][1])-(uv[2][0]-uv[0][0])*(uv[1][1]-uv[0][1])) / 2
+			texels := uvArea * float64(img.Image.Width*img.Image.Height)
+			k = int(math.Min(maxMeshSubdivisions, math.Max(1, math.Ceil(math.Sqrt(2*texels)))))
+		}
+		for i := 0; i < k; i++ {
+			for j := 0; j < k-i; j++ {
+				ml.cells = append(ml.cells, meshCell{face, int32(k), int32(i), int32(j), false})
+				if j < k-i-1 {
+					ml.cells = append(ml.cells, meshCell{face, int32(k), int32(i), int32(j), true})
+				}
+			}
+		}
+	}
+
+	// weight by area and the luminance at the center of each cell, plus a
+	// small floor so that every part of the mesh can be sampled
+	weights := make([]float64, len(ml.cells))
+	areas := make([]float64, len(ml.cells))
+	var total, totalArea float64
+	for c := range ml.cells {
+		p := ml.corners(&ml.cells[c])
+		areas[c] = p[1].Minus(p[0]).Cross(p[2].Minus(p[0])).Len() / 2
+		center := [3]float64{1.0 / 3, 1.0 / 3, 1.0 / 3}
+		weights[c] = areas[c] * luminanceOf(ml.emission(&ml.cells[c], center))
+		total += weights[c]
+		totalArea += areas[c]
+	}
+	if totalArea > 0 {
+		floor := 1e-3 * total / totalArea
+		for c := range weights {
+			weights[c] += floor * areas[c]
+		}
+	}
+	ml.dist = newDistribution1D(weights)
+	return ml
+}
+
+// SampleLi implements Light.
+func (ml *Mesh) SampleLi(point geo.Vec, u1, u2 float64) (Sample, bool) {
+	if len(ml.cells) == 0 {
+		return Sample{}, false
+	}
+	x, pdf, idx := ml.dist.sample(u1)
+	n := float64(len(ml.cells))
+	cell := &ml.cells[idx]
+
+	// reuse what's left of u1 to pick a point uniformly in the cell
+	u := x*n - float64(idx)
+	su := math.Sqrt(u)
+	b := [3]float64{1 - su, su * (1 - u2), su * u2}
+
+	p := ml.corners(cell)
+	pos := p[0].Scale(b[0]).Plus(p[1].Scale(b[1])).Plus(p[2].Scale(b[2]))
+	cross := p[1].Minus(p[0]).Cross(p[2].Minus(p[0]))
+	area := cross.Len() / 2
+	if area == 0 {
+		return Sample{}, false
+	}
+
+	d := pos.Minus(point)
+	dist2 := d.LenSquared()
+	if dist2 == 0 {
+		return Sample{}, false
+	}
+	dist := math.Sqrt(dist2)
+	wi := d.Scale(1 / dist).Unit()
+
+	// only the front side emits
+	cos := -wi.Dot(cross.Unit())
+	if cos <= 0 {
+		return Sample{}, false
+	}
+
+	return Sample{
+		Wi:   wi,
+		Li:   ml.emission(cell, b),
+		Dist: dist,
+		// the cell was chosen with probability pdf/n
+		PDF: pdf / n / area * dist2 / cos,
+	}, true
+}
+
+// Power implements Light.
+func (ml *Mesh) Power() *spectrum.Sampled {
+	power := new(spectrum.Sampled)
+	center := [3]float64{1.0 / 3, 1.0 / 3, 1.0 / 3}
+	for c := range ml.cells {
+		p := ml.corners(&ml.cells[c])
+		area := p[1].Minus(p[0]).Cross(p[2].Minus(p[0])).Len() / 2
+		power = power.Plus(ml.emission(&ml.cells[c], center).Scale(math.Pi * area))
+	}
+	return power
+}
+
+// corners returns the positions of the cell's corners, wound the same way as
+// its face.
+func (ml *Mesh) corners(cell *meshCell) [3]geo.Vec {
+	i0, i1, i2 := ml.vertices(cell.face)
+	pos := ml.Mesh.Positions
+	p0, e1, e2 := pos[i0], pos[i1].Minus(pos[i0]), pos[i2].Minus(pos[i0])
+
+	var p [3]geo.Vec
+	for c, b := range cell.bary() {
+		p[c] = p0.Plus(e1.Scale(b[1])).Plus(e2.Scale(b[2]))
+	}
+	return p
+}
+
+// emission returns the radiance at the point of the cell with barycentric
+// coordinates b (relative to the cell's corners).
+func (ml *Mesh) emission(cell *meshCell, b [3]float64) *spectrum.Sampled {
+	// barycentric coordinates relative to the face
+	var fb [3]float64
+	for c, cb := range cell.bary() {
+		for v := range fb {
+			fb[v] += b[c] * cb[v]
+		}
+	}
+
+	i0, i1, i2 := ml.vertices(cell.face)
+	pos := ml.Mesh.Positions
+	uv := ml.uvs(cell.face)
+	tc := texture.Coords{
+		U: fb[0]*uv[0][0] + fb[1]*uv[1][0] + fb[2]*uv[2][0],
+		V: fb[0]*uv[0][1] + fb[1]*uv[1][1] + fb[2]*uv[2][1],
+		P: pos[i0].Scale(fb[0]).Plus(pos[i1].Scale(fb[1])).Plus(pos[i2].Scale(fb[2])),
+	}
+	return ml.Emission.Eval(tc).Scale(ml.Scale)
+}
+
+// bary returns the barycentric coordinates, relative to its face, of the
+// cell's corners.
+func (cell *meshCell) bary() [3][3]float64 {
+	k := float64(cell.k)
+	at := func(i, j int32) [3]float64 {
+		b1, b2 := float64(i)/k, float64(j)/k
+		return [3]float64{1 - b1 - b2, b1, b2}
+	}
+	if cell.flipped {
+		return [3][3]float64{at(cell.i+1, cell.j+1), at(cell.i, cell.j+1), at(cell.i+1, cell.j)}
+	}
+	return [3][3]float64{at(cell.i, cell.j), at(cell.i+1, cell.j), at(cell.i, cell.j+1)}
+}
+
+func (ml *Mesh) vertices(face int) (int, int, int) {
+	i := 3 * face
+	return ml.Mesh.Indices[i], ml.Mesh.Indices[i+1], ml.Mesh.Indices[i+2]
+}
+
+// uvs returns the face's texture coordinates, or the same default as
+// shape.Mesh if the mesh has none.
+func (ml *Mesh) uvs(face int) [3][2]float64 {
+	if len(ml.Mesh.UVs) == 0 {
+		return [3][2]float64{{0, 0}, {1, 0}, {1, 1}}
+	}
+	i0, i1, i2 := ml.vertices(face)
+	return [3][2]float64{ml.Mesh.UVs[i0], ml.Mesh.UVs[i1], ml.Mesh.UVs[i2]}
+}
+
+// luminanceOf returns the luminance of a spectrum, relative to a flat
+// spectrum of 1.
+func luminanceOf(s *spectrum.Sampled) float64 {
+	return colorspace.CIE1931Reflectance.Convert(s)[1]
+}
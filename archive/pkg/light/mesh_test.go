@@ -0,0 +1,82 @@
+package light
+
+import (
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/imageio"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/gmhorn/gremlin/archive/pkg/texture"
+	"github.com/gmhorn/gremlin/archive/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+// unit quad in the z=0 plane, facing +z
+func testQuad() *shape.Mesh {
+	return shape.NewMesh(
+		[]geo.Vec{geo.V(0, 0, 0), geo.V(1, 0, 0), geo.V(1, 1, 0), geo.V(0, 1, 0)},
+		nil,
+		[][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}},
+		[]int{0, 1, 2, 0, 2, 3},
+	)
+}
+
+func TestMesh_Uniform(t *testing.T) {
+	// with constant emission it's just like a Rect
+	ml := NewMesh(testQuad(), texture.NewConstant(spectrum.Flat(1)), 2)
+	rect := NewRect(geo.V(0, 0, 0), geo.V(1, 0, 0), geo.V(0, 1, 0), spectrum.Flat(2))
+	point := geo.V(0.3, 0.6, 2)
+	rnd := util.NewRand(0)
+
+	for i := 0; i < 100; i++ {
+		s, ok := ml.SampleLi(point, rnd.Float64(), rnd.Float64())
+		assert.True(t, ok)
+		assert.InDelta(t, 2, s.Li[0], 1e-12)
+		cos := -s.Wi.Z
+		assert.InDelta(t, s.Dist*s.Dist/cos, s.PDF, 1e-9)
+	}
+	assert.InDelta(t, rect.Power()[0], ml.Power()[0], 1e-9)
+
+	// the back doesn't emit
+	_, ok := ml.SampleLi(geo.V(0.5, 0.5, -1), 0.5, 0.5)
+	assert.False(t, ok)
+}
+
+func TestMesh_Texture(t *testing.T) {
+	// the left half of the quad is bright, the right half is dark
+	img := imageio.NewRGB(2, 1)
+	img.Set(0, 0, 1, 1, 1)
+	ml := NewMesh(testQuad(), texture.NewImage(img, 1), 1)
+	point := geo.V(0.5, 0.5, 1)
+	rnd := util.NewRand(0)
+
+	// Nearly all samples land on the bright half, and the estimate of the
+	// irradiance is still right. The filtering blurs the edge between them,
+	// so compare against the image as it's looked up.
+	const n = 20000
+	left, sum := 0, 0.0
+	for i := 0; i < n; i++ {
+		s, ok := ml.SampleLi(point, rnd.Float64(), rnd.Float64())
+		if !ok {
+			continue
+		}
+		if pos := point.Plus(geo.Vec(s.Wi).Scale(s.Dist)); pos.X < 0.5 {
+			left++
+		}
+		sum += s.Li[0] * -s.Wi.Z / s.PDF
+	}
+	assert.Greater(t, float64(left)/n, 0.7)
+
+	expected := 0.0
+	const steps = 400
+	for y := 0; y < steps; y++ {
+		for x := 0; x < steps; x++ {
+			u, v := (float64(x)+0.5)/steps, (float64(y)+0.5)/steps
+			d2 := (u-0.5)*(u-0.5) + (v-0.5)*(v-0.5) + 1
+			le := ml.Emission.Eval(texture.Coords{U: u, V: v})[0]
+			expected += le / (d2 * d2) / (steps * steps)
+		}
+	}
+	assert.InDelta(t, expected, sum/n, 0.02*expected)
+}
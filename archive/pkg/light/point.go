@@ -0,0 +1,76 @@
+package light
+
+import (
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+)
+
+// Point is an infinitely small light that emits the same intensity in every
+// direction.
+//
+// https://www.pbr-book.org/3ed-2018/Light_Sources/Point_Lights
+type Point struct {
+	Position  geo.Vec
+	Intensity *spectrum.Sampled
+}
+
+// NewPoint creates a point light.
+func NewPoint(position geo.Vec, intensity spectrum.Distribution) *Point {
+	return &Point{Position: position, Intensity: spectrum.Sample(intensity)}
+}
+
+// SampleLi implements Light. There's only one direction to choose.
+func (p *Point) SampleLi(point geo.Vec, u1, u2 float64) (Sample, bool) {
+	d := p.Position.Minus(point)
+	dist2 := d.LenSquared()
+	if dist2 == 0 {
+		return Sample{}, false
+	}
+
+	return Sample{
+		Wi:   d.Unit(),
+		Li:   p.Intensity.Scale(1 / dist2),
+		Dist: math.Sqrt(dist2),
+		PDF:  1,
+	}, true
+}
+
+// Power implements Light.
+func (p *Point) Power() *spectrum.Sampled {
+	return p.Intensity.Scale(4 * math.Pi)
+}
+
+// Directional is a light infinitely far away, like the sun, so light from it
+// arrives from the same direction everywhere.
+//
+// Its power depends on how much of it reaches the scene, so SceneRadius should
+// be set to the radius of a sphere enclosing the scene.
+//
+// https://www.pbr-book.org/3ed-2018/Light_Sources/Distant_Lights
+type Directional struct {
+	Dir         geo.Unit
+	Radiance    *spectrum.Sampled
+	SceneRadius float64
+}
+
+// NewDirectional creates a directional light shining in the direction dir.
+func NewDirectional(dir geo.Vec, radiance spectrum.Distribution) *Directional {
+	return &Directional{Dir: dir.Unit(), Radiance: spectrum.Sample(radiance)}
+}
+
+// SampleLi implements Light. There's only one direction to choose.
+func (d *Directional) SampleLi(point geo.Vec, u1, u2 float64) (Sample, bool) {
+	return Sample{
+		Wi:   d.Dir.Reverse(),
+		Li:   d.Radiance,
+		Dist: math.Inf(1),
+		PDF:  1,
+	}, true
+}
+
+// Power implements Light.
+func (d *Directional) Power() *spectrum.Sampled {
+	return d.Radiance.Scale(math.Pi * d.SceneRadius * d.SceneRadius)
+}
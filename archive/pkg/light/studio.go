@@ -0,0 +1,183 @@
+package light
+
+import (
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+)
+
+// Profile shapes the emission of a studio light (Panel or Ring) by direction,
+// the way grids and barn doors shape a photographer's lights.
+//
+// Falloff narrows the beam: radiance falls off as cos^Falloff of the angle
+// from the light's axis, so 0 is a bare diffuser and larger values act like
+// tighter honeycomb grids. BarnDoors are the angles in degrees, from the axis,
+// beyond which the light is cut off across its width and its height, like
+// pairs of flaps on either side. 0 leaves a pair open.
+type Profile struct {
+	Falloff   float64
+	BarnDoors [2]float64
+}
+
+// weight returns how much of the light's radiance is emitted in the local
+// direction e, with the axis along +z.
+func (p *Profile) weight(e geo.Unit) float64 {
+	if e.Z <= 0 {
+		return 0
+	}
+	for i, door := range p.BarnDoors {
+		if door <= 0 || door >= 90 {
+			continue
+		}
+		across := e.X
+		if i == 1 {
+			across = e.Y
+		}
+		if math.Abs(across) > e.Z*math.Tan(door*math.Pi/180) {
+			return 0
+		}
+	}
+	if p.Falloff == 0 {
+		return 1
+	}
+	return math.Pow(e.Z, p.Falloff)
+}
+
+// power returns the power emitted by area with radiance along the axis. Barn
+// doors are ignored, so it's an upper bound when they're closed.
+func (p *Profile) power(radiance *spectrum.Sampled, area float64) *spectrum.Sampled {
+	return radiance.Scale(2 * math.Pi / (p.Falloff + 2) * area)
+}
+
+// Panel is a rectangular studio light, like a softbox or strip light. It's
+// centered at Position, facing along Frame.N, with its Width along Frame.S and
+// Height along Frame.T. Radiance is its radiance along the axis, shaped by the
+// Profile.
+type Panel struct {
+	Profile
+	Position      geo.Vec
+	Frame         geo.Frame
+	Width, Height float64
+	Radiance      *spectrum.Sampled
+}
+
+// NewSoftbox creates a width by height softbox at position, aimed at target,
+// held level so its width is horizontal.
+func NewSoftbox(position, target geo.Vec, width, height float64, radiance spectrum.Distribution) *Panel {
+	if width <= 0 || height <= 0 {
+		panic("softbox size must be positive")
+	}
+	return &Panel{
+		Position: position,
+		Frame:    aim(position, target),
+		Width:    width,
+		Height:   height,
+		Radiance: spectrum.Sample(radiance),
+	}
+}
+
+// NewStripLight creates a strip light, a softbox a quarter as wide as it is
+// long, standing upright at position and aimed at target.
+func NewStripLight(position, target geo.Vec, length float64, radiance spectrum.Distribution) *Panel {
+	return NewSoftbox(position, target, length/4, length, radiance)
+}
+
+// SampleLi implements Light. Positions are chosen uniformly over the panel.
+func (p *Panel) SampleLi(point geo.Vec, u1, u2 float64) (Sample, bool) {
+	pos := p.Position.
+		Plus(p.Frame.S.Scale((u1 - 0.5) * p.Width)).
+		Plus(p.Frame.T.Scale((u2 - 0.5) * p.Height))
+	return studioSample(&p.Profile, p.Frame, p.Radiance, p.Width*p.Height, pos, point)
+}
+
+// Power implements Light.
+func (p *Panel) Power() *spectrum.Sampled {
+	return p.power(p.Radiance, p.Width*p.Height)
+}
+
+// Ring is a ring light: a flat annulus between the Inner and Outer radii,
+// centered at Position and facing along Frame.N, usually around the camera
+// for even, shadowless front light. Radiance is its radiance along the axis,
+// shaped by the Profile.
+type Ring struct {
+	Profile
+	Position     geo.Vec
+	Frame        geo.Frame
+	Inner, Outer float64
+	Radiance     *spectrum.Sampled
+}
+
+// NewRingLight creates a ring light of the given outer diameter at position,
+// aimed at target. Its inner diameter is two thirds of that, as is typical.
+func NewRingLight(position, target geo.Vec, diameter float64, radiance spectrum.Distribution) *Ring {
+	if diameter <= 0 {
+		panic("ring light diameter must be positive")
+	}
+	return &Ring{
+		Position: position,
+		Frame:    aim(position, target),
+		Inner:    diameter / 3,
+		Outer:    diameter / 2,
+		Radiance: spectrum.Sample(radiance),
+	}
+}
+
+// SampleLi implements Light. Positions are chosen uniformly over the ring.
+func (r *Ring) SampleLi(point geo.Vec, u1, u2 float64) (Sample, bool) {
+	radius := math.Sqrt(r.Inner*r.Inner + u1*(r.Outer*r.Outer-r.Inner*r.Inner))
+	phi := 2 * math.Pi * u2
+	pos := r.Position.
+		Plus(r.Frame.S.Scale(radius * math.Cos(phi))).
+		Plus(r.Frame.T.Scale(radius * math.Sin(phi)))
+	return studioSample(&r.Profile, r.Frame, r.Radiance, r.area(), pos, point)
+}
+
+// Power implements Light.
+func (r *Ring) Power() *spectrum.Sampled {
+	return r.power(r.Radiance, r.area())
+}
+
+func (r *Ring) area() float64 {
+	return math.Pi * (r.Outer*r.Outer - r.Inner*r.Inner)
+}
+
+// aim returns the frame of a light at position pointing at target, with S
+// horizontal and T as close to up (+y) as it can be. Lights pointing straight
+// up or down get S along +x.
+func aim(position, target geo.Vec) geo.Frame {
+	n := target.Minus(position).Unit()
+	s := geo.V(0, 1, 0).Cross(geo.Vec(n))
+	if s.LenSquared() < 1e-12 {
+		s = geo.V(1, 0, 0)
+	}
+	frame := geo.NewFrame(n, s)
+	if frame.T.Y < 0 {
+		frame.S, frame.T = frame.S.Reverse(), frame.T.Reverse()
+	}
+	return frame
+}
+
+// studioSample completes a sample of a studio light at pos, chosen uniformly
+// over its area.
+func studioSample(p *Profile, frame geo.Frame, radiance *spectrum.Sampled, area float64, pos, point geo.Vec) (Sample, bool) {
+	d := pos.Minus(point)
+	dist2 := d.LenSquared()
+	if dist2 == 0 {
+		return Sample{}, false
+	}
+	dist := math.Sqrt(dist2)
+	wi := d.Scale(1 / dist).Unit()
+
+	e := frame.ToLocal(wi.Reverse())
+	w := p.weight(e)
+	if w == 0 {
+		return Sample{}, false
+	}
+	return Sample{
+		Wi:   wi,
+		Li:   radiance.Scale(w),
+		Dist: dist,
+		PDF:  dist2 / (e.Z * area),
+	}, true
+}
@@ -0,0 +1,104 @@
+package light
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/gmhorn/gremlin/archive/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSoftbox(t *testing.T) {
+	// a 2x1 softbox 4 units in front of the origin, facing it
+	sb := NewSoftbox(geo.V(0, 0, 4), geo.V(0, 0, 0), 2, 1, spectrum.Flat(3))
+	assert.InDelta(t, -1, sb.Frame.N.Z, 1e-12)
+	assert.InDelta(t, 1, sb.Frame.T.Y, 1e-12)
+
+	// without a profile it's the same as a Rect
+	rect := NewRect(geo.V(1, -0.5, 4), geo.V(-2, 0, 0), geo.V(0, 1, 0), spectrum.Flat(3))
+	assert.InDelta(t, rect.Power()[0], sb.Power()[0], 1e-9)
+	rnd := util.NewRand(0)
+	point := geo.V(0.5, 0.2, 0)
+	for i := 0; i < 20; i++ {
+		s, ok := sb.SampleLi(point, rnd.Float64(), rnd.Float64())
+		assert.True(t, ok)
+		assert.Equal(t, 3.0, s.Li[0])
+		cos := s.Wi.Z
+		assert.InDelta(t, s.Dist*s.Dist/(cos*2), s.PDF, 1e-9)
+	}
+
+	// nothing behind it
+	_, ok := sb.SampleLi(geo.V(0, 0, 8), 0.5, 0.5)
+	assert.False(t, ok)
+}
+
+func TestProfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  Profile
+		dir      geo.Vec
+		expected float64
+	}{
+		{"Axis", Profile{Falloff: 4}, geo.V(0, 0, 1), 1},
+		{"Falloff", Profile{Falloff: 4}, geo.V(1, 0, 1), 0.25},
+		{"OpenDoors", Profile{BarnDoors: [2]float64{0, 0}}, geo.V(5, 5, 1), 1},
+		{"InsideDoors", Profile{BarnDoors: [2]float64{30, 30}}, geo.V(0.5, 0, 1), 1},
+		{"OutsideDoors", Profile{BarnDoors: [2]float64{30, 0}}, geo.V(0.6, 0, 1), 0},
+		{"OtherPair", Profile{BarnDoors: [2]float64{30, 0}}, geo.V(0, 0.6, 1), 1},
+		{"Behind", Profile{}, geo.V(0, 0, -1), 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.InDelta(t, test.expected, test.profile.weight(test.dir.Unit()), 1e-12)
+		})
+	}
+}
+
+func TestPanel_Power(t *testing.T) {
+	// The power is the irradiance integrated over a big sphere around a small
+	// panel, here at the origin facing +y.
+	p := NewStripLight(geo.V(0, 0, 0), geo.V(0, 1, 0), 0.04, spectrum.Flat(1))
+	p.Falloff = 3
+	rnd := util.NewRand(0)
+
+	const r, n = 100.0, 200
+	sum := 0.0
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			// uniform over the hemisphere in front
+			cos := (float64(i) + 0.5) / n
+			phi := 2 * math.Pi * (float64(j) + 0.5) / n
+			sin := math.Sqrt(1 - cos*cos)
+			point := geo.V(r*sin*math.Cos(phi), r*cos, r*sin*math.Sin(phi))
+			if s, ok := p.SampleLi(point, rnd.Float64(), rnd.Float64()); ok {
+				// irradiance on the sphere, facing the center
+				sum += s.Li[0] / s.PDF * 2 * math.Pi * r * r / (n * n)
+			}
+		}
+	}
+	assert.InDelta(t, p.Power()[0], sum, 0.02*sum)
+}
+
+func TestRingLight(t *testing.T) {
+	ring := NewRingLight(geo.V(0, 1, 0), geo.V(0, 1, -5), 0.6, spectrum.Flat(1))
+	assert.InDelta(t, 0.2, ring.Inner, 1e-12)
+	assert.InDelta(t, 0.3, ring.Outer, 1e-12)
+
+	// samples land on the ring
+	rnd := util.NewRand(0)
+	point := geo.V(0, 1, -5)
+	for i := 0; i < 50; i++ {
+		s, ok := ring.SampleLi(point, rnd.Float64(), rnd.Float64())
+		assert.True(t, ok)
+		pos := point.Plus(geo.Vec(s.Wi).Scale(s.Dist))
+		assert.InDelta(t, 0, pos.Z, 1e-9)
+		radius := math.Hypot(pos.X, pos.Y-1)
+		assert.GreaterOrEqual(t, radius, 0.2-1e-9)
+		assert.LessOrEqual(t, radius, 0.3+1e-9)
+	}
+	assert.InDelta(t, math.Pi*math.Pi*(0.09-0.04), ring.Power()[0], 1e-12)
+
+	assert.Panics(t, func() { NewRingLight(geo.V(0, 0, 0), geo.V(0, 0, 1), 0, spectrum.Flat(1)) })
+}
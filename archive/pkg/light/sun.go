@@ -0,0 +1,191 @@
+package light
+
+import (
+	"math"
+	"time"
+
+	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/imageio"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+)
+
+// Resolution of the sky image made by NewSunSky.
+const (
+	skyWidth  = 256
+	skyHeight = 128
+)
+
+// Temperature of the sun's photosphere, in Kelvin, and the illuminance of
+// sunlight at the top of the atmosphere, in kilolux.
+const (
+	sunTemperature = 5778
+	sunIlluminance = 128
+)
+
+// SunDirection returns the direction towards the sun seen from latitude and
+// longitude (in degrees, north and east positive) at the time t. Up is +y,
+// north is -z and east is +x, matching Environment images whose center faces
+// north.
+//
+// It uses NOAA's low-accuracy solar position equations, good to a fraction of
+// a degree, which is plenty for lighting.
+//
+// https://gml.noaa.gov/grad/solcalc/solareqns.PDF
+func SunDirection(latitude, longitude float64, t time.Time) geo.Unit {
+	t = t.UTC()
+	days := 365.0
+	if y := t.Year(); y%4 == 0 && (y%100 != 0 || y%400 == 0) {
+		days = 366
+	}
+	hours := float64(t.Hour()) + float64(t.Minute())/60 + float64(t.Second())/3600
+
+	// fractional year, in radians
+	g := 2 * math.Pi / days * (float64(t.YearDay()-1) + (hours-12)/24)
+
+	// equation of time (in minutes) and solar declination (in radians)
+	eqTime := 229.18 * (0.000075 + 0.001868*math.Cos(g) - 0.032077*math.Sin(g) -
+		0.014615*math.Cos(2*g) - 0.040849*math.Sin(2*g))
+	decl := 0.006918 - 0.399912*math.Cos(g) + 0.070257*math.Sin(g) -
+		0.006758*math.Cos(2*g) + 0.000907*math.Sin(2*g) -
+		0.002697*math.Cos(3*g) + 0.00148*math.Sin(3*g)
+
+	// true solar time, in minutes, and the hour angle
+	solarTime := hours*60 + eqTime + 4*longitude
+	ha := (solarTime/4 - 180) * math.Pi / 180
+
+	lat := latitude * math.Pi / 180
+	sinLat, cosLat := math.Sincos(lat)
+	sinDecl, cosDecl := math.Sincos(decl)
+
+	east := -cosDecl * math.Sin(ha)
+	north := sinDecl*cosLat - cosDecl*sinLat*math.Cos(ha)
+	up := sinDecl*sinLat + cosDecl*cosLat*math.Cos(ha)
+	return geo.V(east, up, -north).Unit()
+}
+
+// NewSunSky creates a matched sun and sky for latitude and longitude (in
+// degrees) at the time t, for daylight studies. Turbidity describes the haze
+// in the air: 2 is a very clear sky, 3 a typical clear day and 10 a hazy one.
+//
+// The sky is the Preetham et al. analytic daylight model, and the sun is a
+// black-body attenuated by the same atmosphere, so a low sun is dimmer and
+// redder and the sky around it agrees. Radiance is in kilocandela per square
+// meter (and the sun's irradiance in kilolux), so a midday sky is around 10 and
+// the sun around 100: scale the camera's exposure to suit. When the sun is
+// below the horizon both are black.
+//
+// Like any Directional and Environment light, both need SceneRadius set.
+//
+// "A Practical Analytic Model for Daylight", Preetham, Shirley and Smits, 1999
+func NewSunSky(latitude, longitude float64, t time.Time, turbidity float64) (*Directional, *Environment) {
+	if turbidity < 1 {
+		panic("turbidity must be at least 1")
+	}
+
+	dir := SunDirection(latitude, longitude, t)
+	radiance := new(spectrum.Sampled)
+	img := imageio.NewRGB(skyWidth, skyHeight)
+
+	if dir.Y > 0 {
+		radiance = sunRadiance(math.Acos(dir.Y), turbidity)
+		p := newPreetham(dir, turbidity)
+		var env Environment
+		for y := 0; y < skyHeight; y++ {
+			for x := 0; x < skyWidth; x++ {
+				w := env.dir((float64(x)+0.5)/skyWidth, (float64(y)+0.5)/skyHeight)
+				rgb := p.rgb(w)
+				img.Set(x, y, rgb[0], rgb[1], rgb[2])
+			}
+		}
+	}
+
+	return NewDirectional(dir.Scale(-1), radiance), NewEnvironment(img, 1)
+}
+
+// sunRadiance returns the sun's spectral irradiance after passing through the
+// atmosphere with the sun at zenith angle theta, from the Rayleigh and aerosol
+// terms of the Preetham model's transmittance.
+func sunRadiance(theta, turbidity float64) *spectrum.Sampled {
+	// relative optical air mass, Kasten and Young
+	zenith := theta * 180 / math.Pi
+	m := 1 / (math.Cos(theta) + 0.50572*math.Pow(96.07995-zenith, -1.6364))
+	beta := 0.04608*turbidity - 0.04586
+
+	scale := sunIlluminance / colorspace.CIE1931Reflectance.Convert(spectrum.SampledBlackbodyNormalized(sunTemperature))[1]
+	return spectrum.Sample(spectrum.DistributionFunc(func(wavelength float64) float64 {
+		um := wavelength / 1000
+		rayleigh := math.Exp(-0.008735 * math.Pow(um, -4.08) * m)
+		aerosol := math.Exp(-beta * math.Pow(um, -1.3) * m)
+		return scale * spectrum.BlackbodyNormalized(sunTemperature).Lookup(wavelength) * rayleigh * aerosol
+	}))
+}
+
+// preetham evaluates the Preetham sky model for one sun position.
+type preetham struct {
+	sun        geo.Unit
+	thetaS     float64
+	Y, x, y    perez
+	zY, zx, zy float64
+}
+
+// perez holds the coefficients of the Perez sky luminance distribution.
+type perez [5]float64
+
+// f is the Perez function for a view direction at zenith angle theta and angle
+// gamma from the sun.
+func (p perez) f(theta, gamma float64) float64 {
+	cosGamma := math.Cos(gamma)
+	return (1 + p[0]*math.Exp(p[1]/math.Cos(theta))) *
+		(1 + p[2]*math.Exp(p[3]*gamma) + p[4]*cosGamma*cosGamma)
+}
+
+func newPreetham(sun geo.Unit, t float64) *preetham {
+	thetaS := math.Acos(sun.Y)
+	p := &preetham{
+		sun:    sun,
+		thetaS: thetaS,
+		Y:      perez{0.1787*t - 1.4630, -0.3554*t + 0.4275, -0.0227*t + 5.3251, 0.1206*t - 2.5771, -0.0670*t + 0.3703},
+		x:      perez{-0.0193*t - 0.2592, -0.0665*t + 0.0008, -0.0004*t + 0.2125, -0.0641*t - 0.8989, -0.0033*t + 0.0452},
+		y:      perez{-0.0167*t - 0.2608, -0.0950*t + 0.0092, -0.0079*t + 0.2102, -0.0441*t - 1.6537, -0.0109*t + 0.0529},
+	}
+
+	// zenith luminance (kcd/m^2) and chromaticity
+	chi := (4.0/9 - t/120) * (math.Pi - 2*thetaS)
+	p.zY = (4.0453*t-4.9710)*math.Tan(chi) - 0.2155*t + 2.4192
+	th := [4]float64{thetaS * thetaS * thetaS, thetaS * thetaS, thetaS, 1}
+	p.zx = t*t*dot4(th, [4]float64{0.00166, -0.00375, 0.00209, 0}) +
+		t*dot4(th, [4]float64{-0.02903, 0.06377, -0.03202, 0.00394}) +
+		dot4(th, [4]float64{0.11693, -0.21196, 0.06052, 0.25886})
+	p.zy = t*t*dot4(th, [4]float64{0.00275, -0.00610, 0.00317, 0}) +
+		t*dot4(th, [4]float64{-0.04214, 0.08970, -0.04153, 0.00516}) +
+		dot4(th, [4]float64{0.15346, -0.26756, 0.06670, 0.26688})
+	return p
+}
+
+// rgb returns the sky's linear sRGB radiance in the direction w, or black
+// below the horizon.
+func (p *preetham) rgb(w geo.Unit) colorspace.Point {
+	if w.Y <= 0 {
+		return colorspace.Point{}
+	}
+	theta := math.Acos(w.Y)
+	gamma := math.Acos(math.Max(-1, math.Min(1, w.Dot(p.sun))))
+
+	Y := p.zY * p.Y.f(theta, gamma) / p.Y.f(0, p.thetaS)
+	x := p.zx * p.x.f(theta, gamma) / p.x.f(0, p.thetaS)
+	y := p.zy * p.y.f(theta, gamma) / p.y.f(0, p.thetaS)
+	if Y <= 0 || y <= 0 {
+		return colorspace.Point{}
+	}
+
+	rgb := colorspace.SRGB.Linear(colorspace.Point{x * Y / y, Y, (1 - x - y) * Y / y})
+	for i := range rgb {
+		rgb[i] = math.Max(0, rgb[i])
+	}
+	return rgb
+}
+
+func dot4(a, b [4]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2] + a[3]*b[3]
+}
@@ -0,0 +1,63 @@
+package light
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSunDirection(t *testing.T) {
+	elevation := func(d geo.Unit) float64 { return math.Asin(d.Y) * 180 / math.Pi }
+
+	// around the March equinox the noon sun is overhead at the equator
+	d := SunDirection(0, 0, time.Date(2023, 3, 20, 12, 7, 0, 0, time.UTC))
+	assert.InDelta(t, 90, elevation(d), 1)
+
+	// at the June solstice it's 90 - 40 + 23.4 degrees up at 40N, to the south
+	d = SunDirection(40, 0, time.Date(2023, 6, 21, 12, 2, 0, 0, time.UTC))
+	assert.InDelta(t, 73.4, elevation(d), 0.5)
+	assert.Greater(t, d.Z, 0.0)
+
+	// time zones don't matter; longitude does. Mid-morning in New York the
+	// sun is in the east, and at midnight it's down.
+	nyc, _ := time.LoadLocation("America/New_York")
+	d = SunDirection(40.7, -74, time.Date(2023, 6, 21, 9, 0, 0, 0, nyc))
+	assert.Greater(t, d.X, 0.5)
+	assert.Greater(t, d.Y, 0.0)
+	d = SunDirection(40.7, -74, time.Date(2023, 6, 21, 0, 0, 0, 0, nyc))
+	assert.Less(t, d.Y, 0.0)
+}
+
+func TestNewSunSky(t *testing.T) {
+	noon := time.Date(2023, 6, 21, 12, 0, 0, 0, time.UTC)
+	sun, sky := NewSunSky(40, 0, noon, 3)
+	toSun := sun.Dir.Reverse()
+	assert.InDelta(t, toSun.Dot(SunDirection(40, 0, noon)), 1, 1e-12)
+
+	// the sun is a warm white of the order of 100 klux
+	assert.Greater(t, sun.Radiance[len(sun.Radiance)-1], sun.Radiance[0])
+	assert.Greater(t, sun.Radiance.Max(), 50.0)
+	assert.Less(t, sun.Radiance.Max(), 200.0)
+
+	// the sky is blue, brighter near the sun, and black below the horizon
+	zenith := sky.Le(geo.V(0, 1, 0).Unit())
+	assert.Greater(t, zenith[0], zenith[len(zenith)-1])
+	assert.Greater(t, sky.Le(geo.V(0, 1, 0.5).Unit()).Max(), sky.Le(geo.V(0, 1, -0.5).Unit()).Max())
+	assert.Zero(t, sky.Le(geo.V(0, -1, 0.2).Unit()).Max())
+
+	// a low sun is dimmer and redder
+	low, _ := NewSunSky(40, 0, time.Date(2023, 6, 21, 19, 0, 0, 0, time.UTC), 3)
+	assert.Less(t, low.Radiance.Max(), sun.Radiance.Max())
+	last := len(sun.Radiance) - 1
+	assert.Greater(t, low.Radiance[last]/low.Radiance[0], sun.Radiance[last]/sun.Radiance[0])
+
+	// at night there's nothing
+	sun, sky = NewSunSky(40, 0, time.Date(2023, 6, 21, 0, 0, 0, 0, time.UTC), 3)
+	assert.Zero(t, sun.Radiance.Max())
+	assert.Zero(t, sky.Le(geo.V(0, 1, 0).Unit()).Max())
+
+	assert.Panics(t, func() { NewSunSky(40, 0, noon, 0.5) })
+}
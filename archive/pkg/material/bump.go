@@ -0,0 +1,159 @@
+package material
+
+import (
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/imageio"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/gmhorn/gremlin/archive/pkg/texture"
+)
+
+// bumpDelta is the step in texture coordinates used to find the slope of a
+// bump map.
+const bumpDelta = 0.0005
+
+// Perturbed is implemented by materials that add surface detail without extra
+// geometry by changing the shading normal, like NormalMap and Bump.
+//
+// ShadingNormal returns the normal to shade with at the texture coordinates,
+// given the surface's normal n and the partial derivatives dpdu and dpdv of
+// its position (see shape.Interaction).
+type Perturbed interface {
+	Material
+	ShadingNormal(tc texture.Coords, n geo.Unit, dpdu, dpdv geo.Vec) geo.Unit
+}
+
+// ShadingNormal returns the normal to shade m with: n itself, or for Perturbed
+// materials, the result of their ShadingNormal. Like Resolve, it only looks at
+// m itself, so a NormalMap or Bump should wrap the whole material rather than
+// be part of a Mix.
+func ShadingNormal(m Material, tc texture.Coords, n geo.Unit, dpdu, dpdv geo.Vec) geo.Unit {
+	if pm, ok := m.(Perturbed); ok {
+		return pm.ShadingNormal(tc, n, dpdu, dpdv)
+	}
+	return n
+}
+
+// tangentFrame returns the frame that tangent-space normals are relative to:
+// S along dpdu, N along n, and T on the same side as dpdv, so mirrored UVs
+// give mirrored detail.
+func tangentFrame(n geo.Unit, dpdu, dpdv geo.Vec) geo.Frame {
+	frame := geo.NewFrame(n, dpdu)
+	if geo.Vec(frame.T).Dot(dpdv) < 0 {
+		frame.T = frame.T.Reverse()
+	}
+	return frame
+}
+
+// NormalMap adds detail to a material with a tangent-space normal map: an
+// image whose red, green and blue channels hold the x, y and z of the normal,
+// mapped from [-1, 1] to [0, 1]. X is along dp/du, y along dp/dv (the
+// "OpenGL" convention, green up) and z along the surface normal, which is why
+// normal maps are mostly blue. Load the image with imageio.LoadImageData, since
+// its values aren't sRGB colors.
+//
+// Otherwise it's the wrapped Material.
+type NormalMap struct {
+	Material Material
+	Map      *texture.Image
+}
+
+// NewNormalMap wraps m with a normal map.
+func NewNormalMap(m Material, img *imageio.RGB) *NormalMap {
+	return &NormalMap{Material: m, Map: texture.NewImage(img, 1)}
+}
+
+// ShadingNormal implements Perturbed.
+func (nm *NormalMap) ShadingNormal(tc texture.Coords, n geo.Unit, dpdu, dpdv geo.Vec) geo.Unit {
+	r, g, b := nm.Map.RGB(tc)
+	local := geo.V(2*r-1, 2*g-1, 2*b-1)
+	if local.LenSquared() == 0 || local.Z <= 0 {
+		return n
+	}
+	return tangentFrame(n, dpdu, dpdv).ToWorld(local.Unit())
+}
+
+// At implements Varying.
+func (nm *NormalMap) At(tc texture.Coords) Material {
+	return Resolve(nm.Material, tc)
+}
+
+// Eval implements Material.
+func (nm *NormalMap) Eval(wo, wi geo.Unit) *spectrum.Sampled {
+	return nm.Material.Eval(wo, wi)
+}
+
+// Sample implements Material.
+func (nm *NormalMap) Sample(wo geo.Unit, u1, u2 float64) (Sample, bool) {
+	return nm.Material.Sample(wo, u1, u2)
+}
+
+// PDF implements Material.
+func (nm *NormalMap) PDF(wo, wi geo.Unit) float64 {
+	return nm.Material.PDF(wo, wi)
+}
+
+// Bump adds detail to a material with a bump (height) map: the surface is
+// shaded as if it were displaced along its normal by Height times Scale, in
+// world units. The displaced surface's normal is found from the slope of the
+// height with finite differences.
+//
+// Otherwise it's the wrapped Material.
+//
+// https://www.pbr-book.org/3ed-2018/Materials/Bump_Mapping
+type Bump struct {
+	Material Material
+	Height   texture.Scalar
+	Scale    float64
+}
+
+// NewBump wraps m with a bump map.
+func NewBump(m Material, height texture.Scalar, scale float64) *Bump {
+	return &Bump{Material: m, Height: height, Scale: scale}
+}
+
+// ShadingNormal implements Perturbed. Changes in the normal over the surface
+// (dn/du, dn/dv) are ignored, which is fine for detail much smaller than the
+// surface's curvature.
+func (bm *Bump) ShadingNormal(tc texture.Coords, n geo.Unit, dpdu, dpdv geo.Vec) geo.Unit {
+	h := bm.Height.Value(tc)
+	hu := bm.Height.Value(texture.Coords{U: tc.U + bumpDelta, V: tc.V, P: tc.P.Plus(dpdu.Scale(bumpDelta))})
+	hv := bm.Height.Value(texture.Coords{U: tc.U, V: tc.V + bumpDelta, P: tc.P.Plus(dpdv.Scale(bumpDelta))})
+
+	// Use the derivatives' projections onto the plane perpendicular to n, so
+	// that a flat height gives back n even where it's an interpolated shading
+	// normal.
+	du := dpdu.Minus(n.Scale(dpdu.Dot(geo.Vec(n))))
+	dv := dpdv.Minus(n.Scale(dpdv.Dot(geo.Vec(n))))
+	du = du.Plus(n.Scale(bm.Scale * (hu - h) / bumpDelta))
+	dv = dv.Plus(n.Scale(bm.Scale * (hv - h) / bumpDelta))
+	bumped := du.Cross(dv)
+	if bumped.LenSquared() == 0 {
+		return n
+	}
+
+	// keep it on the same side as the unbumped normal
+	if bumped.Dot(geo.Vec(n)) < 0 {
+		bumped = bumped.Reverse()
+	}
+	return bumped.Unit()
+}
+
+// At implements Varying.
+func (bm *Bump) At(tc texture.Coords) Material {
+	return Resolve(bm.Material, tc)
+}
+
+// Eval implements Material.
+func (bm *Bump) Eval(wo, wi geo.Unit) *spectrum.Sampled {
+	return bm.Material.Eval(wo, wi)
+}
+
+// Sample implements Material.
+func (bm *Bump) Sample(wo geo.Unit, u1, u2 float64) (Sample, bool) {
+	return bm.Material.Sample(wo, u1, u2)
+}
+
+// PDF implements Material.
+func (bm *Bump) PDF(wo, wi geo.Unit) float64 {
+	return bm.Material.PDF(wo, wi)
+}
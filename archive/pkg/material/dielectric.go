@@ -0,0 +1,355 @@
+package material
+
+import (
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+)
+
+// referenceWavelength is the wavelength (the helium d-line, in nanometers)
+// at which refractive indices are evaluated for choosing refracted
+// directions. Since paths carry whole spectra, dispersion isn't simulated.
+const referenceWavelength = 587.6
+
+// Dielectric is a smooth boundary between two transparent media, like glass or
+// water, which reflects and refracts light according to the Fresnel
+// equations. IOR is the refractive index of the material "inside" the surface
+// (opposite the normal) relative to the outside.
+//
+// Priority resolves overlapping dielectric volumes, like an ice cube modeled
+// poking into the water of a glass: where volumes overlap, the one with the
+// highest priority fills the space, and the surfaces of the others inside it
+// don't exist. Renderers supporting this use Against to get the boundary
+// between two media. See Schmidt and Budge, "Simple Nested Dielectrics in Ray
+// Traced Images" (2002).
+//
+// Roughness, from 0 (smooth, the default) to 1, makes it a rough interface,
+// like frosted glass or rippled water: facets distributed by GGX, as for
+// Microfacet, each reflecting and refracting like a smooth one. See Walter et
+// al., "Microfacet Models for Refraction through Rough Surfaces" (2007).
+//
+// Absorption, if set, is the absorption coefficient of the medium inside, per
+// unit length. Renderers that keep track of the medium a path is in attenuate
+// light travelling through it by the Beer-Lambert law, so deep water turns
+// blue.
+//
+// https://www.pbr-book.org/3ed-2018/Reflection_Models/Specular_Reflection_and_Transmission
+// https://pbr-book.org/4ed/Reflection_Models/Dielectric_BSDF#RoughDielectricBSDF
+type Dielectric struct {
+	IOR        spectrum.Distribution
+	Priority   int
+	Roughness  float64
+	Absorption *spectrum.Sampled
+	eta        float64
+}
+
+// NewDielectric creates a dielectric with the given refractive index, e.g. a
+// spectrum.Sellmeier or a spectrum.Flat constant.
+func NewDielectric(ior spectrum.Distribution) *Dielectric {
+	return &Dielectric{IOR: ior, eta: ior.Lookup(referenceWavelength)}
+}
+
+// Against returns the boundary between this dielectric and the outside one,
+// i.e. with the refractive index relative to it instead of to a vacuum. A nil
+// outside is a vacuum (or air), so d itself is returned.
+func (d *Dielectric) Against(outside *Dielectric) *Dielectric {
+	if outside == nil {
+		return d
+	}
+	return &Dielectric{
+		IOR: spectrum.DistributionFunc(func(wavelength float64) float64 {
+			return d.IOR.Lookup(wavelength) / outside.IOR.Lookup(wavelength)
+		}),
+		Priority:  d.Priority,
+		Roughness: d.Roughness,
+		eta:       d.eta / outside.eta,
+	}
+}
+
+// Roughen implements Glossy.
+func (d *Dielectric) Roughen(min float64) Material {
+	if d.Roughness >= min {
+		return d
+	}
+	rough := *d
+	rough.Roughness = min
+	return &rough
+}
+
+// Eval implements Material. It's always zero for a smooth dielectric, see
+// Material.
+func (d *Dielectric) Eval(wo, wi geo.Unit) *spectrum.Sampled {
+	f := new(spectrum.Sampled)
+	if d.Roughness == 0 {
+		return f
+	}
+	wm, reflecting, etap, ok := d.facet(wo, wi)
+	if !ok {
+		return f
+	}
+
+	alpha := d.alpha()
+	dg := ggxD(wm, alpha) * smithG(wo, wi, alpha)
+	fr := FresnelDielectric(wo.Dot(wm), d.eta)
+	v := dg * fr / math.Abs(4*wo.Z*wi.Z)
+	if !reflecting {
+		denom := wi.Dot(wm) + wo.Dot(wm)/etap
+		v = dg * (1 - fr) * math.Abs(wi.Dot(wm)*wo.Dot(wm)/(wi.Z*wo.Z*denom*denom)) / (etap * etap)
+	}
+	for i := range f {
+		f[i] = v
+	}
+	return f
+}
+
+// Sample implements Material. It chooses between reflection and refraction in
+// proportion to the Fresnel reflectance.
+func (d *Dielectric) Sample(wo geo.Unit, u1, u2 float64) (Sample, bool) {
+	if d.Roughness > 0 {
+		return d.sampleRough(wo, u1, u2)
+	}
+
+	fr := FresnelDielectric(geo.CosTheta(wo), d.eta)
+	if u1 < fr {
+		wi := reflect(wo)
+		f := spectrum.Sample(spectrum.Flat(fr / geo.AbsCosTheta(wi)))
+		return Sample{Wi: wi, F: f, PDF: fr, Specular: true}, true
+	}
+
+	// entering if wo is on the outside
+	eta := d.eta
+	if geo.CosTheta(wo) < 0 {
+		eta = 1 / eta
+	}
+	wi, ok := refract(wo, eta)
+	if !ok {
+		return Sample{}, false
+	}
+
+	// Radiance is compressed into a smaller solid angle when entering a
+	// denser medium, hence the 1/eta^2.
+	ft := (1 - fr) / (eta * eta) / geo.AbsCosTheta(wi)
+	f := spectrum.Sample(spectrum.Flat(ft))
+	return Sample{Wi: wi, F: f, PDF: 1 - fr, Specular: true}, true
+}
+
+// PDF implements Material. It's always zero for a smooth dielectric, see
+// Material.
+func (d *Dielectric) PDF(wo, wi geo.Unit) float64 {
+	if d.Roughness == 0 {
+		return 0
+	}
+	wm, reflecting, etap, ok := d.facet(wo, wi)
+	if !ok {
+		return 0
+	}
+
+	// the visible normal pdf of wm, times the Jacobian of reflecting or
+	// refracting through it
+	alpha := d.alpha()
+	pm := smithG1(wo, alpha) * ggxD(wm, alpha) * math.Abs(wo.Dot(wm)) / math.Abs(wo.Z)
+	pr := d.reflectProb(wo)
+	if reflecting {
+		return pr * pm / (4 * math.Abs(wo.Dot(wm)))
+	}
+	denom := wi.Dot(wm) + wo.Dot(wm)/etap
+	return (1 - pr) * pm * math.Abs(wi.Dot(wm)) / (denom * denom)
+}
+
+// sampleRough samples a rough dielectric: it picks reflection or refraction
+// (see reflectProb), then a facet normal visible from wo to do it about.
+func (d *Dielectric) sampleRough(wo geo.Unit, u1, u2 float64) (Sample, bool) {
+	if wo.Z == 0 {
+		return Sample{}, false
+	}
+	pr := d.reflectProb(wo)
+	reflecting := u1 < pr
+	if reflecting {
+		u1 /= pr
+	} else {
+		u1 = (u1 - pr) / (1 - pr)
+	}
+
+	wm := sampleGGXVisible(upper(wo), d.alpha(), u1, u2)
+	if wo.Z < 0 {
+		wm = wm.Reverse()
+	}
+	var wi geo.Unit
+	if reflecting {
+		wi = reflectAbout(wo, wm)
+		if !geo.SameHemisphere(wo, wi) {
+			return Sample{}, false
+		}
+	} else {
+		eta := d.eta
+		if wo.Z < 0 {
+			eta = 1 / eta
+		}
+		var ok bool
+		if wi, ok = refractAbout(wo, wm, eta); !ok || geo.SameHemisphere(wo, wi) || wi.Z == 0 {
+			return Sample{}, false
+		}
+	}
+
+	pdf := d.PDF(wo, wi)
+	if pdf == 0 {
+		return Sample{}, false
+	}
+	return Sample{Wi: wi, F: d.Eval(wo, wi), PDF: pdf}, true
+}
+
+// facet returns the facet normal of a rough dielectric (facing +z) that
+// reflects or refracts wi into wo, whether it's a reflection, and the
+// relative refractive index across the facet (1 for reflections). Returns
+// false if there's no such facet that faces both.
+func (d *Dielectric) facet(wo, wi geo.Unit) (geo.Unit, bool, float64, bool) {
+	if wo.Z == 0 || wi.Z == 0 {
+		return geo.Unit{}, false, 0, false
+	}
+	reflecting := geo.SameHemisphere(wo, wi)
+	etap := 1.0
+	if !reflecting {
+		etap = d.eta
+		if wo.Z < 0 {
+			etap = 1 / d.eta
+		}
+	}
+
+	v := geo.Vec(wi).Scale(etap).Plus(geo.Vec(wo))
+	if v.LenSquared() == 0 {
+		return geo.Unit{}, false, 0, false
+	}
+	wm := v.Unit()
+	if wm.Z < 0 {
+		wm = wm.Reverse()
+	}
+	if wm.Dot(wi)*wi.Z < 0 || wm.Dot(wo)*wo.Z < 0 {
+		return geo.Unit{}, false, 0, false
+	}
+	return wm, reflecting, etap, true
+}
+
+func (d *Dielectric) alpha() float64 {
+	return math.Max(minAlpha, d.Roughness*d.Roughness)
+}
+
+// reflectProb is the chance that a rough dielectric samples reflection: the
+// Fresnel reflectance of the surface as a whole, kept away from 0 and 1 so
+// that facets tilted the other way don't go unsampled.
+func (d *Dielectric) reflectProb(wo geo.Unit) float64 {
+	return math.Max(0.05, math.Min(0.95, FresnelDielectric(geo.CosTheta(wo), d.eta)))
+}
+
+// ThinDielectric is a thin transparent sheet, like a window pane or the wall of
+// a soap bubble, modeled as a single surface. Light passing through refracts
+// twice, entering and leaving, which cancels out: so it continues in the same
+// direction, and the sheet needs no modeled thickness. Light bouncing back and
+// forth inside the sheet is accounted for in the total reflectance.
+//
+// This converges much faster than a closed dielectric shell, since paths
+// through it don't have to find their way through two surfaces separately.
+//
+// https://pbr-book.org/4ed/Reflection_Models/Dielectric_BSDF#ThinDielectricBSDF
+type ThinDielectric struct {
+	IOR spectrum.Distribution
+	eta float64
+}
+
+// NewThinDielectric creates a thin dielectric sheet with the given refractive
+// index.
+func NewThinDielectric(ior spectrum.Distribution) *ThinDielectric {
+	return &ThinDielectric{IOR: ior, eta: ior.Lookup(referenceWavelength)}
+}
+
+// Eval implements Material. It's always zero, see Material.
+func (d *ThinDielectric) Eval(wo, wi geo.Unit) *spectrum.Sampled {
+	return new(spectrum.Sampled)
+}
+
+// Sample implements Material. It chooses between reflection and transmission
+// in proportion to the sheet's total reflectance.
+func (d *ThinDielectric) Sample(wo geo.Unit, u1, u2 float64) (Sample, bool) {
+	// Sum the geometric series of internal reflections: each pass through
+	// the sheet reflects r of what arrives at the far side back again.
+	r := FresnelDielectric(geo.AbsCosTheta(wo), d.eta)
+	t := 1 - r
+	if r < 1 {
+		r += t * t * r / (1 - r*r)
+		t = 1 - r
+	}
+
+	if u1 < r {
+		wi := reflect(wo)
+		f := spectrum.Sample(spectrum.Flat(r / geo.AbsCosTheta(wi)))
+		return Sample{Wi: wi, F: f, PDF: r, Specular: true}, true
+	}
+
+	wi := wo.Reverse()
+	f := spectrum.Sample(spectrum.Flat(t / geo.AbsCosTheta(wi)))
+	return Sample{Wi: wi, F: f, PDF: t, Specular: true}, true
+}
+
+// PDF implements Material. It's always zero, see Material.
+func (d *ThinDielectric) PDF(wo, wi geo.Unit) float64 {
+	return 0
+}
+
+// FresnelDielectric returns the fraction of light reflected at a smooth
+// dielectric boundary, for unpolarized light. cosThetaI is the cosine of the
+// angle between the incident direction and the normal, and eta is the
+// relative refractive index of the side opposite the normal. Negative cosines
+// mean the light is arriving from inside.
+//
+// https://www.pbr-book.org/3ed-2018/Reflection_Models/Specular_Reflection_and_Transmission#FresnelReflectance
+func FresnelDielectric(cosThetaI, eta float64) float64 {
+	cosThetaI = math.Max(-1, math.Min(1, cosThetaI))
+	if cosThetaI < 0 {
+		eta = 1 / eta
+		cosThetaI = -cosThetaI
+	}
+
+	// Snell's law
+	sin2ThetaT := (1 - cosThetaI*cosThetaI) / (eta * eta)
+	if sin2ThetaT >= 1 {
+		return 1 // total internal reflection
+	}
+	cosThetaT := math.Sqrt(1 - sin2ThetaT)
+
+	rParl := (eta*cosThetaI - cosThetaT) / (eta*cosThetaI + cosThetaT)
+	rPerp := (cosThetaI - eta*cosThetaT) / (cosThetaI + eta*cosThetaT)
+	return (rParl*rParl + rPerp*rPerp) / 2
+}
+
+// reflect returns the mirror direction of w about the normal (+z).
+func reflect(w geo.Unit) geo.Unit {
+	return geo.Unit{X: -w.X, Y: -w.Y, Z: w.Z}
+}
+
+// refract returns the direction w refracts to on crossing into a medium with
+// relative refractive index eta. The normal is +z, flipped to w's side.
+// Returns false on total internal reflection.
+func refract(w geo.Unit, eta float64) (geo.Unit, bool) {
+	cosThetaI := w.Z
+	sin2ThetaT := (1 - cosThetaI*cosThetaI) / (eta * eta)
+	if sin2ThetaT >= 1 {
+		return geo.Unit{}, false
+	}
+	cosThetaT := math.Sqrt(1 - sin2ThetaT)
+	if cosThetaI > 0 {
+		cosThetaT = -cosThetaT
+	}
+
+	return geo.Unit{X: -w.X / eta, Y: -w.Y / eta, Z: cosThetaT}, true
+}
+
+// refractAbout is refract for a facet with normal h, on w's side.
+func refractAbout(w, h geo.Unit, eta float64) (geo.Unit, bool) {
+	cosThetaI := w.Dot(h)
+	sin2ThetaT := (1 - cosThetaI*cosThetaI) / (eta * eta)
+	if sin2ThetaT >= 1 {
+		return geo.Unit{}, false
+	}
+	cosThetaT := math.Sqrt(1 - sin2ThetaT)
+	return geo.Vec(w).Scale(-1 / eta).Plus(geo.Vec(h).Scale(cosThetaI/eta - cosThetaT)).Unit(), true
+}
@@ -1,13 +1,145 @@
+// Package material describes how light scatters at surfaces.
 package material
 
-import "github.com/gmhorn/gremlin/archive/pkg/geo"
+import (
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/gmhorn/gremlin/archive/pkg/texture"
+)
 
+// Material is a BSDF: the bidirectional scattering distribution function of a
+// surface.
+//
+// All directions are unit vectors in the local shading frame (see geo.Frame),
+// where the surface normal is +z, and point away from the surface. By
+// convention wo is the outgoing direction (towards the camera, for a path
+// traced from it) and wi is the incident direction (towards the light).
+//
+// https://www.pbr-book.org/3ed-2018/Reflection_Models/Basic_Interface
 type Material interface {
+	// Eval returns the value of the BSDF for the pair of directions. Perfectly
+	// specular materials always return zero, since their BSDF is a delta
+	// distribution; use Sample instead.
+	Eval(wo, wi geo.Unit) *spectrum.Sampled
 
-	// Sample returns an exitant light ray, wo, at from and incident, wi, and
-	// normal, n.
-	//
-	// Note that the returned ray will be slightly displaced along the normal to
-	// avoid self-intersection from floating-point inaccuracies.
-	Sample(point, wi geo.Vec, n geo.Unit, wavelength float64) (wo geo.Ray)
+	// Sample chooses an incident direction for the outgoing direction wo,
+	// using the uniform random numbers u1 and u2. Returns false if no
+	// direction could be sampled.
+	Sample(wo geo.Unit, u1, u2 float64) (Sample, bool)
+
+	// PDF returns the probability density with which Sample would choose wi
+	// for wo, with respect to solid angle.
+	PDF(wo, wi geo.Unit) float64
+}
+
+// Sample is a direction chosen by Material.Sample, along with the value of the
+// BSDF and the pdf for that direction.
+//
+// For specular samples, F and PDF are the delta distribution "coefficients":
+// the usual estimator F * |cos(wi)| / PDF still gives the right weight, but
+// they can't be compared with the result of Eval or PDF.
+type Sample struct {
+	Wi       geo.Unit
+	F        *spectrum.Sampled
+	PDF      float64
+	Specular bool
+}
+
+// Lambertian is a perfectly diffuse reflector, scattering light equally in all
+// directions of the hemisphere. If Texture is set, it's Varying, and the
+// reflectance comes from the texture instead of R.
+//
+// https://www.pbr-book.org/3ed-2018/Reflection_Models/Lambertian_Reflection
+type Lambertian struct {
+	R       *spectrum.Sampled
+	Texture texture.Texture
+}
+
+// NewLambertian creates a diffuse material with the given reflectance.
+func NewLambertian(r spectrum.Distribution) *Lambertian {
+	return &Lambertian{R: spectrum.Sample(r)}
+}
+
+// NewTexturedLambertian creates a diffuse material whose reflectance comes
+// from a texture.
+func NewTexturedLambertian(tex texture.Texture) *Lambertian {
+	return &Lambertian{Texture: tex}
+}
+
+// At implements Varying.
+func (l *Lambertian) At(tc texture.Coords) Material {
+	if l.Texture == nil {
+		return l
+	}
+	return &Lambertian{R: l.Texture.Eval(tc)}
+}
+
+// Eval implements Material.
+func (l *Lambertian) Eval(wo, wi geo.Unit) *spectrum.Sampled {
+	if !geo.SameHemisphere(wo, wi) {
+		return new(spectrum.Sampled)
+	}
+	return l.R.Scale(invPi)
+}
+
+// Sample implements Material. Directions are cosine-weighted.
+func (l *Lambertian) Sample(wo geo.Unit, u1, u2 float64) (Sample, bool) {
+	wi := cosineHemisphere(u1, u2)
+	if wo.Z < 0 {
+		wi.Z = -wi.Z
+	}
+
+	pdf := l.PDF(wo, wi)
+	if pdf == 0 {
+		return Sample{}, false
+	}
+	return Sample{Wi: wi, F: l.Eval(wo, wi), PDF: pdf}, true
+}
+
+// PDF implements Material.
+func (l *Lambertian) PDF(wo, wi geo.Unit) float64 {
+	if !geo.SameHemisphere(wo, wi) {
+		return 0
+	}
+	return geo.AbsCosTheta(wi) * invPi
+}
+
+// Mirror is a perfectly specular reflector, like a polished metal. R is the
+// fraction of light reflected at each wavelength.
+type Mirror struct {
+	R *spectrum.Sampled
+}
+
+// NewMirror creates a mirror with the given reflectance.
+func NewMirror(r spectrum.Distribution) *Mirror {
+	return &Mirror{R: spectrum.Sample(r)}
+}
+
+// Eval implements Material. It's always zero, see Material.
+func (m *Mirror) Eval(wo, wi geo.Unit) *spectrum.Sampled {
+	return new(spectrum.Sampled)
+}
+
+// Sample implements Material. It always picks the mirror direction.
+func (m *Mirror) Sample(wo geo.Unit, u1, u2 float64) (Sample, bool) {
+	wi := reflect(wo)
+	cos := geo.AbsCosTheta(wi)
+	if cos == 0 {
+		return Sample{}, false
+	}
+	return Sample{Wi: wi, F: m.R.Scale(1 / cos), PDF: 1, Specular: true}, true
+}
+
+// PDF implements Material. It's always zero, see Material.
+func (m *Mirror) PDF(wo, wi geo.Unit) float64 {
+	return 0
+}
+
+// Roughen implements Glossy. A rough mirror is a metallic Microfacet with the
+// mirror's reflectance.
+func (m *Mirror) Roughen(min float64) Material {
+	if min <= 0 {
+		return m
+	}
+	return &Microfacet{BaseColor: m.R, Roughness: min, Metallic: 1}
 }
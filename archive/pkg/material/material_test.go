@@ -0,0 +1,316 @@
+package material
+
+import (
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/imageio"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/gmhorn/gremlin/archive/pkg/texture"
+	"github.com/gmhorn/gremlin/archive/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLambertian(t *testing.T) {
+	l := NewLambertian(spectrum.Flat(0.5))
+	wo := geo.V(0.3, -0.2, 1).Unit()
+	rnd := util.NewRand(0)
+
+	// the estimator F*cos/pdf is just the reflectance
+	for i := 0; i < 100; i++ {
+		s, ok := l.Sample(wo, rnd.Float64(), rnd.Float64())
+		assert.True(t, ok)
+		assert.True(t, geo.SameHemisphere(wo, s.Wi))
+		assert.InDelta(t, l.PDF(wo, s.Wi), s.PDF, 1e-12)
+		assert.InDelta(t, 0.5, s.F[0]*geo.AbsCosTheta(s.Wi)/s.PDF, 1e-9)
+	}
+
+	// no transmission
+	assert.Equal(t, 0.0, l.PDF(wo, geo.V(0, 0, -1).Unit()))
+	assert.Equal(t, new(spectrum.Sampled), l.Eval(wo, geo.V(0, 0, -1).Unit()))
+}
+
+func TestMirror(t *testing.T) {
+	m := NewMirror(spectrum.Flat(0.9))
+	wo := geo.V(0.3, -0.2, 1).Unit()
+
+	s, ok := m.Sample(wo, 0.5, 0.5)
+	assert.True(t, ok)
+	assert.True(t, s.Specular)
+	assert.Equal(t, geo.Unit{X: -wo.X, Y: -wo.Y, Z: wo.Z}, s.Wi)
+	assert.InDelta(t, 0.9, s.F[0]*geo.AbsCosTheta(s.Wi)/s.PDF, 1e-12)
+}
+
+func TestRoughen(t *testing.T) {
+	// rougher copies, leaving the original alone
+	mf := NewMicrofacet(spectrum.Flat(0.5), 0.2, 0)
+	assert.Equal(t, 0.5, Roughen(mf, 0.5).(*Microfacet).Roughness)
+	assert.Same(t, mf, Roughen(mf, 0.1))
+	assert.Equal(t, 0.2, mf.Roughness)
+
+	glass := NewDielectric(spectrum.Flat(1.5))
+	glass.Priority = 2
+	rough := Roughen(glass, 0.3).(*Dielectric)
+	assert.Equal(t, 0.3, rough.Roughness)
+	assert.Equal(t, 2, rough.Priority)
+	assert.Equal(t, 0.0, glass.Roughness)
+
+	// a rough mirror is a metal
+	mirror := NewMirror(spectrum.Flat(0.9))
+	assert.Same(t, mirror, Roughen(mirror, 0))
+	metal := Roughen(mirror, 0.3).(*Microfacet)
+	assert.Equal(t, 1.0, metal.Metallic)
+	assert.Equal(t, mirror.R, metal.BaseColor)
+
+	mix := Roughen(NewMix(mirror, NewLambertian(spectrum.Flat(1)), 0.5), 0.3).(*Mix)
+	assert.IsType(t, &Microfacet{}, mix.A)
+	assert.IsType(t, &Lambertian{}, mix.B)
+	assert.Equal(t, 0.5, mix.Amount)
+}
+
+func TestFresnelDielectric(t *testing.T) {
+	// normal incidence: ((n-1)/(n+1))^2
+	assert.InDelta(t, 0.04, FresnelDielectric(1, 1.5), 1e-12)
+	assert.InDelta(t, 0.04, FresnelDielectric(-1, 1.5), 1e-12)
+	// grazing
+	assert.InDelta(t, 1, FresnelDielectric(0, 1.5), 1e-12)
+	// past the critical angle from inside
+	assert.Equal(t, 1.0, FresnelDielectric(-0.5, 1.5))
+	// matched media
+	assert.InDelta(t, 0, FresnelDielectric(0.3, 1), 1e-12)
+}
+
+func TestDielectric(t *testing.T) {
+	d := NewDielectric(spectrum.Flat(1.5))
+	wo := geo.V(0.5, 0, 1).Unit()
+	fr := FresnelDielectric(wo.Z, 1.5)
+
+	s, ok := d.Sample(wo, 0, 0.5)
+	assert.True(t, ok)
+	assert.Equal(t, geo.Unit{X: -wo.X, Y: -wo.Y, Z: wo.Z}, s.Wi)
+	assert.InDelta(t, fr, s.PDF, 1e-12)
+
+	s, ok = d.Sample(wo, 1, 0.5)
+	assert.True(t, ok)
+	assert.Less(t, s.Wi.Z, 0.0)
+	assert.InDelta(t, 1, geo.Vec(s.Wi).Len(), 1e-12)
+	// Snell's law
+	assert.InDelta(t, geo.SinTheta(wo), 1.5*geo.SinTheta(s.Wi), 1e-12)
+	// sampled in proportion to Fresnel, so the weight is just the 1/eta^2
+	// radiance scaling
+	assert.InDelta(t, 1/(1.5*1.5), s.F[0]*geo.AbsCosTheta(s.Wi)/s.PDF, 1e-12)
+
+	// and back out again
+	back, ok := d.Sample(s.Wi, 1, 0.5)
+	assert.True(t, ok)
+	assert.InDelta(t, wo.X, back.Wi.X, 1e-12)
+	assert.InDelta(t, wo.Z, back.Wi.Z, 1e-12)
+
+	// total internal reflection from inside at a shallow angle
+	grazing := geo.V(1, 0, -0.2).Unit()
+	s, ok = d.Sample(grazing, 0.99, 0.5)
+	assert.True(t, ok)
+	assert.Less(t, s.Wi.Z, 0.0)
+	assert.InDelta(t, 1, s.PDF, 1e-12)
+}
+
+func TestDielectric_Rough(t *testing.T) {
+	d := NewDielectric(spectrum.Flat(1.5))
+	d.Roughness = 0.3
+	rnd := util.NewRand(0)
+
+	for _, wo := range []geo.Unit{geo.V(0.3, -0.2, 1).Unit(), geo.V(0.5, 0.1, -1).Unit()} {
+		// Sample agrees with Eval and PDF, and (undoing the 1/eta^2 radiance
+		// scaling) loses only a few percent of the energy to the single
+		// scattering model
+		eta := 1.5
+		if wo.Z < 0 {
+			eta = 1 / eta
+		}
+		sum, reflected := 0.0, 0
+		const n = 20000
+		for i := 0; i < n; i++ {
+			s, ok := d.Sample(wo, rnd.Float64(), rnd.Float64())
+			if !ok {
+				continue
+			}
+			assert.False(t, s.Specular)
+			assert.InDelta(t, d.PDF(wo, s.Wi), s.PDF, 1e-9*s.PDF)
+			assert.InDelta(t, d.Eval(wo, s.Wi)[0], s.F[0], 1e-9*s.F[0])
+
+			w := s.F[0] * geo.AbsCosTheta(s.Wi) / s.PDF
+			if geo.SameHemisphere(wo, s.Wi) {
+				reflected++
+			} else {
+				w *= eta * eta
+			}
+			sum += w
+		}
+		assert.LessOrEqual(t, sum/n, 1.0, "wo %v", wo)
+		assert.Greater(t, sum/n, 0.9, "wo %v", wo)
+		assert.Greater(t, reflected, 0)
+	}
+
+	// nearly smooth, it refracts by Snell's law
+	d.Roughness = 0.01
+	wo := geo.V(0.5, 0, 1).Unit()
+	s, ok := d.Sample(wo, 0.9, 0.3)
+	assert.True(t, ok)
+	assert.Less(t, s.Wi.Z, 0.0)
+	assert.InDelta(t, geo.SinTheta(wo), 1.5*geo.SinTheta(s.Wi), 0.01)
+
+	// boundaries between media keep the roughness
+	assert.Equal(t, 0.01, d.Against(NewDielectric(spectrum.Flat(1.25))).Roughness)
+}
+
+func TestDielectric_Against(t *testing.T) {
+	glass := NewDielectric(spectrum.Flat(1.5))
+	water := NewDielectric(spectrum.Flat(1.25))
+	assert.Same(t, glass, glass.Against(nil))
+
+	// Fresnel at normal incidence uses the relative index
+	b := glass.Against(water)
+	assert.InDelta(t, 1.2, b.IOR.Lookup(500), 1e-12)
+	s, ok := b.Sample(geo.Unit{Z: 1}, 0, 0.5)
+	assert.True(t, ok)
+	assert.InDelta(t, (0.2*0.2)/(2.2*2.2), s.PDF, 1e-12)
+}
+
+func TestThinDielectric(t *testing.T) {
+	d := NewThinDielectric(spectrum.Flat(1.5))
+
+	// normal incidence: r = 0.04 at each surface, 2r/(1+r) in total
+	wo := geo.Unit{Z: 1}
+	s, ok := d.Sample(wo, 0, 0.5)
+	assert.True(t, ok)
+	assert.Equal(t, wo, s.Wi)
+	assert.InDelta(t, 0.08/1.04, s.PDF, 1e-12)
+
+	// transmitted light carries on in a straight line, without any radiance
+	// scaling
+	wo = geo.V(0.5, 0.2, -1).Unit()
+	s, ok = d.Sample(wo, 1, 0.5)
+	assert.True(t, ok)
+	assert.Equal(t, wo.Reverse(), s.Wi)
+	assert.InDelta(t, 1, s.F[0]*geo.AbsCosTheta(s.Wi)/s.PDF, 1e-12)
+
+	// nothing is absorbed
+	r, _ := d.Sample(wo, 0, 0.5)
+	assert.InDelta(t, 1, r.PDF+s.PDF, 1e-12)
+}
+
+func TestCosineHemisphere(t *testing.T) {
+	rnd := util.NewRand(0)
+	sumCos := 0.0
+	n := 10000
+	for i := 0; i < n; i++ {
+		w := cosineHemisphere(rnd.Float64(), rnd.Float64())
+		assert.InDelta(t, 1, geo.Vec(w).Len(), 1e-9)
+		assert.GreaterOrEqual(t, w.Z, 0.0)
+		sumCos += w.Z
+	}
+	// E[cos theta] under a cosine-weighted distribution is 2/3
+	assert.InDelta(t, 2.0/3.0, sumCos/float64(n), 0.01)
+}
+
+func TestWater(t *testing.T) {
+	w := Water(0.1)
+	assert.InDelta(t, 1.333, w.eta, 0.001)
+	assert.InDelta(t, 0.02, FresnelDielectric(1, w.eta), 0.001)
+	assert.Equal(t, 0.1, w.Roughness)
+
+	// red is absorbed more than blue
+	assert.Greater(t, w.Absorption.Lookup(650), 10*w.Absorption.Lookup(450))
+	assert.Panics(t, func() { Water(-1) })
+}
+
+func TestMix(t *testing.T) {
+	diffuse := NewLambertian(spectrum.Flat(0.8))
+	mirror := NewMirror(spectrum.Flat(1))
+	mix := NewMix(diffuse, mirror, 0.25)
+	wo := geo.V(0.3, -0.2, 1).Unit()
+	wi := geo.V(-0.1, 0.5, 1).Unit()
+
+	assert.InDelta(t, 0.75*0.8*invPi, mix.Eval(wo, wi)[0], 1e-12)
+	assert.InDelta(t, 0.75*diffuse.PDF(wo, wi), mix.PDF(wo, wi), 1e-12)
+
+	// u1 below the amount picks the mirror
+	s, ok := mix.Sample(wo, 0.1, 0.5)
+	assert.True(t, ok)
+	assert.True(t, s.Specular)
+
+	// otherwise the diffuse lobe, weighted as the blend
+	s, ok = mix.Sample(wo, 0.6, 0.5)
+	assert.True(t, ok)
+	assert.False(t, s.Specular)
+	assert.InDelta(t, mix.PDF(wo, s.Wi), s.PDF, 1e-12)
+	assert.InDelta(t, 0.8, s.F[0]*geo.AbsCosTheta(s.Wi)/s.PDF, 1e-9)
+}
+
+func TestMix_Mask(t *testing.T) {
+	a := NewLambertian(spectrum.Flat(0))
+	b := NewLambertian(spectrum.Flat(1))
+	half := texture.ScalarFunc(func(tc texture.Coords) float64 {
+		if tc.U < 0.5 {
+			return 0
+		}
+		return 1
+	})
+	mix := NewMaskedMix(a, NewMaskedMix(a, b, half), half)
+	wo := geo.Unit{Z: 1}
+
+	assert.Equal(t, 0.0, Resolve(mix, texture.Coords{U: 0.2}).Eval(wo, wo)[0])
+	assert.InDelta(t, invPi, Resolve(mix, texture.Coords{U: 0.7}).Eval(wo, wo)[0], 1e-12)
+	assert.Equal(t, Material(a), Resolve(a, texture.Coords{U: 0.7}))
+}
+
+func TestLambertian_Texture(t *testing.T) {
+	black := texture.NewConstant(spectrum.Flat(0))
+	white := texture.NewConstant(spectrum.Flat(1))
+	l := NewTexturedLambertian(texture.NewCheckerboard(black, white, 1))
+	wo := geo.Unit{Z: 1}
+
+	assert.Equal(t, 0.0, Resolve(l, texture.Coords{U: 0.5, V: 0.5}).Eval(wo, wo)[0])
+	assert.InDelta(t, invPi, Resolve(l, texture.Coords{U: 1.5, V: 0.5}).Eval(wo, wo)[0], 1e-12)
+}
+
+func TestNormalMap(t *testing.T) {
+	// a flat map (0, 0, 1) and one tilted towards +x
+	flat := imageio.NewRGB(1, 1)
+	flat.Set(0, 0, 0.5, 0.5, 1)
+	tilted := imageio.NewRGB(1, 1)
+	tilted.Set(0, 0, 1, 0.5, 1)
+
+	base := NewLambertian(spectrum.Flat(0.5))
+	n := geo.ZAxis
+	dpdu, dpdv := geo.V(0, 2, 0), geo.V(-1, 0, 0)
+
+	nm := NewNormalMap(base, flat)
+	assert.Equal(t, n, ShadingNormal(nm, texture.Coords{}, n, dpdu, dpdv))
+	assert.Same(t, base, Resolve(nm, texture.Coords{}))
+
+	// x is along dp/du
+	got := ShadingNormal(NewNormalMap(base, tilted), texture.Coords{}, n, dpdu, dpdv)
+	assert.InDelta(t, 0, geo.Vec(got).Minus(geo.Vec(geo.V(0, 1, 1).Unit())).Len(), 1e-9)
+
+	assert.Equal(t, n, ShadingNormal(base, texture.Coords{}, n, dpdu, dpdv))
+}
+
+func TestBump(t *testing.T) {
+	// height rising along u, by 0.5 per unit of u
+	ramp := texture.ScalarFunc(func(tc texture.Coords) float64 { return tc.U })
+	base := NewLambertian(spectrum.Flat(0.5))
+	n := geo.ZAxis
+	dpdu, dpdv := geo.V(1, 0, 0), geo.V(0, 1, 0)
+
+	b := NewBump(base, ramp, 0.5)
+	got := ShadingNormal(b, texture.Coords{U: 0.3}, n, dpdu, dpdv)
+	assert.InDelta(t, 0, geo.Vec(got).Minus(geo.Vec(geo.V(-0.5, 0, 1).Unit())).Len(), 1e-9)
+
+	// a flat height leaves the normal alone, even if it isn't perpendicular
+	// to the derivatives
+	flat := NewBump(base, texture.ScalarFunc(func(texture.Coords) float64 { return 1 }), 1)
+	shading := geo.V(0.2, 0, 1).Unit()
+	got = ShadingNormal(flat, texture.Coords{}, shading, dpdu, dpdv)
+	assert.InDelta(t, 0, geo.Vec(got).Minus(geo.Vec(shading)).Len(), 1e-9)
+}
@@ -0,0 +1,261 @@
+package material
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/asset"
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+)
+
+// Resolution of the MERL tables, in half/difference angle coordinates.
+const (
+	merlThetaH = 90
+	merlThetaD = 90
+	merlPhiD   = 180
+	merlSize   = merlThetaH * merlThetaD * merlPhiD
+)
+
+// Scale factors MERL values are stored with, per channel.
+var merlScale = [3]float64{1.0 / 1500, 1.15 / 1500, 1.66 / 1500}
+
+// MERL is an isotropic BRDF measured by Matusik et al. and distributed as part
+// of the MERL BRDF database. The tables store RGB reflectance in Rusinkiewicz's
+// half/difference angle parameterization; values are converted to spectra with
+// spectrum.FromRGB.
+//
+// The measurements are of the front of the material, but it's two-sided, so
+// directions below the surface are mirrored above it, like the back of a
+// Microfacet.
+//
+// The data has no analytic form to sample, so a lobe is fitted to it on load:
+// a mix of a cosine-weighted diffuse lobe and a Blinn-Phong lobe around the
+// mirror direction, weighted by their share of the measured reflectance.
+//
+// https://www.merl.com/brdf/
+// https://www.cs.princeton.edu/~smr/papers/brdf_change_of_variables/
+type MERL struct {
+	data [3 * merlSize]float64
+
+	// fitted sampling lobes
+	specWeight float64
+	exponent   float64
+}
+
+// LoadMERL opens the named .binary file with the resolver and reads it.
+func LoadMERL(res *asset.Resolver, name string) (*MERL, error) {
+	f, err := res.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := ReadMERL(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return m, nil
+}
+
+// ReadMERL reads a MERL BRDF in the database's binary format: three int32
+// dimensions, followed by the red, green and blue tables as float64s, all
+// little-endian.
+func ReadMERL(r io.Reader) (*MERL, error) {
+	var dims [3]int32
+	if err := binary.Read(r, binary.LittleEndian, &dims); err != nil {
+		return nil, fmt.Errorf("reading MERL header: %w", err)
+	}
+	if dims != [3]int32{merlThetaH, merlThetaD, merlPhiD} {
+		return nil, fmt.Errorf("unexpected MERL dimensions %v", dims)
+	}
+
+	m := &MERL{}
+	if err := binary.Read(r, binary.LittleEndian, m.data[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("reading MERL data: %w", err)
+	}
+
+	for c := 0; c < 3; c++ {
+		for i := c * merlSize; i < (c+1)*merlSize; i++ {
+			// negative values mark missing measurements
+			m.data[i] = math.Max(0, m.data[i]*merlScale[c])
+		}
+	}
+
+	m.fitLobes()
+	return m, nil
+}
+
+// Eval implements Material.
+func (m *MERL) Eval(wo, wi geo.Unit) *spectrum.Sampled {
+	if !geo.SameHemisphere(wo, wi) {
+		return new(spectrum.Sampled)
+	}
+	r, g, b := m.rgb(upper(wo), upper(wi))
+	return spectrum.FromRGB(r, g, b)
+}
+
+// Sample implements Material.
+func (m *MERL) Sample(wo geo.Unit, u1, u2 float64) (Sample, bool) {
+	if wo.Z == 0 {
+		return Sample{}, false
+	}
+
+	var wi geo.Unit
+	up := upper(wo)
+	if u1 < m.specWeight {
+		u1 /= m.specWeight
+		cosThetaH := math.Pow(u1, 1/(m.exponent+1))
+		sinThetaH := math.Sqrt(math.Max(0, 1-cosThetaH*cosThetaH))
+		phi := 2 * math.Pi * u2
+		h := geo.Unit{X: sinThetaH * math.Cos(phi), Y: sinThetaH * math.Sin(phi), Z: cosThetaH}
+		wi = reflectAbout(up, h)
+	} else {
+		u1 = (u1 - m.specWeight) / (1 - m.specWeight)
+		wi = cosineHemisphere(u1, u2)
+	}
+	if wo.Z < 0 {
+		wi.Z = -wi.Z
+	}
+
+	pdf := m.PDF(wo, wi)
+	if pdf == 0 {
+		return Sample{}, false
+	}
+	return Sample{Wi: wi, F: m.Eval(wo, wi), PDF: pdf}, true
+}
+
+// PDF implements Material.
+func (m *MERL) PDF(wo, wi geo.Unit) float64 {
+	if !geo.SameHemisphere(wo, wi) {
+		return 0
+	}
+	wo, wi = upper(wo), upper(wi)
+
+	diffuse := wi.Z * invPi
+	h := geo.Vec(wo).Plus(geo.Vec(wi)).Unit()
+	spec := (m.exponent + 1) / (2 * math.Pi) * math.Pow(h.Z, m.exponent) / (4 * wo.Dot(h))
+	return m.specWeight*spec + (1-m.specWeight)*diffuse
+}
+
+// rgb looks up the measured reflectance for the pair of directions.
+func (m *MERL) rgb(wo, wi geo.Unit) (r, g, b float64) {
+	thetaH, thetaD, phiD := halfDiff(wo, wi)
+	idx := merlIndex(thetaH, thetaD, phiD)
+	return m.data[idx], m.data[idx+merlSize], m.data[idx+2*merlSize]
+}
+
+// fitLobes picks the sampling lobes from the mean (over color channels,
+// difference angles) reflectance as a function of the half angle. The
+// minimum of that profile is taken as diffuse; the rest is specular, and the
+// Blinn-Phong exponent is chosen to match the angle where it falls to half
+// its peak.
+func (m *MERL) fitLobes() {
+	var profile [merlThetaH]float64
+	for h := 0; h < merlThetaH; h++ {
+		sum := 0.0
+		for i := h * merlThetaD * merlPhiD; i < (h+1)*merlThetaD*merlPhiD; i++ {
+			sum += m.data[i] + m.data[i+merlSize] + m.data[i+2*merlSize]
+		}
+		profile[h] = sum / (3 * merlThetaD * merlPhiD)
+	}
+
+	diffuse := profile[0]
+	for _, p := range profile {
+		diffuse = math.Min(diffuse, p)
+	}
+	peak := profile[0] - diffuse
+
+	m.specWeight = 0
+	m.exponent = 1
+	if peak <= 0 {
+		return
+	}
+
+	halfWidth := merlThetaHAngle(merlThetaH - 1)
+	for h, p := range profile {
+		if p-diffuse < peak/2 {
+			halfWidth = merlThetaHAngle(h)
+			break
+		}
+	}
+	m.exponent = math.Max(1, math.Log(0.5)/math.Log(math.Cos(halfWidth)))
+
+	// share of reflectance in each lobe, integrated over half angles
+	specSum, totalSum := 0.0, 0.0
+	for h, p := range profile {
+		theta := merlThetaHAngle(h)
+		w := math.Sin(theta) * math.Cos(theta) * merlThetaHWidth(h)
+		specSum += (p - diffuse) * w
+		totalSum += p * w
+	}
+	// always keep some of each, so neither lobe's pdf goes to zero where
+	// the data isn't
+	m.specWeight = math.Max(0.1, math.Min(0.9, specSum/totalSum))
+}
+
+// halfDiff converts a pair of directions to half angle and difference angle
+// coordinates.
+func halfDiff(wo, wi geo.Unit) (thetaH, thetaD, phiD float64) {
+	h := geo.Vec(wo).Plus(geo.Vec(wi)).Unit()
+	thetaH = math.Acos(math.Max(-1, math.Min(1, h.Z)))
+	phiH := math.Atan2(h.Y, h.X)
+
+	// rotate wi so that h is the z-axis: by -phiH around z, then -thetaH
+	// around y
+	sinP, cosP := math.Sincos(-phiH)
+	x := wi.X*cosP - wi.Y*sinP
+	y := wi.X*sinP + wi.Y*cosP
+	z := wi.Z
+	sinT, cosT := math.Sincos(-thetaH)
+	d := geo.Vec{X: x*cosT + z*sinT, Y: y, Z: -x*sinT + z*cosT}
+
+	thetaD = math.Acos(math.Max(-1, math.Min(1, d.Z)))
+	phiD = math.Atan2(d.Y, d.X)
+	return
+}
+
+// merlIndex returns the index into a channel's table for the given angles.
+// The half angle is sampled non-linearly (more densely near the specular
+// peak), and phiD only over half a circle since the BRDF is reciprocal.
+func merlIndex(thetaH, thetaD, phiD float64) int {
+	h := 0
+	if thetaH > 0 {
+		h = clampIndex(int(math.Sqrt(thetaH/(math.Pi/2))*merlThetaH), merlThetaH)
+	}
+	d := clampIndex(int(thetaD/(math.Pi/2)*merlThetaD), merlThetaD)
+
+	if phiD < 0 {
+		phiD += math.Pi
+	}
+	p := clampIndex(int(phiD/math.Pi*merlPhiD), merlPhiD)
+
+	return p + merlPhiD*(d+merlThetaD*h)
+}
+
+// merlThetaHAngle returns the half angle at the start of the i-th bin.
+func merlThetaHAngle(i int) float64 {
+	f := float64(i) / merlThetaH
+	return f * f * math.Pi / 2
+}
+
+// merlThetaHWidth returns the width of the i-th half angle bin.
+func merlThetaHWidth(i int) float64 {
+	return merlThetaHAngle(i+1) - merlThetaHAngle(i)
+}
+
+func clampIndex(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
@@ -0,0 +1,145 @@
+package material
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+// testMERL encodes a MERL file whose reflectance is diffuse, plus spec for
+// half angle bins below specBins.
+func testMERL(diffuse, spec float64, specBins int) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, [3]int32{merlThetaH, merlThetaD, merlPhiD})
+
+	data := make([]float64, 3*merlSize)
+	for c := 0; c < 3; c++ {
+		for i := 0; i < merlSize; i++ {
+			v := diffuse
+			if i/(merlThetaD*merlPhiD) < specBins {
+				v += spec
+			}
+			data[c*merlSize+i] = v / merlScale[c]
+		}
+	}
+	binary.Write(buf, binary.LittleEndian, data)
+	return buf.Bytes()
+}
+
+func TestReadMERL(t *testing.T) {
+	m, err := ReadMERL(bytes.NewReader(testMERL(0.5*invPi, 0, 0)))
+	assert.NoError(t, err)
+
+	wo := geo.V(0.3, 0.1, 1).Unit()
+	wi := geo.V(-0.5, 0.2, 1).Unit()
+	f := m.Eval(wo, wi)
+	assert.InDelta(t, 0.5*invPi, f[0], 1e-12)
+	assert.InDelta(t, 0.5*invPi, f[len(f)-1], 1e-12)
+	assert.Equal(t, 0.0, m.Eval(wo, wi.Reverse())[0])
+
+	// no specular peak, so sampling is purely diffuse
+	assert.Equal(t, 0.0, m.specWeight)
+}
+
+func TestReadMERL_Errors(t *testing.T) {
+	_, err := ReadMERL(bytes.NewReader(nil))
+	assert.Error(t, err)
+
+	bad := &bytes.Buffer{}
+	binary.Write(bad, binary.LittleEndian, [3]int32{90, 90, 360})
+	_, err = ReadMERL(bad)
+	assert.ErrorContains(t, err, "dimensions")
+
+	data := testMERL(0.1, 0, 0)
+	_, err = ReadMERL(bytes.NewReader(data[:len(data)/2]))
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+}
+
+func TestMERL_Sample(t *testing.T) {
+	m, err := ReadMERL(bytes.NewReader(testMERL(0.1*invPi, 20, 10)))
+	assert.NoError(t, err)
+	assert.Greater(t, m.specWeight, 0.1)
+	assert.Greater(t, m.exponent, 10.0)
+
+	wo := geo.V(0.5, 0, 1).Unit()
+	rnd := util.NewRand(0)
+	for i := 0; i < 1000; i++ {
+		s, ok := m.Sample(wo, rnd.Float64(), rnd.Float64())
+		if !ok {
+			continue
+		}
+		assert.Greater(t, s.Wi.Z, 0.0)
+		assert.InDelta(t, m.PDF(wo, s.Wi), s.PDF, 1e-9)
+	}
+
+	// the pdf integrates to (at most, since part of the specular lobe can
+	// fall below the horizon) one
+	sum := 0.0
+	n := 100000
+	for i := 0; i < n; i++ {
+		// uniform hemisphere sampling, pdf 1/(2pi)
+		z := rnd.Float64()
+		r := math.Sqrt(1 - z*z)
+		phi := 2 * math.Pi * rnd.Float64()
+		wi := geo.Unit{X: r * math.Cos(phi), Y: r * math.Sin(phi), Z: z}
+		sum += m.PDF(wo, wi) * 2 * math.Pi
+	}
+	integral := sum / float64(n)
+	assert.Greater(t, integral, 0.9)
+	assert.Less(t, integral, 1.02)
+}
+
+func TestMERL_TwoSided(t *testing.T) {
+	m, err := ReadMERL(bytes.NewReader(testMERL(0.1*invPi, 20, 10)))
+	assert.NoError(t, err)
+	below := func(w geo.Unit) geo.Unit { return geo.Unit{X: w.X, Y: w.Y, Z: -w.Z} }
+
+	// seen from below, it's the same as from above
+	wo, wi := geo.V(0.5, 0, 1).Unit(), geo.V(-0.3, 0.2, 1).Unit()
+	assert.Greater(t, m.Eval(wo, wi)[0], 0.0)
+	assert.Equal(t, m.Eval(wo, wi), m.Eval(below(wo), below(wi)))
+	assert.Equal(t, m.PDF(wo, wi), m.PDF(below(wo), below(wi)))
+
+	// but it doesn't transmit
+	assert.Zero(t, m.Eval(wo, below(wi)).Max())
+	assert.Zero(t, m.PDF(below(wo), wi))
+
+	rnd := util.NewRand(0)
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		s, ok := m.Sample(below(wo), rnd.Float64(), rnd.Float64())
+		if !ok {
+			continue
+		}
+		sampled++
+		assert.Less(t, s.Wi.Z, 0.0)
+		assert.InDelta(t, m.PDF(below(wo), s.Wi), s.PDF, 1e-9)
+		assert.Equal(t, m.Eval(below(wo), s.Wi), s.F)
+	}
+	assert.Greater(t, sampled, 900)
+}
+
+func TestHalfDiff(t *testing.T) {
+	// retro-reflection: half vector is the direction itself
+	w := geo.V(0.3, 0.4, 1).Unit()
+	thetaH, thetaD, _ := halfDiff(w, w)
+	assert.InDelta(t, math.Acos(w.Z), thetaH, 1e-9)
+	assert.InDelta(t, 0, thetaD, 1e-6)
+
+	// mirror pair around the normal
+	wo := geo.V(1, 0, 1).Unit()
+	wi := geo.V(-1, 0, 1).Unit()
+	thetaH, thetaD, _ = halfDiff(wo, wi)
+	assert.InDelta(t, 0, thetaH, 1e-9)
+	assert.InDelta(t, math.Pi/4, thetaD, 1e-9)
+
+	assert.Equal(t, 0, merlIndex(0, 0, 0))
+	assert.Less(t, merlIndex(math.Pi/2, math.Pi/2, math.Pi), merlSize)
+}
@@ -0,0 +1,254 @@
+package material
+
+import (
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/gmhorn/gremlin/archive/pkg/texture"
+)
+
+// minAlpha keeps the GGX distribution of a Microfacet from collapsing into a
+// delta, which it can't represent, at zero roughness.
+const minAlpha = 1e-3
+
+// dielectricF0 is the reflectance at normal incidence of the non-metallic part
+// of a Microfacet, that of an IOR of 1.5.
+const dielectricF0 = 0.04
+
+// Microfacet is a rough surface made of tiny mirror facets, parameterized like
+// the metallic-roughness materials of glTF and most content tools:
+//
+//   - BaseColor is the diffuse color of a dielectric, or the reflectance of a
+//     metal at normal incidence.
+//   - Roughness goes from 0 (polished) to 1 (matte); the GGX width is its
+//     square, which makes it perceptually linear.
+//   - Metallic blends from a dielectric (0), a diffuse base under a clear
+//     specular coat with 4% reflectance, to a metal (1), with only a specular
+//     lobe tinted by the base color.
+//
+// Facets are distributed by GGX, with height-correlated Smith shadowing and
+// Schlick's approximation to the Fresnel reflectance. If Texture or
+// RoughnessMap is set, it's Varying, and the base color or roughness comes
+// from it instead.
+//
+// Sampling picks the specular lobe or the diffuse one, and samples the
+// specular lobe from the distribution of normals visible from wo, so few
+// samples are wasted on facets facing away. See Heitz, "Sampling the GGX
+// Distribution of Visible Normals" (2018), and Appendix B of the glTF 2.0
+// specification.
+//
+// https://www.pbr-book.org/3ed-2018/Reflection_Models/Microfacet_Models
+type Microfacet struct {
+	BaseColor    *spectrum.Sampled
+	Roughness    float64
+	Metallic     float64
+	Texture      texture.Texture
+	RoughnessMap texture.Scalar
+}
+
+// NewMicrofacet creates a microfacet material.
+func NewMicrofacet(baseColor spectrum.Distribution, roughness, metallic float64) *Microfacet {
+	if roughness < 0 || roughness > 1 || metallic < 0 || metallic > 1 {
+		panic("roughness and metallic must be in [0, 1]")
+	}
+	return &Microfacet{BaseColor: spectrum.Sample(baseColor), Roughness: roughness, Metallic: metallic}
+}
+
+// Glossy is implemented by materials with a roughness that can be raised,
+// including perfectly specular ones that have a rough counterpart. Roughen
+// returns the material with a roughness (as for Microfacet) of at least min.
+//
+// Renderers use it to regularize paths: blurring the reflections seen after a
+// diffuse bounce lets them be lit directly, which turns the fireflies of
+// caustics seen in mirrors into a slightly blurred but converged image.
+type Glossy interface {
+	Material
+	Roughen(min float64) Material
+}
+
+// Roughen returns m with a roughness of at least min: m itself, or for Glossy
+// materials, the result of Roughen. Like their At, Roughen keeps textures, so
+// it should be given materials that have already been resolved.
+func Roughen(m Material, min float64) Material {
+	if gm, ok := m.(Glossy); ok {
+		return gm.Roughen(min)
+	}
+	return m
+}
+
+// Roughen implements Glossy.
+func (m *Microfacet) Roughen(min float64) Material {
+	if m.Roughness >= min {
+		return m
+	}
+	rough := *m
+	rough.Roughness = min
+	return &rough
+}
+
+// At implements Varying.
+func (m *Microfacet) At(tc texture.Coords) Material {
+	if m.Texture == nil && m.RoughnessMap == nil {
+		return m
+	}
+	at := &Microfacet{BaseColor: m.BaseColor, Roughness: m.Roughness, Metallic: m.Metallic}
+	if m.Texture != nil {
+		at.BaseColor = m.Texture.Eval(tc)
+	}
+	if m.RoughnessMap != nil {
+		at.Roughness = math.Max(0, math.Min(1, m.RoughnessMap.Value(tc)))
+	}
+	return at
+}
+
+// Eval implements Material.
+func (m *Microfacet) Eval(wo, wi geo.Unit) *spectrum.Sampled {
+	f := new(spectrum.Sampled)
+	if !geo.SameHemisphere(wo, wi) {
+		return f
+	}
+	wo, wi = upper(wo), upper(wi)
+	wh := geo.Vec(wo).Plus(geo.Vec(wi))
+	if wo.Z == 0 || wi.Z == 0 || wh.LenSquared() == 0 {
+		return f
+	}
+	h := wh.Unit()
+
+	alpha := m.alpha()
+	specular := ggxD(h, alpha) * smithG(wo, wi, alpha) / (4 * wo.Z * wi.Z)
+	fd := schlick(dielectricF0, wi.Dot(h))
+	fm := 1 - wi.Dot(h)
+	fm = fm * fm * fm * fm * fm
+	for i, base := range m.BaseColor {
+		dielectric := (1-fd)*base*invPi + fd*specular
+		metal := (base + (1-base)*fm) * specular
+		f[i] = (1-m.Metallic)*dielectric + m.Metallic*metal
+	}
+	return f
+}
+
+// Sample implements Material.
+func (m *Microfacet) Sample(wo geo.Unit, u1, u2 float64) (Sample, bool) {
+	var wi geo.Unit
+	if p := m.specularProb(); u1 < p {
+		h := sampleGGXVisible(upper(wo), m.alpha(), u1/p, u2)
+		wi = reflectAbout(upper(wo), h)
+		if wi.Z <= 0 {
+			return Sample{}, false
+		}
+	} else {
+		wi = cosineHemisphere((u1-p)/(1-p), u2)
+	}
+	if wo.Z < 0 {
+		wi.Z = -wi.Z
+	}
+
+	pdf := m.PDF(wo, wi)
+	if pdf == 0 {
+		return Sample{}, false
+	}
+	return Sample{Wi: wi, F: m.Eval(wo, wi), PDF: pdf}, true
+}
+
+// PDF implements Material.
+func (m *Microfacet) PDF(wo, wi geo.Unit) float64 {
+	if !geo.SameHemisphere(wo, wi) {
+		return 0
+	}
+	wo, wi = upper(wo), upper(wi)
+	wh := geo.Vec(wo).Plus(geo.Vec(wi))
+	if wo.Z == 0 || wh.LenSquared() == 0 {
+		return 0
+	}
+	h := wh.Unit()
+
+	// the visible normal pdf, times the Jacobian of reflecting about h
+	alpha := m.alpha()
+	specular := smithG1(wo, alpha) * ggxD(h, alpha) / (4 * wo.Z)
+	p := m.specularProb()
+	return p*specular + (1-p)*wi.Z*invPi
+}
+
+func (m *Microfacet) alpha() float64 {
+	return math.Max(minAlpha, m.Roughness*m.Roughness)
+}
+
+// specularProb is the probability of sampling the specular lobe: always for
+// metals, and half the time for dielectrics.
+func (m *Microfacet) specularProb() float64 {
+	return 0.5 + 0.5*m.Metallic
+}
+
+// ggxD is the GGX (Trowbridge-Reitz) distribution of facet normals h, with
+// width alpha.
+func ggxD(h geo.Unit, alpha float64) float64 {
+	a2 := alpha * alpha
+	d := h.Z*h.Z*(a2-1) + 1
+	return a2 / (math.Pi * d * d)
+}
+
+// smithLambda is the auxiliary function of Smith's shadowing for GGX.
+func smithLambda(w geo.Unit, alpha float64) float64 {
+	if w.Z == 0 {
+		return math.Inf(1)
+	}
+	return (math.Sqrt(1+alpha*alpha*geo.Tan2Theta(w)) - 1) / 2
+}
+
+// smithG1 is the fraction of facets visible from w.
+func smithG1(w geo.Unit, alpha float64) float64 {
+	return 1 / (1 + smithLambda(w, alpha))
+}
+
+// smithG is the height-correlated fraction of facets visible from both wo and
+// wi.
+func smithG(wo, wi geo.Unit, alpha float64) float64 {
+	return 1 / (1 + smithLambda(wo, alpha) + smithLambda(wi, alpha))
+}
+
+// sampleGGXVisible samples a facet normal from the GGX distribution of normals
+// visible from wo, which must be in the upper hemisphere.
+func sampleGGXVisible(wo geo.Unit, alpha, u1, u2 float64) geo.Unit {
+	// stretch to the hemisphere configuration
+	vh := geo.V(alpha*wo.X, alpha*wo.Y, wo.Z).Unit()
+
+	// an orthonormal basis around it
+	t1 := geo.V(1, 0, 0)
+	if l2 := vh.X*vh.X + vh.Y*vh.Y; l2 > 0 {
+		t1 = geo.V(-vh.Y, vh.X, 0).Scale(1 / math.Sqrt(l2))
+	}
+	t2 := geo.Vec(vh).Cross(t1)
+
+	// a point on the projected area, warped to the visible half of the disk
+	r := math.Sqrt(u1)
+	phi := 2 * math.Pi * u2
+	p1, p2 := r*math.Cos(phi), r*math.Sin(phi)
+	s := 0.5 * (1 + vh.Z)
+	p2 = (1-s)*math.Sqrt(1-p1*p1) + s*p2
+
+	// back onto the hemisphere, and unstretched
+	nh := t1.Scale(p1).Plus(t2.Scale(p2)).Plus(vh.Scale(math.Sqrt(math.Max(0, 1-p1*p1-p2*p2))))
+	return geo.V(alpha*nh.X, alpha*nh.Y, math.Max(0, nh.Z)).Unit()
+}
+
+// schlick is Schlick's approximation to the Fresnel reflectance at the cosine
+// of the angle of incidence, for reflectance f0 at normal incidence.
+func schlick(f0, cos float64) float64 {
+	m := 1 - cos
+	return f0 + (1-f0)*m*m*m*m*m
+}
+
+// reflectAbout reflects w about the normal h.
+func reflectAbout(w, h geo.Unit) geo.Unit {
+	return geo.Vec(h).Scale(2 * w.Dot(h)).Minus(geo.Vec(w)).Unit()
+}
+
+// upper returns w mirrored into the upper hemisphere, so two-sided materials
+// can work in it alone.
+func upper(w geo.Unit) geo.Unit {
+	if w.Z < 0 {
+		w.Z = -w.Z
+	}
+	return w
+}
@@ -0,0 +1,128 @@
+package material
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/gmhorn/gremlin/archive/pkg/texture"
+	"github.com/gmhorn/gremlin/archive/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMicrofacet_Sample(t *testing.T) {
+	tests := []struct {
+		name                string
+		roughness, metallic float64
+	}{
+		{"Plastic", 0.5, 0},
+		{"Glossy", 0.1, 0},
+		{"Metal", 0.3, 1},
+		{"Polished", 0, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := NewMicrofacet(spectrum.Flat(0.8), test.roughness, test.metallic)
+			rnd := util.NewRand(0)
+
+			for _, wo := range []geo.Unit{geo.V(0.3, -0.2, 1).Unit(), geo.V(0.9, 0, -0.2).Unit()} {
+				for i := 0; i < 100; i++ {
+					s, ok := m.Sample(wo, rnd.Float64(), rnd.Float64())
+					if !ok {
+						continue
+					}
+					assert.True(t, geo.SameHemisphere(wo, s.Wi))
+					assert.False(t, s.Specular)
+					assert.InDelta(t, 1, geo.Vec(s.Wi).Len(), 1e-9)
+					assert.InDelta(t, m.PDF(wo, s.Wi), s.PDF, 1e-9*s.PDF)
+					assert.InDelta(t, m.Eval(wo, s.Wi)[0], s.F[0], 1e-9*s.F[0])
+				}
+			}
+
+			// it's symmetric, and opaque
+			wo, wi := geo.V(0.3, -0.2, 1).Unit(), geo.V(-0.5, 0.1, 0.7).Unit()
+			assert.InDelta(t, m.Eval(wo, wi)[0], m.Eval(wi, wo)[0], 1e-12)
+			assert.Equal(t, 0.0, m.PDF(wo, geo.Unit{Z: -1}))
+			assert.Equal(t, new(spectrum.Sampled), m.Eval(wo, geo.Unit{Z: -1}))
+		})
+	}
+}
+
+func TestMicrofacet_PDF(t *testing.T) {
+	// The pdf integrates to the chance that Sample succeeds, which is less
+	// than 1 since some facets reflect wo below the horizon.
+	m := NewMicrofacet(spectrum.Flat(0.5), 0.4, 0.3)
+	wo := geo.V(0.6, 0.2, 1).Unit()
+	const steps = 400
+	sum := 0.0
+	for i := 0; i < steps; i++ {
+		for j := 0; j < steps; j++ {
+			// uniform in cos(theta) and phi is uniform over the hemisphere
+			cos := (float64(i) + 0.5) / steps
+			phi := 2 * math.Pi * (float64(j) + 0.5) / steps
+			sin := math.Sqrt(1 - cos*cos)
+			wi := geo.Unit{X: sin * math.Cos(phi), Y: sin * math.Sin(phi), Z: cos}
+			sum += m.PDF(wo, wi) * 2 * math.Pi / (steps * steps)
+		}
+	}
+
+	rnd := util.NewRand(0)
+	ok := 0
+	const n = 100000
+	for i := 0; i < n; i++ {
+		if _, sampled := m.Sample(wo, rnd.Float64(), rnd.Float64()); sampled {
+			ok++
+		}
+	}
+	assert.Less(t, sum, 1.0)
+	assert.InDelta(t, float64(ok)/n, sum, 0.005)
+}
+
+func TestMicrofacet_Energy(t *testing.T) {
+	// A white metal loses energy only to the single scattering model, a few
+	// percent when rough, and the visible normal samples all have the same
+	// weight G1(wi) when polished.
+	rnd := util.NewRand(0)
+	wo := geo.V(0.3, -0.2, 1).Unit()
+	for _, roughness := range []float64{0, 0.5} {
+		m := NewMicrofacet(spectrum.Flat(1), roughness, 1)
+		sum := 0.0
+		const n = 10000
+		for i := 0; i < n; i++ {
+			if s, ok := m.Sample(wo, rnd.Float64(), rnd.Float64()); ok {
+				sum += s.F[0] * geo.AbsCosTheta(s.Wi) / s.PDF
+			}
+		}
+		assert.LessOrEqual(t, sum/n, 1.0, "roughness %v", roughness)
+		assert.Greater(t, sum/n, 0.9, "roughness %v", roughness)
+	}
+
+	// a rough dielectric is mostly its diffuse base
+	m := NewMicrofacet(spectrum.Flat(0.5), 1, 0)
+	sum := 0.0
+	const n = 10000
+	for i := 0; i < n; i++ {
+		if s, ok := m.Sample(wo, rnd.Float64(), rnd.Float64()); ok {
+			sum += s.F[0] * geo.AbsCosTheta(s.Wi) / s.PDF
+		}
+	}
+	assert.InDelta(t, 0.5, sum/n, 0.05)
+
+	assert.Panics(t, func() { NewMicrofacet(spectrum.Flat(1), 1.5, 0) })
+}
+
+func TestMicrofacet_Texture(t *testing.T) {
+	smooth := texture.ScalarFunc(func(tc texture.Coords) float64 { return tc.U })
+	m := NewMicrofacet(spectrum.Flat(0.5), 1, 0)
+	m.RoughnessMap = smooth
+	m.Texture = texture.NewConstant(spectrum.Flat(0.2))
+
+	at := Resolve(m, texture.Coords{U: 0.25}).(*Microfacet)
+	assert.Equal(t, 0.25, at.Roughness)
+	assert.Equal(t, 0.2, at.BaseColor[0])
+	assert.Equal(t, 1.0, m.Roughness)
+
+	plain := NewMicrofacet(spectrum.Flat(0.5), 1, 0)
+	assert.Equal(t, Material(plain), Resolve(plain, texture.Coords{}))
+}
@@ -0,0 +1,94 @@
+package material
+
+import (
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/gmhorn/gremlin/archive/pkg/texture"
+)
+
+// Varying is implemented by materials that change over a surface, usually
+// because they're textured. At returns the material at the texture
+// coordinates, which is what should then be evaluated and sampled.
+type Varying interface {
+	Material
+	At(tc texture.Coords) Material
+}
+
+// Resolve returns the material at the texture coordinates: m itself, or for
+// Varying materials, the result of At.
+func Resolve(m Material, tc texture.Coords) Material {
+	if vm, ok := m.(Varying); ok {
+		return vm.At(tc)
+	}
+	return m
+}
+
+// Mix blends two materials: the result is A weighted by 1-Amount plus B
+// weighted by Amount. If Mask is set, it's Varying, and Amount comes from the
+// mask instead, e.g. a rust mask over paint.
+//
+// Sampling chooses one of the two materials in proportion to its weight.
+//
+// https://www.pbr-book.org/3ed-2018/Materials/Material_Interface_and_Implementations#MixMaterial
+type Mix struct {
+	A, B   Material
+	Amount float64
+	Mask   texture.Scalar
+}
+
+// NewMix blends a and b by a fixed amount.
+func NewMix(a, b Material, amount float64) *Mix {
+	return &Mix{A: a, B: b, Amount: amount}
+}
+
+// NewMaskedMix blends a and b by a mask.
+func NewMaskedMix(a, b Material, mask texture.Scalar) *Mix {
+	return &Mix{A: a, B: b, Mask: mask}
+}
+
+// At implements Varying. Varying children are resolved too.
+func (m *Mix) At(tc texture.Coords) Material {
+	amount := m.Amount
+	if m.Mask != nil {
+		amount = m.Mask.Value(tc)
+	}
+	return &Mix{A: Resolve(m.A, tc), B: Resolve(m.B, tc), Amount: amount}
+}
+
+// Roughen implements Glossy. Both children are roughened.
+func (m *Mix) Roughen(min float64) Material {
+	return &Mix{A: Roughen(m.A, min), B: Roughen(m.B, min), Amount: m.Amount, Mask: m.Mask}
+}
+
+// Eval implements Material.
+func (m *Mix) Eval(wo, wi geo.Unit) *spectrum.Sampled {
+	return m.A.Eval(wo, wi).Scale(1 - m.Amount).Plus(m.B.Eval(wo, wi).Scale(m.Amount))
+}
+
+// Sample implements Material.
+//
+// For specular samples, the chosen material's sample is returned as is. Since
+// it was chosen with probability equal to its weight, that's still an
+// unbiased estimate of the blend. Otherwise F and PDF are those of the whole
+// blend, which has lower variance.
+func (m *Mix) Sample(wo geo.Unit, u1, u2 float64) (Sample, bool) {
+	var s Sample
+	var ok bool
+	if u1 < m.Amount {
+		s, ok = m.B.Sample(wo, u1/m.Amount, u2)
+	} else {
+		s, ok = m.A.Sample(wo, (u1-m.Amount)/(1-m.Amount), u2)
+	}
+	if !ok || s.Specular {
+		return s, ok
+	}
+
+	s.F = m.Eval(wo, s.Wi)
+	s.PDF = m.PDF(wo, s.Wi)
+	return s, s.PDF > 0
+}
+
+// PDF implements Material.
+func (m *Mix) PDF(wo, wi geo.Unit) float64 {
+	return (1-m.Amount)*m.A.PDF(wo, wi) + m.Amount*m.B.PDF(wo, wi)
+}
@@ -0,0 +1,19 @@
+package material
+
+import "github.com/gmhorn/gremlin/archive/pkg/spectrum"
+
+// Water returns a dielectric with the refractive index and absorption of pure
+// water, and the given roughness: 0 for a calm surface, or a little more for
+// ripples too small to model. The absorption is per meter (see
+// spectrum.WaterAbsorption), so scenes using it should be in meters. Like any
+// Dielectric it refracts at the index for one wavelength rather than
+// following spectrum.WaterIOR.
+func Water(roughness float64) *Dielectric {
+	if roughness < 0 || roughness > 1 {
+		panic("roughness must be in [0, 1]")
+	}
+	w := NewDielectric(spectrum.WaterIOR)
+	w.Roughness = roughness
+	w.Absorption = spectrum.Sample(spectrum.WaterAbsorption)
+	return w
+}
@@ -0,0 +1,20 @@
+package material
+
+import (
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+)
+
+const invPi = 1 / math.Pi
+
+// cosineHemisphere maps the uniform samples u1, u2 to a direction on the
+// hemisphere around +z, with density proportional to its cosine. Uses
+// Malley's method: uniformly sample the unit disk and project up.
+//
+// https://www.pbr-book.org/3ed-2018/Monte_Carlo_Integration/2D_Sampling_with_Multidimensional_Transformations#Cosine-WeightedHemisphereSampling
+func cosineHemisphere(u1, u2 float64) geo.Unit {
+	r := math.Sqrt(u1)
+	phi := 2 * math.Pi * u2
+	return geo.Unit{X: r * math.Cos(phi), Y: r * math.Sin(phi), Z: math.Sqrt(math.Max(0, 1-u1))}
+}
@@ -0,0 +1,19 @@
+// Package mesh loads triangle meshes from files into shape.Mesh.
+package mesh
+
+import "fmt"
+
+// ParseError is returned by the loaders for malformed input. Line is
+// 1-indexed.
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,202 @@
+package mesh
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gmhorn/gremlin/archive/pkg/asset"
+	"github.com/gmhorn/gremlin/archive/pkg/imageio"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/gmhorn/gremlin/archive/pkg/texture"
+)
+
+// specularExponent is the smallest Ns that's treated as a mirror-like
+// highlight. Below it, highlights are too broad for a perfect mirror to look
+// anything like them, so Ks is ignored.
+const specularExponent = 500
+
+// LoadMTL opens the named MTL file with the resolver and reads it. Texture
+// maps are looked for next to the MTL file first.
+func LoadMTL(res *asset.Resolver, name string) (map[string]material.Material, error) {
+	f, err := res.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := ReadMTL(f, res.Relative(filepath.Dir(name)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return m, nil
+}
+
+// ReadMTL parses a Wavefront MTL file into materials, by name.
+//
+// MTL describes Phong-style materials, which are mapped onto gremlin's as
+// best they can be:
+//
+//   - Kd (diffuse color) becomes a Lambertian
+//   - map_Kd (diffuse texture) replaces Kd with a texture.Image, loaded with
+//     the resolver (if it's not nil). Missing images are skipped, and Kd
+//     used instead, since downloaded models often reference ones that
+//     weren't shipped.
+//   - Ks (specular color) mixes in a Mirror, if Ns (the specular exponent) is
+//     high enough for the highlight to be mirror-like
+//   - transparent materials (illum 4, 6, 7, or d < 1) with a refractive
+//     index Ni become a Dielectric
+//
+// Texture map options and other statements are ignored.
+//
+// http://paulbourke.net/dataformats/mtl/
+func ReadMTL(r io.Reader, res *asset.Resolver) (map[string]material.Material, error) {
+	materials := make(map[string]material.Material)
+
+	var cur *mtlMaterial
+	var curName string
+	finish := func() {
+		if cur != nil {
+			materials[curName] = cur.material()
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	var fields [][]byte
+	for line := 1; scanner.Scan(); line++ {
+		fields = splitFields(stripComment(scanner.Bytes()), fields[:0])
+		if len(fields) == 0 {
+			continue
+		}
+
+		keyword, args := string(fields[0]), fields[1:]
+		if keyword == "newmtl" {
+			if len(args) != 1 {
+				return nil, &ParseError{Line: line, Err: errors.New("newmtl needs a material name")}
+			}
+			finish()
+			cur = newMTLMaterial(res)
+			curName = string(args[0])
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+
+		if err := cur.parse(keyword, args); err != nil {
+			return nil, &ParseError{Line: line, Err: err}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	finish()
+	return materials, nil
+}
+
+// mtlMaterial holds the values of one newmtl block, and the resolver its
+// texture maps are loaded with.
+type mtlMaterial struct {
+	kd, ks [3]float64
+	mapKd  *imageio.RGB
+	ns, ni float64
+	d      float64
+	illum  int
+
+	res *asset.Resolver
+}
+
+func newMTLMaterial(res *asset.Resolver) *mtlMaterial {
+	return &mtlMaterial{kd: [3]float64{0.8, 0.8, 0.8}, ni: 1, d: 1, res: res}
+}
+
+func (m *mtlMaterial) parse(keyword string, args [][]byte) error {
+	var err error
+	switch keyword {
+	case "Kd":
+		m.kd, err = parseColor(args)
+	case "map_Kd":
+		m.mapKd, err = m.loadMap(args)
+	case "Ks":
+		m.ks, err = parseColor(args)
+	case "Ns":
+		m.ns, err = parseScalar(args)
+	case "Ni":
+		m.ni, err = parseScalar(args)
+	case "d":
+		m.d, err = parseScalar(args)
+	case "Tr":
+		var tr float64
+		tr, err = parseScalar(args)
+		m.d = 1 - tr
+	case "illum":
+		var illum float64
+		illum, err = parseScalar(args)
+		m.illum = int(illum)
+	}
+	return err
+}
+
+// loadMap loads the image of a texture map statement, whose file name comes
+// after any options. It returns nil if there's no resolver or the file is
+// missing.
+func (m *mtlMaterial) loadMap(args [][]byte) (*imageio.RGB, error) {
+	if len(args) < 1 {
+		return nil, errors.New("expected a file name")
+	}
+	if m.res == nil {
+		return nil, nil
+	}
+	img, err := imageio.LoadImage(m.res, string(args[len(args)-1]))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return img, err
+}
+
+func (m *mtlMaterial) material() material.Material {
+	transparent := m.illum == 4 || m.illum == 6 || m.illum == 7 || m.d < 1
+	if transparent && m.ni > 1 {
+		return material.NewDielectric(spectrum.Flat(m.ni))
+	}
+
+	diffuse := material.NewLambertian(spectrum.FromRGB(m.kd[0], m.kd[1], m.kd[2]))
+	if m.mapKd != nil {
+		diffuse = material.NewTexturedLambertian(texture.NewImage(m.mapKd, 1))
+	}
+	spec := math.Max(m.ks[0], math.Max(m.ks[1], m.ks[2]))
+	if spec == 0 || m.ns < specularExponent {
+		return diffuse
+	}
+
+	// The mirror reflects Ks, normalized so its brightest channel is 1, and
+	// the mix weights it by that brightest channel.
+	mirror := material.NewMirror(spectrum.FromRGB(m.ks[0]/spec, m.ks[1]/spec, m.ks[2]/spec))
+	return material.NewMix(diffuse, mirror, math.Min(1, spec))
+}
+
+// parseColor parses an "r g b" color. A single value is used for all three.
+func parseColor(args [][]byte) ([3]float64, error) {
+	if len(args) > 0 && (string(args[0]) == "spectral" || string(args[0]) == "xyz") {
+		return [3]float64{}, fmt.Errorf("unsupported color format %q", args[0])
+	}
+	f, err := parseFloats(args, 1)
+	if len(args) == 1 {
+		f[1], f[2] = f[0], f[0]
+	}
+	return f, err
+}
+
+func parseScalar(args [][]byte) (float64, error) {
+	if len(args) < 1 {
+		return 0, errors.New("expected a value")
+	}
+	return strconv.ParseFloat(string(args[0]), 64)
+}
@@ -0,0 +1,123 @@
+package mesh
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gmhorn/gremlin/archive/pkg/asset"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/gmhorn/gremlin/archive/pkg/texture"
+	"github.com/stretchr/testify/assert"
+)
+
+const testMTL = `# materials
+newmtl red
+Kd 0.8 0.1 0.1
+Ks 0.5 0.5 0.5
+Ns 10
+
+newmtl chrome
+Kd 0.1 0.1 0.1
+Ks 0.9 0.9 0.9
+Ns 1000
+
+newmtl glass
+Kd 1 1 1
+Ni 1.5
+d 0.2
+illum 4
+`
+
+func TestReadMTL(t *testing.T) {
+	mats, err := ReadMTL(strings.NewReader(testMTL), nil)
+	assert.NoError(t, err)
+	assert.Len(t, mats, 3)
+
+	red, ok := mats["red"].(*material.Lambertian)
+	if assert.True(t, ok) {
+		assert.Equal(t, spectrum.FromRGB(0.8, 0.1, 0.1), red.R)
+	}
+
+	chrome, ok := mats["chrome"].(*material.Mix)
+	if assert.True(t, ok) {
+		assert.InDelta(t, 0.9, chrome.Amount, 1e-12)
+		assert.IsType(t, &material.Mirror{}, chrome.B)
+	}
+
+	glass, ok := mats["glass"].(*material.Dielectric)
+	if assert.True(t, ok) {
+		assert.Equal(t, 1.5, glass.IOR.Lookup(550))
+	}
+}
+
+func TestReadMTL_Errors(t *testing.T) {
+	_, err := ReadMTL(strings.NewReader("newmtl a\nKd 1 x 1\n"), nil)
+	var perr *ParseError
+	if assert.True(t, errors.As(err, &perr)) {
+		assert.Equal(t, 2, perr.Line)
+	}
+
+	_, err = ReadMTL(strings.NewReader("newmtl\n"), nil)
+	assert.Error(t, err)
+}
+
+func TestLoadMTL_Textures(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "models"), 0o755))
+	mtl := "newmtl wood\nKd 0.1 0.1 0.1\nmap_Kd -s 2 2 1 wood.png\n\nnewmtl lost\nKd 0.1 0.1 0.1\nmap_Kd lost.png\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "models", "wood.mtl"), []byte(mtl), 0o644))
+	f, err := os.Create(filepath.Join(dir, "models", "wood.png"))
+	assert.NoError(t, err)
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, color.White)
+	assert.NoError(t, png.Encode(f, img))
+	assert.NoError(t, f.Close())
+
+	mats, err := LoadMTL(asset.NewResolver(), filepath.Join(dir, "models", "wood.mtl"))
+	assert.NoError(t, err)
+
+	// the texture is found next to the MTL file, after its options
+	wood, ok := mats["wood"].(*material.Lambertian)
+	if assert.True(t, ok) && assert.NotNil(t, wood.Texture) {
+		assert.InDelta(t, 1, wood.Texture.(*texture.Image).Value(texture.Coords{U: 0.5, V: 0.5}), 1e-9)
+	}
+
+	// missing textures fall back to Kd
+	lost, ok := mats["lost"].(*material.Lambertian)
+	if assert.True(t, ok) {
+		assert.Nil(t, lost.Texture)
+		assert.Equal(t, spectrum.FromRGB(0.1, 0.1, 0.1), lost.R)
+	}
+
+	// without a resolver, texture maps are ignored
+	mats, err = ReadMTL(strings.NewReader("newmtl wood\nmap_Kd wood.png\n"), nil)
+	assert.NoError(t, err)
+	assert.Nil(t, mats["wood"].(*material.Lambertian).Texture)
+}
+
+func TestLoadOBJ_MTL(t *testing.T) {
+	res := asset.NewResolver().AddFS(fstest.MapFS{
+		"model.obj": {Data: []byte("mtllib model.mtl missing.mtl\nv 0 0 0\nv 1 0 0\nv 0 1 0\nusemtl glass\nf 1 2 3\n")},
+		"model.mtl": {Data: []byte(testMTL)},
+	})
+
+	m, err := LoadOBJ(res, "model.obj", nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &material.Dielectric{}, m.Faces()[0].Surface())
+
+	// explicit materials win
+	override := material.NewLambertian(spectrum.Flat(0.5))
+	m, err = LoadOBJ(res, "model.obj", &OBJOptions{
+		Materials: map[string]material.Material{"glass": override},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, material.Material(override), m.Faces()[0].Surface())
+}
@@ -0,0 +1,439 @@
+package mesh
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gmhorn/gremlin/archive/pkg/asset"
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+)
+
+// OBJOptions are optional settings for ReadOBJ.
+type OBJOptions struct {
+	// Progress, if set, is called periodically during parsing with the total
+	// number of bytes read so far.
+	Progress func(bytesRead int64)
+
+	// Materials maps the names used by usemtl statements to materials.
+	// Names that aren't in the map (and faces before any usemtl) get a nil
+	// material, i.e. the renderer's default.
+	Materials map[string]material.Material
+
+	// LoadMaterials, if set, is called for each mtllib statement with the
+	// library's name. The materials it returns are used for names that
+	// aren't in Materials. LoadOBJ sets it to load the MTL files with its
+	// resolver.
+	LoadMaterials func(name string) (map[string]material.Material, error)
+}
+
+// LoadOBJ opens the named OBJ file with the resolver and reads it. Material
+// libraries are looked for next to the OBJ file first. Missing libraries are
+// skipped, since downloaded models often reference ones that weren't shipped.
+func LoadOBJ(res *asset.Resolver, name string, opts *OBJOptions) (*shape.Mesh, error) {
+	f, err := res.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if opts == nil {
+		opts = &OBJOptions{}
+	}
+	if opts.LoadMaterials == nil {
+		o := *opts
+		rel := res.Relative(filepath.Dir(name))
+		o.LoadMaterials = func(lib string) (map[string]material.Material, error) {
+			mtl, err := LoadMTL(rel, lib)
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, nil
+			}
+			return mtl, err
+		}
+		opts = &o
+	}
+
+	m, err := ReadOBJ(f, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return m, nil
+}
+
+// ReadOBJ parses a Wavefront OBJ file into a single mesh. It reads v, vt, vn,
+// f, usemtl and mtllib statements; everything else (objects, groups, smoothing
+// groups, lines, ...) is ignored.
+//
+// The input is streamed line by line through a fixed-size buffer, so memory
+// use is bounded by the size of the resulting mesh rather than the file.
+//
+// OBJ faces index positions, texture coordinates and normals independently
+// (f 1/2/3), while shape.Mesh has a single index buffer. Each distinct
+// combination of indices becomes one mesh vertex, so attributes are never
+// mixed up. Negative (relative) indices are supported. Faces with more than
+// three vertices are triangulated by ear clipping, which handles concave
+// polygons.
+//
+// If some but not all face vertices reference a normal, the missing normals
+// (and zero-length ones) are filled with the area-weighted average of the
+// geometric normals of the faces around the vertex; missing texture
+// coordinates are filled with (0, 0).
+//
+// If the file has usemtl statements, each face gets the material that was
+// current when it was defined, looked up by name in opts.Materials or the
+// libraries loaded by opts.LoadMaterials (see shape.Mesh.SetFaceMaterials).
+//
+// http://paulbourke.net/dataformats/obj/
+func ReadOBJ(r io.Reader, opts *OBJOptions) (*shape.Mesh, error) {
+	if opts == nil {
+		opts = &OBJOptions{}
+	}
+
+	p := &objParser{
+		opts:        opts,
+		libraries:   make(map[string]material.Material),
+		vertexIdx:   make(map[objVertex]int),
+		materialIdx: map[string]int{"": 0},
+		materials:   []string{""},
+	}
+
+	cr := &countingReader{r: r, progress: opts.Progress}
+	scanner := bufio.NewScanner(cr)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		p.line++
+		if err := p.parseLine(scanner.Bytes()); err != nil {
+			return nil, &ParseError{Line: p.line, Err: err}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, &ParseError{Line: p.line + 1, Err: err}
+	}
+
+	m := p.mesh()
+	if p.usesMaterials {
+		materials := make([]material.Material, len(p.materials))
+		for i, name := range p.materials {
+			if m, ok := opts.Materials[name]; ok {
+				materials[i] = m
+			} else {
+				materials[i] = p.libraries[name]
+			}
+		}
+		m.SetFaceMaterials(materials, p.faceMaterials)
+	}
+	return m, nil
+}
+
+// objVertex is a unique combination of position, texture coordinate and normal
+// indices (0-based, -1 if absent).
+type objVertex struct {
+	v, vt, vn int
+}
+
+type objParser struct {
+	line int
+	opts *OBJOptions
+
+	// materials from mtllib statements
+	libraries map[string]material.Material
+
+	// attributes as read from the file
+	positions []geo.Vec
+	texcoords [][2]float64
+	normals   []geo.Vec
+
+	// de-duplicated mesh vertices and faces
+	vertices  []objVertex
+	vertexIdx map[objVertex]int
+	indices   []int
+
+	hasNormals, hasTexcoords bool
+
+	// material names in order of first use, and the index of each face's
+	// material
+	usesMaterials   bool
+	materials       []string
+	materialIdx     map[string]int
+	currentMaterial int
+	faceMaterials   []int
+
+	// scratch space reused between faces
+	face    []objVertex
+	corners []geo.Vec
+	fields  [][]byte
+}
+
+func (p *objParser) parseLine(line []byte) error {
+	p.fields = splitFields(stripComment(line), p.fields[:0])
+	if len(p.fields) == 0 {
+		return nil
+	}
+
+	args := p.fields[1:]
+	switch string(p.fields[0]) {
+	case "v":
+		f, err := parseFloats(args, 3)
+		if err != nil {
+			return err
+		}
+		p.positions = append(p.positions, geo.V(f[0], f[1], f[2]))
+	case "vt":
+		f, err := parseFloats(args, 1)
+		if err != nil {
+			return err
+		}
+		p.texcoords = append(p.texcoords, [2]float64{f[0], f[1]})
+	case "vn":
+		f, err := parseFloats(args, 3)
+		if err != nil {
+			return err
+		}
+		// normalized when building the mesh, once we know which are unusable
+		p.normals = append(p.normals, geo.V(f[0], f[1], f[2]))
+	case "f":
+		return p.parseFace(args)
+	case "usemtl":
+		if len(args) != 1 {
+			return errors.New("usemtl needs a material name")
+		}
+		p.useMaterial(string(args[0]))
+	case "mtllib":
+		return p.loadLibraries(args)
+	}
+	return nil
+}
+
+func (p *objParser) parseFace(args [][]byte) error {
+	if len(args) < 3 {
+		return fmt.Errorf("face needs at least 3 vertices, got %d", len(args))
+	}
+
+	p.face = p.face[:0]
+	for _, arg := range args {
+		fv, err := p.parseFaceVertex(arg)
+		if err != nil {
+			return err
+		}
+		p.face = append(p.face, fv)
+	}
+
+	p.corners = p.corners[:0]
+	for _, fv := range p.face {
+		p.corners = append(p.corners, p.positions[fv.v])
+	}
+	for _, tri := range triangulate(p.corners) {
+		for _, c := range tri {
+			p.indices = append(p.indices, p.vertex(p.face[c]))
+		}
+		p.faceMaterials = append(p.faceMaterials, p.currentMaterial)
+	}
+	return nil
+}
+
+func (p *objParser) loadLibraries(names [][]byte) error {
+	if p.opts.LoadMaterials == nil {
+		return nil
+	}
+	for _, name := range names {
+		lib, err := p.opts.LoadMaterials(string(name))
+		if err != nil {
+			return err
+		}
+		for k, v := range lib {
+			p.libraries[k] = v
+		}
+	}
+	return nil
+}
+
+func (p *objParser) useMaterial(name string) {
+	p.usesMaterials = true
+	idx, ok := p.materialIdx[name]
+	if !ok {
+		idx = len(p.materials)
+		p.materials = append(p.materials, name)
+		p.materialIdx[name] = idx
+	}
+	p.currentMaterial = idx
+}
+
+// parseFaceVertex parses one v, v/vt, v//vn or v/vt/vn face element.
+func (p *objParser) parseFaceVertex(arg []byte) (objVertex, error) {
+	fv := objVertex{-1, -1, -1}
+	parts := bytes.SplitN(arg, []byte{'/'}, 3)
+
+	var err error
+	if fv.v, err = resolveIndex(parts[0], len(p.positions)); err != nil {
+		return fv, fmt.Errorf("bad vertex index %q: %w", arg, err)
+	}
+	if len(parts) > 1 && len(parts[1]) > 0 {
+		if fv.vt, err = resolveIndex(parts[1], len(p.texcoords)); err != nil {
+			return fv, fmt.Errorf("bad texture coordinate index %q: %w", arg, err)
+		}
+		p.hasTexcoords = true
+	}
+	if len(parts) > 2 && len(parts[2]) > 0 {
+		if fv.vn, err = resolveIndex(parts[2], len(p.normals)); err != nil {
+			return fv, fmt.Errorf("bad normal index %q: %w", arg, err)
+		}
+		p.hasNormals = true
+	}
+	return fv, nil
+}
+
+// vertex returns the mesh vertex index for the given face vertex, adding it if
+// it's new.
+func (p *objParser) vertex(fv objVertex) int {
+	if idx, ok := p.vertexIdx[fv]; ok {
+		return idx
+	}
+	idx := len(p.vertices)
+	p.vertices = append(p.vertices, fv)
+	p.vertexIdx[fv] = idx
+	return idx
+}
+
+func (p *objParser) mesh() *shape.Mesh {
+	positions := make([]geo.Vec, len(p.vertices))
+	for i, fv := range p.vertices {
+		positions[i] = p.positions[fv.v]
+	}
+
+	var uvs [][2]float64
+	if p.hasTexcoords {
+		uvs = make([][2]float64, len(p.vertices))
+		for i, fv := range p.vertices {
+			if fv.vt >= 0 {
+				uvs[i] = p.texcoords[fv.vt]
+			}
+		}
+	}
+
+	var normals []geo.Unit
+	if p.hasNormals {
+		normals = make([]geo.Unit, len(p.vertices))
+		var sums []geo.Vec
+		for i, fv := range p.vertices {
+			if fv.vn >= 0 && p.normals[fv.vn].Len() > 0 {
+				normals[i] = p.normals[fv.vn].Unit()
+				continue
+			}
+
+			// Fill gaps (and zero-length normals) with the face normals around
+			// the vertex's position, weighted by area
+			if sums == nil {
+				sums = p.faceNormalSums()
+			}
+			if sums[fv.v].Len() > 0 {
+				normals[i] = sums[fv.v].Unit()
+			} else {
+				// only degenerate faces, which can't be hit anyway
+				normals[i] = geo.ZAxis
+			}
+		}
+	}
+
+	return shape.NewMesh(positions, normals, uvs, p.indices)
+}
+
+// faceNormalSums returns the sum of the normals of the faces around each
+// position. The normals aren't normalized: their length is twice the face's
+// area, so bigger faces count for more, and degenerate ones not at all.
+func (p *objParser) faceNormalSums() []geo.Vec {
+	sums := make([]geo.Vec, len(p.positions))
+	for f := 0; f < len(p.indices); f += 3 {
+		v0, v1, v2 := p.vertices[p.indices[f]].v, p.vertices[p.indices[f+1]].v, p.vertices[p.indices[f+2]].v
+		n := p.positions[v1].Minus(p.positions[v0]).Cross(p.positions[v2].Minus(p.positions[v0]))
+		for _, v := range [3]int{v0, v1, v2} {
+			sums[v] = sums[v].Plus(n)
+		}
+	}
+	return sums
+}
+
+// resolveIndex converts a 1-based (or negative, relative to the end) OBJ index
+// into a 0-based index into a list of length n.
+func resolveIndex(b []byte, n int) (int, error) {
+	idx, err := strconv.Atoi(string(b))
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case idx > 0 && idx <= n:
+		return idx - 1, nil
+	case idx < 0 && -idx <= n:
+		return n + idx, nil
+	default:
+		return 0, errors.New("index out of range")
+	}
+}
+
+// parseFloats parses at least min floats from args.
+func parseFloats(args [][]byte, min int) ([3]float64, error) {
+	var f [3]float64
+	if len(args) < min {
+		return f, fmt.Errorf("expected at least %d values, got %d", min, len(args))
+	}
+
+	for i := 0; i < len(args) && i < 3; i++ {
+		v, err := strconv.ParseFloat(string(args[i]), 64)
+		if err != nil {
+			return f, err
+		}
+		f[i] = v
+	}
+	return f, nil
+}
+
+// stripComment removes everything from the first '#'.
+func stripComment(line []byte) []byte {
+	if i := bytes.IndexByte(line, '#'); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
+
+// splitFields is like bytes.Fields, but appends to the given slice so it can
+// be reused between lines.
+func splitFields(line []byte, fields [][]byte) [][]byte {
+	start := -1
+	for i, c := range line {
+		space := c == ' ' || c == '\t' || c == '\r'
+		if space && start >= 0 {
+			fields = append(fields, line[start:i])
+			start = -1
+		} else if !space && start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, line[start:])
+	}
+	return fields
+}
+
+// countingReader reports the number of bytes read through it.
+type countingReader struct {
+	r        io.Reader
+	n        int64
+	progress func(int64)
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	if c.progress != nil && n > 0 {
+		c.progress(c.n)
+	}
+	return n, err
+}
@@ -0,0 +1,242 @@
+package mesh
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gmhorn/gremlin/archive/pkg/asset"
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/stretchr/testify/assert"
+)
+
+const quadOBJ = `# unit quad
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+vt 0 0
+vt 1 0
+vt 1 1
+vt 0 1
+vn 0 0 1
+
+f 1/1/1 2/2/1 3/3/1 4/4/1
+`
+
+func TestReadOBJ(t *testing.T) {
+	m, err := ReadOBJ(strings.NewReader(quadOBJ), nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, m.NumFaces())
+	assert.Len(t, m.Positions, 4)
+	assert.Len(t, m.Normals, 4)
+	for i, p := range m.Positions {
+		assert.Equal(t, p.X, m.UVs[i][0])
+		assert.Equal(t, p.Y, m.UVs[i][1])
+	}
+}
+
+func TestReadOBJ_NegativeIndices(t *testing.T) {
+	src := `
+v 0 0 0
+v 1 0 0
+v 0 1 0
+f -3 -2 -1
+`
+	m, err := ReadOBJ(strings.NewReader(src), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2}, m.Indices)
+	assert.Equal(t, geo.V(1, 0, 0), m.Positions[1])
+	assert.Nil(t, m.Normals)
+	assert.Nil(t, m.UVs)
+}
+
+func TestReadOBJ_SplitVertices(t *testing.T) {
+	// Two faces share positions but have different UVs along the seam, so
+	// the shared positions must become separate vertices.
+	src := `
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+vt 0 0
+vt 1 0
+vt 1 1
+vt 0 1
+vt 0.5 0.5
+f 1/1 2/2 3/3
+f 1/5 3/5 4/4
+`
+	m, err := ReadOBJ(strings.NewReader(src), nil)
+	assert.NoError(t, err)
+	assert.Len(t, m.Positions, 6)
+	assert.Equal(t, [2]float64{0, 0}, m.UVs[m.Indices[0]])
+	assert.Equal(t, [2]float64{0.5, 0.5}, m.UVs[m.Indices[3]])
+	assert.Equal(t, m.Positions[m.Indices[0]], m.Positions[m.Indices[3]])
+}
+
+func TestReadOBJ_Concave(t *testing.T) {
+	// Pentagon with a reflex first vertex; fanning from it would produce a
+	// triangle outside the polygon.
+	src := `
+v 1 1 0
+v 0 2 0
+v 0 0 0
+v 2 0 0
+v 2 1 0
+f 1 2 3 4 5
+`
+	m, err := ReadOBJ(strings.NewReader(src), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, m.NumFaces())
+
+	area := 0.0
+	for f := 0; f < m.NumFaces(); f++ {
+		p0, p1, p2 := m.Positions[m.Indices[3*f]], m.Positions[m.Indices[3*f+1]], m.Positions[m.Indices[3*f+2]]
+		n := p1.Minus(p0).Cross(p2.Minus(p0))
+		// same winding as the polygon
+		assert.GreaterOrEqual(t, n.Z, 0.0)
+		area += n.Len() / 2
+	}
+	assert.InDelta(t, 2.5, area, 1e-9)
+}
+
+func TestReadOBJ_FilledNormals(t *testing.T) {
+	// A roof: two triangles of equal area meeting at a ridge along x, with
+	// the first corner given a zero-length normal and a degenerate triangle
+	// at the last one. Only the degenerate triangle's far corner has a real
+	// normal.
+	src := `
+v 0 0 0
+v 0 1 1
+v 0 0 2
+v 1 1 1
+v 2 0 0
+vn 0 0 0
+vn 1 0 0
+f 1//1 2 4
+f 2 3 4
+f 3 5//2 3
+`
+	m, err := ReadOBJ(strings.NewReader(src), nil)
+	assert.NoError(t, err)
+
+	for i, n := range m.Normals {
+		assert.InDelta(t, 1, geo.Vec(n).Len(), 1e-9, "vertex %d", i)
+	}
+
+	normalAt := func(p geo.Vec) geo.Unit {
+		for i, pos := range m.Positions {
+			if pos == p {
+				return m.Normals[i]
+			}
+		}
+		t.Fatalf("no vertex at %v", p)
+		return geo.Unit{}
+	}
+	s := math.Sqrt(0.5)
+
+	// the zero-length normal is replaced by its face's normal
+	assert.InDeltaSlice(t, []float64{0, s, -s}, unitSlice(normalAt(geo.V(0, 0, 0))), 1e-9)
+	// the ridge averages the two faces
+	assert.InDeltaSlice(t, []float64{0, 1, 0}, unitSlice(normalAt(geo.V(0, 1, 1))), 1e-9)
+	assert.InDeltaSlice(t, []float64{0, 1, 0}, unitSlice(normalAt(geo.V(1, 1, 1))), 1e-9)
+	// and the degenerate face doesn't count
+	assert.InDeltaSlice(t, []float64{0, s, s}, unitSlice(normalAt(geo.V(0, 0, 2))), 1e-9)
+}
+
+func unitSlice(n geo.Unit) []float64 {
+	return []float64{n.X, n.Y, n.Z}
+}
+
+func TestReadOBJ_Errors(t *testing.T) {
+	tests := map[string]struct {
+		src  string
+		line int
+	}{
+		"bad float":         {"v 0 0 0\nv 1 x 0\n", 2},
+		"short vertex":      {"v 0 0\n", 1},
+		"index too big":     {"v 0 0 0\nv 1 0 0\nv 0 1 0\n\nf 1 2 4\n", 5},
+		"zero index":        {"v 0 0 0\nv 1 0 0\nv 0 1 0\nf 0 1 2\n", 4},
+		"missing normal":    {"v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1//1 2//1 3//1\n", 4},
+		"too few vertices":  {"v 0 0 0\nv 1 0 0\nf 1 2\n", 3},
+		"negative too far":  {"v 0 0 0\nf -1 -2 -3\n", 2},
+		"garbage face elem": {"v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 a\n", 4},
+		"usemtl no name":    {"v 0 0 0\nusemtl\n", 2},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadOBJ(strings.NewReader(test.src), nil)
+			var perr *ParseError
+			if assert.True(t, errors.As(err, &perr)) {
+				assert.Equal(t, test.line, perr.Line)
+			}
+		})
+	}
+}
+
+func TestReadOBJ_Progress(t *testing.T) {
+	var last int64
+	_, err := ReadOBJ(strings.NewReader(quadOBJ), &OBJOptions{
+		Progress: func(n int64) { last = n },
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(quadOBJ)), last)
+}
+
+func TestLoadOBJ(t *testing.T) {
+	res := asset.NewResolver().AddFS(fstest.MapFS{
+		"quad.obj": {Data: []byte(quadOBJ)},
+		"bad.obj":  {Data: []byte("v 0 0\n")},
+	})
+
+	m, err := LoadOBJ(res, "quad.obj", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, m.NumFaces())
+
+	_, err = LoadOBJ(res, "bad.obj", nil)
+	assert.ErrorContains(t, err, "bad.obj: line 1")
+
+	_, err = LoadOBJ(res, "missing.obj", nil)
+	assert.Error(t, err)
+}
+
+func TestReadOBJ_Materials(t *testing.T) {
+	src := `
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+f 1 2 3
+usemtl red
+f 1 3 4
+usemtl unknown
+f 1 2 4
+usemtl red
+f 2 3 4
+`
+	red := material.NewLambertian(spectrum.Flat(0.5))
+	m, err := ReadOBJ(strings.NewReader(src), &OBJOptions{
+		Materials: map[string]material.Material{"red": red},
+	})
+	assert.NoError(t, err)
+
+	faces := m.Faces()
+	assert.Len(t, faces, 4)
+	assert.Nil(t, faces[0].Surface())
+	assert.Equal(t, material.Material(red), faces[1].Surface())
+	assert.Nil(t, faces[2].Surface())
+	assert.Equal(t, material.Material(red), faces[3].Surface())
+	assert.Len(t, m.Materials, 3)
+
+	// without usemtl there are no per-face materials
+	m, err = ReadOBJ(strings.NewReader(quadOBJ), nil)
+	assert.NoError(t, err)
+	assert.Nil(t, m.FaceMaterials)
+}
@@ -0,0 +1,102 @@
+package mesh
+
+import (
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+)
+
+// triangulate splits a planar polygon (given by its vertices in order) into
+// triangles, returned as indices into the vertex list. Triangles keep the
+// winding order of the polygon.
+//
+// Uses ear clipping in the polygon's plane, so concave polygons are handled.
+// If no ear can be found (degenerate or self-intersecting input), the rest of
+// the polygon is fanned instead.
+//
+// https://www.geometrictools.com/Documentation/TriangulationByEarClipping.pdf
+func triangulate(poly []geo.Vec) [][3]int {
+	n := len(poly)
+	if n == 3 {
+		return [][3]int{{0, 1, 2}}
+	}
+
+	// Project onto the plane most perpendicular to the polygon's normal
+	// (computed with Newell's method), keeping orientation so the polygon is
+	// counter-clockwise in 2D.
+	normal := geo.Vec{}
+	for i := range poly {
+		a, b := poly[i], poly[(i+1)%n]
+		normal.X += (a.Y - b.Y) * (a.Z + b.Z)
+		normal.Y += (a.Z - b.Z) * (a.X + b.X)
+		normal.Z += (a.X - b.X) * (a.Y + b.Y)
+	}
+	pts := make([][2]float64, n)
+	ax, ay, az := math.Abs(normal.X), math.Abs(normal.Y), math.Abs(normal.Z)
+	for i, p := range poly {
+		switch {
+		case ax >= ay && ax >= az:
+			pts[i] = [2]float64{p.Y, p.Z * math.Copysign(1, normal.X)}
+		case ay >= az:
+			pts[i] = [2]float64{p.Z, p.X * math.Copysign(1, normal.Y)}
+		default:
+			pts[i] = [2]float64{p.X, p.Y * math.Copysign(1, normal.Z)}
+		}
+	}
+
+	remaining := make([]int, n)
+	for i := range remaining {
+		remaining[i] = i
+	}
+
+	tris := make([][3]int, 0, n-2)
+	for len(remaining) > 3 {
+		m := len(remaining)
+		found := false
+		for i := 0; i < m; i++ {
+			prev, cur, next := remaining[(i+m-1)%m], remaining[i], remaining[(i+1)%m]
+			if !isEar(pts, remaining, prev, cur, next) {
+				continue
+			}
+			tris = append(tris, [3]int{prev, cur, next})
+			remaining = append(remaining[:i], remaining[i+1:]...)
+			found = true
+			break
+		}
+		if !found {
+			break
+		}
+	}
+
+	// fan whatever's left (just the final triangle, unless clipping failed)
+	for i := 1; i+1 < len(remaining); i++ {
+		tris = append(tris, [3]int{remaining[0], remaining[i], remaining[i+1]})
+	}
+	return tris
+}
+
+// isEar checks if the triangle prev-cur-next is convex (counter-clockwise) and
+// contains no other remaining vertex.
+func isEar(pts [][2]float64, remaining []int, prev, cur, next int) bool {
+	a, b, c := pts[prev], pts[cur], pts[next]
+	if cross2(a, b, c) <= 0 {
+		return false
+	}
+
+	for _, i := range remaining {
+		if i == prev || i == cur || i == next {
+			continue
+		}
+		p := pts[i]
+		if cross2(a, b, p) >= 0 && cross2(b, c, p) >= 0 && cross2(c, a, p) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// cross2 returns the z component of (b-a) x (c-a); positive if a, b, c turn
+// counter-clockwise.
+func cross2(a, b, c [2]float64) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
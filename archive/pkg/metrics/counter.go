@@ -16,6 +16,11 @@ func (c *Count64) Inc() {
 	atomic.AddUint64((*uint64)(c), 1)
 }
 
+// Add increments the metric by n.
+func (c *Count64) Add(n uint64) {
+	atomic.AddUint64((*uint64)(c), n)
+}
+
 // Get retrieves the metric value
 func (c *Count64) Get() uint64 {
 	return atomic.LoadUint64((*uint64)(c))
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// Acceleration structure and memory metrics. TextureCacheBytes counts the
+// pixel data of the images held by textures, through Textures.
+var (
+	AccelNodes        Gauge64
+	AccelPrimitives   Gauge64
+	TextureCacheBytes Gauge64
+	PeakHeapBytes     Max64
+)
+
+// Textures tracks the images held by textures, counting each image in
+// TextureCacheBytes once however many textures share it.
+var Textures = NewResident(&TextureCacheBytes)
+
+// Gauge64 is an integer metric that can go up and down.
+type Gauge64 int64
+
+// Set sets the metric to the given value.
+func (g *Gauge64) Set(v int64) {
+	atomic.StoreInt64((*int64)(g), v)
+}
+
+// Add adds the given (possibly negative) value to the metric.
+func (g *Gauge64) Add(v int64) {
+	atomic.AddInt64((*int64)(g), v)
+}
+
+// Get retrieves the metric value
+func (g *Gauge64) Get() int64 {
+	return atomic.LoadInt64((*int64)(g))
+}
+
+// Max64 is an unsigned integer metric that records the largest value it has
+// been given.
+type Max64 uint64
+
+// Observe records v, if it's larger than the current value.
+func (m *Max64) Observe(v uint64) {
+	for {
+		old := atomic.LoadUint64((*uint64)(m))
+		if v <= old || atomic.CompareAndSwapUint64((*uint64)(m), old, v) {
+			return
+		}
+	}
+}
+
+// Get retrieves the metric value
+func (m *Max64) Get() uint64 {
+	return atomic.LoadUint64((*uint64)(m))
+}
+
+// SampleHeap records the current heap size in PeakHeapBytes. Note that this
+// briefly stops the world, so call it at coarse intervals (e.g. once per tile)
+// rather than in inner loops.
+func SampleHeap() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	PeakHeapBytes.Observe(stats.HeapAlloc)
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import "sync"
+
+// Resident tracks memory that can be shared, like an image used by several
+// textures. Each distinct key is counted in the gauge once, however many times
+// it's retained, and taken off again when it's been released as many times.
+type Resident struct {
+	gauge *Gauge64
+	mu    sync.Mutex
+	refs  map[any]resident
+}
+
+type resident struct {
+	bytes int64
+	count int
+}
+
+// NewResident creates a Resident counting its memory in the gauge.
+func NewResident(gauge *Gauge64) *Resident {
+	return &Resident{gauge: gauge, refs: make(map[any]resident)}
+}
+
+// Retain records a holder of the memory identified by key, which is bytes
+// large. Only the first holder of a key adds to the gauge.
+func (r *Resident) Retain(key any, bytes int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ref, ok := r.refs[key]
+	if !ok {
+		ref.bytes = bytes
+		r.gauge.Add(bytes)
+	}
+	ref.count++
+	r.refs[key] = ref
+}
+
+// Release drops a holder of the memory identified by key. When the last
+// holder is dropped, the memory is taken off the gauge. Releasing a key that
+// isn't retained does nothing.
+func (r *Resident) Release(key any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ref, ok := r.refs[key]
+	if !ok {
+		return
+	}
+	if ref.count--; ref.count > 0 {
+		r.refs[key] = ref
+		return
+	}
+	delete(r.refs, key)
+	r.gauge.Add(-ref.bytes)
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResident(t *testing.T) {
+	var g Gauge64
+	r := NewResident(&g)
+
+	r.Retain("a", 10)
+	r.Retain("a", 10)
+	r.Retain("b", 5)
+	assert.Equal(t, int64(15), g.Get())
+
+	r.Release("a")
+	assert.Equal(t, int64(15), g.Get())
+	r.Release("a")
+	assert.Equal(t, int64(5), g.Get())
+
+	// releasing again, or something never retained, does nothing
+	r.Release("a")
+	r.Release("c")
+	assert.Equal(t, int64(5), g.Get())
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RenderStats is a point-in-time snapshot of the global metrics, and of each
+// render worker's metrics, indexed by worker ID.
+type RenderStats struct {
+	RayIntersectionTestsSucceeded uint64
+	RayIntersectionTestsFailed    uint64
+	AccelNodes                    int64
+	AccelPrimitives               int64
+	TextureCacheBytes             int64
+	PeakHeapBytes                 uint64
+	Workers                       []WorkerSnapshot
+}
+
+// WorkerSnapshot is a point-in-time snapshot of a worker's metrics.
+type WorkerSnapshot struct {
+	Tiles         uint64
+	Samples       uint64
+	PeakHeapBytes uint64
+}
+
+// Snapshot returns the current value of all the metrics.
+func Snapshot() RenderStats {
+	stats := RenderStats{
+		RayIntersectionTestsSucceeded: RayIntersectionTestsSucceeded.Get(),
+		RayIntersectionTestsFailed:    RayIntersectionTestsFailed.Get(),
+		AccelNodes:                    AccelNodes.Get(),
+		AccelPrimitives:               AccelPrimitives.Get(),
+		TextureCacheBytes:             TextureCacheBytes.Get(),
+		PeakHeapBytes:                 PeakHeapBytes.Get(),
+	}
+
+	workers.Lock()
+	defer workers.Unlock()
+	for _, w := range workers.stats {
+		stats.Workers = append(stats.Workers, WorkerSnapshot{
+			Tiles:         w.Tiles.Get(),
+			Samples:       w.Samples.Get(),
+			PeakHeapBytes: w.PeakHeapBytes.Get(),
+		})
+	}
+	return stats
+}
+
+// String returns a human-readable, multi-line summary of the stats.
+func (s RenderStats) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `Ray intersection tests: %d succeeded, %d failed
+Accelerator: %d nodes, %d primitives
+Texture cache: %s
+Peak heap: %s`,
+		s.RayIntersectionTestsSucceeded, s.RayIntersectionTestsFailed,
+		s.AccelNodes, s.AccelPrimitives,
+		bytes(uint64(s.TextureCacheBytes)), bytes(s.PeakHeapBytes))
+	for id, w := range s.Workers {
+		fmt.Fprintf(&b, "\nWorker %d: %d tiles, %d samples, peak heap %s",
+			id, w.Tiles, w.Samples, bytes(w.PeakHeapBytes))
+	}
+	return b.String()
+}
+
+// bytes formats a byte count with binary (KiB, MiB, ...) units.
+func bytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMax64(t *testing.T) {
+	var m Max64
+	m.Observe(3)
+	m.Observe(7)
+	m.Observe(5)
+	assert.Equal(t, uint64(7), m.Get())
+}
+
+func TestBytes(t *testing.T) {
+	assert.Equal(t, "512 B", bytes(512))
+	assert.Equal(t, "1.5 KiB", bytes(1536))
+	assert.Equal(t, "2.0 GiB", bytes(2<<30))
+}
+
+func TestRenderStats_String(t *testing.T) {
+	s := RenderStats{Workers: []WorkerSnapshot{{Tiles: 2, Samples: 64, PeakHeapBytes: 2048}}}
+	assert.Contains(t, s.String(), "\nWorker 0: 2 tiles, 64 samples, peak heap 2.0 KiB")
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"runtime"
+	"sync"
+)
+
+// WorkerStats are the metrics of one render worker. The Go runtime can't say
+// how much of the heap a goroutine is responsible for, so PeakHeapBytes is the
+// largest process heap seen when the worker finished a tile: a worker whose
+// peak stands out was busy with the tiles that needed the most memory.
+type WorkerStats struct {
+	Tiles         Count64
+	Samples       Count64
+	PeakHeapBytes Max64
+}
+
+// SampleHeap records the current heap size in the worker's PeakHeapBytes, and
+// in the global PeakHeapBytes. Like the global SampleHeap, this briefly stops
+// the world.
+func (w *WorkerStats) SampleHeap() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	w.PeakHeapBytes.Observe(stats.HeapAlloc)
+	PeakHeapBytes.Observe(stats.HeapAlloc)
+}
+
+var workers struct {
+	sync.Mutex
+	stats []*WorkerStats
+}
+
+// Worker returns the metrics of the worker with the given ID, creating them
+// the first time. Renders number their workers from 0, so workers of
+// successive renders share metrics.
+func Worker(id int) *WorkerStats {
+	workers.Lock()
+	defer workers.Unlock()
+	for len(workers.stats) <= id {
+		workers.stats = append(workers.stats, &WorkerStats{})
+	}
+	return workers.stats[id]
+}
@@ -0,0 +1,66 @@
+package render
+
+import (
+	"github.com/gmhorn/gremlin/archive/pkg/accel"
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/gmhorn/gremlin/archive/pkg/sampler"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+)
+
+// aoMaterial is the white diffuse surface ambient occlusion is computed for.
+var aoMaterial = material.NewLambertian(spectrum.Flat(1))
+
+// AmbientOcclusion shades surfaces by how much of the hemisphere above them is
+// unoccluded: what a white diffuse surface would reflect under a uniform white
+// sky. Only occluders within Radius count, so that the result shows contact
+// and crevice detail instead of just the scene's overall openness. Rays that
+// miss everything see the white sky.
+//
+// It ignores materials and lights, which makes it a quick way to check
+// geometry and its proximity.
+//
+// https://en.wikipedia.org/wiki/Ambient_occlusion
+type AmbientOcclusion struct {
+	Radius    float64
+	RayOffset float64
+}
+
+// NewAmbientOcclusion creates an ambient occlusion integrator with the given
+// occlusion radius. Use math.Inf(1) to count all occluders.
+func NewAmbientOcclusion(radius float64) *AmbientOcclusion {
+	return &AmbientOcclusion{
+		Radius:    radius,
+		RayOffset: defaultRayOffset,
+	}
+}
+
+// Radiance implements Integrator.
+func (ao *AmbientOcclusion) Radiance(ray *geo.Ray, scene *accel.BVH, smp sampler.Sampler) spectrum.Distribution {
+	hit, found := scene.Intersect(ray)
+	if !found {
+		return spectrum.Flat(1)
+	}
+
+	si := hit.Interaction(ray)
+	point, n := si.Point, si.Normal
+	frame := geo.FrameFromNormal(n)
+	wo := frame.ToLocal(ray.Dir.Reverse().Unit())
+
+	// Directions are sampled on the side of the surface the ray arrived from
+	u1, u2 := smp.Get2D()
+	bsdf, ok := aoMaterial.Sample(wo, u1, u2)
+	if !ok || bsdf.PDF == 0 {
+		return spectrum.Flat(0)
+	}
+
+	offset := n.Scale(ao.RayOffset)
+	if bsdf.Wi.Z < 0 {
+		offset = offset.Reverse()
+	}
+	occ := geo.NewRayAt(point.Plus(offset), geo.Vec(frame.ToWorld(bsdf.Wi)), ray.Time)
+	if hit, found := scene.Intersect(occ); found && hit.T < ao.Radius {
+		return spectrum.Flat(0)
+	}
+	return bsdf.F.Scale(geo.AbsCosTheta(bsdf.Wi) / bsdf.PDF)
+}
@@ -0,0 +1,108 @@
+package render
+
+import (
+	"github.com/gmhorn/gremlin/archive/pkg/accel"
+	"github.com/gmhorn/gremlin/archive/pkg/camera"
+	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/sampler"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+)
+
+// sceneData is the scene as prepared for rendering.
+type sceneData struct {
+	bvh *accel.BVH
+
+	// objects maps objects (see objectKey) to their AOVObjectID
+	objects map[any]int
+}
+
+func newSceneData(scene []shape.Shape) *sceneData {
+	// refined here as well as by NewBVH, so the parts hits land on have IDs,
+	// as do the shapes in aggregates
+	scene = shape.Refine(scene)
+	objects := make(map[any]int)
+	for _, s := range scene {
+		parts := []shape.Shape{s}
+		if agg, ok := s.(shape.Aggregate); ok {
+			parts = agg.Shapes()
+		}
+		for _, part := range parts {
+			if key := objectKey(part); objects[key] == 0 {
+				objects[key] = len(objects) + 1
+			}
+		}
+	}
+	return &sceneData{bvh: accel.NewBVH(scene), objects: objects}
+}
+
+// objectKey returns what identifies the object a shape belongs to: the mesh
+// for mesh faces, otherwise the shape itself. Instances with the same
+// transform (see shape.NewInstances, shape.NewMotionInstances and
+// accel.NewMotionInstance) belong to the same object if the shapes they place
+// do.
+func objectKey(s shape.Shape) any {
+	switch s := s.(type) {
+	case *shape.MeshFace:
+		return s.Mesh
+	case *shape.Instance:
+		return instanceKey{objectKey(s.Shape), s.ObjectToWorld()}
+	case *shape.MotionInstance:
+		return instanceKey{objectKey(s.Shape), s.Motion}
+	}
+	return s
+}
+
+// instanceKey is the objectKey for instances. The transform is a *geo.Mtx or
+// *geo.AnimatedTransform.
+type instanceKey struct {
+	object    any
+	transform any
+}
+
+// recordAOVs records the film's AOVs for a camera ray sampled at raster
+// coordinates (x, y).
+func (sd *sceneData) recordAOVs(tile *camera.FilmTile, aovs []camera.AOV, ray *geo.Ray, x, y float64, smp sampler.Sampler) {
+	hit, found := sd.bvh.Intersect(ray)
+	if !found {
+		return
+	}
+	si := hit.Interaction(ray)
+	n := si.Normal
+
+	for _, aov := range aovs {
+		var v colorspace.Point
+		switch aov {
+		case camera.AOVNormal:
+			v = colorspace.Point{n.X, n.Y, n.Z}
+		case camera.AOVDepth:
+			d := hit.T * ray.Dir.Len()
+			v = colorspace.Point{d, d, d}
+		case camera.AOVAlbedo:
+			v = albedo(si, ray, smp)
+		case camera.AOVObjectID:
+			id := float64(sd.objects[objectKey(hit.Shape)])
+			v = colorspace.Point{id, id, id}
+		}
+		tile.AddAOV(x, y, aov, v)
+	}
+}
+
+// albedo returns a one-sample estimate of the reflectance of the surface at
+// the hit point, i.e. the fraction of light arriving from all directions that
+// is scattered along the ray back towards the camera. Averaging samples gives
+// the directional albedo.
+func albedo(si shape.Interaction, ray *geo.Ray, smp sampler.Sampler) colorspace.Point {
+	mat, n := shade(si)
+	frame := geo.FrameFromNormal(n)
+	wo := frame.ToLocal(ray.Dir.Reverse().Unit())
+	smp.SetDimension(cameraDims)
+	u1, u2 := smp.Get2D()
+	bsdf, ok := mat.Sample(wo, u1, u2)
+	if !ok || bsdf.PDF == 0 {
+		return colorspace.Point{}
+	}
+
+	refl := bsdf.F.Scale(geo.AbsCosTheta(bsdf.Wi) / bsdf.PDF)
+	return colorspace.CIE1931Reflectance.Convert(refl)
+}
@@ -0,0 +1,50 @@
+package render
+
+import (
+	"fmt"
+
+	"github.com/gmhorn/gremlin/archive/pkg/camera"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+)
+
+// CullReport lists which shapes of a scene are inside the camera's view, by
+// index into the scene slice.
+type CullReport struct {
+	Visible []int
+	Culled  []int
+}
+
+// Cull checks every shape's bounds against the camera frustum.
+//
+// Shapes outside the frustum can't be seen directly, but they can still show
+// up in reflections, cast shadows, or contribute indirect light. So culled
+// shapes are only safe to remove for renders that don't depend on them; the
+// report is mostly useful for spotting junk in imported scenes.
+func Cull(cam *camera.Perspective, scene []shape.Shape) CullReport {
+	frustum := cam.Frustum()
+
+	report := CullReport{}
+	for i, s := range scene {
+		if frustum.Overlaps(s.Bounds()) {
+			report.Visible = append(report.Visible, i)
+		} else {
+			report.Culled = append(report.Culled, i)
+		}
+	}
+	return report
+}
+
+// Prune returns a new scene with only the visible shapes.
+func (r CullReport) Prune(scene []shape.Shape) []shape.Shape {
+	pruned := make([]shape.Shape, len(r.Visible))
+	for i, idx := range r.Visible {
+		pruned[i] = scene[idx]
+	}
+	return pruned
+}
+
+// String returns a short summary of the report.
+func (r CullReport) String() string {
+	total := len(r.Visible) + len(r.Culled)
+	return fmt.Sprintf("%d of %d shapes outside camera frustum", len(r.Culled), total)
+}
@@ -0,0 +1,32 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/camera"
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCull(t *testing.T) {
+	cam := camera.NewPerspective(2, 90).MoveTo(geo.V(0, 0, 5)).PointAt(geo.Origin)
+	scene := []shape.Shape{
+		&shape.Sphere{Center: geo.Origin, Radius: 0.5},                        // in front
+		&shape.Sphere{Center: geo.V(0, 0, 10), Radius: 0.5},                   // behind
+		&shape.Sphere{Center: geo.V(10, 0, 0), Radius: 0.5},                   // straddling the right edge
+		&shape.Sphere{Center: geo.V(0, 8, 0), Radius: 0.5},                    // above
+		shape.NewTriangle(geo.V(-20, 0, 0), geo.V(20, 0, 0), geo.V(0, 0, 10)), // around the camera
+	}
+
+	report := Cull(cam, scene)
+	assert.Equal(t, []int{0, 2, 4}, report.Visible)
+	assert.Equal(t, []int{1, 3}, report.Culled)
+	assert.Equal(t, "2 of 5 shapes outside camera frustum", report.String())
+
+	pruned := report.Prune(scene)
+	assert.Len(t, pruned, 3)
+	assert.Same(t, scene[0], pruned[0])
+	assert.Same(t, scene[2], pruned[1])
+	assert.Same(t, scene[4], pruned[2])
+}
@@ -0,0 +1,54 @@
+package render
+
+import (
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/imageio"
+)
+
+// Mask is a region-of-interest mask, spreading each pass's samples unevenly
+// over the film: more on the pixels that matter (say, the product) and fewer
+// on the rest (the backdrop). It holds a weight per pixel, in the film's
+// order, averaging 1, so a render takes about as many samples as it would
+// without one. Renders use it through Options.
+type Mask []float64
+
+// NewMask creates a mask for a width by height film from a grayscale image,
+// which is stretched over the film. White pixels are the most important; black
+// ones get min times as many samples, so 0 leaves them with just one each.
+//
+// Masks hold data, so the image is best read with imageio.ReadImageData. The
+// channels of colored images are averaged.
+func NewMask(img *imageio.RGB, width, height int, min float64) Mask {
+	if min < 0 || min > 1 {
+		panic("mask minimum must be between 0 and 1")
+	}
+	m := make(Mask, width*height)
+	total := 0.0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// nearest pixel of the image
+			r, g, b := img.At(x*img.Width/width, y*img.Height/height)
+			gray := math.Max(0, math.Min(1, (r+g+b)/3))
+			m[y*width+x] = min + (1-min)*gray
+			total += m[y*width+x]
+		}
+	}
+	if total == 0 {
+		panic("mask is empty")
+	}
+	for i := range m {
+		m[i] *= float64(len(m)) / total
+	}
+	return m
+}
+
+// Samples returns how many of spp samples per pixel the pixel at the index
+// gets. Every pixel gets at least one, so none are left black.
+func (m Mask) Samples(pxIdx, spp int) int {
+	n := int(math.Round(m[pxIdx] * float64(spp)))
+	if n < 1 {
+		return 1
+	}
+	return n
+}
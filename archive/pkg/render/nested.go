@@ -0,0 +1,78 @@
+package render
+
+import (
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+)
+
+// mediumStack lists the dielectrics a path is inside, in the order it entered
+// them, for resolving nested dielectrics (see material.Dielectric). Stacks are
+// never modified in place, so they can be shared.
+type mediumStack []*material.Dielectric
+
+// top returns the medium the path is actually in: the one with the highest
+// priority, or the most recently entered of those. Nil means outside of
+// everything.
+func (s mediumStack) top() *material.Dielectric {
+	var top *material.Dielectric
+	for _, d := range s {
+		if top == nil || d.Priority >= top.Priority {
+			top = d
+		}
+	}
+	return top
+}
+
+// cross returns the boundary between the media on either side of a surface of
+// d that the path is entering or leaving, and the stack on the far side. If
+// the surface is inside a higher-priority medium, it isn't a real boundary,
+// and the returned one is nil.
+//
+// Leaving a dielectric the path never entered (e.g. through the back of an
+// open surface) is always a real boundary, with the current medium outside.
+func (s mediumStack) cross(d *material.Dielectric, entering bool) (*material.Dielectric, mediumStack) {
+	if entering {
+		far := append(append(mediumStack{}, s...), d)
+		if far.top() != d {
+			return nil, far
+		}
+		return d.Against(s.top()), far
+	}
+
+	// leave the most recent entry, in case it was entered more than once
+	idx := -1
+	for i := range s {
+		if s[i] == d {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return d.Against(s.top()), s
+	}
+
+	far := append(append(mediumStack{}, s[:idx]...), s[idx+1:]...)
+	if s.top() != d {
+		return nil, far
+	}
+	return d.Against(far.top()), far
+}
+
+// transmittance returns the fraction of light that makes it dist through the
+// medium the path is in, by the Beer-Lambert law, or nil if the medium doesn't
+// absorb (see material.Dielectric).
+func (s mediumStack) transmittance(dist float64) *spectrum.Sampled {
+	top := s.top()
+	if top == nil || top.Absorption == nil {
+		return nil
+	}
+	tr := new(spectrum.Sampled)
+	for i, a := range top.Absorption {
+		tr[i] = 1
+		if a > 0 {
+			tr[i] = math.Exp(-a * dist)
+		}
+	}
+	return tr
+}
@@ -0,0 +1,72 @@
+package render
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediumStack_Cross(t *testing.T) {
+	water := material.NewDielectric(spectrum.Flat(1.25))
+	water.Priority = 1
+	ice := material.NewDielectric(spectrum.Flat(1.5))
+	ice.Priority = 2
+	glass := material.NewDielectric(spectrum.Flat(2))
+	glass.Priority = 3
+
+	ior := func(d *material.Dielectric) float64 {
+		return d.IOR.Lookup(500)
+	}
+
+	// air -> water -> ice
+	b, s := mediumStack(nil).cross(water, true)
+	assert.Same(t, water, b)
+	assert.Equal(t, mediumStack{water}, s)
+	b, s = s.cross(ice, true)
+	assert.InDelta(t, 1.2, ior(b), 1e-12)
+	assert.Equal(t, mediumStack{water, ice}, s)
+
+	// the water's surface inside the ice isn't there
+	b, far := s.cross(water, false)
+	assert.Nil(t, b)
+	assert.Equal(t, mediumStack{ice}, far)
+
+	// ice -> water
+	b, s = s.cross(ice, false)
+	assert.InDelta(t, 1.5/1.25, ior(b), 1e-12)
+	assert.Equal(t, mediumStack{water}, s)
+
+	// the water inside the glass isn't there either
+	b, s = mediumStack{glass}.cross(water, true)
+	assert.Nil(t, b)
+	assert.Equal(t, glass, s.top())
+
+	// equal priorities: the latest one wins
+	other := material.NewDielectric(spectrum.Flat(1.5))
+	other.Priority = 1
+	b, _ = mediumStack{water}.cross(other, true)
+	assert.InDelta(t, 1.2, ior(b), 1e-12)
+
+	// leaving something never entered is still a boundary
+	b, s = mediumStack{water}.cross(ice, false)
+	assert.InDelta(t, 1.2, ior(b), 1e-12)
+	assert.Equal(t, mediumStack{water}, s)
+}
+
+func TestMediumStack_Transmittance(t *testing.T) {
+	water := material.NewDielectric(spectrum.Flat(1.33))
+	assert.Nil(t, mediumStack{water}.transmittance(1))
+
+	water.Absorption = spectrum.Sample(spectrum.WaterAbsorption)
+	tr := mediumStack{water}.transmittance(10)
+	assert.InDelta(t, math.Exp(-10*spectrum.WaterAbsorption.Lookup(650)), tr.Lookup(650), 1e-3)
+	assert.Less(t, tr.Lookup(650), tr.Lookup(450))
+	assert.Nil(t, mediumStack(nil).transmittance(10))
+
+	// nothing gets through forever
+	tr = mediumStack{water}.transmittance(math.Inf(1))
+	assert.Zero(t, tr.Max())
+}
@@ -0,0 +1,483 @@
+package render
+
+import (
+	"math"
+	"sync"
+
+	"github.com/gmhorn/gremlin/archive/pkg/accel"
+	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/light"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/gmhorn/gremlin/archive/pkg/sampler"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+)
+
+// defaultRayOffset is the integrators' default RayOffset.
+const defaultRayOffset = 1e-4
+
+// bounceDims is how many sampler dimensions each bounce of a path uses: one to
+// pick a light, two for a point on it, two for the BSDF sample and one for
+// Russian roulette. Each bounce starts at a fixed dimension, so dimensions are
+// used consistently even when some of them are skipped.
+const bounceDims = 6
+
+// defaultMaterial is used for shapes without a material.
+var defaultMaterial = material.NewLambertian(spectrum.Flat(0.5))
+
+// shade returns the material to shade the interaction with, the shape's
+// material (or the default) resolved at the interaction's texture
+// coordinates, and the shading normal, perturbed if the material has a normal
+// or bump map.
+func shade(si shape.Interaction) (material.Material, geo.Unit) {
+	mat := si.Material
+	if mat == nil {
+		mat = defaultMaterial
+	}
+	tc := si.TexCoords()
+	n := material.ShadingNormal(mat, tc, si.Normal, si.DPDU, si.DPDV)
+	return material.Resolve(mat, tc), n
+}
+
+// PathTracer is an unbiased, iterative path tracer. Radiance is accumulated
+// spectrally: each path carries a throughput distribution, which is
+// multiplied by the BSDF of the hit surface's material at each bounce, and any
+// emitted light (the background) that the path reaches is added in weighted
+// by it.
+//
+// At each non-specular bounce, one of the Lights is also sampled directly
+// (next-event estimation) and its contribution added if it isn't occluded.
+// Lights are chosen in proportion to the luminance of their power, so a dim
+// fill light isn't sampled as often as the key light, and LightHints (one per
+// light, if set) can adjust that. Each light's power is computed once, the
+// first time it's needed, so changing a light's emission after rendering
+// with it has no effect on how often it's chosen.
+//
+// Lights aren't part of the scene geometry, so this is the only way they're
+// seen: they won't show up to camera rays or in mirrors. The exception is
+// light.Infinite lights, which become the background that escaping rays see
+// instead of the default sky gradient. Since paths can find them both ways,
+// the two are combined by multiple importance sampling with the power
+// heuristic: each is weighted by how likely it was to find the direction
+// compared to the other, which keeps glossy surfaces from picking up
+// fireflies from light samples their BSDF barely reflects.
+//
+// Paths are terminated after MaxDepth bounces, or earlier by Russian roulette
+// once they're more than RRDepth bounces deep: the path survives with a
+// probability based on its throughput, and survivors are reweighted to keep
+// the estimate unbiased.
+//
+// Paths keep track of the dielectrics they're inside, so overlapping
+// dielectric volumes are resolved by their priority (see
+// material.Dielectric), refraction uses the refractive indices on both sides
+// of each boundary, and light is attenuated by the absorption of the medium
+// it travels through. Paths start outside of everything.
+//
+// Regularize, if positive, trades a little bias for a lot less noise where
+// paths go from a diffuse surface to a specular one and then a light, like
+// caustics seen in mirrors. Each non-specular bounce raises the minimum
+// roughness of the materials further along the path by Regularize, up to 1
+// (see material.Glossy), so mirrors and glass seen indirectly are blurred and
+// can be lit directly. Camera rays, and paths that have only been specular,
+// see materials as they are.
+//
+// New rays are pushed RayOffset off the surface they leave along its normal,
+// to avoid re-intersecting it due to floating-point error. Shadow rays ignore
+// occluders further than MaxShadowDist away; this is infinite by default, but
+// can keep distant geometry (like a backdrop dome) from blocking directional
+// lights and the environment.
+//
+// https://www.pbr-book.org/3ed-2018/Light_Transport_I_Surface_Reflection/Path_Tracing
+// https://www.pbr-book.org/3ed-2018/Monte_Carlo_Integration/Importance_Sampling#MultipleImportanceSampling
+type PathTracer struct {
+	MaxDepth      int
+	RRDepth       int
+	Lights        []light.Light
+	LightHints    []LightHint
+	RayOffset     float64
+	MaxShadowDist float64
+	Regularize    float64
+
+	// powers caches the luminance of each light's power, by light
+	powers sync.Map
+}
+
+// NewPathTracer creates a path tracer with the given maximum depth. Russian
+// roulette starts after 3 bounces.
+func NewPathTracer(maxDepth int) *PathTracer {
+	return &PathTracer{
+		MaxDepth:      maxDepth,
+		RRDepth:       3,
+		RayOffset:     defaultRayOffset,
+		MaxShadowDist: math.Inf(1),
+	}
+}
+
+// Radiance implements Integrator.
+func (pt *PathTracer) Radiance(ray *geo.Ray, scene *accel.BVH, smp sampler.Sampler) spectrum.Distribution {
+	radiance := new(spectrum.Sampled)
+	throughput := spectrum.Sample(spectrum.Flat(1))
+	bsdfPDF := 0.0 // of the last bounce, 0 if there's none or it was specular
+	roughness := 0.0
+	startDim := smp.Dimension()
+	var media mediumStack
+
+	for depth := 0; ; depth++ {
+		dim := startDim + depth*bounceDims
+		smp.SetDimension(dim)
+
+		hit, found := scene.Intersect(ray)
+		dist := math.Inf(1)
+		if found {
+			dist = hit.T * ray.Dir.Len()
+		}
+		if tr := media.transmittance(dist); tr != nil {
+			throughput = throughput.Mult(tr)
+		}
+		if !found {
+			radiance = radiance.Plus(throughput.Mult(pt.background(ray, bsdfPDF)))
+			break
+		}
+		if depth >= pt.MaxDepth {
+			break
+		}
+
+		si := hit.Interaction(ray)
+		point, n := si.Point, si.Normal
+		mat, shading := shade(si)
+
+		// Surfaces of dielectrics inside higher-priority ones aren't there:
+		// carry straight on through them, without counting a bounce.
+		farMedia := media
+		d, crossing := mat.(*material.Dielectric)
+		if crossing {
+			entering := ray.Dir.Dot(geo.Vec(n)) < 0
+			var boundary *material.Dielectric
+			boundary, farMedia = media.cross(d, entering)
+			if boundary == nil {
+				media = farMedia
+				offset := n.Scale(pt.RayOffset)
+				if entering {
+					offset = offset.Reverse()
+				}
+				ray = geo.NewRayAt(point.Plus(offset), ray.Dir, ray.Time)
+				depth--
+				continue
+			}
+			mat = boundary
+		}
+		if roughness > 0 {
+			mat = material.Roughen(mat, roughness)
+		}
+
+		frame := geo.FrameFromNormal(shading)
+		wo := frame.ToLocal(ray.Dir.Reverse().Unit())
+		sp := &shadingPoint{point: point, time: ray.Time, n: n, frame: frame, wo: wo, mat: mat, media: media, farMedia: farMedia}
+		if ld := pt.sampleLight(sp, scene, smp); ld != nil {
+			radiance = radiance.Plus(throughput.Mult(ld))
+		}
+
+		smp.SetDimension(dim + 3)
+		u1, u2 := smp.Get2D()
+		bsdf, ok := mat.Sample(wo, u1, u2)
+		if !ok || bsdf.PDF == 0 {
+			break
+		}
+		throughput = throughput.Mult(bsdf.F).Scale(geo.AbsCosTheta(bsdf.Wi) / bsdf.PDF)
+		bsdfPDF = bsdf.PDF
+		if bsdf.Specular {
+			bsdfPDF = 0
+		} else if pt.Regularize > 0 {
+			roughness = math.Min(1, roughness+pt.Regularize)
+		}
+		wi := frame.ToWorld(bsdf.Wi)
+
+		if depth >= pt.RRDepth {
+			q := math.Max(0.05, 1-throughput.Max())
+			if smp.Get1D() < q {
+				break
+			}
+			throughput = throughput.Scale(1 / (1 - q))
+		}
+
+		// offset to whichever side of the surface the new ray leaves from
+		offset := n.Scale(pt.RayOffset)
+		if wi.Dot(n) < 0 {
+			offset = offset.Reverse()
+		}
+		if crossing && bsdf.Wi.Z*wo.Z < 0 {
+			media = farMedia
+		}
+		ray = geo.NewRayAt(point.Plus(offset), geo.Vec(wi), ray.Time)
+	}
+
+	return radiance
+}
+
+// background returns the radiance of a ray that escaped the scene: that of the
+// Infinite lights if there are any, or else the default sky.
+//
+// Infinite lights are also sampled directly at non-specular bounces, so after
+// one, their radiance is weighted by the power heuristic against the chance
+// of direct lighting choosing the same direction. bsdfPDF is the density the
+// ray's direction was sampled with, or 0 for camera rays and after specular
+// bounces, which direct lighting can't reproduce, so they aren't weighted.
+func (pt *PathTracer) background(ray *geo.Ray, bsdfPDF float64) *spectrum.Sampled {
+	dir := ray.Dir.Unit()
+	le := new(spectrum.Sampled)
+	infinite := false
+	var sel lightSelection
+	for i, l := range pt.Lights {
+		inf, ok := l.(light.Infinite)
+		if !ok {
+			continue
+		}
+		if !infinite && bsdfPDF > 0 {
+			sel = pt.selection()
+		}
+		infinite = true
+
+		li := inf.Le(dir)
+		if bsdfPDF > 0 {
+			li = li.Scale(powerHeuristic(bsdfPDF, sel.prob(i)*inf.PDF(dir)))
+		}
+		le = le.Plus(li)
+	}
+
+	if !infinite {
+		return sky(ray)
+	}
+	return le
+}
+
+// powerHeuristic is the weight of a sample taken with density f, that could
+// also have been taken by another strategy with density g (with one sample
+// from each).
+func powerHeuristic(f, g float64) float64 {
+	if f == 0 {
+		return 0
+	}
+	return f * f / (f*f + g*g)
+}
+
+// LightHint tunes how PathTracer samples one of its lights, for when selection
+// by power undersamples a light that matters, like a dim light that's the
+// only one reaching part of the scene.
+//
+// Importance multiplies the light's chance of being chosen for direct
+// lighting (0 means 1, leaving it in proportion to power; it mustn't be
+// negative).
+// Always lights aren't chosen at all: they're sampled at every bounce, on top
+// of the chosen light, which costs a shadow ray each but takes all of their
+// variance from selection away.
+type LightHint struct {
+	Importance float64
+	Always     bool
+}
+
+// hint returns the hint for light i, or the default.
+func (pt *PathTracer) hint(i int) LightHint {
+	if i < len(pt.LightHints) {
+		return pt.LightHints[i]
+	}
+	return LightHint{}
+}
+
+// shadingPoint is what direct lighting needs to know about the point being
+// shaded: where it is, its normal and shading frame, the outgoing direction
+// in that frame, its material, and the media on wo's side of the surface and
+// the other.
+type shadingPoint struct {
+	point           geo.Vec
+	time            float64
+	n               geo.Unit
+	frame           geo.Frame
+	wo              geo.Unit
+	mat             material.Material
+	media, farMedia mediumStack
+}
+
+// sampleLight picks one of the lights at random (see lightSelection), and
+// returns the radiance it reflects from the point towards wo, plus that of
+// every Always light. Returns nil if there's none.
+//
+// https://www.pbr-book.org/3ed-2018/Light_Transport_I_Surface_Reflection/Direct_Lighting
+func (pt *PathTracer) sampleLight(sp *shadingPoint, scene *accel.BVH, smp sampler.Sampler) *spectrum.Sampled {
+	if len(pt.Lights) == 0 {
+		return nil
+	}
+	u := smp.Get1D()
+	u1, u2 := smp.Get2D()
+
+	var total *spectrum.Sampled
+	add := func(ld *spectrum.Sampled) {
+		if ld == nil {
+			return
+		} else if total == nil {
+			total = ld
+		} else {
+			total = total.Plus(ld)
+		}
+	}
+
+	// The Always lights reuse the chosen light's sample: each light's
+	// estimate is unbiased on its own, so correlation between them is fine.
+	for i, l := range pt.Lights {
+		if pt.hint(i).Always {
+			add(pt.directLight(l, 1, sp, u1, u2, scene))
+		}
+	}
+
+	sel := pt.selection()
+	if chosen := sel.choose(u); chosen >= 0 {
+		add(pt.directLight(pt.Lights[chosen], sel.prob(chosen), sp, u1, u2, scene))
+	}
+	return total
+}
+
+// lightSelection is the chance of each of a PathTracer's lights being chosen
+// for direct lighting: in proportion to the luminance of its power times its
+// importance. Always lights aren't chosen.
+//
+// Lights without a power to go by (like a Directional light whose SceneRadius
+// isn't set) are weighted as if they had the mean power of the others, so
+// they're still sampled.
+type lightSelection struct {
+	pt          *PathTracer
+	mean, total float64
+}
+
+// selection works out the lights' selection weights.
+func (pt *PathTracer) selection() lightSelection {
+	sel := lightSelection{pt: pt, mean: 1}
+	sum, n := 0.0, 0
+	for i, l := range pt.Lights {
+		if p := pt.power(l); p > 0 && !pt.hint(i).Always {
+			sum += p
+			n++
+		}
+	}
+	if n > 0 {
+		sel.mean = sum / float64(n)
+	}
+	for i := range pt.Lights {
+		sel.total += sel.weight(i)
+	}
+	return sel
+}
+
+// weight returns the selection weight of light i.
+func (sel lightSelection) weight(i int) float64 {
+	h := sel.pt.hint(i)
+	if h.Always {
+		return 0
+	}
+	p := sel.pt.power(sel.pt.Lights[i])
+	if p <= 0 {
+		p = sel.mean
+	}
+	return p * importance(h)
+}
+
+// choose returns the light chosen with the uniform random number u, or -1 if
+// there are no lights to choose from.
+func (sel lightSelection) choose(u float64) int {
+	if sel.total == 0 {
+		return -1
+	}
+
+	// Walk the weights to the chosen light. Rounding can leave target just
+	// past the end, in which case the last light with any weight is it.
+	chosen, target := -1, u*sel.total
+	for i := range sel.pt.Lights {
+		w := sel.weight(i)
+		if w == 0 {
+			continue
+		}
+		chosen = i
+		if target < w {
+			break
+		}
+		target -= w
+	}
+	return chosen
+}
+
+// prob returns the probability that light i is sampled at a bounce: 1 for
+// Always lights.
+func (sel lightSelection) prob(i int) float64 {
+	if sel.pt.hint(i).Always {
+		return 1
+	}
+	if sel.total == 0 {
+		return 0
+	}
+	return sel.weight(i) / sel.total
+}
+
+// power returns the luminance of the light's power.
+func (pt *PathTracer) power(l light.Light) float64 {
+	if p, ok := pt.powers.Load(l); ok {
+		return p.(float64)
+	}
+	p := colorspace.CIE1931Reflectance.Convert(l.Power())[1]
+	if math.IsNaN(p) || math.IsInf(p, 0) {
+		p = 0
+	}
+	pt.powers.Store(l, p)
+	return p
+}
+
+// importance returns the hint's selection weight.
+func importance(h LightHint) float64 {
+	if h.Importance == 0 {
+		return 1
+	}
+	return h.Importance
+}
+
+// directLight samples the light l, which was chosen with probability pick,
+// with the uniform random numbers u1 and u2, and returns the radiance it
+// reflects from the point towards wo, or nil if it's occluded.
+//
+// Infinite lights can also be found by sampling the BSDF, so their samples are
+// weighted by the power heuristic against that (see background).
+func (pt *PathTracer) directLight(l light.Light, pick float64, sp *shadingPoint, u1, u2 float64, scene *accel.BVH) *spectrum.Sampled {
+	ls, ok := l.SampleLi(sp.point, u1, u2)
+	if !ok || ls.PDF == 0 {
+		return nil
+	}
+	wi := sp.frame.ToLocal(ls.Wi)
+	f := sp.mat.Eval(sp.wo, wi)
+	if f.Max() == 0 {
+		return nil
+	}
+
+	offset := sp.n.Scale(pt.RayOffset)
+	if ls.Wi.Dot(sp.n) < 0 {
+		offset = offset.Reverse()
+	}
+	shadow := geo.NewRayAt(sp.point.Plus(offset), geo.Vec(ls.Wi), sp.time)
+	maxDist := math.Min(ls.Dist-2*pt.RayOffset, pt.MaxShadowDist)
+	if hit, found := scene.Intersect(shadow); found && hit.T < maxDist {
+		return nil
+	}
+
+	// light reaching the far side of a surface travels through the media
+	// there
+	media := sp.media
+	if wi.Z*sp.wo.Z < 0 {
+		media = sp.farMedia
+	}
+	if tr := media.transmittance(ls.Dist); tr != nil {
+		f = f.Mult(tr)
+	}
+
+	lightPDF := pick * ls.PDF
+	weight := geo.AbsCosTheta(wi) / lightPDF
+	if _, ok := l.(light.Infinite); ok {
+		weight *= powerHeuristic(lightPDF, sp.mat.PDF(sp.wo, wi))
+	}
+	return f.Mult(ls.Li).Scale(weight)
+}
@@ -1,12 +1,17 @@
 package render
 
 import (
-	"math"
-	"math/rand"
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
 
+	"github.com/gmhorn/gremlin/archive/pkg/accel"
 	"github.com/gmhorn/gremlin/archive/pkg/camera"
 	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
 	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/metrics"
+	"github.com/gmhorn/gremlin/archive/pkg/sampler"
 	"github.com/gmhorn/gremlin/archive/pkg/shape"
 	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
 	"github.com/gmhorn/gremlin/archive/pkg/util"
@@ -15,58 +20,234 @@ import (
 const tileSize = 64
 const samples = 32
 
-func Fixed(film *camera.Film, cam *camera.Perspective, scene []shape.Shape) error {
+// cameraDims is how many sampler dimensions camera rays use: two for the
+// position in the pixel, two for the lens and one for time.
+const cameraDims = 5
+
+// Options configure how Render and Progressive sample the film. A nil
+// *Options uses the defaults, which are the zero values of each field.
+type Options struct {
+	// Seed is the base seed for all the random numbers used while rendering.
+	// Renders of the same scene with different seeds are independent, so
+	// they can be merged (see camera.Film.Add) to bring noise down further.
+	Seed uint64
+
+	// NewSampler creates the sampler each tile's samples are drawn from. It's
+	// called with Seed. By default it's sampler.NewSobol.
+	NewSampler func(seed uint64) sampler.Sampler
+
+	// Dither, if set, gives every pixel the same samples, offset by blue
+	// noise instead of randomized per pixel (see sampler.Dithered).
+	Dither bool
+
+	// Mask, if set, spreads each pass's samples over the film by importance.
+	// It must be the size of the film, or rendering fails.
+	Mask Mask
+}
+
+// check returns an error if the options can't be used for the film.
+func (o *Options) check(film *camera.Film) error {
+	if o.Mask != nil && len(o.Mask) != len(film.Pixels) {
+		return fmt.Errorf("sample mask has %d pixels, but the film has %d", len(o.Mask), len(film.Pixels))
+	}
+	return nil
+}
+
+// newSampler creates a sampler for a tile of a film the given width.
+func (o *Options) newSampler(width int) sampler.Sampler {
+	var smp sampler.Sampler
+	if o.NewSampler == nil {
+		smp = sampler.NewSobol(o.Seed)
+	} else {
+		smp = o.NewSampler(o.Seed)
+	}
+	if o.Dither {
+		smp = sampler.NewDithered(smp, width)
+	}
+	return smp
+}
+
+// Integrator computes the spectral radiance arriving along a camera ray. The
+// sampler has been started for the ray's sample, and its first dimensions
+// used for the camera; integrators draw whatever they need from the rest.
+type Integrator interface {
+	Radiance(ray *geo.Ray, scene *accel.BVH, smp sampler.Sampler) spectrum.Distribution
+}
+
+// IntegratorFunc is a convenience typedef to make it easy to define an
+// Integrator from a function.
+type IntegratorFunc func(ray *geo.Ray, scene *accel.BVH, smp sampler.Sampler) spectrum.Distribution
+
+// Radiance just calls the IntegratorFunc itself.
+func (f IntegratorFunc) Radiance(ray *geo.Ray, scene *accel.BVH, smp sampler.Sampler) spectrum.Distribution {
+	return f(ray, scene, smp)
+}
+
+// Fixed renders the scene shaded by surface normal, which is handy for
+// checking geometry.
+func Fixed(ctx context.Context, film *camera.Film, cam *camera.Perspective, scene []shape.Shape) error {
+	return Render(ctx, film, cam, scene, IntegratorFunc(rayColor), nil)
+}
+
+// Render renders the scene into the film, using the integrator to compute the
+// radiance of each camera ray.
+//
+// The film is split into tiles, which are rendered by a pool of GOMAXPROCS
+// workers. If the context is cancelled, rendering stops as soon as possible
+// and the context's error is returned. Whatever was finished by then is still
+// merged into the film, so it holds a partial render. Options that don't fit
+// the film (see Options) are an error, and nothing is rendered.
+func Render(ctx context.Context, film *camera.Film, cam *camera.Perspective, scene []shape.Shape, integrator Integrator, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if err := opts.check(film); err != nil {
+		return err
+	}
+	return renderPass(ctx, film, cam, newSceneData(scene), integrator, opts, samples, 0)
+}
+
+// Progressive renders the scene into the film in repeated passes over the
+// whole film, with samplesPerPass samples per pixel each. After each pass,
+// callback is called with the number of passes done so far and the film,
+// which then holds the average of all samples so far (e.g. for a live
+// preview). Rendering stops when callback returns false, after the given
+// number of passes (0 means no limit), or when the context is cancelled, in
+// which case the context's error is returned. Like Render, it fails before
+// the first pass if the options don't fit the film.
+//
+// Callbacks run between passes, so they can safely read the film.
+func Progressive(ctx context.Context, film *camera.Film, cam *camera.Perspective, scene []shape.Shape, integrator Integrator, opts *Options, passes, samplesPerPass int, callback func(pass int, film *camera.Film) bool) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if err := opts.check(film); err != nil {
+		return err
+	}
+	sd := newSceneData(scene)
+	for pass := 0; passes == 0 || pass < passes; pass++ {
+		if err := renderPass(ctx, film, cam, sd, integrator, opts, samplesPerPass, pass); err != nil {
+			return err
+		}
+		if !callback(pass+1, film) {
+			break
+		}
+	}
+	return nil
+}
+
+// renderPass renders spp samples per pixel into the film, or that many on
+// average if there's a mask. Each pass gets its own random number streams.
+func renderPass(ctx context.Context, film *camera.Film, cam *camera.Perspective, sd *sceneData, integrator Integrator, opts *Options, spp, pass int) error {
 	// Split up film into tiles
 	tiles := util.Partition(len(film.Pixels), tileSize)
+	jobs := make(chan util.Bin)
 	results := make(chan *camera.FilmTile)
 
-	for _, tile := range tiles {
-		go func(offset, size int) {
-			pixels := make([]camera.Pixel, size)
-			rnd := rand.New(rand.NewSource(rand.Int63()))
-
-			for i := range pixels {
-				for s := 0; s < samples; s++ {
-					ray := cam.Ray(film.RandomNDC(i+offset, rnd))
-					dist := rayColor(ray, scene)
-					pixels[i].AddColor(colorspace.CIE1931.Convert(dist))
-				}
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func(stats *metrics.WorkerStats) {
+			defer wg.Done()
+			for tile := range jobs {
+				results <- renderTile(ctx, film, cam, sd, integrator, opts, tile, spp, pass, stats)
+				stats.Tiles.Inc()
+				stats.SampleHeap()
 			}
+		}(metrics.Worker(w))
+	}
 
-			results <- &camera.FilmTile{Pixels: pixels, Offset: offset}
+	go func() {
+		defer close(jobs)
+		for _, tile := range tiles {
+			select {
+			case jobs <- tile:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 
-		}(tile.Offset, tile.Size)
-	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
 
-	for range tiles {
-		film.Merge(<-results)
+	for tile := range results {
+		film.Merge(tile)
 	}
 
-	return nil
+	return ctx.Err()
 }
 
-func rayColor(ray *geo.Ray, scene []shape.Shape) spectrum.Distribution {
-	var tInt = math.Inf(1)
-	var sInt shape.Shape
+// renderTile renders one tile of the film, counting its samples in the
+// worker's stats. If the context is cancelled, the remaining pixels are left
+// black.
+func renderTile(ctx context.Context, film *camera.Film, cam *camera.Perspective, sd *sceneData, integrator Integrator, opts *Options, tile util.Bin, spp, pass int, stats *metrics.WorkerStats) *camera.FilmTile {
+	filmTile := film.NewTile(tile.Offset, tile.Size)
+	aovs := film.AOVs()
+	luminance := false
+	for _, aov := range aovs {
+		luminance = luminance || aov == camera.AOVLuminance
+	}
+	smp := opts.newSampler(film.Width)
+	taken := 0
 
-	for _, shape := range scene {
-		t := shape.Intersect(ray)
-		if t > 0 && t < tInt {
-			tInt = t
-			sInt = shape
+	for i := 0; i < tile.Size; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+		pxIdx := tile.Offset + i
+		px, py := film.RasterCoords(pxIdx)
+		n := spp
+		if opts.Mask != nil {
+			n = opts.Mask.Samples(pxIdx, spp)
+		}
+		for s := 0; s < n; s++ {
+			// passes continue each pixel's sample sequence
+			smp.StartSample(pxIdx, pass*n+s)
+			dx, dy := smp.Get2D()
+			x, y := float64(px)+dx, float64(py)+dy
+			u, v := x/float64(film.Width), y/float64(film.Height)
+			lensU, lensV := smp.Get2D()
+			t := smp.Get1D()
+			ray := cam.LensRay(u, v, t, lensU, lensV)
+			dist := integrator.Radiance(ray, sd.bvh, smp)
+			filmTile.AddSample(x, y, film.Observer.Convert(dist))
+			if luminance {
+				l := colorspace.CIE1931Reflectance.Convert(dist)[1]
+				filmTile.AddAOV(x, y, camera.AOVLuminance, colorspace.Point{l, l, l})
+			}
+			if len(aovs) > 0 {
+				sd.recordAOVs(filmTile, aovs, ray, x, y, smp)
+			}
 		}
+		taken += n
 	}
+	stats.Samples.Add(uint64(taken))
 
-	if !math.IsInf(tInt, 0) {
-		pt := ray.At(tInt)
-		norm := sInt.Normal(pt)
+	return filmTile
+}
+
+func rayColor(ray *geo.Ray, scene *accel.BVH, _ sampler.Sampler) spectrum.Distribution {
+	if hit, found := scene.Intersect(ray); found {
+		norm := hit.Interaction(ray).Normal
 
-		r := spectrum.Red.Scale(norm.X + 1)
-		g := spectrum.Green.Scale(norm.Y + 1)
-		b := spectrum.Blue.Scale(norm.Z + 1)
-		return r.Plus(g.Plus(b)).Scale(0.5)
+		return spectrum.FromRGB((norm.X+1)/2, (norm.Y+1)/2, (norm.Z+1)/2)
 	}
 
+	return sky(ray)
+}
+
+// sky is the radiance of the background: a simple gradient from white at the
+// horizon to blue overhead, both about as bright as a white surface lit by
+// them would be.
+func sky(ray *geo.Ray) *spectrum.Sampled {
 	t := 0.5 * (ray.Dir.Unit().Y + 1.0)
-	return spectrum.Blue.Lerp(&spectrum.ACESIllumD60, t)
+	return skyBlue.Lerp(skyWhite, t)
 }
+
+var (
+	skyBlue  = spectrum.FromRGB(0.5, 0.7, 1)
+	skyWhite = spectrum.ACESIllumD60.Scale(1 / colorspace.CIE1931XYZ.Convert(&spectrum.ACESIllumD60)[1])
+)
@@ -1,13 +1,23 @@
 package render
 
 import (
+	"context"
 	"fmt"
 	"image/png"
+	"math"
 	"os"
 	"testing"
 
+	"github.com/gmhorn/gremlin/archive/pkg/accel"
 	"github.com/gmhorn/gremlin/archive/pkg/camera"
 	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/imageio"
+	"github.com/gmhorn/gremlin/archive/pkg/light"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/gmhorn/gremlin/archive/pkg/metrics"
+	"github.com/gmhorn/gremlin/archive/pkg/sampler"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
 	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
 	"github.com/stretchr/testify/assert"
 )
@@ -16,7 +26,7 @@ func TestFixed(t *testing.T) {
 	film := camera.NewFilm(640, 320)
 	cam := camera.NewPerspective(film.AspectRatio, 75.0)
 
-	err := Fixed(film, cam, nil)
+	err := Fixed(context.Background(), film, cam, nil)
 	assert.NoError(t, err)
 
 	file, err := os.Create("test.png")
@@ -27,6 +37,202 @@ func TestFixed(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestRender_Cancel(t *testing.T) {
+	film := camera.NewFilm(640, 320)
+	cam := camera.NewPerspective(film.AspectRatio, 75.0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := Render(ctx, film, cam, nil, NewPathTracer(8), nil)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// nothing got rendered
+	for i := range film.Pixels {
+		assert.Equal(t, colorspace.Point{}, film.Color(i))
+	}
+}
+
+func TestRender_WorkerStats(t *testing.T) {
+	film := camera.NewFilm(32, 16)
+	cam := camera.NewPerspective(film.AspectRatio, 75.0)
+	before := metrics.Snapshot()
+
+	err := Render(context.Background(), film, cam, nil, NewPathTracer(1), nil)
+	assert.NoError(t, err)
+
+	// every sample and tile was taken by some worker
+	after := metrics.Snapshot()
+	var tiles, taken uint64
+	for id, w := range after.Workers {
+		tiles += w.Tiles
+		taken += w.Samples
+		if id < len(before.Workers) {
+			tiles -= before.Workers[id].Tiles
+			taken -= before.Workers[id].Samples
+		}
+	}
+	assert.Equal(t, uint64((32*16+tileSize-1)/tileSize), tiles)
+	assert.Equal(t, uint64(32*16*samples), taken)
+}
+
+func TestProgressive(t *testing.T) {
+	film := camera.NewFilm(32, 16)
+	cam := camera.NewPerspective(film.AspectRatio, 75.0)
+
+	var seen []int
+	err := Progressive(context.Background(), film, cam, nil, NewPathTracer(4), nil, 3, 2, func(pass int, f *camera.Film) bool {
+		assert.Same(t, film, f)
+		seen = append(seen, pass)
+		for _, px := range f.Pixels {
+			assert.Equal(t, uint64(2*pass), px.Samples)
+		}
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, seen)
+
+	// the film holds the sky, averaged over all passes
+	for i := range film.Pixels {
+		for _, c := range film.Color(i) {
+			assert.Greater(t, c, 0.0)
+		}
+	}
+}
+
+func TestNewMask(t *testing.T) {
+	// left half white, right half black, stretched over a 4x2 film
+	img := imageio.NewRGB(2, 1)
+	img.Set(0, 0, 1, 1, 1)
+	mask := NewMask(img, 4, 2, 0.5)
+	assert.InDeltaSlice(t, []float64{4. / 3, 4. / 3, 2. / 3, 2. / 3, 4. / 3, 4. / 3, 2. / 3, 2. / 3}, []float64(mask), 1e-9)
+	assert.Equal(t, 8, mask.Samples(0, 6))
+	assert.Equal(t, 4, mask.Samples(2, 6))
+
+	// black pixels keep one sample
+	mask = NewMask(img, 4, 2, 0)
+	assert.Equal(t, 1, mask.Samples(2, 6))
+
+	assert.Panics(t, func() { NewMask(imageio.NewRGB(1, 1), 4, 2, 0) })
+	assert.Panics(t, func() { NewMask(img, 4, 2, 2) })
+}
+
+func TestProgressive_Mask(t *testing.T) {
+	film := camera.NewFilm(32, 16)
+	cam := camera.NewPerspective(film.AspectRatio, 75.0)
+	img := imageio.NewRGB(2, 1)
+	img.Set(0, 0, 1, 1, 1)
+	opts := &Options{Mask: NewMask(img, film.Width, film.Height, 0.5)}
+
+	err := Progressive(context.Background(), film, cam, nil, NewPathTracer(4), opts, 2, 3, func(int, *camera.Film) bool {
+		return true
+	})
+	assert.NoError(t, err)
+	for i, px := range film.Pixels {
+		if x, _ := film.RasterCoords(i); x < film.Width/2 {
+			assert.Equal(t, uint64(8), px.Samples)
+		} else {
+			assert.Equal(t, uint64(4), px.Samples)
+		}
+	}
+
+	// a mask of the wrong size is an error, before anything's rendered
+	opts.Mask = make(Mask, 4)
+	film = camera.NewFilm(32, 16)
+	err = Render(context.Background(), film, cam, nil, NewPathTracer(4), opts)
+	assert.EqualError(t, err, "sample mask has 4 pixels, but the film has 512")
+	called := false
+	err = Progressive(context.Background(), film, cam, nil, NewPathTracer(4), opts, 2, 3, func(int, *camera.Film) bool {
+		called = true
+		return true
+	})
+	assert.Error(t, err)
+	assert.False(t, called)
+	for _, px := range film.Pixels {
+		assert.Zero(t, px.Samples)
+	}
+}
+
+func TestProgressive_Stop(t *testing.T) {
+	film := camera.NewFilm(32, 16)
+	cam := camera.NewPerspective(film.AspectRatio, 75.0)
+
+	// unlimited passes, stopped by the callback
+	calls := 0
+	err := Progressive(context.Background(), film, cam, nil, NewPathTracer(4), nil, 0, 1, func(pass int, f *camera.Film) bool {
+		calls++
+		return pass < 4
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, calls)
+
+	// cancelled before the first pass
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = Progressive(ctx, film, cam, nil, NewPathTracer(4), nil, 0, 1, func(int, *camera.Film) bool {
+		t.Fatal("callback called after cancel")
+		return true
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRender_AOVs(t *testing.T) {
+	film := camera.NewFilm(9, 9)
+	film.EnableAOVs(camera.AOVNormal, camera.AOVDepth, camera.AOVAlbedo, camera.AOVObjectID, camera.AOVLuminance)
+	cam := camera.NewPerspective(film.AspectRatio, 30)
+	scene := []shape.Shape{
+		&shape.Sphere{Center: geo.V(0, 0, -5), Radius: 1},
+		&shape.Sphere{Center: geo.V(0, 0, -5), Radius: 1},
+	}
+
+	err := Render(context.Background(), film, cam, scene, NewPathTracer(4), nil)
+	assert.NoError(t, err)
+
+	// the center pixel sees the front of the first sphere...
+	x, y := 4, 4
+	r, g, b := film.AOV(camera.AOVNormal, colorspace.SRGB).At(x, y)
+	assert.InDelta(t, 0, r, 0.05)
+	assert.InDelta(t, 0, g, 0.05)
+	assert.InDelta(t, 1, b, 0.01)
+
+	d, _, _ := film.AOV(camera.AOVDepth, colorspace.SRGB).At(x, y)
+	assert.InDelta(t, 4, d, 0.01)
+
+	// ...which has the default 50% gray material (slightly tinted, since
+	// equal-energy white isn't sRGB white)
+	r, g, b = film.AOV(camera.AOVAlbedo, colorspace.SRGB).At(x, y)
+	for _, c := range []float64{r, g, b} {
+		assert.InDelta(t, 0.5, c, 0.15)
+	}
+
+	id, _, _ := film.AOV(camera.AOVObjectID, colorspace.SRGB).At(x, y)
+	assert.Contains(t, []float64{1, 2}, id)
+
+	// corners miss it
+	id, _, _ = film.AOV(camera.AOVObjectID, colorspace.SRGB).At(0, 0)
+	assert.Equal(t, 0.0, id)
+	d, _, _ = film.AOV(camera.AOVDepth, colorspace.SRGB).At(0, 0)
+	assert.Equal(t, 0.0, d)
+
+	// and see the sky, somewhere between its blue and white
+	l, _, _ := film.AOV(camera.AOVLuminance, colorspace.SRGB).At(0, 0)
+	assert.Greater(t, l, colorspace.CIE1931Reflectance.Convert(skyBlue)[1])
+	assert.Less(t, l, colorspace.CIE1931Reflectance.Convert(skyWhite)[1])
+}
+
+func TestObjectIDs_MotionGroup(t *testing.T) {
+	// shapes moved together by one instance have IDs of their own, which hits
+	// on them find
+	a := &shape.Sphere{Center: geo.V(0, 0, -5), Radius: 1}
+	b := &shape.Sphere{Center: geo.V(3, 0, -5), Radius: 1}
+	motion := geo.NewAnimatedTransform(geo.Identity, 0, geo.Shift(geo.V(0, 1, 0)), 1)
+	sd := newSceneData([]shape.Shape{accel.NewMotionInstance([]shape.Shape{a, b}, motion), &shape.Sphere{Radius: 1}})
+	assert.Len(t, sd.objects, 3)
+
+	hit, found := sd.bvh.Intersect(geo.NewRayAt(geo.V(3, 1, 0), geo.V(0, 0, -1), 1))
+	assert.True(t, found)
+	assert.NotZero(t, sd.objects[objectKey(hit.Shape)])
+}
+
 func TestSomeSpectra(t *testing.T) {
 	redSpec := spectrum.Sample(spectrum.Peak(675, 0.2))
 	greenSpec := spectrum.Sample(spectrum.Peak(540, 0.2))
@@ -42,3 +248,471 @@ func TestSomeSpectra(t *testing.T) {
 
 	fmt.Println(redCol, greenCol, blueCol, whiteCol)
 }
+
+func TestPathTracer_Radiance(t *testing.T) {
+	ground := &shape.Sphere{Center: geo.V(0, -100, 0), Radius: 100}
+	bvh := accel.NewBVH([]shape.Shape{ground})
+	pt := NewPathTracer(8)
+	smp := sampler.NewRandom(0)
+
+	// misses see the sky directly
+	up := geo.NewRay(geo.V(0, 1, 0), geo.V(0, 1, 0))
+	assert.Equal(t, sky(up), pt.Radiance(up, bvh, smp))
+
+	// looking at the ground, we see the sky reflected once (the ground is
+	// convex, so paths can't bounce more than that), dimmed by the albedo
+	down := geo.NewRay(geo.V(0, 1, 0), geo.V(0, -1, 0))
+	maxSky := spectrum.Sample(spectrum.Flat(math.Max(sky(up).Max(), sky(down).Max())))
+	for i := 0; i < 100; i++ {
+		smp.StartSample(0, i)
+		l := spectrum.Sample(pt.Radiance(down, bvh, smp))
+		for j := range l {
+			assert.GreaterOrEqual(t, l[j], 0.0)
+			assert.LessOrEqual(t, l[j], 0.5*maxSky[j]+1e-9)
+		}
+	}
+
+	// no bounces allowed, so no light
+	assert.Equal(t, new(spectrum.Sampled), NewPathTracer(0).Radiance(down, bvh, smp))
+}
+
+// dimSampler records the dimensions a path tracer draws samples from.
+type dimSampler struct {
+	sampler.Sampler
+	dims []int
+}
+
+func (s *dimSampler) Get1D() float64 {
+	s.dims = append(s.dims, s.Dimension())
+	return s.Sampler.Get1D()
+}
+
+func (s *dimSampler) Get2D() (float64, float64) {
+	s.dims = append(s.dims, s.Dimension(), s.Dimension()+1)
+	return s.Sampler.Get2D()
+}
+
+// constSampler returns the same value for each dimension of every sample.
+type constSampler struct {
+	values []float64
+	dim    int
+}
+
+func (s *constSampler) StartSample(pixel, index int) { s.dim = 0 }
+func (s *constSampler) Dimension() int               { return s.dim }
+func (s *constSampler) SetDimension(dim int)         { s.dim = dim }
+
+func (s *constSampler) Get1D() float64 {
+	s.dim++
+	return s.values[(s.dim-1)%len(s.values)]
+}
+
+func (s *constSampler) Get2D() (float64, float64) {
+	return s.Get1D(), s.Get1D()
+}
+
+func TestRender_CameraDimensions(t *testing.T) {
+	// pixel center, lens center, and late in the shutter
+	opts := &Options{NewSampler: func(uint64) sampler.Sampler {
+		return &constSampler{values: []float64{0.5, 0.5, 0.5, 0.5, 0.9}}
+	}}
+
+	film := camera.NewFilm(1, 1)
+	eye := geo.V(1, 2, 3)
+	cam := camera.NewPerspective(1, 30).MoveTo(eye).Shutter(0, 1).Lens(0.5, 10)
+	var rays []*geo.Ray
+	err := Progressive(context.Background(), film, cam, nil, IntegratorFunc(func(ray *geo.Ray, _ *accel.BVH, _ sampler.Sampler) spectrum.Distribution {
+		rays = append(rays, ray)
+		return spectrum.Flat(0)
+	}), opts, 1, 1, func(int, *camera.Film) bool { return true })
+	assert.NoError(t, err)
+
+	if assert.Len(t, rays, 1) {
+		assert.InDelta(t, 0.9, rays[0].Time, 1e-12)
+		assert.InDelta(t, 0, rays[0].Origin.Minus(eye).Len(), 1e-9)
+	}
+}
+
+func TestRender_Dither(t *testing.T) {
+	// every pixel gets the same samples, offset by blue noise
+	film := camera.NewFilm(4, 1)
+	cam := camera.NewPerspective(4, 30)
+	opts := &Options{Dither: true, NewSampler: func(uint64) sampler.Sampler {
+		return &constSampler{values: []float64{0.5}}
+	}}
+	err := Progressive(context.Background(), film, cam, nil, IntegratorFunc(func(_ *geo.Ray, _ *accel.BVH, smp sampler.Sampler) spectrum.Distribution {
+		assert.IsType(t, &sampler.Dithered{}, smp)
+		return spectrum.Flat(smp.Get1D())
+	}), opts, 1, 1, func(int, *camera.Film) bool { return true })
+	assert.NoError(t, err)
+
+	seen := make(map[colorspace.Point]bool)
+	for i := range film.Pixels {
+		seen[film.Color(i)] = true
+	}
+	assert.Len(t, seen, 4)
+}
+
+func TestRender_Variance(t *testing.T) {
+	film := camera.NewFilm(1, 1)
+	film.EnableAOVs(camera.AOVVariance)
+	cam := camera.NewPerspective(1, 30)
+
+	// luminance alternates between 1 and 3, for a sample variance of
+	// n/(n-1), so the variance of the mean is 1/(n-1)
+	calls := 0
+	err := Render(context.Background(), film, cam, nil, IntegratorFunc(func(*geo.Ray, *accel.BVH, sampler.Sampler) spectrum.Distribution {
+		calls++
+		return spectrum.Flat(float64(1 + 2*(calls%2)))
+	}), nil)
+	assert.NoError(t, err)
+
+	v, _, _ := film.AOV(camera.AOVVariance, colorspace.SRGB).At(0, 0)
+	assert.InDelta(t, 1/float64(samples-1), v, 1e-9)
+}
+
+func TestPathTracer_Dimensions(t *testing.T) {
+	// Without lights, light sampling is skipped, but BSDF samples still use
+	// the same dimensions at each bounce.
+	room := &shape.Sphere{Center: geo.V(0, 0, 0), Radius: 10}
+	bvh := accel.NewBVH([]shape.Shape{room})
+	pt := NewPathTracer(5)
+	pt.RRDepth = 100
+	smp := &dimSampler{Sampler: sampler.NewSobol(0)}
+	smp.StartSample(0, 0)
+	smp.SetDimension(cameraDims)
+
+	pt.Radiance(geo.NewRay(geo.V(0, 0, 0), geo.V(0, -1, 0)), bvh, smp)
+	want := []int{}
+	for depth := 0; depth < 5; depth++ {
+		base := cameraDims + depth*bounceDims
+		want = append(want, base+3, base+4)
+	}
+	assert.Equal(t, want, smp.dims)
+
+	// with a light, every bounce uses all its dimensions
+	pt.Lights = []light.Light{light.NewPoint(geo.V(0, 5, 0), spectrum.Flat(1))}
+	pt.RRDepth = 0
+	smp.dims = nil
+	smp.StartSample(0, 1)
+	smp.SetDimension(cameraDims)
+	pt.Radiance(geo.NewRay(geo.V(0, 0, 0), geo.V(0, -1, 0)), bvh, smp)
+	for i, dim := range smp.dims {
+		assert.Equal(t, cameraDims+i, dim)
+	}
+}
+
+func TestPathTracer_DirectLighting(t *testing.T) {
+	// Inside a closed sphere the sky can't be seen, and with one bounce only
+	// the directly sampled light contributes.
+	room := &shape.Sphere{Center: geo.V(0, 0, 0), Radius: 10}
+	bvh := accel.NewBVH([]shape.Shape{room})
+	pt := NewPathTracer(1)
+	pt.Lights = []light.Light{light.NewPoint(geo.V(0, 5, 0), spectrum.Flat(100))}
+	smp := sampler.NewRandom(0)
+
+	down := geo.NewRay(geo.V(0, 0, 0), geo.V(0, -1, 0))
+	l := spectrum.Sample(pt.Radiance(down, bvh, smp))
+	assert.InDelta(t, 0.5/math.Pi*100/(15*15), l[0], 1e-9)
+
+	// block the light
+	blocker := &shape.Sphere{Center: geo.V(0, 2, 0), Radius: 1}
+	bvh = accel.NewBVH([]shape.Shape{room, blocker})
+	l = spectrum.Sample(pt.Radiance(geo.NewRay(geo.V(0, 0, 0), geo.V(1, -1, 0)), bvh, smp))
+	assert.Greater(t, l[0], 0.0)
+	l = spectrum.Sample(pt.Radiance(geo.NewRay(geo.V(0, 5, 5), geo.V(0, -1, 0)), bvh, smp))
+	assert.Greater(t, l[0], 0.0)
+	l = spectrum.Sample(pt.Radiance(geo.NewRay(geo.V(0, 0, 0), geo.V(0, 1, 0)), bvh, smp))
+	assert.Equal(t, 0.0, l[0])
+}
+
+func TestPathTracer_LightHints(t *testing.T) {
+	room := &shape.Sphere{Center: geo.V(0, 0, 0), Radius: 10}
+	bvh := accel.NewBVH([]shape.Shape{room})
+	pt := NewPathTracer(1)
+	pt.Lights = []light.Light{
+		light.NewPoint(geo.V(0, 5, 0), spectrum.Flat(100)),
+		light.NewPoint(geo.V(0, 5, 5), spectrum.Flat(100)),
+	}
+	smp := sampler.NewRandom(0)
+	down := geo.NewRay(geo.V(0, 0, 0), geo.V(0, -1, 0))
+
+	// what each light alone contributes to the floor below
+	a := 0.5 / math.Pi * 100 / (15 * 15)
+	b := 0.5 / math.Pi * 100 * (15 / math.Sqrt(250)) / 250
+
+	// always sampling both is exact
+	pt.LightHints = []LightHint{{Always: true}, {Always: true}}
+	l := spectrum.Sample(pt.Radiance(down, bvh, smp))
+	assert.InDelta(t, a+b, l[0], 1e-9)
+
+	// choosing the first 3 times as often reweights both accordingly
+	pt.LightHints = []LightHint{{Importance: 3}, {}}
+	sum := 0.0
+	for i := 0; i < 1000; i++ {
+		smp.StartSample(0, i)
+		l = spectrum.Sample(pt.Radiance(down, bvh, smp))
+		if math.Abs(l[0]-a*4/3) > 1e-9 {
+			assert.InDelta(t, b*4, l[0], 1e-9)
+		}
+		sum += l[0]
+	}
+	assert.InDelta(t, a+b, sum/1000, 0.05*(a+b))
+
+	// mixing both: the second is always sampled, so the first is always
+	// chosen
+	pt.LightHints = []LightHint{{}, {Always: true}}
+	l = spectrum.Sample(pt.Radiance(down, bvh, smp))
+	assert.InDelta(t, a+b, l[0], 1e-9)
+}
+
+func TestPathTracer_LightSelection(t *testing.T) {
+	pt := NewPathTracer(1)
+	pt.Lights = []light.Light{
+		light.NewPoint(geo.V(0, 5, 0), spectrum.Flat(1)),  // fill
+		light.NewPoint(geo.V(0, 5, 5), spectrum.Flat(3)),  // key
+		light.NewPoint(geo.V(0, 5, -5), spectrum.Flat(9)), // always sampled
+		light.NewDirectional(geo.V(0, -1, 0), spectrum.Flat(1)),
+	}
+	pt.LightHints = []LightHint{{Importance: 2}, {}, {Always: true}}
+
+	// The fill light is a third as powerful as the key light, but twice as
+	// important. The directional light has no SceneRadius, so no power, and
+	// gets the mean of the others'.
+	counts := make([]int, len(pt.Lights))
+	sel := pt.selection()
+	n := 10000
+	for i := 0; i < n; i++ {
+		counts[sel.choose((float64(i)+0.5)/float64(n))]++
+	}
+	assert.InDelta(t, 2.0/7, float64(counts[0])/float64(n), 1e-3)
+	assert.InDelta(t, 3.0/7, float64(counts[1])/float64(n), 1e-3)
+	assert.Equal(t, 0, counts[2])
+	assert.InDelta(t, 2.0/7, float64(counts[3])/float64(n), 1e-3)
+
+	for i, want := range []float64{2.0 / 7, 3.0 / 7, 1, 2.0 / 7} {
+		assert.InDelta(t, want, sel.prob(i), 1e-12, "light %d", i)
+	}
+}
+
+func TestPathTracer_MaxShadowDist(t *testing.T) {
+	// A directional light shining into a closed room is blocked by its
+	// ceiling, unless shadow rays stop short of it.
+	room := &shape.Sphere{Center: geo.V(0, 0, 0), Radius: 10}
+	bvh := accel.NewBVH([]shape.Shape{room})
+	pt := NewPathTracer(1)
+	pt.Lights = []light.Light{light.NewDirectional(geo.V(0, -1, 0), spectrum.Flat(1))}
+	smp := sampler.NewRandom(0)
+
+	down := geo.NewRay(geo.V(0, 0, 0), geo.V(0, -1, 0))
+	l := spectrum.Sample(pt.Radiance(down, bvh, smp))
+	assert.Equal(t, 0.0, l[0])
+
+	pt.MaxShadowDist = 15
+	l = spectrum.Sample(pt.Radiance(down, bvh, smp))
+	assert.InDelta(t, 0.5/math.Pi, l[0], 1e-9)
+}
+
+func TestPathTracer_Regularize(t *testing.T) {
+	// A diffuse ball in a mirrored room, lit by a point light. Light reflected
+	// by the walls onto the ball can't be found: walls reached by bouncing
+	// off the ball are specular, and the path ends there. Unless they're
+	// regularized, and the light can be sampled from them.
+	room := &shape.Sphere{Center: geo.V(0, 0, 0), Radius: 10, Material: material.NewMirror(spectrum.Flat(1))}
+	ball := &shape.Sphere{Center: geo.V(0, 0, 0), Radius: 1}
+	bvh := accel.NewBVH([]shape.Shape{room, ball})
+	pt := NewPathTracer(2)
+	pt.Lights = []light.Light{light.NewPoint(geo.V(0, 0, 5), spectrum.Flat(1))}
+	ray := geo.NewRay(geo.V(0, 0, 5), geo.V(0, 0, -1))
+
+	smp := sampler.NewSobol(0)
+	plain, regularized := 0.0, 0.0
+	for i := 0; i < 256; i++ {
+		smp.StartSample(0, i)
+		pt.Regularize = 0
+		a := spectrum.Sample(pt.Radiance(ray, bvh, smp))[0]
+		smp.StartSample(0, i)
+		pt.Regularize = 0.5
+		b := spectrum.Sample(pt.Radiance(ray, bvh, smp))[0]
+
+		// the direct light is the same
+		assert.GreaterOrEqual(t, b, a-1e-12)
+		plain += a
+		regularized += b
+	}
+	direct := 0.5 / math.Pi / 16
+	assert.InDelta(t, direct, plain/256, 1e-9)
+	assert.Greater(t, regularized/256, 1.1*direct)
+}
+
+func TestPathTracer_NestedDielectrics(t *testing.T) {
+	// A lower-priority sphere inside a glass ball is hidden by it, so the
+	// ball looks the same with or without it
+	glass := material.NewDielectric(spectrum.Flat(1.5))
+	glass.Priority = 1
+	ball := &shape.Sphere{Center: geo.V(0, 0, 0), Radius: 2, Material: glass}
+	inner := &shape.Sphere{Center: geo.V(0, 0, 0), Radius: 1, Material: material.NewDielectric(spectrum.Flat(1.2))}
+
+	pt := NewPathTracer(8)
+	with := accel.NewBVH([]shape.Shape{ball, inner})
+	without := accel.NewBVH([]shape.Shape{ball})
+	ray := geo.NewRay(geo.V(0, 0, 5), geo.V(0, 0, -1))
+	smp := sampler.NewRandom(0)
+	for i := 0; i < 20; i++ {
+		smp.StartSample(0, i)
+		a := spectrum.Sample(pt.Radiance(ray, with, smp))
+		smp.StartSample(0, i)
+		b := spectrum.Sample(pt.Radiance(ray, without, smp))
+		assert.InDelta(t, b[0], a[0], 1e-9)
+	}
+}
+
+func TestPathTracer_Absorption(t *testing.T) {
+	// A ray straight through an absorbing ball that doesn't refract keeps
+	// exp(-absorption * 2 * radius) of the background, give or take the ray
+	// offsets
+	clear := material.NewDielectric(spectrum.Flat(1))
+	clear.Absorption = spectrum.Sample(spectrum.Flat(0.5))
+	ball := &shape.Sphere{Center: geo.V(0, 0, 0), Radius: 1, Material: clear}
+
+	pt := NewPathTracer(8)
+	ray := geo.NewRay(geo.V(0, 0, 5), geo.V(0, 0, -1))
+	smp := sampler.NewRandom(0)
+	smp.StartSample(0, 0)
+	through := spectrum.Sample(pt.Radiance(ray, accel.NewBVH([]shape.Shape{ball}), smp))
+	smp.StartSample(0, 0)
+	background := spectrum.Sample(pt.Radiance(ray, accel.NewBVH(nil), smp))
+	assert.InEpsilon(t, math.Exp(-1)*background[0], through[0], 1e-3)
+}
+
+func TestAmbientOcclusion(t *testing.T) {
+	ground := &shape.Sphere{Center: geo.V(0, -100, 0), Radius: 100}
+	ball := &shape.Sphere{Center: geo.V(0, 1, 0), Radius: 1}
+	bvh := accel.NewBVH([]shape.Shape{ground, ball})
+	smp := sampler.NewRandom(0)
+
+	average := func(ao *AmbientOcclusion, ray *geo.Ray) float64 {
+		sum := 0.0
+		for i := 0; i < 1000; i++ {
+			smp.StartSample(0, i)
+			sum += spectrum.Sample(ao.Radiance(ray, bvh, smp))[0]
+		}
+		return sum / 1000
+	}
+
+	// The ground next to the ball is partly occluded by it, but not if the
+	// radius is too small to reach it
+	contact := geo.NewRay(geo.V(1.1, 1, 0), geo.V(0, -1, 0))
+	assert.Less(t, average(NewAmbientOcclusion(math.Inf(1)), contact), 0.9)
+	assert.InDelta(t, 1.0, average(NewAmbientOcclusion(0.01), contact), 1e-9)
+
+	// Away from the ball, nothing is occluded
+	open := geo.NewRay(geo.V(50, 1, 0), geo.V(0, -1, 0))
+	assert.InDelta(t, 1.0, average(NewAmbientOcclusion(math.Inf(1)), open), 1e-9)
+
+	miss := geo.NewRay(geo.V(0, 5, 0), geo.V(0, 1, 0))
+	assert.Equal(t, 1.0, spectrum.Sample(NewAmbientOcclusion(1).Radiance(miss, bvh, smp))[0])
+}
+
+func TestAmbientOcclusion_Render(t *testing.T) {
+	ground := &shape.Sphere{Center: geo.V(0, -100, 0), Radius: 100}
+	ball := &shape.Sphere{Center: geo.V(0, 1, 0), Radius: 1}
+	scene := []shape.Shape{ground, ball}
+
+	// looking down at 45 degrees onto the target
+	luminance := func(target geo.Vec, scene []shape.Shape) colorspace.Point {
+		film := camera.NewFilm(1, 1)
+		cam := camera.NewPerspective(1, 1).MoveTo(target.Plus(geo.V(0, 5, 5))).PointAt(target)
+		assert.NoError(t, Render(context.Background(), film, cam, scene, NewAmbientOcclusion(math.Inf(1)), nil))
+		return film.Color(0)
+	}
+
+	// open ground is as bright as white under the white sky
+	open := luminance(geo.V(50, 0, 0), scene)
+	assert.InDelta(t, 1, open[1], 1e-9)
+
+	// next to the ball, it's darker, but not black
+	contact := luminance(geo.V(1.1, 0, 0), scene)
+	assert.Less(t, contact[1], 0.9)
+	assert.Greater(t, contact[1], 0.1)
+
+	// and fully enclosed, it is black, not NaN
+	room := &shape.Sphere{Center: geo.V(0, 0, 0), Radius: 20}
+	closed := luminance(geo.V(0, -5, -5), []shape.Shape{room})
+	assert.Equal(t, colorspace.Point{}, closed)
+}
+
+func TestPathTracer_Environment(t *testing.T) {
+	img := imageio.NewRGB(8, 4)
+	for i := range img.Pix {
+		img.Pix[i] = 1
+	}
+	env := light.NewEnvironment(img, 1)
+	pt := NewPathTracer(4)
+	pt.Lights = []light.Light{env}
+	smp := sampler.NewRandom(0)
+
+	// camera rays see the environment
+	up := geo.NewRay(geo.V(0, 1, 0), geo.V(0, 1, 0))
+	assert.InDelta(t, 1, spectrum.Sample(pt.Radiance(up, accel.NewBVH(nil), smp))[0], 1e-12)
+
+	// a diffuse plane under a uniform environment reflects its albedo
+	ground := &shape.Sphere{Center: geo.V(0, -1000, 0), Radius: 1000}
+	bvh := accel.NewBVH([]shape.Shape{ground})
+	down := geo.NewRay(geo.V(0, 1, 0), geo.V(0, -1, 0))
+	sum := 0.0
+	n := 20000
+	for i := 0; i < n; i++ {
+		smp.StartSample(0, i)
+		sum += spectrum.Sample(pt.Radiance(down, bvh, smp))[0]
+	}
+	assert.InDelta(t, 0.5, sum/float64(n), 0.02)
+}
+
+func TestPathTracer_EnvironmentMIS(t *testing.T) {
+	// A polished metal floor under a white environment with a small, bright
+	// spot: light samples rarely land in its narrow lobe, and BSDF samples
+	// rarely find the spot, so either alone is noisy
+	img := imageio.NewRGB(64, 32)
+	for i := range img.Pix {
+		img.Pix[i] = 1
+	}
+	for y := 5; y < 7; y++ {
+		for x := 30; x < 32; x++ {
+			img.Set(x, y, 50, 50, 50)
+		}
+	}
+	env := light.NewEnvironment(img, 1)
+	metal := material.NewMicrofacet(spectrum.Flat(0.8), 0.05, 1)
+	ground := &shape.Sphere{Center: geo.V(0, -1000, 0), Radius: 1000, Material: metal}
+	bvh := accel.NewBVH([]shape.Shape{ground})
+	pt := NewPathTracer(1)
+	pt.Lights = []light.Light{env}
+	smp := sampler.NewRandom(0)
+	frame := geo.FrameFromNormal(geo.YAxis)
+	wo := frame.ToLocal(geo.V(0, 1, -1).Unit())
+
+	// the reference only samples the BSDF, which is fine for the white
+	// background the floor reflects
+	n := 50000
+	ref := 0.0
+	for i := 0; i < n; i++ {
+		smp.StartSample(0, i)
+		u1, u2 := smp.Get2D()
+		if s, ok := metal.Sample(wo, u1, u2); ok {
+			ref += s.F[0] * geo.AbsCosTheta(s.Wi) / s.PDF * env.Le(frame.ToWorld(s.Wi))[0]
+		}
+	}
+	ref /= float64(n)
+
+	ray := geo.NewRay(geo.V(0, 1, 0), geo.V(0, -1, 1))
+	sum, max := 0.0, 0.0
+	for i := 0; i < n; i++ {
+		smp.StartSample(1, i)
+		l := spectrum.Sample(pt.Radiance(ray, bvh, smp))[0]
+		sum += l
+		max = math.Max(max, l)
+	}
+	assert.InEpsilon(t, ref, sum/float64(n), 0.01)
+	assert.Less(t, max, 2.0)
+}
@@ -0,0 +1,139 @@
+package sample
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+)
+
+// meshTries is how many candidates per point that would fit MeshPoissonDisk
+// tries.
+const meshTries = 30
+
+// SurfacePoint is a point scattered on a mesh: where it is, the (shading)
+// normal there and the face it's on.
+type SurfacePoint struct {
+	Point  geo.Vec
+	Normal geo.Unit
+	Face   int
+}
+
+// MeshJittered returns n points on the mesh's surface, spread by area: the
+// faces are laid end to end along [0, 1) with lengths in proportion to their
+// areas, which is split into n equal strata with a random point in each. Every
+// part of the surface gets its share, as with Jittered.
+func MeshJittered(rng *rand.Rand, m *shape.Mesh, n int) []SurfacePoint {
+	s := newSurface(m)
+	points := make([]SurfacePoint, 0, n)
+	if s.area == 0 {
+		return points
+	}
+	for i := 0; i < n; i++ {
+		points = append(points, s.point((float64(i)+rng.Float64())/float64(n), rng.Float64()))
+	}
+	return points
+}
+
+// MeshPoissonDisk returns points on the mesh's surface no two closer than r,
+// measured in a straight line, so points on either side of a thin wall may
+// be. It throws darts: candidates are spread uniformly by area, and kept
+// unless they're too close to one kept already, which a grid of cells finds
+// quickly as in PoissonDisk.
+func MeshPoissonDisk(rng *rand.Rand, m *shape.Mesh, r float64) []SurfacePoint {
+	if r <= 0 {
+		panic("Poisson disk radius must be positive")
+	}
+	s := newSurface(m)
+	if s.area == 0 {
+		return nil
+	}
+
+	// about as many candidates per point as would fit packed on a plane
+	candidates := int(math.Ceil(meshTries * s.area / (math.Pi * r * r / 4)))
+
+	// cells of size r, so neighbors are at most one cell away
+	grid := make(map[[3]int][]int)
+	cellOf := func(p geo.Vec) [3]int {
+		return [3]int{int(math.Floor(p.X / r)), int(math.Floor(p.Y / r)), int(math.Floor(p.Z / r))}
+	}
+
+	var points []SurfacePoint
+	for c := 0; c < candidates; c++ {
+		p := s.point(rng.Float64(), rng.Float64())
+		cell := cellOf(p.Point)
+		if !isolated(p.Point, cell, grid, points, r) {
+			continue
+		}
+		grid[cell] = append(grid[cell], len(points))
+		points = append(points, p)
+	}
+	return points
+}
+
+// surface picks points on a mesh uniformly by area.
+type surface struct {
+	faces []shape.Shape
+	// cdf[i] is the fraction of the area in faces before i
+	cdf  []float64
+	area float64
+}
+
+func newSurface(m *shape.Mesh) *surface {
+	s := &surface{faces: m.Faces(), cdf: make([]float64, m.NumFaces()+1)}
+	for i := range s.faces {
+		p := s.corners(i)
+		s.area += p[1].Minus(p[0]).Cross(p[2].Minus(p[0])).Len() / 2
+		s.cdf[i+1] = s.area
+	}
+	for i := range s.cdf {
+		s.cdf[i] /= s.area
+	}
+	return s
+}
+
+func (s *surface) corners(face int) [3]geo.Vec {
+	f := s.faces[face].(*shape.MeshFace)
+	i0, i1, i2 := f.Vertices()
+	return [3]geo.Vec{f.Mesh.Positions[i0], f.Mesh.Positions[i1], f.Mesh.Positions[i2]}
+}
+
+// point returns the point at u along the faces laid end to end, using what's
+// left of u within its face and v to place it in the face.
+func (s *surface) point(u, v float64) SurfacePoint {
+	face := sort.SearchFloat64s(s.cdf, u) - 1
+	if face < 0 {
+		face = 0
+	}
+	for face < len(s.faces)-1 && s.cdf[face+1] <= u {
+		face++
+	}
+	t := 0.0
+	if width := s.cdf[face+1] - s.cdf[face]; width > 0 {
+		t = math.Max(0, math.Min(1, (u-s.cdf[face])/width))
+	}
+
+	// uniform in the triangle
+	st := math.Sqrt(t)
+	p := s.corners(face)
+	pos := p[0].Scale(1 - st).Plus(p[1].Scale(st * (1 - v))).Plus(p[2].Scale(st * v))
+	return SurfacePoint{Point: pos, Normal: s.faces[face].Interaction(pos).Normal, Face: face}
+}
+
+// isolated reports whether no point in the grid is within r of p.
+func isolated(p geo.Vec, cell [3]int, grid map[[3]int][]int, points []SurfacePoint, r float64) bool {
+	for dx := -1; dx <= 1; dx++ {
+		for dy := -1; dy <= 1; dy++ {
+			for dz := -1; dz <= 1; dz++ {
+				for _, i := range grid[[3]int{cell[0] + dx, cell[1] + dy, cell[2] + dz}] {
+					if points[i].Point.Minus(p).LenSquared() < r*r {
+						return false
+					}
+				}
+			}
+		}
+	}
+	return true
+}
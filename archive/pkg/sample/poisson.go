@@ -0,0 +1,87 @@
+package sample
+
+import (
+	"math"
+	"math/rand"
+)
+
+// poissonTries is how many candidates are tried around a point before giving
+// up on it.
+const poissonTries = 30
+
+// PoissonDisk returns random points in the rectangle from (0, 0) to (width,
+// height), no two closer than r, and packed so no more would fit. Unlike
+// uniform random points they don't clump, which looks natural for things like
+// trees and rocks.
+//
+// It uses Bridson's algorithm: new points are tried in the ring between r and
+// 2r around existing ones, with a grid of cells small enough to hold one
+// point each to find neighbors quickly.
+//
+// An empty rectangle has no points. Panics if r isn't positive.
+//
+// "Fast Poisson Disk Sampling in Arbitrary Dimensions", Bridson, 2007
+func PoissonDisk(rng *rand.Rand, width, height, r float64) [][2]float64 {
+	if r <= 0 {
+		panic("Poisson disk radius must be positive")
+	}
+	if width <= 0 || height <= 0 {
+		return nil
+	}
+
+	cell := r / math.Sqrt2
+	nx, ny := int(math.Ceil(width/cell)), int(math.Ceil(height/cell))
+	// index+1 of the point in each cell, or 0 if empty
+	grid := make([]int, nx*ny)
+	gridIndex := func(p [2]float64) (int, int) {
+		return int(math.Min(p[0]/cell, float64(nx-1))), int(math.Min(p[1]/cell, float64(ny-1)))
+	}
+
+	var points [][2]float64
+	var active []int
+	add := func(p [2]float64) {
+		points = append(points, p)
+		x, y := gridIndex(p)
+		grid[y*nx+x] = len(points)
+		active = append(active, len(points)-1)
+	}
+	fits := func(p [2]float64) bool {
+		if p[0] < 0 || p[0] >= width || p[1] < 0 || p[1] >= height {
+			return false
+		}
+		x, y := gridIndex(p)
+		for j := y - 2; j <= y+2; j++ {
+			for i := x - 2; i <= x+2; i++ {
+				if i < 0 || i >= nx || j < 0 || j >= ny || grid[j*nx+i] == 0 {
+					continue
+				}
+				q := points[grid[j*nx+i]-1]
+				if dx, dy := p[0]-q[0], p[1]-q[1]; dx*dx+dy*dy < r*r {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	add([2]float64{rng.Float64() * width, rng.Float64() * height})
+	for len(active) > 0 {
+		k := rng.Intn(len(active))
+		p := points[active[k]]
+		found := false
+		for try := 0; try < poissonTries && !found; try++ {
+			angle := 2 * math.Pi * rng.Float64()
+			dist := r * (1 + rng.Float64())
+			q := [2]float64{p[0] + dist*math.Cos(angle), p[1] + dist*math.Sin(angle)}
+			if fits(q) {
+				add(q)
+				found = true
+			}
+		}
+		if !found {
+			active[k] = active[len(active)-1]
+			active = active[:len(active)-1]
+		}
+	}
+	return points
+}
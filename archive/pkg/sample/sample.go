@@ -0,0 +1,27 @@
+// Package sample scatters points evenly but randomly, over rectangles and over
+// the surfaces of meshes: for placing instances (see the scenes package),
+// stamping textures and the like. Unlike the samplers in pkg/sampler, which
+// feed the renderer one sample at a time, these return whole point sets.
+//
+// Randomness comes from the caller's generator, so seeding it (e.g. with
+// util.NewRand) makes the results repeatable.
+package sample
+
+import "math/rand"
+
+// Jittered returns nx*ny points in the rectangle from (0, 0) to (width,
+// height): one at a random position in each cell of an nx by ny grid. They're
+// cheaper than a Poisson disk and just as free of big gaps, though neighbors
+// can come close.
+func Jittered(rng *rand.Rand, nx, ny int, width, height float64) [][2]float64 {
+	points := make([][2]float64, 0, nx*ny)
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			points = append(points, [2]float64{
+				(float64(i) + rng.Float64()) * width / float64(nx),
+				(float64(j) + rng.Float64()) * height / float64(ny),
+			})
+		}
+	}
+	return points
+}
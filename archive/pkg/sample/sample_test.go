@@ -0,0 +1,88 @@
+package sample
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+	"github.com/gmhorn/gremlin/archive/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoissonDisk(t *testing.T) {
+	points := PoissonDisk(util.NewRand(1), 10, 5, 0.5)
+
+	// a 10x5 area fits roughly 50/(0.5^2 * 1.5) points at that spacing
+	assert.Greater(t, len(points), 100)
+	for i, p := range points {
+		assert.True(t, p[0] >= 0 && p[0] < 10 && p[1] >= 0 && p[1] < 5, "%v", p)
+		for _, q := range points[:i] {
+			assert.GreaterOrEqual(t, math.Hypot(p[0]-q[0], p[1]-q[1]), 0.5)
+		}
+	}
+
+	assert.Equal(t, points, PoissonDisk(util.NewRand(1), 10, 5, 0.5))
+	assert.NotEqual(t, points, PoissonDisk(util.NewRand(2), 10, 5, 0.5))
+
+	// empty rectangles have no points
+	assert.Empty(t, PoissonDisk(util.NewRand(1), 0, 5, 0.5))
+	assert.Empty(t, PoissonDisk(util.NewRand(1), 10, 0, 0.5))
+
+	assert.Panics(t, func() { PoissonDisk(util.NewRand(1), 10, 5, 0) })
+}
+
+func TestJittered(t *testing.T) {
+	points := Jittered(util.NewRand(1), 4, 2, 8, 1)
+	assert.Len(t, points, 8)
+	for i, p := range points {
+		// one in each 2x0.5 cell, row by row
+		assert.Equal(t, i%4, int(p[0]/2))
+		assert.Equal(t, i/4, int(p[1]/0.5))
+	}
+}
+
+// testMesh is a 1x1 square and a 2x2 square beside it, made of triangles of
+// different sizes.
+func testMesh() *shape.Mesh {
+	return shape.NewMesh([]geo.Vec{
+		geo.V(0, 0, 0), geo.V(1, 0, 0), geo.V(1, 0, 1), geo.V(0, 0, 1),
+		geo.V(2, 0, 0), geo.V(4, 0, 0), geo.V(4, 0, 2), geo.V(2, 0, 2),
+	}, nil, nil, []int{0, 2, 1, 0, 3, 2, 4, 6, 5, 4, 7, 6})
+}
+
+func TestMeshJittered(t *testing.T) {
+	points := MeshJittered(util.NewRand(1), testMesh(), 1000)
+	assert.Len(t, points, 1000)
+
+	// spread by area: the big square has four times the area of the small one
+	big := 0
+	for _, p := range points {
+		assert.Zero(t, p.Point.Y)
+		assert.Equal(t, geo.YAxis, p.Normal)
+		if p.Point.X >= 2 {
+			big++
+			assert.GreaterOrEqual(t, p.Face, 2)
+		}
+	}
+	assert.Equal(t, 800, big)
+}
+
+func TestMeshPoissonDisk(t *testing.T) {
+	points := MeshPoissonDisk(util.NewRand(1), testMesh(), 0.2)
+
+	// both squares are covered, and nothing's too close
+	big := 0
+	for i, p := range points {
+		if p.Point.X >= 2 {
+			big++
+		}
+		for _, q := range points[:i] {
+			assert.GreaterOrEqual(t, p.Point.Minus(q.Point).Len(), 0.2)
+		}
+	}
+	assert.Greater(t, len(points)-big, 10)
+	assert.Greater(t, big, 3*(len(points)-big))
+
+	assert.Panics(t, func() { MeshPoissonDisk(util.NewRand(1), testMesh(), 0) })
+}
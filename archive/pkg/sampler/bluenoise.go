@@ -0,0 +1,205 @@
+package sampler
+
+import (
+	"math"
+	"sync"
+
+	"github.com/gmhorn/gremlin/archive/pkg/util"
+)
+
+// blueNoiseSize is the width and height of the blue-noise tile.
+const blueNoiseSize = 64
+
+// blueNoiseSigma is the width, in pixels, of the Gaussian filter the
+// void-and-cluster method uses to find voids and clusters.
+const blueNoiseSigma = 1.5
+
+var (
+	blueNoiseOnce sync.Once
+	blueNoiseTile []float64
+)
+
+// blueNoise returns a tile of blue noise: each of its values is a distinct
+// multiple of 1/blueNoiseSize^2 (offset by half of that), so they're uniformly
+// distributed, and they're laid out so that similar values are spread apart,
+// leaving it with no low frequencies. It tiles seamlessly, and is made the
+// first time it's needed.
+func blueNoise() []float64 {
+	blueNoiseOnce.Do(func() {
+		blueNoiseTile = voidAndCluster(blueNoiseSize, blueNoiseSigma, 0x5eed)
+	})
+	return blueNoiseTile
+}
+
+// voidAndCluster makes an n by n tile of blue noise, with Ulichney's
+// void-and-cluster method: starting from an evenly spread pattern of points,
+// points are ranked by removing them from the tightest clusters, and then
+// adding them to the largest voids, until every pixel has a rank.
+//
+// Ulichney, "The void-and-cluster method for dither array generation", 1993.
+func voidAndCluster(n int, sigma float64, seed uint64) []float64 {
+	size := n * n
+
+	// the Gaussian filter, for every (toroidal) offset
+	kernel := make([]float64, size)
+	for dy := 0; dy < n; dy++ {
+		for dx := 0; dx < n; dx++ {
+			x, y := float64(dx), float64(dy)
+			if dx > n/2 {
+				x = float64(n - dx)
+			}
+			if dy > n/2 {
+				y = float64(n - dy)
+			}
+			kernel[dy*n+dx] = math.Exp(-(x*x + y*y) / (2 * sigma * sigma))
+		}
+	}
+
+	// pattern is a set of points, with each pixel's filtered density of them
+	type pattern struct {
+		on     []bool
+		energy []float64
+	}
+	newPattern := func() *pattern {
+		return &pattern{on: make([]bool, size), energy: make([]float64, size)}
+	}
+	toggle := func(p *pattern, i int) {
+		p.on[i] = !p.on[i]
+		sign := 1.0
+		if !p.on[i] {
+			sign = -1
+		}
+		ix, iy := i%n, i/n
+		for j := range p.energy {
+			dx, dy := (j%n-ix+n)%n, (j/n-iy+n)%n
+			p.energy[j] += sign * kernel[dy*n+dx]
+		}
+	}
+	// tightest returns the point with the most others around it, and
+	// largest the empty pixel with the fewest
+	tightest := func(p *pattern) int {
+		best := -1
+		for i, on := range p.on {
+			if on && (best < 0 || p.energy[i] > p.energy[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	largest := func(p *pattern) int {
+		best := -1
+		for i, on := range p.on {
+			if !on && (best < 0 || p.energy[i] < p.energy[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+
+	// a tenth of the pixels at random, then moved from clusters to voids
+	// until they're evenly spread
+	initial := newPattern()
+	ones := size / 10
+	rnd := util.NewRand(seed)
+	for _, i := range rnd.Perm(size)[:ones] {
+		toggle(initial, i)
+	}
+	for {
+		cluster := tightest(initial)
+		toggle(initial, cluster)
+		void := largest(initial)
+		toggle(initial, void)
+		if void == cluster {
+			break
+		}
+	}
+
+	rank := make([]int, size)
+	p := newPattern()
+	for i, on := range initial.on {
+		if on {
+			toggle(p, i)
+		}
+	}
+	for r := ones - 1; r >= 0; r-- {
+		i := tightest(p)
+		toggle(p, i)
+		rank[i] = r
+	}
+
+	p = initial
+	for r := ones; r < size; r++ {
+		i := largest(p)
+		toggle(p, i)
+		rank[i] = r
+	}
+
+	tile := make([]float64, size)
+	for i, r := range rank {
+		tile[i] = (float64(r) + 0.5) / float64(size)
+	}
+	return tile
+}
+
+// Dithered wraps a sampler to decorrelate pixels with blue noise instead of
+// at random. Every pixel gets the same sequence of samples, offset (modulo 1)
+// by the value of a tiled blue-noise texture at the pixel, so neighboring
+// pixels get samples that are as different as possible. Each dimension uses
+// the tile shifted by a different amount.
+//
+// It doesn't make any one pixel less noisy, but the error that's left is
+// pushed to high frequencies: it looks like fine grain rather than blotches,
+// which eyes and denoisers both deal with better.
+//
+// Pixels must be keyed by their index in an image Width pixels wide, as
+// render keys them.
+//
+// Georgiev and Fajardo, "Blue-noise Dithered Sampling", 2016.
+// https://www.arnoldrenderer.com/research/dither_abstract.pdf
+type Dithered struct {
+	Sampler Sampler
+	Width   int
+
+	x, y int
+}
+
+// NewDithered wraps the sampler for an image of the given width.
+func NewDithered(s Sampler, width int) *Dithered {
+	return &Dithered{Sampler: s, Width: width}
+}
+
+func (d *Dithered) StartSample(pixel, index int) {
+	d.x, d.y = pixel%d.Width, pixel/d.Width
+	d.Sampler.StartSample(0, index)
+}
+
+func (d *Dithered) Get1D() float64 {
+	dim := d.Sampler.Dimension()
+	return d.offset(d.Sampler.Get1D(), dim)
+}
+
+func (d *Dithered) Get2D() (float64, float64) {
+	dim := d.Sampler.Dimension()
+	u, v := d.Sampler.Get2D()
+	return d.offset(u, dim), d.offset(v, dim+1)
+}
+
+func (d *Dithered) Dimension() int {
+	return d.Sampler.Dimension()
+}
+
+func (d *Dithered) SetDimension(dim int) {
+	d.Sampler.SetDimension(dim)
+}
+
+// offset returns v offset by the pixel's blue noise for the dimension.
+func (d *Dithered) offset(v float64, dim int) float64 {
+	shift := util.Hash(uint64(dim), 0xd17e)
+	x := (d.x + int(shift%blueNoiseSize)) % blueNoiseSize
+	y := (d.y + int(shift>>32%blueNoiseSize)) % blueNoiseSize
+	v += blueNoise()[y*blueNoiseSize+x]
+	if v >= 1 {
+		v--
+	}
+	return v
+}
@@ -0,0 +1,77 @@
+package sampler
+
+// haltonDims is how many dimensions the Halton sampler covers; higher ones are
+// padded (see Sampler). Halton dimensions with large prime bases need a lot
+// of samples before they're well distributed anyway.
+const haltonDims = 64
+
+// primes are the bases of the Halton sequence's dimensions.
+var primes = func() []int {
+	var primes []int
+	for n := 2; len(primes) < haltonDims; n++ {
+		prime := true
+		for _, p := range primes {
+			if n%p == 0 {
+				prime = false
+				break
+			}
+		}
+		if prime {
+			primes = append(primes, n)
+		}
+	}
+	return primes
+}()
+
+// Halton is a sampler for the Halton low-discrepancy sequence, whose
+// dimension d is the radical inverse of the sample index in the d-th prime
+// base. Each pixel gets its own random rotation (Cranley-Patterson) of every
+// dimension, so pixels aren't correlated with each other.
+//
+// https://www.pbr-book.org/3ed-2018/Sampling_and_Reconstruction/The_Halton_Sampler
+type Halton struct {
+	state
+}
+
+// NewHalton creates a Halton sampler.
+func NewHalton(seed uint64) *Halton {
+	return &Halton{state{seed: seed}}
+}
+
+func (s *Halton) Get1D() float64 {
+	v := s.value(s.dim)
+	s.dim++
+	return v
+}
+
+func (s *Halton) Get2D() (float64, float64) {
+	return s.Get1D(), s.Get1D()
+}
+
+func (s *Halton) value(dim int) float64 {
+	base, index := primes[dim%2], s.index
+	if dim < haltonDims {
+		base = primes[dim]
+	} else {
+		index = s.paddedIndex(dim)
+	}
+
+	v := radicalInverse(base, index) + toFloat(s.hash(uint64(dim)))
+	if v >= 1 {
+		v--
+	}
+	return v
+}
+
+// radicalInverse mirrors the digits of n in the given base about the decimal
+// point, e.g. 6 = 110 in base 2 becomes 0.011 = 0.375.
+func radicalInverse(base, n int) float64 {
+	inv := 1 / float64(base)
+	scale := inv
+	v := 0.0
+	for ; n > 0; n /= base {
+		v += float64(n%base) * scale
+		scale *= inv
+	}
+	return v
+}
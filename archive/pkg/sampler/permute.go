@@ -0,0 +1,44 @@
+package sampler
+
+// permute returns the element at position i of a pseudo-random permutation of
+// [0, n) determined by key. It doesn't need to store the permutation, so it
+// works for any n and key.
+//
+// From Kensler, "Correlated Multi-Jittered Sampling", Pixar technical memo
+// 13-01, 2013.
+// https://graphics.pixar.com/library/MultiJitteredSampling/paper.pdf
+func permute(i, n int, key uint32) int {
+	l := uint32(n)
+	w := l - 1
+	w |= w >> 1
+	w |= w >> 2
+	w |= w >> 4
+	w |= w >> 8
+	w |= w >> 16
+
+	v := uint32(i)
+	for {
+		v ^= key
+		v *= 0xe170893d
+		v ^= key >> 16
+		v ^= (v & w) >> 4
+		v ^= key >> 8
+		v *= 0x0929eb3f
+		v ^= key >> 23
+		v ^= (v & w) >> 1
+		v *= 1 | key>>27
+		v *= 0x6935fa69
+		v ^= (v & w) >> 11
+		v *= 0x74dcb303
+		v ^= (v & w) >> 2
+		v *= 0x9e501cc3
+		v ^= (v & w) >> 2
+		v *= 0xc860a3df
+		v &= w
+		v ^= v >> 5
+		if v < l {
+			break
+		}
+	}
+	return int((v + key) % l)
+}
@@ -0,0 +1,119 @@
+// Package sampler generates the sample values that renderers use for Monte
+// Carlo integration, in place of plain pseudo-random numbers. Well-distributed
+// (stratified or low-discrepancy) samples cover the integration domain more
+// evenly, which means less noise for the same number of samples.
+//
+// https://www.pbr-book.org/3ed-2018/Sampling_and_Reconstruction/Sampling_Interface
+package sampler
+
+import "github.com/gmhorn/gremlin/archive/pkg/util"
+
+// Sampler generates the values of samples, one dimension at a time. Each
+// sample is identified by a pixel and an index within that pixel. Calling
+// StartSample starts a new sample, and successive Get1D and Get2D calls then
+// return its values for successive dimensions, all in [0, 1).
+//
+// Low-discrepancy samplers rely on each dimension being used for the same
+// thing in every sample: if a path uses dimension 7 for a BSDF sample in one
+// sample and for a light sample in the next, neither is well distributed.
+// Code whose number of dimensions varies (e.g. a light sample that's skipped)
+// should use SetDimension to get back in step, e.g. at the start of each
+// bounce. Samplers only have good (low-discrepancy) values for so many
+// dimensions; past those, dimensions are padded with randomly permuted copies
+// of their first two dimensions, so they're still stratified but not
+// correlated with each other.
+//
+// Samplers are stateful, so each goroutine needs its own. Values only depend
+// on the seed, pixel, index and dimension, though, so samplers created with
+// the same seed produce the same samples.
+type Sampler interface {
+	// StartSample starts generating sample number index of the pixel. Pixels
+	// are identified by any integer key, e.g. their index in a film.
+	StartSample(pixel, index int)
+
+	// Get1D returns the value of the next dimension of the sample.
+	Get1D() float64
+
+	// Get2D returns the values of the next two dimensions of the sample.
+	Get2D() (float64, float64)
+
+	// Dimension returns the next dimension Get1D or Get2D will use.
+	Dimension() int
+
+	// SetDimension makes Get1D and Get2D continue from the given dimension.
+	SetDimension(dim int)
+}
+
+// padBlock is the number of consecutive sample indices whose order is
+// shuffled in padded dimensions. Taking a multiple of padBlock samples per
+// pixel keeps padded dimensions as well stratified as the first two.
+const padBlock = 16
+
+// state is what all samplers keep track of.
+type state struct {
+	seed         uint64
+	pixel, index int
+	dim          int
+}
+
+func (s *state) StartSample(pixel, index int) {
+	s.pixel, s.index, s.dim = pixel, index, 0
+}
+
+func (s *state) Dimension() int {
+	return s.dim
+}
+
+func (s *state) SetDimension(dim int) {
+	s.dim = dim
+}
+
+// paddedIndex returns the sample index to use for a padded dimension: the
+// index shuffled within its block of padBlock indices. Pairs of dimensions
+// get the same shuffle, so their 2D distribution is kept.
+//
+// Kollig and Keller, "Efficient Multidimensional Sampling", 2002.
+// https://www.uni-kl.de/AG-Heinrich/EMS.pdf
+func (s *state) paddedIndex(dim int) int {
+	block := s.index / padBlock
+	key := uint32(s.hash(uint64(dim/2), uint64(block), 0x9added))
+	return block*padBlock + permute(s.index%padBlock, padBlock, key)
+}
+
+// hash returns a hash of the seed, pixel and given keys, for randomizing
+// samples per pixel.
+func (s *state) hash(keys ...uint64) uint64 {
+	return util.Hash(append([]uint64{s.seed, uint64(s.pixel)}, keys...)...)
+}
+
+// random returns a pseudo-random value in [0, 1) for the current sample and
+// the given dimension.
+func (s *state) random(dim int) float64 {
+	return toFloat(s.hash(uint64(s.index), uint64(dim)))
+}
+
+// toFloat turns the high 53 bits of a hash into a float in [0, 1).
+func toFloat(h uint64) float64 {
+	return float64(h>>11) * 0x1p-53
+}
+
+// Random is a sampler that returns independent, uniformly distributed
+// pseudo-random values, i.e. plain Monte Carlo.
+type Random struct {
+	state
+}
+
+// NewRandom creates a random sampler.
+func NewRandom(seed uint64) *Random {
+	return &Random{state{seed: seed}}
+}
+
+func (s *Random) Get1D() float64 {
+	v := s.random(s.dim)
+	s.dim++
+	return v
+}
+
+func (s *Random) Get2D() (float64, float64) {
+	return s.Get1D(), s.Get1D()
+}
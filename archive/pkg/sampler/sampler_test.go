@@ -0,0 +1,263 @@
+package sampler
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermute(t *testing.T) {
+	for _, n := range []int{1, 2, 5, 16, 33, 100} {
+		for _, key := range []uint32{0, 1, 0xdeadbeef} {
+			seen := make([]bool, n)
+			for i := 0; i < n; i++ {
+				p := permute(i, n, key)
+				assert.False(t, seen[p], "n=%d key=%d", n, key)
+				seen[p] = true
+			}
+		}
+	}
+}
+
+func TestSamplers(t *testing.T) {
+	samplers := map[string]func() Sampler{
+		"random":     func() Sampler { return NewRandom(1) },
+		"stratified": func() Sampler { return NewStratified(1, 4, 4) },
+		"halton":     func() Sampler { return NewHalton(1) },
+		"sobol":      func() Sampler { return NewSobol(1) },
+		"dithered":   func() Sampler { return NewDithered(NewSobol(1), 4) },
+	}
+	for name, newSampler := range samplers {
+		a, b := newSampler(), newSampler()
+		for i := 0; i < 64; i++ {
+			a.StartSample(7, i)
+			b.StartSample(7, i)
+			for d := 0; d < 100; d++ {
+				v := a.Get1D()
+				assert.GreaterOrEqual(t, v, 0.0, name)
+				assert.Less(t, v, 1.0, name)
+				assert.Equal(t, v, b.Get1D(), name)
+			}
+		}
+
+		// pixels get different samples
+		a.StartSample(1, 0)
+		b.StartSample(2, 0)
+		assert.NotEqual(t, a.Get1D(), b.Get1D(), name)
+	}
+}
+
+// assertStratified checks that n samples of a dimension land in n different
+// strata.
+func assertStratified(t *testing.T, s Sampler, n, dim int) {
+	seen := make(map[int]bool)
+	for i := 0; i < n; i++ {
+		s.StartSample(3, i)
+		for d := 0; d < dim; d++ {
+			s.Get1D()
+		}
+		seen[int(s.Get1D()*float64(n))] = true
+	}
+	assert.Len(t, seen, n, "dimension %d", dim)
+}
+
+func TestStratified(t *testing.T) {
+	s := NewStratified(0, 4, 2)
+	for dim := 0; dim < 10; dim++ {
+		assertStratified(t, s, 8, dim)
+	}
+
+	// 2D samples cover the 4x2 grid
+	cells := make(map[[2]int]bool)
+	for i := 0; i < 8; i++ {
+		s.StartSample(0, i)
+		s.Get1D()
+		x, y := s.Get2D()
+		cells[[2]int{int(4 * x), int(2 * y)}] = true
+	}
+	assert.Len(t, cells, 8)
+
+	assert.Panics(t, func() { NewStratified(0, 0, 1) })
+}
+
+func TestHalton(t *testing.T) {
+	assert.Equal(t, 0.375, radicalInverse(2, 6))
+	assert.InDelta(t, 7.0/9, radicalInverse(3, 5), 1e-12)
+	assert.Equal(t, []int{2, 3, 5, 7, 11}, primes[:5])
+
+	s := NewHalton(0)
+	assertStratified(t, s, 16, 0)
+	assertStratified(t, s, 27, 1)
+	assertStratified(t, s, 25, 2)
+}
+
+func TestSobol(t *testing.T) {
+	s := NewSobol(0)
+	for dim := 0; dim < sobolDims; dim++ {
+		assertStratified(t, s, 64, dim)
+	}
+
+	// the first two dimensions are a (0, 2)-sequence: 16 samples fall in
+	// every elementary interval of area 1/16 once
+	for k := 0; k <= 4; k++ {
+		nx, ny := 1<<k, 1<<(4-k)
+		cells := make(map[[2]int]bool)
+		for i := 0; i < 16; i++ {
+			s.StartSample(0, i)
+			x, y := s.Get2D()
+			cells[[2]int{int(x * float64(nx)), int(y * float64(ny))}] = true
+		}
+		assert.Len(t, cells, 16, "%dx%d", nx, ny)
+	}
+}
+
+func TestBlueNoise(t *testing.T) {
+	tile := blueNoise()
+	n := blueNoiseSize * blueNoiseSize
+	assert.Len(t, tile, n)
+
+	// every rank once...
+	seen := make([]bool, n)
+	for _, v := range tile {
+		r := int(v * float64(n))
+		assert.False(t, seen[r])
+		seen[r] = true
+	}
+
+	// ...with no low frequencies: averaged over 4x4 blocks, it's much flatter
+	// than white noise, whose block averages vary by 1/12/16
+	variance := 0.0
+	for by := 0; by < blueNoiseSize; by += 4 {
+		for bx := 0; bx < blueNoiseSize; bx += 4 {
+			mean := 0.0
+			for y := by; y < by+4; y++ {
+				for x := bx; x < bx+4; x++ {
+					mean += tile[y*blueNoiseSize+x] / 16
+				}
+			}
+			variance += (mean - 0.5) * (mean - 0.5)
+		}
+	}
+	variance /= float64(n / 16)
+	assert.Less(t, variance, 1.0/12/16/4)
+}
+
+func TestDithered(t *testing.T) {
+	d := NewDithered(NewSobol(0), 100)
+
+	// every pixel gets the same sequence, shifted by its blue noise
+	shift := func(pixel, i int) (float64, float64) {
+		d.StartSample(0, i)
+		u0, v0 := d.Get2D()
+		d.StartSample(pixel, i)
+		u, v := d.Get2D()
+		return math.Mod(u-u0+1, 1), math.Mod(v-v0+1, 1)
+	}
+	for _, pixel := range []int{1, 101, 4321} {
+		su, sv := shift(pixel, 0)
+		for i := 1; i < 8; i++ {
+			u, v := shift(pixel, i)
+			assert.InDelta(t, su, u, 1e-9)
+			assert.InDelta(t, sv, v, 1e-9)
+		}
+	}
+
+	// pixels a tile apart get the same shift
+	su, sv := shift(blueNoiseSize, 3)
+	u, v := shift(0, 3)
+	assert.Equal(t, u, su)
+	assert.Equal(t, v, sv)
+}
+
+func TestSamplers_Integrate(t *testing.T) {
+	// estimate the integral of a smooth 2D function over the unit square:
+	// well distributed samples should beat plain random ones
+	f := func(x, y float64) float64 { return math.Sin(math.Pi*x) * y * y }
+	want := 2 / math.Pi / 3
+
+	estimateError := func(s Sampler) float64 {
+		errSum := 0.0
+		for pixel := 0; pixel < 32; pixel++ {
+			sum := 0.0
+			for i := 0; i < 64; i++ {
+				s.StartSample(pixel, i)
+				sum += f(s.Get2D())
+			}
+			errSum += math.Abs(sum/64 - want)
+		}
+		return errSum / 32
+	}
+
+	random := estimateError(NewRandom(0))
+	for name, s := range map[string]Sampler{
+		"stratified": NewStratified(0, 8, 8),
+		"halton":     NewHalton(0),
+		"sobol":      NewSobol(0),
+	} {
+		assert.Less(t, estimateError(s), random/2, name)
+	}
+}
+
+func TestPadding(t *testing.T) {
+	for name, s := range map[string]Sampler{
+		"halton": NewHalton(0),
+		"sobol":  NewSobol(0),
+	} {
+		start := map[string]int{"halton": haltonDims, "sobol": sobolDims}[name]
+
+		// padded dimensions are still stratified (per block), as much as the
+		// first two dimensions are (Halton's base 3 dimension isn't, in 16
+		// samples)...
+		for dim := start; dim < start+6; dim++ {
+			if name == "sobol" || dim%2 == 0 {
+				assertStratified(t, s, padBlock, dim)
+			}
+		}
+
+		// ...but not correlated with each other or with the dimensions
+		// they're copies of
+		n := 4 * padBlock
+		values := make([][]float64, n)
+		for i := range values {
+			s.StartSample(5, i)
+			s.SetDimension(start)
+			values[i] = []float64{s.Get1D(), s.Get1D(), s.Get1D(), s.Get1D()}
+			s.SetDimension(0)
+			values[i] = append(values[i], s.Get1D())
+		}
+		for a := 0; a < 5; a++ {
+			for b := a + 1; b < 5; b++ {
+				assert.Less(t, math.Abs(correlation(values, a, b)), 0.35, "%s: %d vs %d", name, a, b)
+			}
+		}
+	}
+}
+
+func TestSetDimension(t *testing.T) {
+	s := NewSobol(0)
+	s.StartSample(0, 3)
+	s.Get2D()
+	s.Get1D()
+	assert.Equal(t, 3, s.Dimension())
+	v := s.Get1D()
+
+	s.StartSample(0, 3)
+	s.SetDimension(3)
+	assert.Equal(t, v, s.Get1D())
+}
+
+// correlation returns the Pearson correlation of columns a and b.
+func correlation(values [][]float64, a, b int) float64 {
+	n := float64(len(values))
+	var sa, sb, saa, sbb, sab float64
+	for _, v := range values {
+		sa += v[a]
+		sb += v[b]
+		saa += v[a] * v[a]
+		sbb += v[b] * v[b]
+		sab += v[a] * v[b]
+	}
+	cov := sab/n - sa*sb/n/n
+	return cov / math.Sqrt((saa/n-sa*sa/n/n)*(sbb/n-sb*sb/n/n))
+}
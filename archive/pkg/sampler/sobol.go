@@ -0,0 +1,100 @@
+package sampler
+
+// sobolPolys are the primitive polynomials and initial direction numbers for
+// Sobol dimensions 2 and up: the degree s, the polynomial's coefficients a and
+// the initial numbers m. The first dimension is the van der Corput sequence.
+//
+// From Joe and Kuo's new-joe-kuo-6.21201 table.
+// https://web.maths.unsw.edu.au/~fkuo/sobol/
+var sobolPolys = []struct {
+	s, a int
+	m    []uint32
+}{
+	{1, 0, []uint32{1}},
+	{2, 1, []uint32{1, 3}},
+	{3, 1, []uint32{1, 3, 1}},
+	{3, 2, []uint32{1, 1, 1}},
+	{4, 1, []uint32{1, 1, 3, 3}},
+	{4, 4, []uint32{1, 3, 5, 13}},
+	{5, 2, []uint32{1, 1, 5, 5, 17}},
+	{5, 4, []uint32{1, 1, 5, 5, 5}},
+	{5, 7, []uint32{1, 1, 7, 11, 19}},
+	{5, 11, []uint32{1, 1, 5, 1, 1}},
+	{5, 13, []uint32{1, 1, 1, 3, 11}},
+	{5, 14, []uint32{1, 3, 5, 5, 31}},
+	{6, 1, []uint32{1, 3, 3, 9, 7, 49}},
+	{6, 13, []uint32{1, 1, 1, 15, 21, 21}},
+	{6, 16, []uint32{1, 3, 1, 13, 27, 49}},
+}
+
+// sobolDims is how many dimensions the Sobol sampler covers; higher ones are
+// padded (see Sampler).
+var sobolDims = len(sobolPolys) + 1
+
+// sobolMatrices holds each dimension's 32 direction numbers (the columns of
+// its generator matrix).
+var sobolMatrices = func() [][32]uint32 {
+	matrices := make([][32]uint32, sobolDims)
+	for k := range matrices[0] {
+		matrices[0][k] = 1 << (31 - k)
+	}
+
+	for d, poly := range sobolPolys {
+		v := &matrices[d+1]
+		for k := 0; k < poly.s; k++ {
+			v[k] = poly.m[k] << (31 - k)
+		}
+		for k := poly.s; k < 32; k++ {
+			v[k] = v[k-poly.s] ^ (v[k-poly.s] >> poly.s)
+			for j := 1; j < poly.s; j++ {
+				if (poly.a>>(poly.s-1-j))&1 != 0 {
+					v[k] ^= v[k-j]
+				}
+			}
+		}
+	}
+	return matrices
+}()
+
+// Sobol is a sampler for the Sobol low-discrepancy sequence. Its first 2^k
+// samples are perfectly stratified in every dimension, and the first two
+// dimensions form a (0, 2)-sequence, so power-of-two sample counts work best.
+// Each pixel gets its own random digital shift (XOR scrambling) of every
+// dimension, which keeps those properties while decorrelating pixels.
+//
+// https://www.pbr-book.org/3ed-2018/Sampling_and_Reconstruction/Sobol_Sampler
+type Sobol struct {
+	state
+}
+
+// NewSobol creates a Sobol sampler.
+func NewSobol(seed uint64) *Sobol {
+	return &Sobol{state{seed: seed}}
+}
+
+func (s *Sobol) Get1D() float64 {
+	v := s.value(s.dim)
+	s.dim++
+	return v
+}
+
+func (s *Sobol) Get2D() (float64, float64) {
+	return s.Get1D(), s.Get1D()
+}
+
+func (s *Sobol) value(dim int) float64 {
+	matrix, index := &sobolMatrices[dim%2], s.index
+	if dim < sobolDims {
+		matrix = &sobolMatrices[dim]
+	} else {
+		index = s.paddedIndex(dim)
+	}
+
+	v := uint32(s.hash(uint64(dim)))
+	for k, n := 0, uint32(index); n != 0; k, n = k+1, n>>1 {
+		if n&1 != 0 {
+			v ^= matrix[k]
+		}
+	}
+	return float64(v) * 0x1p-32
+}
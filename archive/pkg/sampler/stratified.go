@@ -0,0 +1,47 @@
+package sampler
+
+// Stratified is a sampler for a fixed number of samples per pixel, nx * ny.
+// For each dimension, the samples of a pixel are spread over as many equal
+// strata (a grid of nx by ny cells in 2D) and jittered within them. Strata
+// are assigned in a different random order per pixel and dimension, so
+// dimensions aren't correlated.
+//
+// Samples with an index past nx * ny go around the strata again, so taking a
+// multiple of nx * ny samples per pixel keeps them stratified.
+//
+// https://www.pbr-book.org/3ed-2018/Sampling_and_Reconstruction/Stratified_Sampling
+type Stratified struct {
+	state
+	nx, ny int
+}
+
+// NewStratified creates a stratified sampler for nx * ny samples per pixel.
+// Panics if nx or ny isn't positive.
+func NewStratified(seed uint64, nx, ny int) *Stratified {
+	if nx < 1 || ny < 1 {
+		panic("Stratified sampler needs a positive number of strata")
+	}
+	return &Stratified{state: state{seed: seed}, nx: nx, ny: ny}
+}
+
+func (s *Stratified) Get1D() float64 {
+	n := s.nx * s.ny
+	stratum := s.stratum(n)
+	v := (float64(stratum) + s.random(s.dim)) / float64(n)
+	s.dim++
+	return v
+}
+
+func (s *Stratified) Get2D() (float64, float64) {
+	cell := s.stratum(s.nx * s.ny)
+	x := (float64(cell%s.nx) + s.random(s.dim)) / float64(s.nx)
+	y := (float64(cell/s.nx) + s.random(s.dim+1)) / float64(s.ny)
+	s.dim += 2
+	return x, y
+}
+
+// stratum returns the stratum of the current sample in the current dimension.
+func (s *Stratified) stratum(n int) int {
+	round := uint64(s.index / n)
+	return permute(s.index%n, n, uint32(s.hash(uint64(s.dim), round)))
+}
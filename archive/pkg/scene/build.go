@@ -0,0 +1,654 @@
+package scene
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gmhorn/gremlin/archive/pkg/accel"
+	"github.com/gmhorn/gremlin/archive/pkg/asset"
+	"github.com/gmhorn/gremlin/archive/pkg/camera"
+	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+	"github.com/gmhorn/gremlin/archive/pkg/imageio"
+	"github.com/gmhorn/gremlin/archive/pkg/light"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/gmhorn/gremlin/archive/pkg/mesh"
+	"github.com/gmhorn/gremlin/archive/pkg/render"
+	"github.com/gmhorn/gremlin/archive/pkg/sampler"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/gmhorn/gremlin/archive/pkg/texture"
+)
+
+// Defaults for settings the scene file leaves out.
+const (
+	defaultSamples   = 32
+	defaultMaxDepth  = 16
+	defaultFOV       = 60
+	defaultTurbidity = 3
+)
+
+// colorspaces are the RGB spaces films can be written in, by name.
+var colorspaces = map[string]*colorspace.RGB{
+	"":           &colorspace.SRGB,
+	"sRGB":       &colorspace.SRGB,
+	"linearSRGB": &colorspace.LinearSRGB,
+	"ACEScg":     &colorspace.ACEScg,
+	"rec2020":    &colorspace.Rec2020,
+	"displayP3":  &colorspace.DisplayP3,
+	"adobeRGB":   &colorspace.AdobeRGB,
+}
+
+// builder turns a scene description into a Scene.
+type builder struct {
+	res       *asset.Resolver
+	desc      *sceneDesc
+	materials map[string]material.Material
+	building  map[string]bool
+
+	// lightHints has one entry per light in the scene
+	lightHints []render.LightHint
+
+	// report, if set, is filled in with checks of the shapes as they're built
+	report *Report
+}
+
+func newBuilder(desc *sceneDesc, res *asset.Resolver) *builder {
+	return &builder{
+		res:       res,
+		desc:      desc,
+		materials: make(map[string]material.Material),
+		building:  make(map[string]bool),
+	}
+}
+
+func (b *builder) build() (*Scene, error) {
+	desc := b.desc
+	s := &Scene{}
+	var err error
+	if s.Film, err = b.film(); err != nil {
+		return nil, err
+	}
+	cs, ok := colorspaces[desc.Film.Colorspace]
+	if !ok {
+		return nil, fmt.Errorf("film: unknown colorspace %q", desc.Film.Colorspace)
+	}
+	s.Colorspace = *cs
+	if s.Camera, err = b.camera(s.Film.AspectRatio); err != nil {
+		return nil, err
+	}
+	for i := range desc.Shapes {
+		shapes, err := b.shape(&desc.Shapes[i])
+		if err != nil {
+			return nil, fmt.Errorf("shape %d: %w", i, err)
+		}
+		s.Shapes = append(s.Shapes, shapes...)
+		if b.report != nil {
+			b.report.checkShapes(i, shapes)
+		}
+	}
+
+	// Directional and environment lights need the scene's size
+	radius := 0.0
+	if bounds := accel.NewBVH(s.Shapes).Bounds(); bounds != nil {
+		radius = bounds.Diagonal().Len() / 2
+	}
+	for i := range desc.Lights {
+		d := &desc.Lights[i]
+		l, err := b.lights(d, radius)
+		if err == nil && d.Importance < 0 {
+			err = errors.New("importance must not be negative")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("light %d: %w", i, err)
+		}
+		s.Lights = append(s.Lights, l...)
+		for range l {
+			b.lightHints = append(b.lightHints, render.LightHint{Importance: d.Importance, Always: d.Always})
+		}
+	}
+
+	if err := b.render(s); err != nil {
+		return nil, fmt.Errorf("render: %w", err)
+	}
+	return s, nil
+}
+
+func (b *builder) film() (*camera.Film, error) {
+	d := &b.desc.Film
+	if d.Width <= 0 || d.Height <= 0 {
+		return nil, fmt.Errorf("film: invalid resolution %dx%d", d.Width, d.Height)
+	}
+	film := camera.NewFilm(d.Width, d.Height)
+	if d.LUT != "" {
+		lut, err := colorspace.LoadCube(b.res, d.LUT)
+		if err != nil {
+			return nil, fmt.Errorf("film: %w", err)
+		}
+		film.LUT = lut
+	}
+	if d.Filter == nil {
+		return film, nil
+	}
+
+	f := d.Filter
+	if f.Radius <= 0 {
+		return nil, fmt.Errorf("film: filter radius must be positive")
+	}
+	switch f.Type {
+	case "box":
+		film.Filter = camera.NewBoxFilter(f.Radius)
+	case "tent":
+		film.Filter = camera.NewTentFilter(f.Radius)
+	case "gaussian":
+		film.Filter = camera.NewGaussianFilter(f.Radius, f.Alpha)
+	case "mitchell":
+		film.Filter = camera.NewMitchellFilter(f.Radius, f.B, f.C)
+	default:
+		return nil, fmt.Errorf("film: unknown filter type %q", f.Type)
+	}
+	return film, nil
+}
+
+func (b *builder) camera(aspect float64) (*camera.Perspective, error) {
+	d := &b.desc.Camera
+	fov := d.FOV
+	if fov == 0 {
+		fov = defaultFOV
+	}
+	if fov < 0 || fov >= 180 {
+		return nil, fmt.Errorf("camera: invalid fov %g", fov)
+	}
+
+	cam := camera.NewPerspective(aspect, fov)
+	if d.Eye != nil {
+		cam.MoveTo(d.Eye.geo())
+	}
+	if d.Target != nil {
+		cam.PointAt(d.Target.geo())
+	}
+	if d.Aperture > 0 {
+		cam.Lens(d.Aperture, d.Focus)
+	}
+	if d.Shutter != nil {
+		cam.Shutter(d.Shutter[0], d.Shutter[1])
+	}
+	return cam, nil
+}
+
+// material returns the named material, building it (and any it mixes) the
+// first time. An empty name is the renderer's default material.
+func (b *builder) material(name string) (material.Material, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if m, ok := b.materials[name]; ok {
+		return m, nil
+	}
+	d, ok := b.desc.Materials[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown material %q", name)
+	}
+	if b.building[name] {
+		return nil, fmt.Errorf("material %q mixes itself", name)
+	}
+	b.building[name] = true
+	defer delete(b.building, name)
+
+	m, err := b.newMaterial(&d)
+	if err == nil {
+		m, err = b.detail(m, &d)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("material %q: %w", name, err)
+	}
+	b.materials[name] = m
+	return m, nil
+}
+
+func (b *builder) newMaterial(d *materialDesc) (material.Material, error) {
+	switch d.Type {
+	case "lambertian":
+		if d.Texture != nil {
+			tex, err := b.texture(d.Texture)
+			if err != nil {
+				return nil, err
+			}
+			return material.NewTexturedLambertian(tex), nil
+		}
+		return material.NewLambertian(d.Color.or(0.5)), nil
+	case "mirror":
+		return material.NewMirror(d.Color.or(1)), nil
+	case "dielectric":
+		if d.IOR == nil {
+			return nil, errors.New("dielectric needs an ior")
+		}
+		if d.Roughness < 0 || d.Roughness > 1 {
+			return nil, errors.New("roughness must be between 0 and 1")
+		}
+		m := material.NewDielectric(d.IOR.dist)
+		m.Priority = d.Priority
+		m.Roughness = d.Roughness
+		if d.Absorption != nil {
+			m.Absorption = spectrum.Sample(d.Absorption.dist)
+		}
+		return m, nil
+	case "water":
+		if d.Roughness < 0 || d.Roughness > 1 {
+			return nil, errors.New("roughness must be between 0 and 1")
+		}
+		m := material.Water(d.Roughness)
+		m.Priority = d.Priority
+		return m, nil
+	case "thinDielectric":
+		if d.IOR == nil {
+			return nil, errors.New("thinDielectric needs an ior")
+		}
+		return material.NewThinDielectric(d.IOR.dist), nil
+	case "merl":
+		return material.LoadMERL(b.res, d.File)
+	case "microfacet":
+		if d.Roughness < 0 || d.Roughness > 1 || d.Metallic < 0 || d.Metallic > 1 {
+			return nil, errors.New("roughness and metallic must be between 0 and 1")
+		}
+		m := material.NewMicrofacet(d.Color.or(0.5), d.Roughness, d.Metallic)
+		if d.Texture != nil {
+			tex, err := b.texture(d.Texture)
+			if err != nil {
+				return nil, err
+			}
+			m.Texture = tex
+		}
+		if d.RoughnessMap != nil {
+			roughness, err := b.texture(d.RoughnessMap)
+			if err != nil {
+				return nil, err
+			}
+			m.RoughnessMap = roughness
+		}
+		return m, nil
+	case "mix":
+		ma, err := b.material(d.A)
+		if err != nil {
+			return nil, err
+		}
+		mb, err := b.material(d.B)
+		if err != nil {
+			return nil, err
+		}
+		if d.Mask != nil {
+			mask, err := b.texture(d.Mask)
+			if err != nil {
+				return nil, err
+			}
+			return material.NewMaskedMix(ma, mb, mask), nil
+		}
+		return material.NewMix(ma, mb, d.Amount), nil
+	default:
+		return nil, fmt.Errorf("unknown material type %q", d.Type)
+	}
+}
+
+// detail wraps m with the description's normal or bump map, if it has one.
+func (b *builder) detail(m material.Material, d *materialDesc) (material.Material, error) {
+	switch {
+	case d.NormalMap != "" && d.Bump != nil:
+		return nil, errors.New("a material can't have both a normal map and a bump map")
+	case d.NormalMap != "":
+		img, err := imageio.LoadImageData(b.res, d.NormalMap)
+		if err != nil {
+			return nil, err
+		}
+		return material.NewNormalMap(m, img), nil
+	case d.Bump != nil:
+		height, err := b.texture(d.Bump)
+		if err != nil {
+			return nil, err
+		}
+		return material.NewBump(m, height, d.BumpScale), nil
+	}
+	return m, nil
+}
+
+// maskTexture is a texture that can also be a mask. All the textures a scene
+// can describe are.
+type maskTexture interface {
+	texture.Texture
+	texture.Scalar
+}
+
+func (b *builder) texture(d *textureDesc) (maskTexture, error) {
+	frequency := d.Frequency
+	if frequency == 0 {
+		frequency = 1
+	}
+	first, second := texture.NewConstant(d.A.or(0)), texture.NewConstant(d.B.or(1))
+
+	switch d.Type {
+	case "checkerboard":
+		return texture.NewCheckerboard(first, second, frequency), nil
+	case "image":
+		img, err := imageio.LoadImage(b.res, d.File)
+		if err != nil {
+			return nil, err
+		}
+		scale := d.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		return texture.NewImage(img, scale), nil
+	case "noise":
+		octaves := d.Octaves
+		if octaves == 0 {
+			octaves = 1
+		} else if octaves < 0 {
+			return nil, errors.New("noise octaves must be positive")
+		}
+		return texture.NewNoise(first, second, frequency, octaves), nil
+	default:
+		return nil, fmt.Errorf("unknown texture type %q", d.Type)
+	}
+}
+
+func (b *builder) shape(d *shapeDesc) ([]shape.Shape, error) {
+	mat, err := b.material(d.Material)
+	if err != nil {
+		return nil, err
+	}
+
+	switch d.Type {
+	case "sphere":
+		if d.Radius <= 0 {
+			return nil, errors.New("sphere radius must be positive")
+		}
+		return []shape.Shape{&shape.Sphere{
+			Center:   d.Center.geo(),
+			Radius:   d.Radius,
+			Material: mat,
+		}}, nil
+	case "triangle":
+		t := shape.NewTriangle(d.Vertices[0].geo(), d.Vertices[1].geo(), d.Vertices[2].geo())
+		t.Material = mat
+		return []shape.Shape{t}, nil
+	case "obj":
+		m, err := mesh.LoadOBJ(b.res, d.File, nil)
+		if err != nil {
+			return nil, err
+		}
+		if mat != nil {
+			m.Material = mat
+			m.Materials, m.FaceMaterials = nil, nil
+		}
+		if _, ok := mat.(material.Perturbed); ok && len(m.UVs) != 0 {
+			m.ComputeTangents()
+		}
+		return m.Faces(), nil
+	default:
+		return nil, fmt.Errorf("unknown shape type %q", d.Type)
+	}
+}
+
+func (b *builder) lights(d *lightDesc, sceneRadius float64) ([]light.Light, error) {
+	if d.Type == "sunSky" {
+		t, err := time.Parse(time.RFC3339, d.Time)
+		if err != nil {
+			return nil, err
+		}
+		turbidity := d.Turbidity
+		if turbidity == 0 {
+			turbidity = defaultTurbidity
+		} else if turbidity < 1 {
+			return nil, errors.New("turbidity must be at least 1")
+		}
+		sun, sky := light.NewSunSky(d.Latitude, d.Longitude, t, turbidity)
+		sun.SceneRadius, sky.SceneRadius = sceneRadius, sceneRadius
+		if d.Clouds == nil {
+			return []light.Light{sun, sky}, nil
+		}
+		clouds, err := newClouds(d.Clouds, sky, sun)
+		if err != nil {
+			return nil, err
+		}
+		return []light.Light{sun, clouds}, nil
+	}
+
+	l, err := b.light(d, sceneRadius)
+	if err != nil {
+		return nil, err
+	}
+	if d.Lumens < 0 {
+		return nil, errors.New("lumens must not be negative")
+	}
+	if d.Lumens > 0 && !light.SetLumens(l, d.Lumens) {
+		return nil, fmt.Errorf("can't set lumens of %s light", d.Type)
+	}
+	return []light.Light{l}, nil
+}
+
+func newClouds(d *cloudsDesc, sky *light.Environment, sun *light.Directional) (*light.Clouds, error) {
+	if d.Coverage < 0 || d.Coverage > 1 {
+		return nil, errors.New("cloud coverage must be in [0, 1]")
+	}
+	c := light.NewClouds(sky, sun, d.Coverage)
+	if d.Bottom != 0 || d.Top != 0 {
+		c.Bottom, c.Top = d.Bottom, d.Top
+	}
+	if c.Bottom < 0 || c.Top <= c.Bottom {
+		return nil, errors.New("clouds need 0 <= bottom < top")
+	}
+	if d.Extinction < 0 {
+		return nil, errors.New("cloud extinction must not be negative")
+	} else if d.Extinction > 0 {
+		c.Extinction = d.Extinction
+	}
+	return c, nil
+}
+
+func (b *builder) light(d *lightDesc, sceneRadius float64) (light.Light, error) {
+	switch d.Type {
+	case "point":
+		return light.NewPoint(d.Position.geo(), d.Intensity.or(1)), nil
+	case "directional":
+		l := light.NewDirectional(d.Direction.geo(), d.Radiance.or(1))
+		l.SceneRadius = sceneRadius
+		return l, nil
+	case "rect":
+		return light.NewRect(d.Corner.geo(), d.Edge1.geo(), d.Edge2.geo(), d.Radiance.or(1)), nil
+	case "sphere":
+		if d.Radius <= 0 {
+			return nil, errors.New("sphere radius must be positive")
+		}
+		return light.NewSphere(d.Center.geo(), d.Radius, d.Radiance.or(1)), nil
+	case "environment":
+		img, err := imageio.LoadHDR(b.res, d.File)
+		if err != nil {
+			return nil, err
+		}
+		scale := d.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		l := light.NewEnvironment(img, scale)
+		l.SceneRadius = sceneRadius
+		return l, nil
+	case "softbox", "stripLight", "ringLight":
+		return b.studioLight(d)
+	case "mesh":
+		m, err := mesh.LoadOBJ(b.res, d.File, nil)
+		if err != nil {
+			return nil, err
+		}
+		var emission texture.Texture = texture.NewConstant(d.Radiance.or(1))
+		if d.Emission != nil {
+			if emission, err = b.texture(d.Emission); err != nil {
+				return nil, err
+			}
+		}
+		scale := d.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		return light.NewMesh(m, emission, scale), nil
+	default:
+		return nil, fmt.Errorf("unknown light type %q", d.Type)
+	}
+}
+
+func (b *builder) studioLight(d *lightDesc) (light.Light, error) {
+	if d.Falloff < 0 {
+		return nil, errors.New("falloff must not be negative")
+	}
+	profile := light.Profile{Falloff: d.Falloff, BarnDoors: d.BarnDoors}
+	position, target := d.Position.geo(), d.Target.geo()
+	if position == target {
+		return nil, errors.New("studio light must not be aimed at itself")
+	}
+
+	switch d.Type {
+	case "softbox":
+		if d.Width <= 0 || d.Height <= 0 {
+			return nil, errors.New("softbox size must be positive")
+		}
+		p := light.NewSoftbox(position, target, d.Width, d.Height, d.Radiance.or(1))
+		p.Profile = profile
+		return p, nil
+	case "stripLight":
+		if d.Length <= 0 {
+			return nil, errors.New("strip light length must be positive")
+		}
+		p := light.NewStripLight(position, target, d.Length, d.Radiance.or(1))
+		p.Profile = profile
+		return p, nil
+	default:
+		if d.Diameter <= 0 {
+			return nil, errors.New("ring light diameter must be positive")
+		}
+		r := light.NewRingLight(position, target, d.Diameter, d.Radiance.or(1))
+		r.Profile = profile
+		return r, nil
+	}
+}
+
+func (b *builder) render(s *Scene) error {
+	d := &b.desc.Render
+
+	s.Samples = d.Samples
+	if s.Samples == 0 {
+		s.Samples = defaultSamples
+	}
+	if s.Samples < 0 {
+		return fmt.Errorf("invalid samples %d", d.Samples)
+	}
+
+	if d.RayOffset < 0 || d.MaxShadowDist < 0 || d.AORadius < 0 {
+		return errors.New("distances must not be negative")
+	}
+	switch d.Integrator {
+	case "", "path":
+		maxDepth := d.MaxDepth
+		if maxDepth == 0 {
+			maxDepth = defaultMaxDepth
+		}
+		if maxDepth < 0 {
+			return fmt.Errorf("invalid maxDepth %d", d.MaxDepth)
+		}
+		pt := render.NewPathTracer(maxDepth)
+		pt.Lights = s.Lights
+		pt.LightHints = b.lightHints
+		if d.RayOffset > 0 {
+			pt.RayOffset = d.RayOffset
+		}
+		if d.MaxShadowDist > 0 {
+			pt.MaxShadowDist = d.MaxShadowDist
+		}
+		if d.Regularize < 0 || d.Regularize > 1 {
+			return errors.New("regularize must be in [0, 1]")
+		}
+		pt.Regularize = d.Regularize
+		s.Integrator = pt
+	case "ao":
+		ao := render.NewAmbientOcclusion(math.Inf(1))
+		if d.AORadius > 0 {
+			ao.Radius = d.AORadius
+		}
+		if d.RayOffset > 0 {
+			ao.RayOffset = d.RayOffset
+		}
+		s.Integrator = ao
+	default:
+		return fmt.Errorf("unknown integrator %q", d.Integrator)
+	}
+
+	s.Seed = d.Seed
+	s.Dither = d.Dither
+	switch d.Sampler {
+	case "", "sobol":
+		s.NewSampler = func(seed uint64) sampler.Sampler { return sampler.NewSobol(seed) }
+	case "halton":
+		s.NewSampler = func(seed uint64) sampler.Sampler { return sampler.NewHalton(seed) }
+	case "random":
+		s.NewSampler = func(seed uint64) sampler.Sampler { return sampler.NewRandom(seed) }
+	case "stratified":
+		// As close to square as the sample count allows
+		nx := int(math.Sqrt(float64(s.Samples)))
+		for s.Samples%nx != 0 {
+			nx--
+		}
+		ny := s.Samples / nx
+		s.NewSampler = func(seed uint64) sampler.Sampler { return sampler.NewStratified(seed, nx, ny) }
+	default:
+		return fmt.Errorf("unknown sampler %q", d.Sampler)
+	}
+
+	aovs := make([]camera.AOV, len(d.AOVs))
+	for i, name := range d.AOVs {
+		aov, err := camera.ParseAOV(name)
+		if err != nil {
+			return err
+		}
+		aovs[i] = aov
+	}
+	s.Film.EnableAOVs(aovs...)
+
+	if fc := d.FalseColor; fc != nil {
+		if fc.Min >= fc.Max || (fc.Log && fc.Min <= 0) || fc.Steps < 0 || fc.Units < 0 {
+			return errors.New("invalid falseColor scale")
+		}
+		s.FalseColor = &camera.FalseColorScale{Min: fc.Min, Max: fc.Max, Log: fc.Log, Steps: fc.Steps, Units: fc.Units}
+		s.Film.EnableAOVs(camera.AOVLuminance)
+	}
+
+	if m := d.Mask; m != nil {
+		if m.Min < 0 || m.Min > 1 {
+			return errors.New("mask min must be between 0 and 1")
+		}
+		img, err := imageio.LoadImageData(b.res, m.File)
+		if err != nil {
+			return err
+		}
+		s.Mask = render.NewMask(img, s.Film.Width, s.Film.Height, m.Min)
+	}
+
+	switch d.Cull {
+	case "":
+	case "report", "prune":
+		report := render.Cull(s.Camera, s.Shapes)
+		s.Cull = &report
+		if d.Cull == "prune" {
+			s.Shapes = report.Prune(s.Shapes)
+		}
+	default:
+		return fmt.Errorf("unknown cull %q", d.Cull)
+	}
+	return nil
+}
+
+// or returns the color's distribution, or a flat one with the given value if
+// the color was left out.
+func (c *color) or(value float64) spectrum.Distribution {
+	if c == nil {
+		return spectrum.Flat(value)
+	}
+	return c.dist
+}
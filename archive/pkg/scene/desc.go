@@ -0,0 +1,298 @@
+package scene
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+)
+
+// sceneDesc is the top level of a scene file.
+type sceneDesc struct {
+	Film      filmDesc                `json:"film"`
+	Camera    cameraDesc              `json:"camera"`
+	Materials map[string]materialDesc `json:"materials"`
+	Shapes    []shapeDesc             `json:"shapes"`
+	Lights    []lightDesc             `json:"lights"`
+	Render    renderDesc              `json:"render"`
+}
+
+// filmDesc describes the film. The filter type is one of "box", "tent",
+// "gaussian" (with alpha) or "mitchell" (with b and c); it defaults to a box
+// of radius 0.5. LUT is a .cube file applied to the final image (see
+// camera.Film).
+//
+// Colorspace is the RGB space images are written in: "sRGB" (the default),
+// "linearSRGB", "ACEScg", "rec2020", "displayP3" or "adobeRGB".
+type filmDesc struct {
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	LUT        string `json:"lut"`
+	Colorspace string `json:"colorspace"`
+	Filter     *struct {
+		Type   string  `json:"type"`
+		Radius float64 `json:"radius"`
+		Alpha  float64 `json:"alpha"`
+		B      float64 `json:"b"`
+		C      float64 `json:"c"`
+	} `json:"filter"`
+}
+
+// cameraDesc describes the camera. FOV is in degrees. Aperture is the lens
+// radius (0 for a pinhole) and Focus the focus distance.
+type cameraDesc struct {
+	FOV      float64     `json:"fov"`
+	Eye      *vec        `json:"eye"`
+	Target   *vec        `json:"target"`
+	Aperture float64     `json:"aperture"`
+	Focus    float64     `json:"focus"`
+	Shutter  *[2]float64 `json:"shutter"`
+}
+
+// materialDesc describes a material. The type is one of
+//
+//   - "lambertian" (color, or a texture)
+//   - "mirror" (color)
+//   - "dielectric" (ior, priority for nested dielectrics, roughness,
+//     absorption per unit length)
+//   - "water" (roughness, priority; see material.Water, whose absorption
+//     assumes the scene is in meters)
+//   - "thinDielectric" (ior)
+//   - "merl" (file)
+//   - "mix" (a, b: material names, amount: of b, or a texture mask)
+//   - "microfacet" (color, or a texture; roughness, or a roughnessMap
+//     texture; metallic; see material.Microfacet)
+//
+// Any material can also have surface detail from a normalMap (a PNG or JPEG
+// file, see material.NormalMap) or a bump texture, whose values are heights
+// scaled by bumpScale (see material.Bump).
+type materialDesc struct {
+	Type       string       `json:"type"`
+	Color      *color       `json:"color"`
+	Texture    *textureDesc `json:"texture"`
+	IOR        *color       `json:"ior"`
+	Absorption *color       `json:"absorption"`
+	Priority   int          `json:"priority"`
+	File       string       `json:"file"`
+	A          string       `json:"a"`
+	B          string       `json:"b"`
+	Amount     float64      `json:"amount"`
+	Mask       *textureDesc `json:"mask"`
+
+	Roughness    float64      `json:"roughness"`
+	RoughnessMap *textureDesc `json:"roughnessMap"`
+	Metallic     float64      `json:"metallic"`
+
+	NormalMap string       `json:"normalMap"`
+	Bump      *textureDesc `json:"bump"`
+	BumpScale float64      `json:"bumpScale"`
+}
+
+// textureDesc describes a texture. The type is one of
+//
+//   - "checkerboard" (a, b: colors, frequency)
+//   - "image" (file, a PNG or JPEG; scale)
+//   - "noise" (a, b: colors, frequency, octaves)
+//
+// Colors default to black and white, so as masks they blend from the first
+// material to the second.
+type textureDesc struct {
+	Type      string  `json:"type"`
+	A         *color  `json:"a"`
+	B         *color  `json:"b"`
+	Frequency float64 `json:"frequency"`
+	File      string  `json:"file"`
+	Scale     float64 `json:"scale"`
+	Octaves   int     `json:"octaves"`
+}
+
+// shapeDesc describes a shape. The type is one of
+//
+//   - "sphere" (center, radius)
+//   - "triangle" (vertices)
+//   - "obj" (file, a Wavefront OBJ mesh; material replaces the OBJ's own
+//     materials if given)
+//
+// Material names one of the scene's materials; no material means the
+// renderer's default.
+type shapeDesc struct {
+	Type     string  `json:"type"`
+	Material string  `json:"material"`
+	Center   vec     `json:"center"`
+	Radius   float64 `json:"radius"`
+	Vertices [3]vec  `json:"vertices"`
+	File     string  `json:"file"`
+}
+
+// lightDesc describes a light. The type is one of
+//
+//   - "point" (position, intensity)
+//   - "directional" (direction, radiance)
+//   - "rect" (corner, edge1, edge2, radiance; it emits along edge1 × edge2)
+//   - "sphere" (center, radius, radiance)
+//   - "environment" (file, a Radiance .hdr image; scale)
+//   - "mesh" (file, a Wavefront OBJ mesh; radiance, or an emission texture
+//     looked up with the mesh's UVs; scale), like a TV screen. Add the mesh
+//     as a shape too to see it.
+//   - "softbox" (position, target, width, height, radiance)
+//   - "stripLight" (position, target, length, radiance)
+//   - "ringLight" (position, target, diameter, radiance)
+//   - "sunSky" (latitude, longitude, time as RFC 3339, turbidity; see
+//     light.NewSunSky), which adds both a sun and a sky, and optionally clouds
+//     (coverage, from 0 to 1; bottom, top and extinction; see light.Clouds)
+//
+// The studio lights (softbox, stripLight and ringLight) can also have a
+// falloff and barnDoors (see light.Profile).
+//
+// Point and area lights can have their brightness given in lumens instead,
+// which scales their emission to match (see light.SetLumens); "D65" at 800
+// lumens is a daylight bulb.
+//
+// Any light can have sampling hints for the path tracer: an importance
+// multiplier, or always to sample it at every bounce (see render.LightHint).
+type lightDesc struct {
+	Type      string       `json:"type"`
+	Position  vec          `json:"position"`
+	Direction vec          `json:"direction"`
+	Corner    vec          `json:"corner"`
+	Edge1     vec          `json:"edge1"`
+	Edge2     vec          `json:"edge2"`
+	Center    vec          `json:"center"`
+	Radius    float64      `json:"radius"`
+	Intensity *color       `json:"intensity"`
+	Radiance  *color       `json:"radiance"`
+	File      string       `json:"file"`
+	Scale     float64      `json:"scale"`
+	Latitude  float64      `json:"latitude"`
+	Longitude float64      `json:"longitude"`
+	Time      string       `json:"time"`
+	Turbidity float64      `json:"turbidity"`
+	Clouds    *cloudsDesc  `json:"clouds"`
+	Emission  *textureDesc `json:"emission"`
+	Target    vec          `json:"target"`
+	Width     float64      `json:"width"`
+	Height    float64      `json:"height"`
+	Length    float64      `json:"length"`
+	Diameter  float64      `json:"diameter"`
+	Falloff   float64      `json:"falloff"`
+	BarnDoors [2]float64   `json:"barnDoors"`
+
+	Lumens     float64 `json:"lumens"`
+	Importance float64 `json:"importance"`
+	Always     bool    `json:"always"`
+}
+
+// cloudsDesc describes a sunSky's cloud layer. Zeros keep light.NewClouds'
+// defaults, except for coverage.
+type cloudsDesc struct {
+	Coverage   float64 `json:"coverage"`
+	Bottom     float64 `json:"bottom"`
+	Top        float64 `json:"top"`
+	Extinction float64 `json:"extinction"`
+}
+
+// renderDesc holds the render settings. Integrator is "path" (the default,
+// see render.PathTracer) or "ao" (see render.AmbientOcclusion). Sampler is one
+// of "random", "stratified", "halton" or "sobol" (the default), and Dither
+// offsets its samples by blue noise (see sampler.Dithered). AOVs are named as
+// by camera.AOV.String.
+//
+// Distances left out (or 0) keep the integrators' defaults: no limit for
+// shadow rays and AO. Regularize is the path tracer's (see
+// render.PathTracer), off by default.
+//
+// FalseColor, if given, records the luminance and sets Scene.FalseColor (see
+// camera.FalseColorScale).
+//
+// Mask, if given, is a grayscale image file that spreads the samples over the
+// film by importance, with Min the share black areas get (see render.NewMask).
+//
+// Cull checks the shapes against the camera frustum (see render.Cull): "report"
+// sets Scene.Cull, and "prune" also leaves the culled shapes out.
+type renderDesc struct {
+	Integrator    string   `json:"integrator"`
+	Samples       int      `json:"samples"`
+	MaxDepth      int      `json:"maxDepth"`
+	RayOffset     float64  `json:"rayOffset"`
+	MaxShadowDist float64  `json:"maxShadowDistance"`
+	AORadius      float64  `json:"aoRadius"`
+	Regularize    float64  `json:"regularize"`
+	Sampler       string   `json:"sampler"`
+	Seed          uint64   `json:"seed"`
+	Dither        bool     `json:"dither"`
+	AOVs          []string `json:"aovs"`
+	Cull          string   `json:"cull"`
+
+	FalseColor *struct {
+		Min   float64 `json:"min"`
+		Max   float64 `json:"max"`
+		Log   bool    `json:"log"`
+		Steps int     `json:"steps"`
+		Units float64 `json:"units"`
+	} `json:"falseColor"`
+
+	Mask *struct {
+		File string  `json:"file"`
+		Min  float64 `json:"min"`
+	} `json:"mask"`
+}
+
+// vec is a point or vector, written as [x, y, z].
+type vec [3]float64
+
+func (v vec) geo() geo.Vec {
+	return geo.V(v[0], v[1], v[2])
+}
+
+// color is a spectral distribution, written either as a number (a flat
+// spectrum), as [r, g, b] (see spectrum.FromRGB), or as the name of a standard
+// illuminant ("D65", "D50", "A" or one of the F-series, e.g. "F2") scaled to a
+// luminance of 1, for lights.
+type color struct {
+	dist spectrum.Distribution
+}
+
+// illuminants are the standard illuminants colors can name.
+var illuminants = map[string]*spectrum.Sampled{
+	"D65": spectrum.IlluminantD65,
+	"D50": spectrum.IlluminantD50,
+	"A":   spectrum.IlluminantA,
+	"F1":  spectrum.Sample(spectrum.IlluminantF1),
+	"F2":  spectrum.Sample(spectrum.IlluminantF2),
+	"F3":  spectrum.Sample(spectrum.IlluminantF3),
+	"F4":  spectrum.Sample(spectrum.IlluminantF4),
+	"F5":  spectrum.Sample(spectrum.IlluminantF5),
+	"F6":  spectrum.Sample(spectrum.IlluminantF6),
+	"F7":  spectrum.Sample(spectrum.IlluminantF7),
+	"F8":  spectrum.Sample(spectrum.IlluminantF8),
+	"F9":  spectrum.Sample(spectrum.IlluminantF9),
+	"F10": spectrum.Sample(spectrum.IlluminantF10),
+	"F11": spectrum.Sample(spectrum.IlluminantF11),
+	"F12": spectrum.Sample(spectrum.IlluminantF12),
+}
+
+func (s *color) UnmarshalJSON(data []byte) error {
+	var flat float64
+	if err := json.Unmarshal(data, &flat); err == nil {
+		s.dist = spectrum.Flat(flat)
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		illum, ok := illuminants[name]
+		if !ok {
+			return fmt.Errorf("unknown illuminant %q", name)
+		}
+		s.dist = illum.Scale(1 / colorspace.CIE1931Reflectance.Convert(illum)[1])
+		return nil
+	}
+	var rgb [3]float64
+	if err := json.Unmarshal(data, &rgb); err != nil {
+		return errors.New("spectrum must be a number, [r, g, b] or an illuminant name")
+	}
+	s.dist = spectrum.FromRGB(rgb[0], rgb[1], rgb[2])
+	return nil
+}
@@ -0,0 +1,267 @@
+package scene
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gmhorn/gremlin/archive/pkg/asset"
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+)
+
+// Report is what Inspect finds out about a scene without rendering it.
+type Report struct {
+	// Shapes and Lights count the scene's primitives and lights by type. The
+	// triangles of OBJ meshes are MeshFaces.
+	Shapes, Lights map[string]int
+
+	// Bounds encloses all the shapes with finite coordinates, or is nil if
+	// there are none.
+	Bounds *geo.Bounds
+
+	// Missing lists the files the scene refers to that can't be opened. The
+	// scene can't be built without them, so nothing is counted.
+	Missing []string
+
+	// Problems lists anything else suspicious: shapes with NaN or infinite
+	// coordinates (from their vertices or transforms), zero-area triangles, a
+	// camera whose transform is NaN, materials no shape uses, and the error
+	// building the scene, if there was one (in which case the counts stop
+	// there).
+	Problems []string
+}
+
+// Inspect opens the named scene file with the resolver, like Load, and
+// reports on it.
+func Inspect(res *asset.Resolver, name string) (*Report, error) {
+	f, err := res.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := InspectReader(f, res.Relative(filepath.Dir(name)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return r, nil
+}
+
+// InspectReader reads a scene description and reports on it. Only a
+// description that can't be read at all is an error: everything else wrong
+// with the scene is in the report.
+func InspectReader(r io.Reader, res *asset.Resolver) (*Report, error) {
+	desc, err := decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &Report{Shapes: make(map[string]int), Lights: make(map[string]int)}
+	for _, name := range assets(desc) {
+		f, err := res.Open(name)
+		if err != nil {
+			rep.Missing = append(rep.Missing, name)
+			continue
+		}
+		f.Close()
+	}
+
+	if len(rep.Missing) == 0 {
+		b := newBuilder(desc, res)
+		b.report = rep
+		if s, err := b.build(); err != nil {
+			rep.Problems = append(rep.Problems, err.Error())
+		} else {
+			for _, l := range s.Lights {
+				rep.Lights[typeName(l)]++
+			}
+			if ray := s.Camera.Ray(0.5, 0.5); !finite(ray.Origin) || !finite(ray.Dir) {
+				rep.Problems = append(rep.Problems, "camera: transform is NaN (is the eye at the target?)")
+			}
+		}
+	}
+
+	for _, name := range unusedMaterials(desc) {
+		rep.Problems = append(rep.Problems, fmt.Sprintf("material %q is never used", name))
+	}
+	return rep, nil
+}
+
+func (r *Report) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "shapes: %s\n", counts(r.Shapes))
+	fmt.Fprintf(&sb, "lights: %s\n", counts(r.Lights))
+	if r.Bounds != nil {
+		fmt.Fprintf(&sb, "bounds: %v to %v\n", r.Bounds[0], r.Bounds[1])
+	}
+	for _, name := range r.Missing {
+		fmt.Fprintf(&sb, "missing: %s\n", name)
+	}
+	for _, p := range r.Problems {
+		fmt.Fprintf(&sb, "problem: %s\n", p)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// checkShapes counts and checks the shapes built for the i'th shape in the
+// description.
+func (r *Report) checkShapes(i int, shapes []shape.Shape) {
+	nonFinite, zeroArea := 0, 0
+	for _, sh := range shapes {
+		r.Shapes[typeName(sh)]++
+		b := sh.Bounds()
+		if !finite(b[0]) || !finite(b[1]) {
+			nonFinite++
+			continue
+		}
+		if r.Bounds == nil {
+			r.Bounds = &geo.Bounds{b[0], b[1]}
+		} else {
+			r.Bounds = r.Bounds.Union(b)
+		}
+		if area, ok := triangleArea(sh); ok && area == 0 {
+			zeroArea++
+		}
+	}
+
+	if nonFinite > 0 {
+		r.Problems = append(r.Problems, fmt.Sprintf("shape %d: %d primitives with NaN or infinite coordinates", i, nonFinite))
+	}
+	if zeroArea > 0 {
+		r.Problems = append(r.Problems, fmt.Sprintf("shape %d: %d zero-area triangles", i, zeroArea))
+	}
+}
+
+// assets returns the files the description refers to, sorted.
+func assets(desc *sceneDesc) []string {
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name != "" {
+			seen[name] = true
+		}
+	}
+	addTexture := func(d *textureDesc) {
+		if d != nil && d.Type == "image" {
+			add(d.File)
+		}
+	}
+
+	add(desc.Film.LUT)
+	for _, m := range desc.Materials {
+		if m.Type == "merl" {
+			add(m.File)
+		}
+		add(m.NormalMap)
+		for _, t := range []*textureDesc{m.Texture, m.Mask, m.RoughnessMap, m.Bump} {
+			addTexture(t)
+		}
+	}
+	for _, s := range desc.Shapes {
+		if s.Type == "obj" {
+			add(s.File)
+		}
+	}
+	for _, l := range desc.Lights {
+		if l.Type == "environment" || l.Type == "mesh" {
+			add(l.File)
+		}
+		addTexture(l.Emission)
+	}
+	if desc.Render.Mask != nil {
+		add(desc.Render.Mask.File)
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// unusedMaterials returns the names of the materials no shape uses, directly
+// or mixed into another, sorted.
+func unusedMaterials(desc *sceneDesc) []string {
+	used := make(map[string]bool)
+	var use func(name string)
+	use = func(name string) {
+		if name == "" || used[name] {
+			return
+		}
+		used[name] = true
+		if d, ok := desc.Materials[name]; ok && d.Type == "mix" {
+			use(d.A)
+			use(d.B)
+		}
+	}
+	for _, s := range desc.Shapes {
+		use(s.Material)
+	}
+
+	var unused []string
+	for name := range desc.Materials {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// triangleArea returns the area of the shape if it's a triangle.
+func triangleArea(sh shape.Shape) (float64, bool) {
+	var p0, p1, p2 geo.Vec
+	switch t := sh.(type) {
+	case *shape.Triangle:
+		p0, p1, p2 = t.P1, t.P2, t.P3
+	case *shape.MeshFace:
+		i0, i1, i2 := t.Vertices()
+		pos := t.Mesh.Positions
+		p0, p1, p2 = pos[i0], pos[i1], pos[i2]
+	default:
+		return 0, false
+	}
+	return p1.Minus(p0).Cross(p2.Minus(p0)).Len() / 2, true
+}
+
+// typeName returns the name of v's type, without its package.
+func typeName(v any) string {
+	t := fmt.Sprintf("%T", v)
+	return t[strings.LastIndex(t, ".")+1:]
+}
+
+// counts formats counts by name, like "2 Sphere, 10 MeshFace", largest first.
+func counts(m map[string]int) string {
+	if len(m) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if m[names[i]] != m[names[j]] {
+			return m[names[i]] > m[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%d %s", m[name], name)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func finite(v geo.Vec) bool {
+	for _, x := range []float64{v.X, v.Y, v.Z} {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package scene
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/asset"
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+const inspectOBJ = `
+v 0 0 0
+v 1 0 0
+v 0 1 0
+v 2 0 0
+v nan 0 0
+f 1 2 3
+f 1 2 4
+f 1 2 5
+`
+
+func TestInspect(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "bad.obj"), []byte(inspectOBJ), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "test.json"), []byte(`{
+		"film": {"width": 4, "height": 4},
+		"materials": {
+			"red": {"type": "lambertian", "color": [1, 0, 0]},
+			"blue": {"type": "lambertian", "color": [0, 0, 1]},
+			"mixed": {"type": "mix", "a": "red", "b": "blue"},
+			"spare": {"type": "mirror"}
+		},
+		"shapes": [
+			{"type": "sphere", "center": [0, 0, -2], "radius": 1, "material": "mixed"},
+			{"type": "triangle", "vertices": [[0, 0, 0], [1, 1, 1], [2, 2, 2]]},
+			{"type": "obj", "file": "bad.obj"}
+		],
+		"lights": [{"type": "point"}, {"type": "point"}, {"type": "directional", "direction": [0, -1, 0]}]
+	}`), 0o644))
+
+	r, err := Inspect(asset.NewResolver(), filepath.Join(dir, "test.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"Sphere": 1, "Triangle": 1, "MeshFace": 3}, r.Shapes)
+	assert.Equal(t, map[string]int{"Point": 2, "Directional": 1}, r.Lights)
+	assert.Equal(t, &geo.Bounds{geo.V(-1, -1, -3), geo.V(2, 2, 2)}, r.Bounds)
+	assert.Empty(t, r.Missing)
+	assert.Equal(t, []string{
+		"shape 1: 1 zero-area triangles",
+		"shape 2: 1 primitives with NaN or infinite coordinates",
+		"shape 2: 1 zero-area triangles",
+		`material "spare" is never used`,
+	}, r.Problems)
+	assert.Contains(t, r.String(), "shapes: 3 MeshFace, 1 Sphere, 1 Triangle")
+}
+
+func TestInspect_Missing(t *testing.T) {
+	r, err := InspectReader(strings.NewReader(`{
+		"film": {"width": 4, "height": 4, "lut": "look.cube"},
+		"materials": {"m": {"type": "lambertian", "texture": {"type": "image", "file": "wood.png"}}},
+		"shapes": [{"type": "obj", "file": "teapot.obj", "material": "m"}, {"type": "obj", "file": "teapot.obj"}],
+		"lights": [{"type": "environment", "file": "sky.hdr"}]
+	}`), asset.NewResolver())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"look.cube", "sky.hdr", "teapot.obj", "wood.png"}, r.Missing)
+	assert.Empty(t, r.Shapes)
+	assert.Contains(t, r.String(), "missing: wood.png")
+
+	// a scene that can't be built is a problem, not an error
+	r, err = InspectReader(strings.NewReader(`{"film": {"width": 4, "height": 4}, "camera": {"eye": [1, 1, 1], "target": [1, 1, 1]}}`), asset.NewResolver())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"camera: transform is NaN (is the eye at the target?)"}, r.Problems)
+	r, err = InspectReader(strings.NewReader(`{"film": {"width": 0}}`), asset.NewResolver())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"film: invalid resolution 0x0"}, r.Problems)
+
+	_, err = InspectReader(strings.NewReader(`{"film": {"dpi": 300}}`), asset.NewResolver())
+	assert.ErrorContains(t, err, "unknown field")
+}
@@ -0,0 +1,126 @@
+// Package scene loads scenes from a declarative JSON description: the film,
+// camera, shapes, materials and lights, plus render settings. A minimal scene
+// looks like
+//
+//	{
+//	  "film": {"width": 640, "height": 360},
+//	  "camera": {"fov": 60, "eye": [0, 1, 4], "target": [0, 0, 0]},
+//	  "materials": {
+//	    "red": {"type": "lambertian", "color": [0.8, 0.1, 0.1]}
+//	  },
+//	  "shapes": [
+//	    {"type": "sphere", "center": [0, 0, 0], "radius": 1, "material": "red"}
+//	  ],
+//	  "lights": [
+//	    {"type": "point", "position": [0, 4, 2], "intensity": 50}
+//	  ],
+//	  "render": {"samples": 64, "maxDepth": 8}
+//	}
+//
+// Colors and spectra are either a single number (a flat spectrum) or red,
+// green and blue values. File names (meshes, MERL materials, environment maps)
+// are resolved relative to the scene file first. See the desc types for all
+// the options.
+package scene
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/gmhorn/gremlin/archive/pkg/asset"
+	"github.com/gmhorn/gremlin/archive/pkg/camera"
+	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+	"github.com/gmhorn/gremlin/archive/pkg/light"
+	"github.com/gmhorn/gremlin/archive/pkg/render"
+	"github.com/gmhorn/gremlin/archive/pkg/sampler"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+)
+
+// Scene is a loaded scene, ready to render.
+type Scene struct {
+	Film       *camera.Film
+	Camera     *camera.Perspective
+	Shapes     []shape.Shape
+	Lights     []light.Light
+	Integrator render.Integrator
+
+	// Colorspace is the RGB space to write the film's images in.
+	Colorspace colorspace.RGB
+
+	// Samples is the number of samples per pixel.
+	Samples int
+
+	// Seed, NewSampler and Dither are the render options (see
+	// render.Options) used by Render. A nil NewSampler is the default sampler.
+	Seed       uint64
+	NewSampler func(seed uint64) sampler.Sampler
+	Dither     bool
+
+	// FalseColor, if set, is the scale for a false color image of the
+	// luminance, which the film records (see camera.Film.FalseColor).
+	FalseColor *camera.FalseColorScale
+
+	// Mask, if set, spreads the samples over the film by importance (see
+	// render.Mask).
+	Mask render.Mask
+
+	// Cull, if the scene asked for it, reports which shapes are outside the
+	// camera frustum. If the scene also asked to prune them, they've been
+	// left out of Shapes; the report's indices are from before that.
+	Cull *render.CullReport
+}
+
+// Load opens the named scene file with the resolver and reads it. Files the
+// scene refers to are looked for next to it first.
+func Load(res *asset.Resolver, name string) (*Scene, error) {
+	f, err := res.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s, err := Read(f, res.Relative(filepath.Dir(name)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return s, nil
+}
+
+// Read reads a scene description and builds the scene, loading the files it
+// refers to with the resolver. Unknown fields are errors, to catch typos.
+func Read(r io.Reader, res *asset.Resolver) (*Scene, error) {
+	desc, err := decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return newBuilder(desc, res).build()
+}
+
+// decode reads a scene description.
+func decode(r io.Reader) (*sceneDesc, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var desc sceneDesc
+	if err := dec.Decode(&desc); err != nil {
+		return nil, err
+	}
+	return &desc, nil
+}
+
+// Render renders the scene into its film. If the context is cancelled, it
+// stops early like render.Render.
+func (s *Scene) Render(ctx context.Context) error {
+	opts := &render.Options{Seed: s.Seed, NewSampler: s.NewSampler, Dither: s.Dither, Mask: s.Mask}
+	return render.Progressive(ctx, s.Film, s.Camera, s.Shapes, s.Integrator, opts, 1, s.Samples, func(int, *camera.Film) bool {
+		return true
+	})
+}
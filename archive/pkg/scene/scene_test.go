@@ -0,0 +1,265 @@
+package scene
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/asset"
+	"github.com/gmhorn/gremlin/archive/pkg/camera"
+	"github.com/gmhorn/gremlin/archive/pkg/colorspace"
+	"github.com/gmhorn/gremlin/archive/pkg/light"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/gmhorn/gremlin/archive/pkg/render"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+	"github.com/gmhorn/gremlin/archive/pkg/texture"
+	"github.com/stretchr/testify/assert"
+)
+
+const testScene = `{
+  "film": {"width": 8, "height": 4, "filter": {"type": "tent", "radius": 1}, "lut": "look.cube", "colorspace": "ACEScg"},
+  "camera": {"fov": 45, "eye": [0, 1, 4], "target": [0, 0, 0]},
+  "materials": {
+    "red": {"type": "lambertian", "color": [0.8, 0.1, 0.1]},
+    "glass": {"type": "dielectric", "ior": 1.5, "priority": 2},
+    "window": {"type": "thinDielectric", "ior": 1.5},
+    "blend": {"type": "mix", "a": "red", "b": "glass", "amount": 0.25},
+    "checks": {"type": "lambertian", "texture": {"type": "checkerboard", "a": 0.2, "b": [0.8, 0.8, 0.1], "frequency": 8}},
+    "tiles": {"type": "lambertian", "bump": {"type": "checkerboard", "frequency": 4}, "bumpScale": 0.01},
+    "marble": {"type": "mix", "a": "red", "b": "checks", "mask": {"type": "noise", "frequency": 4, "octaves": 3}},
+    "gold": {"type": "microfacet", "color": [1, 0.8, 0.3], "metallic": 1, "roughnessMap": {"type": "noise", "a": 0.2, "b": 0.4}}
+  },
+  "shapes": [
+    {"type": "sphere", "center": [0, 0, 0], "radius": 1, "material": "blend"},
+    {"type": "triangle", "vertices": [[-5, -1, -5], [5, -1, -5], [0, -1, 5]], "material": "marble"},
+    {"type": "obj", "file": "quad.obj", "material": "tiles"},
+    {"type": "sphere", "center": [2, 0, 0], "radius": 0.5, "material": "gold"}
+  ],
+  "lights": [
+    {"type": "point", "position": [0, 4, 2], "intensity": "D65", "lumens": 800, "always": true},
+    {"type": "directional", "direction": [0, -1, 0], "importance": 0.5},
+    {"type": "sunSky", "latitude": 40, "longitude": -74, "time": "2023-06-21T12:00:00-04:00"},
+    {"type": "mesh", "file": "quad.obj", "emission": {"type": "checkerboard", "frequency": 2}, "scale": 5},
+    {"type": "softbox", "position": [-3, 2, 2], "target": [0, 0, 0], "width": 1, "height": 1.5, "radiance": 4, "falloff": 2, "barnDoors": [40, 0]}
+  ],
+  "render": {"samples": 2, "maxDepth": 4, "maxShadowDistance": 20, "regularize": 0.25, "sampler": "halton", "seed": 7, "dither": true, "aovs": ["depth"],
+    "falseColor": {"min": 1, "max": 1000, "log": true, "steps": 8}, "mask": {"file": "mask.png", "min": 0.5}}
+}`
+
+const quadOBJ = `
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+vt 0 0
+vt 1 0
+vt 1 1
+vt 0 1
+f 1/1 2/2 3/3 4/4
+`
+
+func TestLoad(t *testing.T) {
+	// The mesh is found next to the scene file
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "test.json"), []byte(testScene), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "quad.obj"), []byte(quadOBJ), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "look.cube"), []byte("LUT_1D_SIZE 2\n0 0 0\n1 1 0.9\n"), 0o644))
+	mask := image.NewGray(image.Rect(0, 0, 2, 1))
+	mask.Pix[0] = 255
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, mask))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "mask.png"), buf.Bytes(), 0o644))
+
+	s, err := Load(asset.NewResolver(), filepath.Join(dir, "test.json"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 8, s.Film.Width)
+	assert.IsType(t, &camera.TentFilter{}, s.Film.Filter)
+	assert.Equal(t, 2, s.Film.LUT.Size)
+	assert.Equal(t, colorspace.ACEScg.Linear(colorspace.Point{1, 1, 1}), s.Colorspace.Linear(colorspace.Point{1, 1, 1}))
+	assert.Equal(t, []camera.AOV{camera.AOVDepth, camera.AOVLuminance}, s.Film.AOVs())
+	assert.Equal(t, &camera.FalseColorScale{Min: 1, Max: 1000, Log: true, Steps: 8}, s.FalseColor)
+	assert.Equal(t, 4, s.Mask.Samples(0, 3))
+	assert.Equal(t, 2, s.Mask.Samples(7, 3))
+
+	// Sphere, triangle, the quad's two faces and another sphere
+	assert.Len(t, s.Shapes, 5)
+	sphere := s.Shapes[0].(*shape.Sphere)
+	assert.IsType(t, &material.Mix{}, sphere.Material)
+	assert.Equal(t, 2, sphere.Material.(*material.Mix).B.(*material.Dielectric).Priority)
+	marble := s.Shapes[1].(*shape.Triangle).Material.(*material.Mix)
+	assert.IsType(t, &texture.Noise{}, marble.Mask)
+	assert.IsType(t, &texture.Checkerboard{}, marble.B.(*material.Lambertian).Texture)
+
+	// the bump mapped mesh gets smooth tangents
+	quad := s.Shapes[2].(*shape.MeshFace)
+	assert.IsType(t, &material.Bump{}, quad.Surface())
+	assert.Len(t, quad.Mesh.Tangents, 4)
+	gold := s.Shapes[4].(*shape.Sphere).Material.(*material.Microfacet)
+	assert.Equal(t, 1.0, gold.Metallic)
+	assert.IsType(t, &texture.Noise{}, gold.RoughnessMap)
+
+	bulb := s.Lights[0].(*light.Point)
+	assert.InDelta(t, 800, colorspace.CIE1931Reflectance.Convert(bulb.Power())[1], 1e-6)
+
+	// the sun and sky come as a pair
+	assert.Len(t, s.Lights, 6)
+	assert.Greater(t, s.Lights[1].(*light.Directional).SceneRadius, 0.0)
+	assert.Less(t, s.Lights[2].(*light.Directional).Dir.Y, 0.0)
+	assert.Greater(t, s.Lights[3].(*light.Environment).SceneRadius, 0.0)
+	screen := s.Lights[4].(*light.Mesh)
+	assert.IsType(t, &texture.Checkerboard{}, screen.Emission)
+	assert.Equal(t, 5.0, screen.Scale)
+	softbox := s.Lights[5].(*light.Panel)
+	assert.Equal(t, light.Profile{Falloff: 2, BarnDoors: [2]float64{40, 0}}, softbox.Profile)
+
+	assert.Equal(t, 2, s.Samples)
+	pt := s.Integrator.(*render.PathTracer)
+	assert.Equal(t, 4, pt.MaxDepth)
+	assert.Equal(t, 20.0, pt.MaxShadowDist)
+	assert.Equal(t, 0.25, pt.Regularize)
+	assert.Equal(t, s.Lights, pt.Lights)
+	assert.Equal(t, []render.LightHint{{Always: true}, {Importance: 0.5}, {}, {}, {}, {}}, pt.LightHints)
+	assert.Equal(t, uint64(7), s.Seed)
+	assert.True(t, s.Dither)
+
+	assert.NoError(t, s.Render(context.Background()))
+}
+
+func TestRead_Defaults(t *testing.T) {
+	s, err := Read(strings.NewReader(`{"film": {"width": 4, "height": 4}}`), asset.NewResolver())
+	assert.NoError(t, err)
+	assert.Equal(t, defaultSamples, s.Samples)
+	assert.Equal(t, defaultMaxDepth, s.Integrator.(*render.PathTracer).MaxDepth)
+	assert.NotNil(t, s.NewSampler)
+	assert.Empty(t, s.Shapes)
+}
+
+func TestRead_AmbientOcclusion(t *testing.T) {
+	s, err := Read(strings.NewReader(`{"film": {"width": 4, "height": 4}, "render": {"integrator": "ao", "aoRadius": 2}}`), asset.NewResolver())
+	assert.NoError(t, err)
+	assert.Equal(t, 2.0, s.Integrator.(*render.AmbientOcclusion).Radius)
+}
+
+func TestRead_Cull(t *testing.T) {
+	const scene = `{
+		"film": {"width": 4, "height": 4},
+		"camera": {"fov": 60, "eye": [0, 0, 5], "target": [0, 0, 0]},
+		"shapes": [
+			{"type": "sphere", "center": [0, 0, 0], "radius": 1},
+			{"type": "sphere", "center": [0, 0, 10], "radius": 1}
+		],
+		"render": {"cull": "%s"}
+	}`
+
+	s, err := Read(strings.NewReader(fmt.Sprintf(scene, "report")), asset.NewResolver())
+	assert.NoError(t, err)
+	assert.Equal(t, &render.CullReport{Visible: []int{0}, Culled: []int{1}}, s.Cull)
+	assert.Len(t, s.Shapes, 2)
+
+	s, err = Read(strings.NewReader(fmt.Sprintf(scene, "prune")), asset.NewResolver())
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, s.Cull.Culled)
+	assert.Len(t, s.Shapes, 1)
+
+	s, err = Read(strings.NewReader(fmt.Sprintf(scene, "")), asset.NewResolver())
+	assert.NoError(t, err)
+	assert.Nil(t, s.Cull)
+}
+
+func TestRead_Illuminant(t *testing.T) {
+	s, err := Read(strings.NewReader(`{"film": {"width": 4, "height": 4},
+		"lights": [{"type": "point", "intensity": "F10"}]}`), asset.NewResolver())
+	assert.NoError(t, err)
+	// scaled, but with the tri-band peaks of F10's table
+	intensity := s.Lights[0].(*light.Point).Intensity
+	assert.InDelta(t, 73.69/40.98, intensity[33]/intensity[32], 1e-9)
+}
+
+func TestRead_Water(t *testing.T) {
+	s, err := Read(strings.NewReader(`{"film": {"width": 4, "height": 4},
+		"materials": {
+			"pool": {"type": "water", "roughness": 0.1, "priority": 1},
+			"frosted": {"type": "dielectric", "ior": 1.5, "roughness": 0.4, "absorption": [0.1, 0.2, 0.3]}
+		},
+		"shapes": [
+			{"type": "sphere", "radius": 1, "material": "pool"},
+			{"type": "sphere", "radius": 1, "material": "frosted"}
+		]}`), asset.NewResolver())
+	assert.NoError(t, err)
+	pool := s.Shapes[0].(*shape.Sphere).Material.(*material.Dielectric)
+	assert.Equal(t, 0.1, pool.Roughness)
+	assert.Equal(t, 1, pool.Priority)
+	assert.NotNil(t, pool.Absorption)
+	frosted := s.Shapes[1].(*shape.Sphere).Material.(*material.Dielectric)
+	assert.Equal(t, 0.4, frosted.Roughness)
+	assert.Greater(t, frosted.Absorption.Lookup(450), frosted.Absorption.Lookup(650))
+}
+
+func TestRead_Clouds(t *testing.T) {
+	s, err := Read(strings.NewReader(`{"film": {"width": 4, "height": 4}, "lights": [{"type": "sunSky",
+		"time": "2023-06-21T12:00:00Z", "clouds": {"coverage": 0.4, "bottom": 500, "top": 900}}]}`), asset.NewResolver())
+	assert.NoError(t, err)
+	assert.Len(t, s.Lights, 2)
+	clouds := s.Lights[1].(*light.Clouds)
+	assert.Same(t, s.Lights[0], clouds.Sun)
+	assert.IsType(t, &light.Environment{}, clouds.Sky)
+	assert.Equal(t, 0.4, clouds.Coverage)
+	assert.Equal(t, [2]float64{500, 900}, [2]float64{clouds.Bottom, clouds.Top})
+}
+
+func TestRead_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		scene string
+		err   string
+	}{
+		{"Resolution", `{}`, "invalid resolution"},
+		{"UnknownField", `{"film": {"width": 4, "height": 4, "dpi": 300}}`, "unknown field"},
+		{"Color", `{"film": {"width": 4, "height": 4}, "materials": {"m": {"type": "mirror", "color": {"r": 1}}}}`, "spectrum must be"},
+		{"Filter", `{"film": {"width": 4, "height": 4, "filter": {"type": "lanczos", "radius": 1}}}`, "unknown filter"},
+		{"Material", `{"film": {"width": 4, "height": 4}, "shapes": [{"type": "sphere", "radius": 1, "material": "gold"}]}`, `shape 0: unknown material "gold"`},
+		{"MixCycle", `{"film": {"width": 4, "height": 4},
+			"materials": {"a": {"type": "mix", "a": "b", "b": "b"}, "b": {"type": "mix", "a": "a", "b": "a"}},
+			"shapes": [{"type": "sphere", "radius": 1, "material": "a"}]}`, "mixes itself"},
+		{"Texture", `{"film": {"width": 4, "height": 4}, "materials": {"m": {"type": "lambertian", "texture": {"type": "wood"}}}, "shapes": [{"type": "sphere", "radius": 1, "material": "m"}]}`, `unknown texture type "wood"`},
+		{"Detail", `{"film": {"width": 4, "height": 4}, "materials": {"m": {"type": "mirror", "normalMap": "n.png", "bump": {"type": "noise"}}}, "shapes": [{"type": "sphere", "radius": 1, "material": "m"}]}`, "both a normal map and a bump map"},
+		{"Roughness", `{"film": {"width": 4, "height": 4}, "materials": {"m": {"type": "microfacet", "roughness": 2}}, "shapes": [{"type": "sphere", "radius": 1, "material": "m"}]}`, "between 0 and 1"},
+		{"Water", `{"film": {"width": 4, "height": 4}, "materials": {"m": {"type": "water", "roughness": -0.1}}, "shapes": [{"type": "sphere", "radius": 1, "material": "m"}]}`, "between 0 and 1"},
+		{"Colorspace", `{"film": {"width": 4, "height": 4, "colorspace": "P3"}}`, `unknown colorspace "P3"`},
+		{"Shape", `{"film": {"width": 4, "height": 4}, "shapes": [{"type": "cube"}]}`, "unknown shape type"},
+		{"MissingFile", `{"film": {"width": 4, "height": 4}, "shapes": [{"type": "obj", "file": "missing.obj"}]}`, "missing.obj"},
+		{"Light", `{"film": {"width": 4, "height": 4}, "lights": [{"type": "spot"}]}`, "light 0: unknown light type"},
+		{"SunTime", `{"film": {"width": 4, "height": 4}, "lights": [{"type": "sunSky", "time": "noon"}]}`, "light 0: parsing time"},
+		{"Clouds", `{"film": {"width": 4, "height": 4}, "lights": [{"type": "sunSky", "time": "2023-06-21T12:00:00Z", "clouds": {"coverage": 2}}]}`, "cloud coverage must be in [0, 1]"},
+		{"CloudLayer", `{"film": {"width": 4, "height": 4}, "lights": [{"type": "sunSky", "time": "2023-06-21T12:00:00Z", "clouds": {"bottom": 900, "top": 500}}]}`, "bottom < top"},
+		{"Softbox", `{"film": {"width": 4, "height": 4}, "lights": [{"type": "softbox", "target": [0, 0, -1]}]}`, "light 0: softbox size must be positive"},
+		{"Aim", `{"film": {"width": 4, "height": 4}, "lights": [{"type": "ringLight", "diameter": 1}]}`, "aimed at itself"},
+		{"Illuminant", `{"film": {"width": 4, "height": 4}, "lights": [{"type": "point", "intensity": "D75"}]}`, `unknown illuminant "D75"`},
+		{"Lumens", `{"film": {"width": 4, "height": 4}, "lights": [{"type": "directional", "lumens": 100}]}`, "can't set lumens of directional light"},
+		{"Importance", `{"film": {"width": 4, "height": 4}, "lights": [{"type": "point", "importance": -1}]}`, "light 0: importance must not be negative"},
+		{"Integrator", `{"film": {"width": 4, "height": 4}, "render": {"integrator": "bdpt"}}`, "unknown integrator"},
+		{"Distance", `{"film": {"width": 4, "height": 4}, "render": {"aoRadius": -1}}`, "must not be negative"},
+		{"Regularize", `{"film": {"width": 4, "height": 4}, "render": {"regularize": 2}}`, "regularize must be"},
+		{"Sampler", `{"film": {"width": 4, "height": 4}, "render": {"sampler": "sobel"}}`, "unknown sampler"},
+		{"FalseColor", `{"film": {"width": 4, "height": 4}, "render": {"falseColor": {"min": 0, "max": 10, "log": true}}}`, "invalid falseColor scale"},
+		{"Mask", `{"film": {"width": 4, "height": 4}, "render": {"mask": {"file": "mask.png", "min": -1}}}`, "mask min must be between 0 and 1"},
+		{"AOV", `{"film": {"width": 4, "height": 4}, "render": {"aovs": ["normals"]}}`, `unknown AOV "normals"`},
+		{"Cull", `{"film": {"width": 4, "height": 4}, "render": {"cull": "hide"}}`, `unknown cull "hide"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(test.scene), asset.NewResolver())
+			assert.ErrorContains(t, err, test.err)
+		})
+	}
+}
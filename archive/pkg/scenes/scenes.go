@@ -0,0 +1,184 @@
+// Package scenes generates procedural scenes: fields of spheres, city blocks
+// of boxes and copies of a shape scattered over the ground or a mesh, for
+// benchmarks and generative art. Everything is driven by a seed, so the same
+// seed always gives the same scene.
+//
+// Generators return shapes resting on the ground plane (y = 0) and centered on
+// the origin. The ground itself, the camera and lights are left to the caller.
+package scenes
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/gmhorn/gremlin/archive/pkg/sample"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/gmhorn/gremlin/archive/pkg/util"
+)
+
+// SphereField returns spheres of random sizes, up to radius, scattered over a
+// size by size square without touching. Their materials are a random mix of
+// diffuse, metal and glass, like the cover of "Ray Tracing in One Weekend".
+func SphereField(seed uint64, size, radius float64) []shape.Shape {
+	if size <= 0 || radius <= 0 {
+		panic("sphere field size and radius must be positive")
+	}
+	rng := util.NewRand(seed)
+	points := sample.PoissonDisk(rng, size, size, 2*radius)
+
+	spheres := make([]shape.Shape, len(points))
+	for i, p := range points {
+		r := radius * (0.5 + 0.5*rng.Float64())
+		spheres[i] = &shape.Sphere{
+			Center:   geo.V(p[0]-size/2, r, p[1]-size/2),
+			Radius:   r,
+			Material: randomMaterial(rng),
+		}
+	}
+	return spheres
+}
+
+// randomMaterial returns a diffuse material four times out of five, and
+// otherwise a metal or glass one.
+func randomMaterial(rng *rand.Rand) material.Material {
+	switch x := rng.Float64(); {
+	case x < 0.8:
+		// squared, for richer colors
+		r, g, b := rng.Float64(), rng.Float64(), rng.Float64()
+		return material.NewLambertian(spectrum.FromRGB(r*r, g*g, b*b))
+	case x < 0.95:
+		r, g, b := rng.Float64(), rng.Float64(), rng.Float64()
+		color := spectrum.FromRGB(0.5+0.5*r, 0.5+0.5*g, 0.5+0.5*b)
+		return material.NewMicrofacet(color, 0.5*rng.Float64(), 1)
+	default:
+		return material.NewDielectric(spectrum.Flat(1.5))
+	}
+}
+
+// City returns a blocks by blocks grid of city blocks of boxes, each block a
+// size by size square with streets a fifth as wide between them. Blocks are
+// split into lots of a few buildings, most of them low and a few towering.
+func City(seed uint64, blocks int, size float64) []shape.Shape {
+	if blocks < 1 || size <= 0 {
+		panic("city must have positive blocks and size")
+	}
+	rng := util.NewRand(seed)
+
+	// a box per concrete color, shared by the buildings that use it
+	var boxes [][]shape.Shape
+	for _, gray := range []float64{0.3, 0.45, 0.6, 0.75} {
+		boxes = append(boxes, Box(material.NewLambertian(spectrum.Flat(gray))).Faces())
+	}
+
+	const lots = 2
+	street := size / 5
+	lot := size / lots
+	offset := (float64(blocks)*(size+street) - street) / 2
+
+	var shapes []shape.Shape
+	for bx := 0; bx < blocks; bx++ {
+		for bz := 0; bz < blocks; bz++ {
+			for lx := 0; lx < lots; lx++ {
+				for lz := 0; lz < lots; lz++ {
+					// footprints leave a gap between neighbors
+					w := lot * (0.6 + 0.3*rng.Float64())
+					d := lot * (0.6 + 0.3*rng.Float64())
+					h := size * 0.3 * math.Exp(1.2*rng.NormFloat64())
+					x := float64(bx)*(size+street) + (float64(lx)+0.5)*lot - offset
+					z := float64(bz)*(size+street) + (float64(lz)+0.5)*lot - offset
+
+					box := boxes[rng.Intn(len(boxes))]
+					shapes = append(shapes, shape.NewInstances(box, geo.Shift(geo.V(x, 0, z)).Mult(geo.Scale(geo.V(w, h, d))))...)
+				}
+			}
+		}
+	}
+	return shapes
+}
+
+// Scatter returns copies of the shapes (e.g. the faces of a mesh) placed over
+// a width by depth rectangle, at least spacing apart. Each copy is turned
+// about the y axis at random, and scaled by up to 20% either way, so they
+// don't look stamped out.
+func Scatter(seed uint64, shapes []shape.Shape, width, depth, spacing float64) []shape.Shape {
+	if width <= 0 || depth <= 0 || spacing <= 0 {
+		panic("scatter area and spacing must be positive")
+	}
+	rng := util.NewRand(seed)
+	points := sample.PoissonDisk(rng, width, depth, spacing)
+
+	var scattered []shape.Shape
+	for _, p := range points {
+		s := 0.8 + 0.4*rng.Float64()
+		objectToWorld := geo.Shift(geo.V(p[0]-width/2, 0, p[1]-depth/2)).
+			Mult(geo.Rotate(2*math.Pi*rng.Float64(), geo.YAxis)).
+			Mult(geo.Scale(geo.V(s, s, s)))
+		scattered = append(scattered, shape.NewInstances(shapes, objectToWorld)...)
+	}
+	return scattered
+}
+
+// ScatterOnMesh is like Scatter, but places the copies on the surface of a
+// mesh, such as terrain, with their y axes along its normal.
+func ScatterOnMesh(seed uint64, shapes []shape.Shape, surface *shape.Mesh, spacing float64) []shape.Shape {
+	rng := util.NewRand(seed)
+	points := sample.MeshPoissonDisk(rng, surface, spacing)
+
+	var scattered []shape.Shape
+	for _, p := range points {
+		s := 0.8 + 0.4*rng.Float64()
+		objectToWorld := geo.Shift(p.Point).
+			Mult(upAlong(p.Normal)).
+			Mult(geo.Rotate(2*math.Pi*rng.Float64(), geo.YAxis)).
+			Mult(geo.Scale(geo.V(s, s, s)))
+		scattered = append(scattered, shape.NewInstances(shapes, objectToWorld)...)
+	}
+	return scattered
+}
+
+// upAlong returns a rotation taking the y axis to n.
+func upAlong(n geo.Unit) *geo.Mtx {
+	f := geo.FrameFromNormal(n)
+	// x to S and y to N, so z goes to S cross N, which is -T
+	return &geo.Mtx{
+		{f.S.X, f.N.X, -f.T.X, 0},
+		{f.S.Y, f.N.Y, -f.T.Y, 0},
+		{f.S.Z, f.N.Z, -f.T.Z, 0},
+		{0, 0, 0, 1},
+	}
+}
+
+// Box returns a unit cube mesh standing on the ground plane: from -0.5 to 0.5
+// along x and z, and from 0 to 1 along y. Faces are flat shaded.
+func Box(m material.Material) *shape.Mesh {
+	var positions []geo.Vec
+	var indices []int
+	// each side is a quad of its own, so the corners aren't smoothed
+	for axis := 0; axis < 3; axis++ {
+		for _, sign := range []float64{-1, 1} {
+			n := [3]float64{}
+			n[axis] = sign
+			u := [3]float64{}
+			u[(axis+1)%3] = 1
+			v := [3]float64{}
+			v[(axis+2)%3] = sign
+
+			base := len(positions)
+			for _, c := range [][2]float64{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+				var p [3]float64
+				for i := range p {
+					p[i] = 0.5 * (n[i] + c[0]*u[i] + c[1]*v[i])
+				}
+				positions = append(positions, geo.V(p[0], p[1]+0.5, p[2]))
+			}
+			indices = append(indices, base, base+1, base+2, base, base+2, base+3)
+		}
+	}
+
+	mesh := shape.NewMesh(positions, nil, nil, indices)
+	mesh.Material = m
+	return mesh
+}
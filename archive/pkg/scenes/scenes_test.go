@@ -0,0 +1,102 @@
+package scenes
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/gmhorn/gremlin/archive/pkg/shape"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSphereField(t *testing.T) {
+	spheres := SphereField(3, 10, 0.4)
+	assert.NotEmpty(t, spheres)
+	for i, s := range spheres {
+		a := s.(*shape.Sphere)
+		assert.Equal(t, a.Radius, a.Center.Y, "resting on the ground")
+		assert.LessOrEqual(t, a.Radius, 0.4)
+		assert.LessOrEqual(t, math.Abs(a.Center.X), 5.0)
+		for _, o := range spheres[:i] {
+			b := o.(*shape.Sphere)
+			assert.GreaterOrEqual(t, a.Center.Minus(b.Center).Len(), a.Radius+b.Radius)
+		}
+	}
+	assert.Equal(t, len(spheres), len(SphereField(3, 10, 0.4)))
+
+	assert.Panics(t, func() { SphereField(3, 10, 0) })
+}
+
+func TestCity(t *testing.T) {
+	city := City(5, 3, 10)
+	// 3x3 blocks of 4 buildings of 12 triangles
+	assert.Len(t, city, 3*3*4*12)
+
+	bounds := city[0].Bounds()
+	for _, s := range city {
+		bounds = bounds.Union(s.Bounds())
+	}
+	assert.InDelta(t, 0, bounds[0].Y, 1e-9)
+	assert.Greater(t, bounds[1].Y, 0.0)
+	// the blocks and streets between them are centered
+	assert.InDelta(t, -bounds[0].X, bounds[1].X, 2)
+	assert.Less(t, bounds[1].X, 3*10+2*2.0)
+}
+
+func TestScatter(t *testing.T) {
+	faces := Box(nil).Faces()
+	scattered := Scatter(7, faces, 20, 10, 2)
+	assert.Zero(t, len(scattered)%len(faces))
+	assert.Greater(t, len(scattered)/len(faces), 20)
+	for _, s := range scattered {
+		b := s.Bounds()
+		assert.GreaterOrEqual(t, b[0].X, -10-1.0)
+		assert.LessOrEqual(t, b[1].Z, 5+1.0)
+	}
+}
+
+func TestScatterOnMesh(t *testing.T) {
+	// a slope rising along z
+	ground := shape.NewMesh([]geo.Vec{geo.V(0, 0, 0), geo.V(10, 0, 0), geo.V(10, 10, 10), geo.V(0, 10, 10)}, nil, nil, []int{0, 2, 1, 0, 3, 2})
+	up := geo.V(0, 1, -1).Unit()
+
+	faces := Box(nil).Faces()
+	scattered := ScatterOnMesh(9, faces, ground, 2)
+	assert.Greater(t, len(scattered)/len(faces), 10)
+	for i := 0; i < len(scattered); i += len(faces) {
+		// the boxes stand up from the slope
+		m := scattered[i].(*shape.Instance).ObjectToWorld()
+		base := m.MultPoint(geo.V(0, 0, 0))
+		top := m.MultPoint(geo.V(0, 1, 0))
+		assert.InDelta(t, base.Y, base.Z, 1e-9)
+		assert.InDelta(t, 1, top.Minus(base).Unit().Dot(up), 1e-9)
+	}
+}
+
+func TestBox(t *testing.T) {
+	box := Box(material.NewMirror(spectrum.Flat(1)))
+	assert.Equal(t, 12, box.NumFaces())
+
+	// rays from outside hit each side facing back at them
+	for _, dir := range []geo.Unit{geo.XAxis, geo.YAxis, geo.ZAxis} {
+		for _, sign := range []float64{-1, 1} {
+			d := geo.Vec(dir).Scale(sign)
+			origin := geo.V(0, 0.5, 0).Minus(d.Scale(3))
+			ray := geo.NewRay(origin, d)
+			nearest := math.Inf(1)
+			var face shape.Shape
+			for _, f := range box.Faces() {
+				if dist := f.Intersect(ray); dist > 0 && dist < nearest {
+					nearest, face = dist, f
+				}
+			}
+			if assert.NotNil(t, face) {
+				assert.InDelta(t, 2.5, nearest, 1e-9)
+				n := face.Interaction(ray.At(nearest)).Normal
+				assert.Less(t, geo.Vec(n).Dot(d), 0.0)
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package shape
+
+import (
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+)
+
+// Instance places a shape in the scene with an object-to-world transform.
+// Rays are transformed into the shape's object space for intersection, and
+// points and normals on it back to world space, so many copies of one shape
+// (e.g. a mesh's faces, see NewInstances) can be placed without duplicating
+// its geometry.
+//
+// Ray directions aren't renormalized in object space, so intersection
+// distances are the same in both spaces.
+//
+// https://www.pbr-book.org/3ed-2018/Primitives_and_Intersection_Acceleration/Primitive_Interface_and_Geometric_Primitives#TransformedPrimitive:ObjectInstancingandAnimatedPrimitives
+type Instance struct {
+	Shape Shape
+
+	objectToWorld *geo.Mtx
+	worldToObject *geo.Mtx
+}
+
+// NewInstance places the shape with the given object-to-world transform, which
+// must be invertible.
+func NewInstance(s Shape, objectToWorld *geo.Mtx) *Instance {
+	return &Instance{
+		Shape:         s,
+		objectToWorld: objectToWorld,
+		worldToObject: objectToWorld.Inv(),
+	}
+}
+
+// NewInstances places all the shapes with the same transform, e.g. the faces
+// of a mesh. The transform (and its inverse) is shared between the instances.
+func NewInstances(shapes []Shape, objectToWorld *geo.Mtx) []Shape {
+	worldToObject := objectToWorld.Inv()
+	instances := make([]Instance, len(shapes))
+	result := make([]Shape, len(shapes))
+	for i, s := range shapes {
+		instances[i] = Instance{Shape: s, objectToWorld: objectToWorld, worldToObject: worldToObject}
+		result[i] = &instances[i]
+	}
+	return result
+}
+
+// ObjectToWorld returns the instance's transform.
+func (in *Instance) ObjectToWorld() *geo.Mtx {
+	return in.objectToWorld
+}
+
+func (in *Instance) Intersect(ray *geo.Ray) float64 {
+	return in.Shape.Intersect(in.worldToObject.MultRay(ray))
+}
+
+// Bounds returns the bounds of the transformed corners of the shape's bounds.
+func (in *Instance) Bounds() *geo.Bounds {
+	return in.objectToWorld.MultBounds(in.Shape.Bounds())
+}
+
+// Interaction implements Shape. It's the shape's interaction, transformed to
+// world space.
+func (in *Instance) Interaction(point geo.Vec) Interaction {
+	si := in.Shape.Interaction(in.worldToObject.MultPoint(point))
+	si.Point = point
+	si.Normal = in.objectToWorld.MultNormal(si.Normal)
+	si.DPDU = in.objectToWorld.MultVec(si.DPDU)
+	si.DPDV = in.objectToWorld.MultVec(si.DPDV)
+	si.Shape = in
+	return si
+}
+
+func (in *Instance) Surface() material.Material {
+	return in.Shape.Surface()
+}
+
+// Animated is implemented by shapes that move over time. At returns the shape
+// as it is at the given time, which is what should then be asked for the
+// interaction at a point where a ray of that time hit it.
+type Animated interface {
+	Shape
+	At(time float64) Shape
+}
+
+// Resolve returns the shape at the given time: s itself, or for Animated
+// shapes, the result of At.
+func Resolve(s Shape, time float64) Shape {
+	if as, ok := s.(Animated); ok {
+		return as.At(time)
+	}
+	return s
+}
+
+// Aggregate is implemented by shapes made of others, like an accelerator over
+// them placed as one (see accel.Group). IntersectShape returns the closest
+// intersection of the ray with its shapes, naming the one that was hit, which
+// is what should be asked for the interaction: accelerators containing
+// aggregates report their hits instead of the aggregate itself. Shapes
+// returns the shapes hits can be on.
+type Aggregate interface {
+	Shape
+	IntersectShape(ray *geo.Ray) (Intersection, bool)
+	Shapes() []Shape
+}
+
+// MotionInstance is like Instance, but with a transform that changes over time
+// (typically over the camera's shutter interval), for motion blur. Rays hit
+// the shape where it is at their time.
+//
+// The transform has to be interpolated and inverted for each ray, so moving
+// many shapes together (like the faces of a mesh) is best done with one
+// instance of an Aggregate of them (see accel.NewMotionInstance), which does
+// that once per ray, rather than with an instance per shape. Hits on an
+// aggregate's shapes are instances of the shape that was hit, with the same
+// motion.
+//
+// Like all Animated shapes, a hit should be resolved to the shape at the
+// ray's time (see Resolve and Intersection.Interaction) before asking for its
+// interaction; otherwise, the transform at the start time is used.
+type MotionInstance struct {
+	Shape  Shape
+	Motion *geo.AnimatedTransform
+}
+
+// NewMotionInstances places all the shapes with the same animated transform,
+// each with an instance of its own.
+func NewMotionInstances(shapes []Shape, motion *geo.AnimatedTransform) []Shape {
+	instances := make([]MotionInstance, len(shapes))
+	result := make([]Shape, len(shapes))
+	for i, s := range shapes {
+		instances[i] = MotionInstance{Shape: s, Motion: motion}
+		result[i] = &instances[i]
+	}
+	return result
+}
+
+// At implements Animated.
+func (in *MotionInstance) At(time float64) Shape {
+	return NewInstance(in.Shape, in.Motion.At(time))
+}
+
+func (in *MotionInstance) Intersect(ray *geo.Ray) float64 {
+	hit, found := in.IntersectShape(ray)
+	if !found {
+		return -1
+	}
+	return hit.T
+}
+
+// Shapes implements Aggregate: the instance itself, or if its shape is an
+// Aggregate, instances of that's shapes.
+func (in *MotionInstance) Shapes() []Shape {
+	agg, ok := in.Shape.(Aggregate)
+	if !ok {
+		return []Shape{in}
+	}
+	return NewMotionInstances(agg.Shapes(), in.Motion)
+}
+
+// IntersectShape implements Aggregate. If the instance's shape isn't an
+// Aggregate, the hit shape is the instance itself.
+func (in *MotionInstance) IntersectShape(ray *geo.Ray) (Intersection, bool) {
+	local := in.Motion.At(ray.Time).Inv().MultRay(ray)
+	agg, ok := in.Shape.(Aggregate)
+	if !ok {
+		t := in.Shape.Intersect(local)
+		return Intersection{Shape: in, T: t}, t > 0
+	}
+
+	hit, found := agg.IntersectShape(local)
+	if found {
+		hit.Shape = &MotionInstance{Shape: hit.Shape, Motion: in.Motion}
+	}
+	return hit, found
+}
+
+// Bounds returns bounds enclosing the shape over its whole motion.
+func (in *MotionInstance) Bounds() *geo.Bounds {
+	return in.Motion.MotionBounds(in.Shape.Bounds())
+}
+
+func (in *MotionInstance) Interaction(point geo.Vec) Interaction {
+	return in.At(in.Motion.StartTime).Interaction(point)
+}
+
+func (in *MotionInstance) Surface() material.Material {
+	return in.Shape.Surface()
+}
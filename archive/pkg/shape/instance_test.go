@@ -0,0 +1,92 @@
+package shape
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstance(t *testing.T) {
+	// A unit sphere stretched along x and moved to (5, 0, 0)
+	xf := geo.Shift(geo.V(5, 0, 0)).Mult(geo.Scale(geo.V(2, 1, 1)))
+	in := NewInstance(&Sphere{Radius: 1}, xf)
+
+	hit := in.Intersect(geo.NewRay(geo.V(0, 0, 0), geo.V(1, 0, 0)))
+	assert.InDelta(t, 3.0, hit, 1e-9)
+	miss := in.Intersect(geo.NewRay(geo.V(0, 2, 0), geo.V(1, 0, 0)))
+	assert.Less(t, miss, 0.0)
+
+	// Distances are in world space even for unnormalized directions
+	hit = in.Intersect(geo.NewRay(geo.V(5, 5, 0), geo.V(0, -2, 0)))
+	assert.InDelta(t, 2.0, hit, 1e-9)
+
+	b := in.Bounds()
+	assert.InDelta(t, 3.0, b[0].X, 1e-9)
+	assert.InDelta(t, 7.0, b[1].X, 1e-9)
+	assert.InDelta(t, -1.0, b[0].Y, 1e-9)
+
+	// The normal of the stretched sphere at 45° in object space leans
+	// towards y
+	p := xf.MultPoint(geo.V(math.Sqrt2/2, math.Sqrt2/2, 0))
+	si := in.Interaction(p)
+	assert.Equal(t, p, si.Point)
+	assert.InDelta(t, 1.0, geo.Vec(si.Normal).Len(), 1e-9)
+	assert.Greater(t, si.Normal.Y, si.Normal.X)
+	assert.Same(t, in, si.Shape)
+
+	// UVs are the object's, and the tangents are stretched with it
+	si = in.Interaction(geo.V(7, 0, 0))
+	obj := (&Sphere{Radius: 1}).Interaction(geo.V(1, 0, 0))
+	assert.Equal(t, obj.U, si.U)
+	assert.Equal(t, obj.V, si.V)
+	assert.InDelta(t, obj.DPDU.Len(), si.DPDU.Len(), 1e-9)
+	assert.InDelta(t, 0, si.DPDU.Dot(geo.Vec(si.Normal)), 1e-9)
+}
+
+func TestNewInstances(t *testing.T) {
+	tri := NewTriangle(geo.V(0, 0, 0), geo.V(1, 0, 0), geo.V(1, 1, 0))
+	sphere := &Sphere{Radius: 1}
+	xf := geo.Shift(geo.V(0, 0, -3))
+
+	instances := NewInstances([]Shape{tri, sphere}, xf)
+	assert.Len(t, instances, 2)
+
+	a, b := instances[0].(*Instance), instances[1].(*Instance)
+	assert.Same(t, tri, a.Shape)
+	assert.Same(t, xf, a.ObjectToWorld())
+	assert.Same(t, a.worldToObject, b.worldToObject)
+
+	hit := a.Intersect(geo.NewRay(geo.V(0.75, 0.25, 1), geo.V(0, 0, -1)))
+	assert.InDelta(t, 4.0, hit, 1e-9)
+}
+
+func TestMotionInstance(t *testing.T) {
+	// A unit sphere moving from x = 0 to x = 4 over [0, 1]
+	motion := geo.NewAnimatedTransform(geo.Identity, 0, geo.Shift(geo.V(4, 0, 0)), 1)
+	in := NewMotionInstances([]Shape{&Sphere{Radius: 1}}, motion)[0].(*MotionInstance)
+
+	ray := func(time float64) *geo.Ray {
+		return geo.NewRayAt(geo.V(2, 5, 0), geo.V(0, -1, 0), time)
+	}
+	assert.Less(t, in.Intersect(ray(0)), 0.0)
+	assert.InDelta(t, 4.0, in.Intersect(ray(0.5)), 1e-9)
+	assert.Less(t, in.Intersect(ray(1)), 0.0)
+
+	// Bounds cover the whole motion
+	b := in.Bounds()
+	assert.InDelta(t, -1.0, b[0].X, 1e-9)
+	assert.InDelta(t, 5.0, b[1].X, 1e-9)
+
+	// Resolved to the ray's time, the normal is the moved sphere's
+	n := Resolve(in, 0.5).Interaction(geo.V(3, 0, 0)).Normal
+	assert.InDelta(t, 1.0, n.X, 1e-9)
+	hit := Intersection{Shape: in, T: 5}
+	n = hit.Interaction(geo.NewRayAt(geo.V(3, 0, 5), geo.V(0, 0, -1), 0.5)).Normal
+	assert.InDelta(t, 1.0, n.X, 1e-9)
+
+	// Static shapes resolve to themselves
+	s := &Sphere{Radius: 1}
+	assert.Same(t, s, Resolve(s, 0.5))
+}
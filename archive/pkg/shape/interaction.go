@@ -0,0 +1,61 @@
+package shape
+
+import (
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/gmhorn/gremlin/archive/pkg/texture"
+)
+
+// Interaction describes the surface of a shape at a point, typically where a
+// ray hit it: everything shading and texturing need to know about it.
+//
+// Normal is the shading normal (e.g. interpolated from a mesh's vertex
+// normals), which is what BSDFs are evaluated around. (U, V) are the surface
+// coordinates of the point, and DPDU, DPDV the partial derivatives of the
+// surface position with respect to them, which span the tangent plane (e.g.
+// for bump mapping, or anisotropic materials). Material is the shape's, or nil
+// for the renderer's default.
+//
+// https://www.pbr-book.org/3ed-2018/Geometry_and_Transformations/Interactions#SurfaceInteraction
+type Interaction struct {
+	Point      geo.Vec
+	Normal     geo.Unit
+	U, V       float64
+	DPDU, DPDV geo.Vec
+	Shape      Shape
+	Material   material.Material
+}
+
+// Interaction returns the surface interaction where the ray hit the shape,
+// with animated shapes resolved to the ray's time (see Resolve).
+func (h Intersection) Interaction(ray *geo.Ray) Interaction {
+	return Resolve(h.Shape, ray.Time).Interaction(ray.At(h.T))
+}
+
+// TexCoords returns where textures are looked up for the interaction.
+func (si Interaction) TexCoords() texture.Coords {
+	return texture.Coords{U: si.U, V: si.V, P: si.Point}
+}
+
+// triangleDerivatives returns dp/du and dp/dv for a triangle with vertices p
+// and surface coordinates uv, which are constant across it. If the surface
+// coordinates are degenerate, any two vectors spanning the plane with normal n
+// are returned instead.
+//
+// https://www.pbr-book.org/3ed-2018/Shapes/Triangle_Meshes#Triangle
+func triangleDerivatives(p [3]geo.Vec, uv [3][2]float64, n geo.Unit) (dpdu, dpdv geo.Vec) {
+	duv02 := [2]float64{uv[0][0] - uv[2][0], uv[0][1] - uv[2][1]}
+	duv12 := [2]float64{uv[1][0] - uv[2][0], uv[1][1] - uv[2][1]}
+	dp02, dp12 := p[0].Minus(p[2]), p[1].Minus(p[2])
+
+	det := duv02[0]*duv12[1] - duv02[1]*duv12[0]
+	if det > -1e-12 && det < 1e-12 {
+		frame := geo.FrameFromNormal(n)
+		return geo.Vec(frame.S), geo.Vec(frame.T)
+	}
+
+	inv := 1 / det
+	dpdu = dp02.Scale(duv12[1]).Minus(dp12.Scale(duv02[1])).Scale(inv)
+	dpdv = dp12.Scale(duv02[0]).Minus(dp02.Scale(duv12[0])).Scale(inv)
+	return
+}
@@ -0,0 +1,288 @@
+package shape
+
+import (
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+)
+
+// Mesh is a triangle mesh. Vertex attributes are stored in flat slices shared
+// by all faces, and faces are defined by an index buffer: face i is made of the
+// vertices Indices[3*i], Indices[3*i+1] and Indices[3*i+2].
+//
+// Normals and UVs are optional; if present they must have one entry per
+// position, and are interpolated across each face. Without normals, faces are
+// flat shaded with their geometric normal. Without UVs, faces use the same
+// default parameterization as Triangle.
+//
+// Tangents and Bitangents are optional too: per-vertex dp/du and dp/dv,
+// interpolated like normals so that tangent frames (for normal mapping) vary
+// smoothly across faces instead of jumping at every edge. ComputeTangents
+// fills them in from the UVs.
+//
+// Material applies to the whole mesh, unless FaceMaterials is set: then face i
+// uses Materials[FaceMaterials[i]], for multi-material assets.
+//
+// Compared to a slice of Triangles, a Mesh stores each vertex once and
+// computes edges on the fly, which keeps memory reasonable for models with
+// hundreds of thousands of faces.
+//
+// https://www.pbr-book.org/3ed-2018/Shapes/Triangle_Meshes
+type Mesh struct {
+	Positions []geo.Vec
+	Normals   []geo.Unit
+	UVs       [][2]float64
+	Indices   []int
+	Material  material.Material
+
+	Tangents, Bitangents []geo.Vec
+
+	Materials     []material.Material
+	FaceMaterials []int
+}
+
+// NewMesh creates a new mesh, checking that the buffers are consistent.
+// Panics if they aren't (bad input should be caught by whatever loaded it).
+func NewMesh(positions []geo.Vec, normals []geo.Unit, uvs [][2]float64, indices []int) *Mesh {
+	if len(indices)%3 != 0 {
+		panic("Mesh index buffer length must be a multiple of 3")
+	}
+	if len(normals) != 0 && len(normals) != len(positions) {
+		panic("Mesh must have no normals or one normal per position")
+	}
+	if len(uvs) != 0 && len(uvs) != len(positions) {
+		panic("Mesh must have no UVs or one UV per position")
+	}
+	for _, idx := range indices {
+		if idx < 0 || idx >= len(positions) {
+			panic("Mesh index out of range")
+		}
+	}
+
+	return &Mesh{
+		Positions: positions,
+		Normals:   normals,
+		UVs:       uvs,
+		Indices:   indices,
+	}
+}
+
+// SetFaceMaterials assigns a material to each face: face i gets
+// materials[faceMaterials[i]]. Panics if there isn't one index per face, or
+// they're out of range.
+func (m *Mesh) SetFaceMaterials(materials []material.Material, faceMaterials []int) {
+	if len(faceMaterials) != m.NumFaces() {
+		panic("Mesh must have one material index per face")
+	}
+	for _, idx := range faceMaterials {
+		if idx < 0 || idx >= len(materials) {
+			panic("Mesh material index out of range")
+		}
+	}
+
+	m.Materials = materials
+	m.FaceMaterials = faceMaterials
+}
+
+// ComputeTangents sets Tangents and Bitangents by averaging each face's dp/du
+// and dp/dv (see Interaction) over the faces around each vertex, weighted by
+// the faces' areas. Vertices shared by faces whose UVs run in opposite
+// directions (mirrored UV islands) should be split, or the averages cancel
+// out; OBJ loading does this whenever the UV indices differ.
+//
+// http://www.terathon.com/code/tangent.html
+func (m *Mesh) ComputeTangents() {
+	tangents := make([]geo.Vec, len(m.Positions))
+	bitangents := make([]geo.Vec, len(m.Positions))
+	weights := make([]float64, len(m.Positions))
+	for i := 0; i < m.NumFaces(); i++ {
+		f := MeshFace{Mesh: m, Index: i}
+		i0, i1, i2 := f.Vertices()
+		dpdu, dpdv := f.derivatives()
+		pos := m.Positions
+		area := pos[i1].Minus(pos[i0]).Cross(pos[i2].Minus(pos[i0])).Len()
+		for _, v := range [3]int{i0, i1, i2} {
+			tangents[v] = tangents[v].Plus(dpdu.Scale(area))
+			bitangents[v] = bitangents[v].Plus(dpdv.Scale(area))
+			weights[v] += area
+		}
+	}
+
+	for i, w := range weights {
+		if w > 0 {
+			tangents[i] = tangents[i].Scale(1 / w)
+			bitangents[i] = bitangents[i].Scale(1 / w)
+		}
+	}
+	m.Tangents, m.Bitangents = tangents, bitangents
+}
+
+// NumFaces returns the number of triangles in the mesh.
+func (m *Mesh) NumFaces() int {
+	return len(m.Indices) / 3
+}
+
+// Faces returns a Shape for each face of the mesh, e.g. for building a BVH.
+// The faces are lightweight references into the mesh's buffers, allocated
+// together in a single slice.
+func (m *Mesh) Faces() []Shape {
+	faces := make([]MeshFace, m.NumFaces())
+	shapes := make([]Shape, len(faces))
+	for i := range faces {
+		faces[i] = MeshFace{Mesh: m, Index: i}
+		shapes[i] = &faces[i]
+	}
+	return shapes
+}
+
+// MeshFace is a single triangle of a Mesh.
+type MeshFace struct {
+	Mesh  *Mesh
+	Index int
+}
+
+// Vertices returns the vertex indices of the face.
+func (f *MeshFace) Vertices() (int, int, int) {
+	i := 3 * f.Index
+	return f.Mesh.Indices[i], f.Mesh.Indices[i+1], f.Mesh.Indices[i+2]
+}
+
+// Intersect calculates the ray-triangle intersection using Moller-Trumbore.
+func (f *MeshFace) Intersect(ray *geo.Ray) float64 {
+	i0, i1, i2 := f.Vertices()
+	p0 := f.Mesh.Positions[i0]
+	edge1 := f.Mesh.Positions[i1].Minus(p0)
+	edge2 := f.Mesh.Positions[i2].Minus(p0)
+
+	h := ray.Dir.Cross(edge2)
+	a := h.Dot(edge1)
+	if a > -1e-12 && a < 1e-12 {
+		return -1 // ray parallel to triangle
+	}
+
+	inv := 1 / a
+	s := ray.Origin.Minus(p0)
+	u := inv * s.Dot(h)
+	if u < 0 || u > 1 {
+		return -1
+	}
+
+	q := s.Cross(edge1)
+	v := inv * q.Dot(ray.Dir)
+	if v < 0 || u+v > 1 {
+		return -1
+	}
+
+	return inv * q.Dot(edge2)
+}
+
+func (f *MeshFace) Bounds() *geo.Bounds {
+	i0, i1, i2 := f.Vertices()
+	pos := f.Mesh.Positions
+	return geo.NewBounds(pos[i0], pos[i1]).Extend(pos[i2])
+}
+
+// Normal returns the interpolated vertex normal at the point if the mesh has
+// normals, otherwise the geometric normal of the face.
+func (f *MeshFace) Normal(point geo.Vec) geo.Unit {
+	i0, i1, i2 := f.Vertices()
+
+	if len(f.Mesh.Normals) == 0 {
+		pos := f.Mesh.Positions
+		return pos[i1].Minus(pos[i0]).Cross(pos[i2].Minus(pos[i0])).Unit()
+	}
+
+	b0, b1, b2 := f.barycentric(point)
+	n := f.Mesh.Normals
+	return n[i0].Scale(b0).Plus(n[i1].Scale(b1)).Plus(n[i2].Scale(b2)).Unit()
+}
+
+// UV returns the interpolated texture coordinates at the point if the mesh
+// has UVs, otherwise the default (0, 0), (1, 0), (1, 1) parameterization.
+func (f *MeshFace) UV(point geo.Vec) (u, v float64) {
+	b0, b1, b2 := f.barycentric(point)
+	if len(f.Mesh.UVs) == 0 {
+		return b1 + b2, b2
+	}
+
+	i0, i1, i2 := f.Vertices()
+	uv := f.Mesh.UVs
+	u = b0*uv[i0][0] + b1*uv[i1][0] + b2*uv[i2][0]
+	v = b0*uv[i0][1] + b1*uv[i1][1] + b2*uv[i2][1]
+	return
+}
+
+// Interaction implements Shape. DPDU and DPDV are interpolated from the
+// mesh's tangents if it has them, and otherwise constant across the face.
+func (f *MeshFace) Interaction(point geo.Vec) Interaction {
+	var dpdu, dpdv geo.Vec
+	if len(f.Mesh.Tangents) == 0 {
+		dpdu, dpdv = f.derivatives()
+	} else {
+		i0, i1, i2 := f.Vertices()
+		b0, b1, b2 := f.barycentric(point)
+		t, bt := f.Mesh.Tangents, f.Mesh.Bitangents
+		dpdu = t[i0].Scale(b0).Plus(t[i1].Scale(b1)).Plus(t[i2].Scale(b2))
+		dpdv = bt[i0].Scale(b0).Plus(bt[i1].Scale(b1)).Plus(bt[i2].Scale(b2))
+	}
+
+	u, v := f.UV(point)
+	return Interaction{
+		Point:    point,
+		Normal:   f.Normal(point),
+		U:        u,
+		V:        v,
+		DPDU:     dpdu,
+		DPDV:     dpdv,
+		Shape:    f,
+		Material: f.Surface(),
+	}
+}
+
+// derivatives returns the face's constant dp/du and dp/dv.
+func (f *MeshFace) derivatives() (dpdu, dpdv geo.Vec) {
+	i0, i1, i2 := f.Vertices()
+	pos := f.Mesh.Positions
+	p := [3]geo.Vec{pos[i0], pos[i1], pos[i2]}
+	uv := defaultUVs
+	if len(f.Mesh.UVs) != 0 {
+		uv = [3][2]float64{f.Mesh.UVs[i0], f.Mesh.UVs[i1], f.Mesh.UVs[i2]}
+	}
+	geometric := p[1].Minus(p[0]).Cross(p[2].Minus(p[0])).Unit()
+	return triangleDerivatives(p, uv, geometric)
+}
+
+// Surface returns the face's material.
+func (f *MeshFace) Surface() material.Material {
+	if f.Mesh.FaceMaterials != nil {
+		return f.Mesh.Materials[f.Mesh.FaceMaterials[f.Index]]
+	}
+	return f.Mesh.Material
+}
+
+// barycentric returns the barycentric coordinates of the point with respect
+// to the face's vertices.
+func (f *MeshFace) barycentric(point geo.Vec) (b0, b1, b2 float64) {
+	i0, i1, i2 := f.Vertices()
+	pos := f.Mesh.Positions
+	b1, b2 = barycentric(pos[i0], pos[i1].Minus(pos[i0]), pos[i2].Minus(pos[i0]), point)
+	return 1 - b1 - b2, b1, b2
+}
+
+// barycentric returns the weights (b1, b2) of the second and third vertices of
+// the triangle with first vertex p0 and edges edge1, edge2 for the given
+// point.
+//
+// https://gamedev.stackexchange.com/a/23745
+func barycentric(p0, edge1, edge2, point geo.Vec) (b1, b2 float64) {
+	vp := point.Minus(p0)
+	d00 := edge1.Dot(edge1)
+	d01 := edge1.Dot(edge2)
+	d11 := edge2.Dot(edge2)
+	d20 := vp.Dot(edge1)
+	d21 := vp.Dot(edge2)
+
+	denom := d00*d11 - d01*d01
+	b1 = (d11*d20 - d01*d21) / denom
+	b2 = (d00*d21 - d01*d20) / denom
+	return
+}
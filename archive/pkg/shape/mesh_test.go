@@ -0,0 +1,97 @@
+package shape
+
+import (
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/stretchr/testify/assert"
+)
+
+// unit quad in the z=0 plane, split along its diagonal
+func testQuad() *Mesh {
+	return NewMesh(
+		[]geo.Vec{geo.V(0, 0, 0), geo.V(1, 0, 0), geo.V(1, 1, 0), geo.V(0, 1, 0)},
+		[]geo.Unit{geo.ZAxis, geo.ZAxis, geo.V(1, 0, 1).Unit(), geo.V(1, 0, 1).Unit()},
+		[][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}},
+		[]int{0, 1, 2, 0, 2, 3},
+	)
+}
+
+func TestMesh_Faces(t *testing.T) {
+	faces := testQuad().Faces()
+	assert.Len(t, faces, 2)
+
+	ray := geo.NewRay(geo.V(0.25, 0.75, 2), geo.V(0, 0, -1))
+	assert.Less(t, faces[0].Intersect(ray), 0.0)
+	assert.InDelta(t, 2, faces[1].Intersect(ray), 1e-9)
+
+	assert.Equal(t, geo.NewBounds(geo.V(0, 0, 0), geo.V(1, 1, 0)), faces[0].Bounds())
+}
+
+func TestMeshFace_UV(t *testing.T) {
+	faces := testQuad().Faces()
+	si := faces[1].Interaction(geo.V(0.25, 0.75, 0))
+	assert.InDelta(t, 0.25, si.U, 1e-9)
+	assert.InDelta(t, 0.75, si.V, 1e-9)
+
+	// the quad's UVs line up with x and y
+	assert.InDelta(t, 0, si.DPDU.Minus(geo.V(1, 0, 0)).Len(), 1e-9)
+	assert.InDelta(t, 0, si.DPDV.Minus(geo.V(0, 1, 0)).Len(), 1e-9)
+}
+
+func TestMeshFace_Normal(t *testing.T) {
+	mesh := testQuad()
+	faces := mesh.Faces()
+
+	// at a vertex, the normal is that vertex's normal
+	n := faces[0].Interaction(geo.V(1, 1, 0)).Normal
+	assert.InDelta(t, 0, geo.Vec(n).Minus(geo.Vec(geo.V(1, 0, 1).Unit())).Len(), 1e-9)
+
+	// without normals, faces are flat shaded
+	mesh.Normals = nil
+	assert.Equal(t, geo.ZAxis, faces[0].Interaction(geo.V(1, 1, 0)).Normal)
+}
+
+func TestNewMesh_Panics(t *testing.T) {
+	pos := []geo.Vec{geo.V(0, 0, 0), geo.V(1, 0, 0), geo.V(1, 1, 0)}
+	assert.Panics(t, func() { NewMesh(pos, nil, nil, []int{0, 1}) })
+	assert.Panics(t, func() { NewMesh(pos, nil, nil, []int{0, 1, 3}) })
+	assert.Panics(t, func() { NewMesh(pos, []geo.Unit{geo.ZAxis}, nil, []int{0, 1, 2}) })
+}
+
+func TestMesh_SetFaceMaterials(t *testing.T) {
+	mesh := testQuad()
+	a := material.NewLambertian(spectrum.Flat(0.5))
+	b := material.NewMirror(spectrum.Flat(1))
+	mesh.Material = a
+
+	faces := mesh.Faces()
+	assert.Equal(t, material.Material(a), faces[1].Surface())
+
+	mesh.SetFaceMaterials([]material.Material{a, b}, []int{0, 1})
+	assert.Equal(t, material.Material(a), faces[0].Surface())
+	assert.Equal(t, material.Material(b), faces[1].Surface())
+
+	assert.Panics(t, func() { mesh.SetFaceMaterials([]material.Material{a}, []int{0}) })
+	assert.Panics(t, func() { mesh.SetFaceMaterials([]material.Material{a}, []int{0, 1}) })
+}
+
+func TestMesh_ComputeTangents(t *testing.T) {
+	// a quad stretched 2x along x, with u along x and v along y
+	m := NewMesh(
+		[]geo.Vec{geo.V(0, 0, 0), geo.V(2, 0, 0), geo.V(2, 1, 0), geo.V(0, 1, 0)},
+		nil,
+		[][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}},
+		[]int{0, 1, 2, 0, 2, 3},
+	)
+	m.ComputeTangents()
+	assert.Len(t, m.Tangents, 4)
+
+	for _, f := range m.Faces() {
+		si := f.Interaction(geo.V(1, 0.5, 0))
+		assert.InDelta(t, 0, si.DPDU.Minus(geo.V(2, 0, 0)).Len(), 1e-9)
+		assert.InDelta(t, 0, si.DPDV.Minus(geo.V(0, 1, 0)).Len(), 1e-9)
+	}
+}
@@ -0,0 +1,74 @@
+package shape
+
+// Refinable is implemented by shapes that may not be intersectable
+// themselves, like subdivision surfaces, CSG or curves, but break down into
+// shapes that are. If CanIntersect returns false, only Bounds and Surface
+// need to work, and Refine must return the shapes to use instead, which can
+// be refinable in turn.
+//
+// Scenes are refined (see Refine) before an accelerator is built over them,
+// so it only ever sees intersectable shapes, and the work of breaking shapes
+// down is left until then. This is PBRT's Refine/CanIntersect, from its
+// second edition.
+type Refinable interface {
+	Shape
+	CanIntersect() bool
+	Refine() []Shape
+}
+
+// Refine returns the shapes, with any that can't be intersected replaced by
+// what they refine to, all the way down. Shapes that can be intersected are
+// kept as they are, in order, so a scene without refinable shapes comes back
+// unchanged.
+func Refine(shapes []Shape) []Shape {
+	refined := make([]Shape, 0, len(shapes))
+	for _, s := range shapes {
+		refined = refine(refined, s)
+	}
+	return refined
+}
+
+// refine appends s, or what it refines to, to refined.
+func refine(refined []Shape, s Shape) []Shape {
+	if r, ok := s.(Refinable); ok && !r.CanIntersect() {
+		for _, part := range r.Refine() {
+			refined = refine(refined, part)
+		}
+		return refined
+	}
+	return append(refined, s)
+}
+
+// CanIntersect implements Refinable. An instance can be intersected if the
+// shape it places can.
+func (in *Instance) CanIntersect() bool {
+	return canIntersect(in.Shape)
+}
+
+// Refine implements Refinable, placing each of the shape's parts with the
+// instance's transform.
+func (in *Instance) Refine() []Shape {
+	parts := in.Shape.(Refinable).Refine()
+	instances := make([]Instance, len(parts))
+	result := make([]Shape, len(parts))
+	for i, s := range parts {
+		instances[i] = Instance{Shape: s, objectToWorld: in.objectToWorld, worldToObject: in.worldToObject}
+		result[i] = &instances[i]
+	}
+	return result
+}
+
+// CanIntersect implements Refinable, like Instance.CanIntersect.
+func (in *MotionInstance) CanIntersect() bool {
+	return canIntersect(in.Shape)
+}
+
+// Refine implements Refinable, like Instance.Refine.
+func (in *MotionInstance) Refine() []Shape {
+	return NewMotionInstances(in.Shape.(Refinable).Refine(), in.Motion)
+}
+
+func canIntersect(s Shape) bool {
+	r, ok := s.(Refinable)
+	return !ok || r.CanIntersect()
+}
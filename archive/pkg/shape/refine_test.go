@@ -0,0 +1,73 @@
+package shape
+
+import (
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+	"github.com/stretchr/testify/assert"
+)
+
+// group is a stand-in for a complex shape: it can't be intersected, but
+// refines to its parts.
+type group []Shape
+
+func (g group) Intersect(ray *geo.Ray) float64 {
+	panic("group must be refined")
+}
+
+func (g group) Interaction(point geo.Vec) Interaction {
+	panic("group must be refined")
+}
+
+func (g group) Bounds() *geo.Bounds {
+	b := g[0].Bounds()
+	for _, s := range g[1:] {
+		b = b.Union(s.Bounds())
+	}
+	return b
+}
+
+func (g group) Surface() material.Material { return nil }
+func (g group) CanIntersect() bool         { return false }
+func (g group) Refine() []Shape            { return g }
+
+func TestRefine(t *testing.T) {
+	a := &Sphere{Radius: 1}
+	b := &Sphere{Center: geo.V(3, 0, 0), Radius: 1}
+	c := &Sphere{Center: geo.V(6, 0, 0), Radius: 1}
+
+	// nested groups flatten, in order
+	refined := Refine([]Shape{a, group{b, group{c}}})
+	assert.Equal(t, []Shape{a, b, c}, refined)
+
+	// intersectable shapes come back as they were
+	shapes := []Shape{a, NewInstance(b, geo.Shift(geo.V(0, 1, 0)))}
+	assert.Equal(t, shapes, Refine(shapes))
+}
+
+func TestInstance_Refine(t *testing.T) {
+	b := &Sphere{Radius: 1}
+	c := &Sphere{Center: geo.V(3, 0, 0), Radius: 1}
+	xf := geo.Shift(geo.V(0, 5, 0))
+	in := NewInstance(group{b, c}, xf)
+	assert.False(t, in.CanIntersect())
+
+	refined := Refine([]Shape{in})
+	if assert.Len(t, refined, 2) {
+		for i, s := range []Shape{b, c} {
+			part := refined[i].(*Instance)
+			assert.Same(t, s, part.Shape)
+			assert.Same(t, xf, part.ObjectToWorld())
+		}
+	}
+	// the parts are placed like the group
+	hit := refined[1].Intersect(geo.NewRay(geo.V(3, 0, 0), geo.V(0, 1, 0)))
+	assert.InDelta(t, 4.0, hit, 1e-9)
+
+	motion := NewMotionInstances([]Shape{group{b}}, geo.NewAnimatedTransform(geo.Identity, 0, xf, 1))
+	refined = Refine(motion)
+	if assert.Len(t, refined, 1) {
+		assert.Same(t, b, refined[0].(*MotionInstance).Shape)
+	}
+}
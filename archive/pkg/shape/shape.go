@@ -1,6 +1,9 @@
 package shape
 
-import "github.com/gmhorn/gremlin/archive/pkg/geo"
+import (
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
+)
 
 type Intersection struct {
 	Shape Shape
@@ -12,5 +15,15 @@ type Shape interface {
 	// Intersect returns the closest intersection of the ray with this primitive.
 	// A negative value means it does not intersect the primitive.
 	Intersect(ray *geo.Ray) float64
-	Normal(point geo.Vec) geo.Unit
+
+	// Interaction returns the surface interaction at a point on the shape,
+	// e.g. where a ray hit it. Surface coordinates are in the range [0, 1].
+	Interaction(point geo.Vec) Interaction
+
+	// Bounds returns the axis-aligned bounding box of the shape.
+	Bounds() *geo.Bounds
+
+	// Surface returns the material of the shape's surface. Nil means the
+	// renderer's default material.
+	Surface() material.Material
 }
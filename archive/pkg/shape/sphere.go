@@ -1,13 +1,17 @@
 package shape
 
 import (
+	"math"
+
 	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
 	"github.com/gmhorn/gremlin/archive/pkg/util"
 )
 
 type Sphere struct {
-	Center geo.Vec
-	Radius float64
+	Center   geo.Vec
+	Radius   float64
+	Material material.Material
 }
 
 // https://www.scratchapixel.com/lessons/3d-basic-rendering/minimal-ray-tracer-rendering-simple-shapes/ray-sphere-intersection
@@ -29,6 +33,62 @@ func (s *Sphere) Intersect(ray *geo.Ray) float64 {
 	return t0
 }
 
+func (s *Sphere) Bounds() *geo.Bounds {
+	r := geo.V(s.Radius, s.Radius, s.Radius)
+	return geo.NewBounds(s.Center.Minus(r), s.Center.Plus(r))
+}
+
 func (s *Sphere) Normal(point geo.Vec) geo.Unit {
 	return point.Minus(s.Center).Unit()
 }
+
+// UV returns spherical coordinates for the point. The sphere is parameterized
+// with the y-axis as its pole: v runs from 0 at the bottom (-y) to 1 at the top
+// (+y), and u runs around the equator starting at -x.
+//
+// https://raytracing.github.io/books/RayTracingTheNextWeek.html#imagetexturemapping/texturecoordinatesforspheres
+func (s *Sphere) UV(point geo.Vec) (u, v float64) {
+	p := point.Minus(s.Center).Unit()
+	theta := math.Acos(math.Max(-1, math.Min(1, -p.Y)))
+	phi := math.Atan2(-p.Z, p.X) + math.Pi
+
+	return phi / (2 * math.Pi), theta / math.Pi
+}
+
+// Interaction implements Shape. The derivatives follow the UV
+// parameterization; at the poles, where it's degenerate, they're just an
+// arbitrary pair of tangents.
+//
+// https://www.pbr-book.org/3ed-2018/Shapes/Spheres#SurfaceAreaandPartialDerivatives
+func (s *Sphere) Interaction(point geo.Vec) Interaction {
+	n := s.Normal(point)
+	u, v := s.UV(point)
+
+	// With theta the polar angle from -y and phi around y (see UV), points
+	// are at r(-sinθcosφ, -cosθ, sinθsinφ) from the center, and u, v = φ/2π,
+	// θ/π.
+	var dpdu, dpdv geo.Vec
+	sinTheta := math.Sqrt(n.X*n.X + n.Z*n.Z)
+	if sinTheta < 1e-9 {
+		frame := geo.FrameFromNormal(n)
+		dpdu, dpdv = geo.Vec(frame.S), geo.Vec(frame.T)
+	} else {
+		dpdu = geo.V(n.Z, 0, -n.X).Scale(2 * math.Pi * s.Radius)
+		dpdv = geo.V(-n.X*n.Y/sinTheta, sinTheta, -n.Y*n.Z/sinTheta).Scale(math.Pi * s.Radius)
+	}
+
+	return Interaction{
+		Point:    point,
+		Normal:   n,
+		U:        u,
+		V:        v,
+		DPDU:     dpdu,
+		DPDV:     dpdv,
+		Shape:    s,
+		Material: s.Material,
+	}
+}
+
+func (s *Sphere) Surface() material.Material {
+	return s.Material
+}
@@ -2,13 +2,16 @@ package shape
 
 import (
 	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/material"
 )
 
 type Triangle struct {
 	P1, P2, P3   geo.Vec
+	Material     material.Material
 	edge1, edge2 geo.Vec
 	normal       geo.Unit
 	centroid     geo.Vec
+	dpdu, dpdv   geo.Vec
 }
 
 func NewTriangle(p1, p2, p3 geo.Vec) *Triangle {
@@ -17,11 +20,12 @@ func NewTriangle(p1, p2, p3 geo.Vec) *Triangle {
 		P2:    p2,
 		P3:    p3,
 		edge1: p2.Minus(p1),
-		edge2: p3.Minus(p2),
+		edge2: p3.Minus(p1),
 	}
 
 	tri.normal = tri.edge1.Cross(tri.edge2).Unit()
 	tri.centroid = (p1.Plus(p2).Plus(p3)).Scale(1.0 / 3.0)
+	tri.dpdu, tri.dpdv = triangleDerivatives([3]geo.Vec{p1, p2, p3}, defaultUVs, tri.normal)
 
 	return tri
 }
@@ -51,3 +55,48 @@ func (tri *Triangle) Intersect(ray *geo.Ray) float64 {
 
 	return f * q.Dot(tri.edge2)
 }
+
+func (tri *Triangle) Bounds() *geo.Bounds {
+	return geo.NewBounds(tri.P1, tri.P2).Extend(tri.P3)
+}
+
+func (tri *Triangle) Surface() material.Material {
+	return tri.Material
+}
+
+func (tri *Triangle) Normal(point geo.Vec) geo.Unit {
+	return tri.normal
+}
+
+// Interaction implements Shape.
+func (tri *Triangle) Interaction(point geo.Vec) Interaction {
+	u, v := tri.UV(point)
+	return Interaction{
+		Point:    point,
+		Normal:   tri.normal,
+		U:        u,
+		V:        v,
+		DPDU:     tri.dpdu,
+		DPDV:     tri.dpdv,
+		Shape:    tri,
+		Material: tri.Material,
+	}
+}
+
+// defaultUVs are the surface coordinates of the vertices of triangles without
+// their own (see Triangle.UV).
+var defaultUVs = [3][2]float64{{0, 0}, {1, 0}, {1, 1}}
+
+// UV returns texture coordinates using PBRT's default parameterization, where
+// the vertices P1, P2, P3 are at (0, 0), (1, 0), (1, 1). In terms of the
+// barycentric weights b1, b2 of P2 and P3 in
+//
+//	point == (1-b1-b2)*P1 + b1*P2 + b2*P3
+//
+// that's u, v = b1+b2, b2.
+//
+// https://www.pbr-book.org/3ed-2018/Shapes/Triangle_Meshes#Triangle
+func (tri *Triangle) UV(point geo.Vec) (u, v float64) {
+	b1, b2 := barycentric(tri.P1, tri.edge1, tri.edge2, point)
+	return b1 + b2, b2
+}
@@ -0,0 +1,89 @@
+package shape
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTriangle_Intersect(t *testing.T) {
+	tri := NewTriangle(geo.V(0, 0, 0), geo.V(1, 0, 0), geo.V(1, 1, 0))
+
+	hit := tri.Intersect(geo.NewRay(geo.V(0.75, 0.25, 1), geo.V(0, 0, -1)))
+	assert.InDelta(t, 1.0, hit, 1e-9)
+
+	miss := tri.Intersect(geo.NewRay(geo.V(0.25, 0.75, 1), geo.V(0, 0, -1)))
+	assert.Less(t, miss, 0.0)
+}
+
+func TestTriangle_UV(t *testing.T) {
+	tri := NewTriangle(geo.V(0, 0, 0), geo.V(2, 0, 0), geo.V(2, 2, 0))
+
+	tests := []struct {
+		point    geo.Vec
+		expected [2]float64
+	}{
+		{geo.V(0, 0, 0), [2]float64{0, 0}},
+		{geo.V(2, 0, 0), [2]float64{1, 0}},
+		{geo.V(2, 2, 0), [2]float64{1, 1}},
+		{geo.V(1.5, 0.5, 0), [2]float64{0.75, 0.25}},
+	}
+
+	for _, tt := range tests {
+		u, v := tri.UV(tt.point)
+		assert.InDelta(t, tt.expected[0], u, 1e-9)
+		assert.InDelta(t, tt.expected[1], v, 1e-9)
+	}
+}
+
+func TestSphere_UV(t *testing.T) {
+	s := &Sphere{Center: geo.V(1, 1, 1), Radius: 2}
+
+	_, v := s.UV(geo.V(1, -1, 1))
+	assert.InDelta(t, 0, v, 1e-9)
+	_, v = s.UV(geo.V(1, 3, 1))
+	assert.InDelta(t, 1, v, 1e-9)
+
+	u, v := s.UV(geo.V(3, 1, 1))
+	assert.InDelta(t, 0.5, u, 1e-9)
+	assert.InDelta(t, 0.5, v, 1e-9)
+}
+
+func TestTriangle_Interaction(t *testing.T) {
+	tri := NewTriangle(geo.V(0, 0, 0), geo.V(2, 0, 0), geo.V(2, 2, 0))
+	si := tri.Interaction(geo.V(1.5, 0.5, 0))
+
+	assert.Equal(t, geo.ZAxis, si.Normal)
+	assert.InDelta(t, 0.75, si.U, 1e-9)
+	assert.InDelta(t, 0.25, si.V, 1e-9)
+	// u runs from P1 to P2, and v from P2 to P3
+	assert.InDelta(t, 0, si.DPDU.Minus(geo.V(2, 0, 0)).Len(), 1e-9)
+	assert.InDelta(t, 0, si.DPDV.Minus(geo.V(0, 2, 0)).Len(), 1e-9)
+	assert.Same(t, tri, si.Shape)
+}
+
+func TestSphere_Interaction(t *testing.T) {
+	s := &Sphere{Center: geo.V(1, 1, 1), Radius: 2}
+
+	// Moving along the derivatives moves along the surface, to the point
+	// with the corresponding UVs
+	for _, p := range []geo.Vec{geo.V(3, 1, 1), geo.V(1, 2, 1+math.Sqrt(3)), geo.V(0, 0, 1+math.Sqrt2)} {
+		si := s.Interaction(p)
+		assert.InDelta(t, 2, p.Minus(s.Center).Len(), 1e-9)
+
+		const h = 1e-4
+		u, v := s.UV(s.Center.Plus(p.Plus(si.DPDU.Scale(h)).Minus(s.Center).Unit().Scale(2)))
+		assert.InDelta(t, si.U+h, u, 1e-6)
+		assert.InDelta(t, si.V, v, 1e-6)
+		u, v = s.UV(s.Center.Plus(p.Plus(si.DPDV.Scale(h)).Minus(s.Center).Unit().Scale(2)))
+		assert.InDelta(t, si.U, u, 1e-6)
+		assert.InDelta(t, si.V+h, v, 1e-6)
+	}
+
+	// at the poles, the derivatives are still tangent
+	si := s.Interaction(geo.V(1, 3, 1))
+	assert.InDelta(t, 0, si.DPDU.Dot(geo.Vec(si.Normal)), 1e-9)
+	assert.InDelta(t, 0, si.DPDV.Dot(geo.Vec(si.Normal)), 1e-9)
+}
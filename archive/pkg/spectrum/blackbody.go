@@ -1,6 +1,9 @@
 package spectrum
 
-import "math"
+import (
+	"math"
+	"sync"
+)
 
 // Blackbody is the spectrum around a black-body of a given temperature. Units
 // are Kelvin.
@@ -21,3 +24,55 @@ func (temp Blackbody) Lookup(wavelength float64) float64 {
 	powerTerm := c1 * math.Pow(wavelength, -5.0)
 	return powerTerm / (math.Exp(c2/(wavelength*float64(temp))) - 1.0)
 }
+
+// Wien's displacement constant, in meter-Kelvins.
+// https://en.wikipedia.org/wiki/Wien%27s_displacement_law
+const wienB = 2.897771955e-3
+
+// BlackbodyNormalized is a Blackbody spectrum scaled so that its peak value is
+// 1. The peak is found with Wien's displacement law, so it may lie outside the
+// visible range, in which case all visible values are below 1. Handy as a
+// reflectance-safe "color temperature" tint.
+type BlackbodyNormalized float64
+
+// Lookup returns the normalized spectral radiant existance at the given
+// wavelength (in nanometers).
+func (temp BlackbodyNormalized) Lookup(wavelength float64) float64 {
+	peak := (wienB / float64(temp)) * 1e9
+	return Blackbody(temp).Lookup(wavelength) / Blackbody(temp).Lookup(peak)
+}
+
+// Caches of precomputed blackbody spectra, keyed by temperature.
+var (
+	blackbodyCacheMu         sync.Mutex
+	blackbodyCache           = map[float64]*Sampled{}
+	blackbodyNormalizedCache = map[float64]*Sampled{}
+)
+
+// SampledBlackbody returns the Blackbody spectrum for the given temperature,
+// precomputed at the Sampled wavelengths. Lookups on the result are just array
+// indexing, instead of an exp call per wavelength.
+//
+// Results are cached per temperature, so the returned distribution is shared.
+// Don't modify it.
+func SampledBlackbody(temp float64) *Sampled {
+	return cachedBlackbody(blackbodyCache, Blackbody(temp), temp)
+}
+
+// SampledBlackbodyNormalized is like SampledBlackbody, but for the
+// BlackbodyNormalized spectrum.
+func SampledBlackbodyNormalized(temp float64) *Sampled {
+	return cachedBlackbody(blackbodyNormalizedCache, BlackbodyNormalized(temp), temp)
+}
+
+func cachedBlackbody(cache map[float64]*Sampled, dist Distribution, temp float64) *Sampled {
+	blackbodyCacheMu.Lock()
+	defer blackbodyCacheMu.Unlock()
+
+	s, ok := cache[temp]
+	if !ok {
+		s = Sample(dist)
+		cache[temp] = s
+	}
+	return s
+}
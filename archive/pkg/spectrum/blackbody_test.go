@@ -0,0 +1,37 @@
+package spectrum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var benchResultFloat64 float64
+
+func TestBlackbodyNormalized_Lookup(t *testing.T) {
+	// 5000K peaks at ~579.6nm, well inside the visible range
+	temp := BlackbodyNormalized(5000)
+	assert.InDelta(t, 1.0, temp.Lookup(579.554), 1e-6)
+	assert.Less(t, temp.Lookup(450), 1.0)
+	assert.Less(t, temp.Lookup(700), 1.0)
+}
+
+func TestSampledBlackbody(t *testing.T) {
+	assert.Equal(t, Sample(Blackbody(4500)), SampledBlackbody(4500))
+	assert.Same(t, SampledBlackbody(4500), SampledBlackbody(4500))
+	assert.Equal(t, Sample(BlackbodyNormalized(4500)), SampledBlackbodyNormalized(4500))
+}
+
+func BenchmarkBlackbody_Lookup(b *testing.B) {
+	dist := Blackbody(4500)
+	for i := 0; i < b.N; i++ {
+		benchResultFloat64 = dist.Lookup(SampledMin + float64(i%400))
+	}
+}
+
+func BenchmarkSampledBlackbody_Lookup(b *testing.B) {
+	dist := SampledBlackbody(4500)
+	for i := 0; i < b.N; i++ {
+		benchResultFloat64 = dist.Lookup(SampledMin + float64(i%400))
+	}
+}
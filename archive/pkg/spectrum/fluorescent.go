@@ -0,0 +1,167 @@
+package spectrum
+
+// The CIE F-series fluorescent illuminants, relative like the other standard
+// illuminants: lines of mercury over the broad emission of the tube's
+// phosphors. They're tabulated at 5nm from 380nm to 780nm, from CIE 15:2004.
+//
+// F1 to F6 are standard halophosphate tubes, from daylight (F1, 6430K) to
+// warm white (F4, 2940K). F2, cool white, is the usual one for tests.
+//
+// https://en.wikipedia.org/wiki/Standard_illuminant#Illuminant_series_F
+var (
+	IlluminantF1 = NewTabulated(fluorescentWavelengths, []float64{
+		1.87, 2.36, 2.94, 3.47, 5.17, 19.49, 6.13, 6.24, 7.01,
+		7.79, 8.56, 43.67, 16.94, 10.72, 11.35, 11.89, 12.37, 12.75,
+		13.00, 13.15, 13.23, 13.17, 13.13, 12.85, 12.52, 12.20, 11.83,
+		11.50, 11.22, 11.05, 11.03, 11.18, 11.53, 27.74, 17.05, 13.55,
+		14.33, 15.01, 15.52, 18.29, 19.55, 15.48, 14.91, 14.15, 13.22,
+		12.19, 11.12, 10.03, 8.95, 7.96, 7.02, 6.20, 5.42, 4.73,
+		4.15, 3.64, 3.20, 2.81, 2.47, 2.18, 1.93, 1.72, 1.67,
+		1.43, 1.29, 1.19, 1.08, 0.96, 0.88, 0.81, 0.77, 0.75,
+		0.73, 0.68, 0.69, 0.64, 0.68, 0.69, 0.61, 0.52, 0.43,
+	})
+	IlluminantF2 = NewTabulated(fluorescentWavelengths, []float64{
+		1.18, 1.48, 1.84, 2.15, 3.44, 15.69, 3.85, 3.74, 4.19,
+		4.62, 5.06, 34.98, 11.81, 6.27, 6.63, 6.93, 7.19, 7.40,
+		7.54, 7.62, 7.65, 7.62, 7.62, 7.45, 7.28, 7.15, 7.05,
+		7.04, 7.16, 7.47, 8.04, 8.88, 10.01, 24.88, 16.64, 14.59,
+		16.16, 17.56, 18.62, 21.47, 22.79, 19.29, 18.66, 17.73, 16.54,
+		15.21, 13.80, 12.36, 10.95, 9.65, 8.40, 7.32, 6.31, 5.43,
+		4.68, 4.02, 3.45, 2.96, 2.55, 2.19, 1.89, 1.64, 1.53,
+		1.27, 1.10, 0.99, 0.88, 0.76, 0.68, 0.61, 0.56, 0.54,
+		0.51, 0.47, 0.47, 0.43, 0.46, 0.47, 0.40, 0.33, 0.27,
+	})
+	IlluminantF3 = NewTabulated(fluorescentWavelengths, []float64{
+		0.82, 1.02, 1.26, 1.44, 2.57, 14.36, 2.70, 2.45, 2.73,
+		3.00, 3.28, 31.85, 9.47, 4.02, 4.25, 4.44, 4.59, 4.72,
+		4.80, 4.86, 4.87, 4.85, 4.88, 4.77, 4.67, 4.62, 4.62,
+		4.73, 4.99, 5.48, 6.25, 7.34, 8.78, 23.82, 16.14, 14.59,
+		16.63, 18.49, 19.95, 23.11, 24.69, 21.41, 20.85, 19.93, 18.67,
+		17.22, 15.65, 14.04, 12.45, 10.95, 9.51, 8.27, 7.11, 6.09,
+		5.22, 4.45, 3.80, 3.23, 2.75, 2.33, 1.99, 1.70, 1.55,
+		1.27, 1.09, 0.96, 0.83, 0.71, 0.62, 0.54, 0.49, 0.46,
+		0.43, 0.39, 0.39, 0.35, 0.38, 0.39, 0.33, 0.28, 0.21,
+	})
+	IlluminantF4 = NewTabulated(fluorescentWavelengths, []float64{
+		0.57, 0.70, 0.87, 0.98, 2.01, 13.75, 1.95, 1.59, 1.76,
+		1.93, 2.10, 30.28, 8.03, 2.55, 2.70, 2.82, 2.91, 2.99,
+		3.04, 3.08, 3.09, 3.09, 3.14, 3.06, 3.00, 2.98, 3.01,
+		3.14, 3.41, 3.90, 4.69, 5.81, 7.32, 22.59, 15.11, 13.88,
+		16.33, 18.68, 20.64, 24.28, 26.26, 23.28, 22.94, 22.14, 20.91,
+		19.43, 17.74, 16.00, 14.42, 12.56, 10.93, 9.52, 8.18, 7.01,
+		6.00, 5.11, 4.36, 3.69, 3.13, 2.64, 2.24, 1.91, 1.70,
+		1.39, 1.18, 1.03, 0.88, 0.74, 0.64, 0.54, 0.49, 0.46,
+		0.42, 0.37, 0.37, 0.33, 0.35, 0.36, 0.31, 0.26, 0.19,
+	})
+	IlluminantF5 = NewTabulated(fluorescentWavelengths, []float64{
+		1.87, 2.35, 2.92, 3.45, 5.10, 18.91, 6.00, 6.11, 6.85,
+		7.58, 8.31, 40.76, 16.06, 10.32, 10.91, 11.40, 11.83, 12.17,
+		12.40, 12.54, 12.58, 12.52, 12.47, 12.20, 11.89, 11.61, 11.33,
+		11.10, 10.96, 10.97, 11.16, 11.54, 12.12, 27.78, 17.73, 14.47,
+		15.20, 15.77, 16.10, 18.54, 19.50, 15.39, 14.64, 13.72, 12.69,
+		11.57, 10.45, 9.35, 8.29, 7.32, 6.41, 5.63, 4.90, 4.26,
+		3.72, 3.25, 2.83, 2.49, 2.19, 1.93, 1.71, 1.52, 1.43,
+		1.26, 1.13, 1.05, 0.96, 0.85, 0.78, 0.72, 0.68, 0.67,
+		0.65, 0.61, 0.62, 0.59, 0.62, 0.64, 0.55, 0.47, 0.40,
+	})
+	IlluminantF6 = NewTabulated(fluorescentWavelengths, []float64{
+		1.05, 1.31, 1.63, 1.90, 3.11, 14.80, 3.43, 3.30, 3.68,
+		4.07, 4.45, 32.61, 10.74, 5.48, 5.78, 6.03, 6.25, 6.41,
+		6.52, 6.58, 6.59, 6.56, 6.56, 6.42, 6.28, 6.20, 6.19,
+		6.30, 6.60, 7.12, 7.94, 9.07, 10.49, 25.22, 17.46, 15.63,
+		17.22, 18.53, 19.43, 21.97, 23.01, 19.41, 18.56, 17.42, 16.09,
+		14.64, 13.15, 11.68, 10.25, 8.95, 7.74, 6.69, 5.71, 4.87,
+		4.16, 3.55, 3.02, 2.57, 2.20, 1.87, 1.60, 1.37, 1.29,
+		1.05, 0.91, 0.81, 0.71, 0.61, 0.54, 0.48, 0.44, 0.43,
+		0.40, 0.37, 0.38, 0.35, 0.39, 0.41, 0.33, 0.26, 0.21,
+	})
+)
+
+// F7 to F9 are broadband tubes with better color rendering: D65-like (F7),
+// D50-like (F8) and cool white deluxe (F9).
+var (
+	IlluminantF7 = NewTabulated(fluorescentWavelengths, []float64{
+		2.56, 3.18, 3.84, 4.53, 6.15, 19.37, 7.37, 7.05, 7.71,
+		8.41, 9.15, 44.14, 17.52, 11.35, 12.00, 12.58, 13.08, 13.45,
+		13.71, 13.88, 13.95, 13.93, 13.82, 13.64, 13.43, 13.25, 13.08,
+		12.93, 12.78, 12.60, 12.44, 12.33, 12.26, 29.52, 17.05, 12.44,
+		12.58, 12.72, 12.83, 15.46, 16.75, 12.83, 12.67, 12.45, 12.19,
+		11.89, 11.60, 11.35, 11.12, 10.95, 10.76, 10.42, 10.11, 10.04,
+		10.02, 10.11, 9.87, 8.65, 7.27, 6.44, 5.83, 5.41, 5.04,
+		4.57, 4.12, 3.77, 3.46, 3.08, 2.73, 2.47, 2.25, 2.06,
+		1.90, 1.75, 1.62, 1.54, 1.45, 1.32, 1.17, 0.99, 0.81,
+	})
+	IlluminantF8 = NewTabulated(fluorescentWavelengths, []float64{
+		1.21, 1.50, 1.81, 2.13, 3.17, 13.08, 3.83, 3.45, 3.86,
+		4.42, 5.09, 34.10, 12.42, 7.68, 8.60, 9.46, 10.24, 10.84,
+		11.33, 11.71, 11.98, 12.17, 12.28, 12.32, 12.35, 12.44, 12.55,
+		12.68, 12.77, 12.72, 12.60, 12.43, 12.22, 28.96, 16.51, 11.79,
+		11.76, 11.77, 11.84, 14.61, 16.11, 12.34, 12.53, 12.72, 12.92,
+		13.12, 13.34, 13.61, 13.87, 14.07, 14.20, 14.16, 14.13, 14.34,
+		14.50, 14.46, 14.00, 12.58, 10.99, 9.98, 9.22, 8.62, 8.07,
+		7.39, 6.71, 6.16, 5.63, 5.03, 4.46, 4.02, 3.66, 3.36,
+		3.09, 2.85, 2.65, 2.51, 2.37, 2.15, 1.89, 1.61, 1.32,
+	})
+	IlluminantF9 = NewTabulated(fluorescentWavelengths, []float64{
+		0.90, 1.12, 1.36, 1.60, 2.59, 12.80, 3.05, 2.56, 2.86,
+		3.30, 3.82, 32.62, 10.77, 5.84, 6.57, 7.25, 7.86, 8.35,
+		8.75, 9.06, 9.31, 9.48, 9.61, 9.68, 9.74, 9.88, 10.04,
+		10.26, 10.48, 10.63, 10.76, 10.96, 11.18, 27.71, 16.29, 12.28,
+		12.74, 13.21, 13.65, 16.57, 18.14, 14.55, 14.65, 14.66, 14.61,
+		14.50, 14.39, 14.40, 14.47, 14.62, 14.72, 14.55, 14.40, 14.58,
+		14.88, 15.51, 15.47, 13.20, 10.57, 9.18, 8.25, 7.57, 7.03,
+		6.35, 5.72, 5.25, 4.80, 4.29, 3.80, 3.43, 3.12, 2.86,
+		2.64, 2.43, 2.26, 2.14, 2.02, 1.83, 1.61, 1.38, 1.12,
+	})
+)
+
+// F10 to F12 are narrow tri-band tubes, with most of their power in three
+// peaks (F10 at 5000K, F11 at 4000K, F12 at 3000K).
+//
+// F10's chromaticity, from its table, is (0.3458, 0.3588), not quite the
+// (0.34609, 0.35986) CIE 15 publishes for it: unlike the others, its table
+// doesn't integrate to its published white point to three decimals.
+var (
+	IlluminantF10 = NewTabulated(fluorescentWavelengths, []float64{
+		1.11, 0.63, 0.62, 0.57, 1.48, 12.16, 2.12, 2.70, 3.74,
+		5.14, 6.75, 34.39, 14.86, 10.40, 10.76, 10.67, 10.11, 9.27,
+		8.29, 7.29, 7.91, 16.64, 16.73, 10.44, 5.94, 3.34, 2.35,
+		1.88, 1.59, 1.47, 1.80, 5.71, 40.98, 73.69, 33.61, 8.24,
+		3.38, 2.47, 2.14, 4.86, 11.45, 14.79, 12.16, 8.97, 6.52,
+		8.31, 44.12, 34.55, 12.09, 12.15, 10.52, 4.43, 1.95, 2.19,
+		3.19, 2.77, 2.29, 2.00, 1.52, 1.35, 1.47, 1.79, 1.74,
+		1.02, 1.14, 3.32, 4.49, 2.05, 0.49, 0.24, 0.21, 0.21,
+		0.24, 0.24, 0.21, 0.17, 0.21, 0.22, 0.17, 0.12, 0.09,
+	})
+	IlluminantF11 = NewTabulated(fluorescentWavelengths, []float64{
+		0.91, 0.63, 0.46, 0.37, 1.29, 12.68, 1.59, 1.79, 2.46,
+		3.33, 4.49, 33.94, 12.13, 6.95, 7.19, 7.12, 6.72, 6.13,
+		5.46, 4.79, 5.66, 14.29, 14.96, 8.97, 4.72, 2.33, 1.47,
+		1.10, 0.89, 0.83, 1.18, 4.90, 39.59, 72.84, 32.61, 7.52,
+		2.83, 1.96, 1.67, 4.43, 11.28, 14.76, 12.73, 9.74, 7.33,
+		9.72, 55.27, 42.58, 13.18, 13.16, 12.26, 5.11, 2.07, 2.34,
+		3.58, 3.01, 2.48, 2.14, 1.54, 1.33, 1.46, 1.94, 2.00,
+		1.20, 1.35, 4.10, 5.58, 2.51, 0.57, 0.27, 0.23, 0.21,
+		0.24, 0.24, 0.20, 0.24, 0.32, 0.26, 0.16, 0.12, 0.09,
+	})
+	IlluminantF12 = NewTabulated(fluorescentWavelengths, []float64{
+		0.96, 0.64, 0.40, 0.33, 1.19, 12.48, 1.12, 0.94, 1.08,
+		1.37, 1.78, 29.05, 7.90, 2.65, 2.71, 2.65, 2.49, 2.33,
+		2.10, 1.91, 3.01, 10.83, 11.88, 6.88, 3.43, 1.49, 0.92,
+		0.71, 0.60, 0.63, 1.10, 4.56, 34.40, 65.40, 29.48, 7.16,
+		3.08, 2.47, 2.27, 5.09, 11.96, 15.32, 14.27, 11.86, 9.28,
+		12.31, 68.53, 53.02, 14.67, 14.38, 14.71, 6.46, 2.57, 2.75,
+		4.18, 3.44, 2.81, 2.42, 1.64, 1.36, 1.49, 2.14, 2.34,
+		1.42, 1.61, 5.04, 6.98, 3.19, 0.71, 0.30, 0.26, 0.23,
+		0.28, 0.28, 0.21, 0.17, 0.21, 0.19, 0.15, 0.10, 0.05,
+	})
+)
+
+// fluorescentWavelengths are the wavelengths of the F-series tables.
+var fluorescentWavelengths = func() []float64 {
+	w := make([]float64, 81)
+	for i := range w {
+		w[i] = float64(380 + 5*i)
+	}
+	return w
+}()
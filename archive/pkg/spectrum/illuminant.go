@@ -0,0 +1,88 @@
+package spectrum
+
+import "math"
+
+// Daylight is the spectrum of a CIE standard daylight illuminant (the
+// D-series) with the given correlated color temperature, in Kelvin, between
+// 4000 and 25000. Like the standard tables it's relative, with a value of 100
+// at 560nm.
+//
+// The named illuminants use temperatures adjusted for a later revision of the
+// radiation constant c2: D65 is Daylight(6504) and D50 is Daylight(5003).
+//
+// https://en.wikipedia.org/wiki/Standard_illuminant#Computation
+type Daylight float64
+
+// Lookup implements Distribution, interpolating linearly between the 10nm
+// samples of the CIE daylight components.
+func (temp Daylight) Lookup(wavelength float64) float64 {
+	m1, m2 := temp.weights()
+	return daylightS0.Lookup(wavelength) + m1*daylightS1.Lookup(wavelength) + m2*daylightS2.Lookup(wavelength)
+}
+
+// weights returns the weights of the S1 and S2 components, from the
+// chromaticity of daylight at the temperature.
+func (temp Daylight) weights() (m1, m2 float64) {
+	t := math.Max(4000, math.Min(25000, float64(temp)))
+	var x float64
+	if t <= 7000 {
+		x = -4.6070e9/(t*t*t) + 2.9678e6/(t*t) + 0.09911e3/t + 0.244063
+	} else {
+		x = -2.0064e9/(t*t*t) + 1.9018e6/(t*t) + 0.24748e3/t + 0.237040
+	}
+	y := -3*x*x + 2.870*x - 0.275
+
+	m := 0.0241 + 0.2562*x - 0.7341*y
+	return (-1.3515 - 1.7703*x + 5.9114*y) / m, (0.0300 - 31.4424*x + 30.0717*y) / m
+}
+
+// IlluminantA is the spectrum of CIE standard illuminant A, typical tungsten
+// filament lighting: a black-body at 2856K, relative to 100 at 560nm.
+//
+// https://en.wikipedia.org/wiki/Standard_illuminant#Illuminant_A
+var IlluminantA = Sample(DistributionFunc(func(wavelength float64) float64 {
+	const c = 1.435e7 / 2848
+	return 100 * math.Pow(560/wavelength, 5) * (math.Exp(c/560) - 1) / (math.Exp(c/wavelength) - 1)
+}))
+
+// daylightWavelengths are the wavelengths of the daylight components' samples.
+var daylightWavelengths = func() []float64 {
+	w := make([]float64, 41)
+	for i := range w {
+		w[i] = float64(380 + 10*i)
+	}
+	return w
+}()
+
+// The components of CIE daylight, from 380nm to 780nm: the mean S0, and S1
+// and S2, which mostly shift it between yellow-blue and pink-green.
+var (
+	daylightS0 = NewTabulated(daylightWavelengths, []float64{
+		63.4, 65.8, 94.8, 104.8, 105.9, 96.8, 113.9, 125.6, 125.5, 121.3,
+		121.3, 113.5, 113.1, 110.8, 106.5, 108.8, 105.3, 104.4, 100.0, 96.0,
+		95.1, 89.1, 90.5, 90.3, 88.4, 84.0, 85.1, 81.9, 82.6, 84.9,
+		81.3, 71.9, 74.3, 76.4, 63.3, 71.7, 77.0, 65.2, 47.7, 68.6,
+		65.0,
+	})
+	daylightS1 = NewTabulated(daylightWavelengths, []float64{
+		38.5, 35.0, 43.4, 46.3, 43.9, 37.1, 36.7, 35.9, 32.6, 27.9,
+		24.3, 20.1, 16.2, 13.2, 8.6, 6.1, 4.2, 1.9, 0.0, -1.6,
+		-3.5, -3.5, -5.8, -7.2, -8.6, -9.5, -10.9, -10.7, -12.0, -14.0,
+		-13.6, -12.0, -13.3, -12.9, -10.6, -11.6, -12.2, -10.2, -7.8, -11.2,
+		-10.4,
+	})
+	daylightS2 = NewTabulated(daylightWavelengths, []float64{
+		3.0, 1.2, -1.1, -0.5, -0.7, -1.2, -2.6, -2.9, -2.8, -2.6,
+		-2.6, -1.8, -1.5, -1.3, -1.2, -1.0, -0.5, -0.3, 0.0, 0.2,
+		0.5, 2.1, 3.2, 4.1, 4.7, 5.1, 6.7, 7.3, 8.6, 9.8,
+		10.2, 8.3, 9.6, 8.5, 7.0, 7.6, 8.0, 6.7, 5.2, 7.4,
+		6.8,
+	})
+)
+
+// The CIE standard illuminants D65 (noon daylight, the white of sRGB) and D50
+// (horizon light, the white of print work).
+var (
+	IlluminantD65 = Sample(Daylight(6504))
+	IlluminantD50 = Sample(Daylight(5003))
+)
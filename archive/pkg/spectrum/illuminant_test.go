@@ -0,0 +1,23 @@
+package spectrum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDaylight(t *testing.T) {
+	// ACES D60 is daylight at 6000K, with the same adjustment as D65 (and
+	// M1 and M2 rounded as the CIE does, hence the small differences)
+	d60 := Daylight(6000 * 1.4388 / 1.4380)
+	for w := 380.0; w <= 780; w += 10 {
+		assert.InDelta(t, ACESIllumD60.Lookup(w), d60.Lookup(w), 0.1, "%vnm", w)
+	}
+
+	// D65 is bluer than D50, which is bluer than A
+	for _, s := range []*Sampled{IlluminantD65, IlluminantD50, IlluminantA} {
+		assert.InDelta(t, 100, s.Lookup(560), 1e-9)
+	}
+	assert.Greater(t, IlluminantD65.Lookup(450), IlluminantD50.Lookup(450))
+	assert.Greater(t, IlluminantD50.Lookup(450), IlluminantA.Lookup(450))
+}
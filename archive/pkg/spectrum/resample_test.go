@@ -0,0 +1,43 @@
+package spectrum
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResample(t *testing.T) {
+	t.Run("flat", func(t *testing.T) {
+		b := Resample(Flat(2), 400, 700, 30)
+		for _, v := range b.Values {
+			assert.InDelta(t, 2, v, 1e-12)
+		}
+	})
+
+	t.Run("linear", func(t *testing.T) {
+		b := Resample(DistributionFunc(func(w float64) float64 { return w }), 400, 500, 10)
+		assert.InDelta(t, 405, b.Values[0], 1e-9)
+		assert.InDelta(t, 495, b.Values[9], 1e-9)
+	})
+
+	t.Run("preserves power of narrow peaks", func(t *testing.T) {
+		// very narrow line between bin centers; total power is sqrt(2*pi*var)
+		variance := 0.01
+		b := Resample(Peak(552.5, variance), 380, 780, 40)
+		power := 0.0
+		for _, v := range b.Values {
+			power += v * b.BinWidth()
+		}
+		assert.InEpsilon(t, math.Sqrt(2*math.Pi*variance), power, 1e-3)
+	})
+}
+
+func TestBinned_Lookup(t *testing.T) {
+	b := &Binned{Min: 400, Max: 700, Values: []float64{1, 2, 3}}
+	assert.Equal(t, 0.0, b.Lookup(399))
+	assert.Equal(t, 1.0, b.Lookup(400))
+	assert.Equal(t, 2.0, b.Lookup(550))
+	assert.Equal(t, 3.0, b.Lookup(700))
+	assert.Equal(t, 0.0, b.Lookup(701))
+}
@@ -0,0 +1,86 @@
+package spectrum
+
+// Box functions covering the blue, green and red parts of the visible
+// spectrum, used by BoxRGB.
+var (
+	boxBlue  = Sample(box(SampledMin, 490))
+	boxGreen = Sample(box(490, 580))
+	boxRed   = Sample(box(580, SampledMax+1))
+)
+
+// BoxRGB returns a spectrum with roughly the given linear RGB values, made of
+// three boxes covering the blue, green and red wavelengths. The conversion is
+// crude, but cheap, and keeps reflectances in [0, 1] if the components are;
+// FromRGB gives smoother, more realistic spectra.
+func BoxRGB(r, g, b float64) *Sampled {
+	return boxRed.Scale(r).Plus(boxGreen.Scale(g)).Plus(boxBlue.Scale(b))
+}
+
+func box(min, max float64) Distribution {
+	return DistributionFunc(func(w float64) float64 {
+		if w >= min && w < max {
+			return 1
+		}
+		return 0
+	})
+}
+
+// smitsWavelengths are the centers of the ten bins Smits' spectra are given
+// for, evenly covering 380-720nm.
+var smitsWavelengths = func() []float64 {
+	w := make([]float64, 10)
+	for i := range w {
+		w[i] = 380 + (float64(i)+0.5)*34
+	}
+	return w
+}()
+
+// smits returns one of Smits' basis spectra, interpolated between the bins.
+func smits(values ...float64) *Sampled {
+	return Sample(NewTabulated(smitsWavelengths, values))
+}
+
+// Smits' basis spectra: smooth reflectances of the white, primary and
+// secondary colors.
+var (
+	smitsWhite   = smits(1.0000, 1.0000, 0.9999, 0.9993, 0.9992, 0.9998, 1.0000, 1.0000, 1.0000, 1.0000)
+	smitsCyan    = smits(0.9710, 0.9426, 1.0007, 1.0007, 1.0007, 1.0007, 0.1564, 0.0000, 0.0000, 0.0000)
+	smitsMagenta = smits(1.0000, 1.0000, 0.9685, 0.2229, 0.0000, 0.0458, 0.8369, 1.0000, 1.0000, 0.9959)
+	smitsYellow  = smits(0.0001, 0.0000, 0.1088, 0.6651, 1.0000, 1.0000, 0.9996, 0.9586, 0.9685, 0.9840)
+	smitsRed     = smits(0.1012, 0.0515, 0.0000, 0.0000, 0.0000, 0.0000, 0.8325, 1.0149, 1.0149, 1.0149)
+	smitsGreen   = smits(0.0000, 0.0000, 0.0273, 0.7937, 1.0000, 0.9418, 0.1719, 0.0000, 0.0000, 0.0025)
+	smitsBlue    = smits(1.0000, 1.0000, 0.8916, 0.3323, 0.0000, 0.0000, 0.0003, 0.0369, 0.0483, 0.0496)
+)
+
+// FromRGB returns a smooth spectrum with the given linear RGB values, for
+// turning colors and texture values into spectra. Unlike BoxRGB, the result
+// looks like a real reflectance, without steps, so colors mix and render
+// under colored lights more plausibly; it also stays in [0, 1] if the
+// components are, so it suits reflectances.
+//
+// It uses Smits' method: the smallest component is white, the next is made up
+// with cyan, magenta or yellow, and the rest with red, green or blue, each a
+// smooth spectrum fitted for the purpose. See Smits, "An RGB-to-Spectrum
+// Conversion for Reflectances" (1999).
+func FromRGB(r, g, b float64) *Sampled {
+	switch {
+	case r <= g && r <= b:
+		s := smitsWhite.Scale(r)
+		if g <= b {
+			return s.Plus(smitsCyan.Scale(g - r)).Plus(smitsBlue.Scale(b - g))
+		}
+		return s.Plus(smitsCyan.Scale(b - r)).Plus(smitsGreen.Scale(g - b))
+	case g <= r && g <= b:
+		s := smitsWhite.Scale(g)
+		if r <= b {
+			return s.Plus(smitsMagenta.Scale(r - g)).Plus(smitsBlue.Scale(b - r))
+		}
+		return s.Plus(smitsMagenta.Scale(b - g)).Plus(smitsRed.Scale(r - b))
+	default:
+		s := smitsWhite.Scale(b)
+		if r <= g {
+			return s.Plus(smitsYellow.Scale(r - b)).Plus(smitsGreen.Scale(g - r))
+		}
+		return s.Plus(smitsYellow.Scale(g - b)).Plus(smitsRed.Scale(r - g))
+	}
+}
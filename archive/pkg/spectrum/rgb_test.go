@@ -0,0 +1,55 @@
+package spectrum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoxRGB(t *testing.T) {
+	white := BoxRGB(1, 1, 1)
+	for _, v := range white {
+		assert.Equal(t, 1.0, v)
+	}
+
+	c := BoxRGB(0.1, 0.2, 0.3)
+	assert.Equal(t, 0.3, c.Lookup(450))
+	assert.Equal(t, 0.2, c.Lookup(540))
+	assert.Equal(t, 0.1, c.Lookup(650))
+}
+
+func TestFromRGB(t *testing.T) {
+	white := FromRGB(1, 1, 1)
+	for _, v := range white {
+		assert.InDelta(t, 1, v, 1e-3)
+	}
+	assert.Equal(t, new(Sampled), FromRGB(0, 0, 0))
+
+	// the primaries peak where they should
+	tests := []struct {
+		name           string
+		r, g, b        float64
+		bright, darker float64
+	}{
+		{"Red", 0.8, 0.1, 0.1, 650, 500},
+		{"Green", 0.1, 0.8, 0.1, 540, 450},
+		{"Blue", 0.1, 0.1, 0.8, 450, 600},
+		{"Yellow", 0.8, 0.8, 0.1, 600, 450},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := FromRGB(test.r, test.g, test.b)
+			assert.Greater(t, s.Lookup(test.bright), s.Lookup(test.darker))
+			for _, v := range s {
+				assert.GreaterOrEqual(t, v, 0.0)
+				assert.LessOrEqual(t, v, 1.02)
+			}
+		})
+	}
+
+	// it's linear along each of the six sectors
+	a, b := FromRGB(0.2, 0.4, 0.6), FromRGB(0.4, 0.8, 1.2)
+	for i := range a {
+		assert.InDelta(t, 2*a[i], b[i], 1e-12)
+	}
+}
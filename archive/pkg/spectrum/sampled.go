@@ -87,3 +87,23 @@ func (s *Sampled) Lerp(t *Sampled, n float64) *Sampled {
 	}
 	return lerp
 }
+
+// Mult returns the element-wise product of the two distributions.
+func (s *Sampled) Mult(t *Sampled) *Sampled {
+	r := new(Sampled)
+	for i, v := range s {
+		r[i] = v * t[i]
+	}
+	return r
+}
+
+// Max returns the largest value of the distribution.
+func (s *Sampled) Max() float64 {
+	max := s[0]
+	for _, v := range s[1:] {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
@@ -49,6 +49,17 @@ func TestSampled_Lookup(t *testing.T) {
 	}
 }
 
+func TestSampled_Mult(t *testing.T) {
+	a := Sample(Flat(2))
+	b := Sample(DistributionFunc(func(w float64) float64 { return w }))
+
+	prod := a.Mult(b)
+	for i, w := range sampledWavelengths {
+		assert.Equal(t, 2*w, prod[i])
+	}
+	assert.Equal(t, 2.0*SampledMax, prod.Max())
+}
+
 func BenchmarkSample_AlreadySampled(b *testing.B) {
 	dist := Sample(Blackbody(4500))
 	for i := 0; i < b.N; i++ {
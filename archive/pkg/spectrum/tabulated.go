@@ -0,0 +1,41 @@
+package spectrum
+
+import "sort"
+
+// Tabulated is a distribution given by values at arbitrary (increasing)
+// wavelengths, like published measurements. Lookup interpolates linearly
+// between them, and clamps to the first and last value outside the table.
+type Tabulated struct {
+	Wavelengths []float64
+	Values      []float64
+}
+
+// NewTabulated creates a tabulated distribution. Panics if the slices have
+// different lengths, are empty, or the wavelengths aren't increasing.
+func NewTabulated(wavelengths, values []float64) *Tabulated {
+	if len(wavelengths) != len(values) || len(wavelengths) == 0 {
+		panic("Tabulated needs the same, non-zero number of wavelengths and values")
+	}
+	for i := 1; i < len(wavelengths); i++ {
+		if wavelengths[i] <= wavelengths[i-1] {
+			panic("Tabulated wavelengths must be increasing")
+		}
+	}
+	return &Tabulated{Wavelengths: wavelengths, Values: values}
+}
+
+// Lookup implements Distribution.
+func (t *Tabulated) Lookup(wavelength float64) float64 {
+	n := len(t.Wavelengths)
+	if wavelength <= t.Wavelengths[0] {
+		return t.Values[0]
+	}
+	if wavelength >= t.Wavelengths[n-1] {
+		return t.Values[n-1]
+	}
+
+	i := sort.SearchFloat64s(t.Wavelengths, wavelength)
+	w0, w1 := t.Wavelengths[i-1], t.Wavelengths[i]
+	f := (wavelength - w0) / (w1 - w0)
+	return t.Values[i-1]*(1-f) + t.Values[i]*f
+}
@@ -0,0 +1,44 @@
+package spectrum
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTabulated_Lookup(t *testing.T) {
+	tab := NewTabulated([]float64{400, 500, 700}, []float64{1, 2, 0})
+
+	tests := []struct {
+		name       string
+		wavelength float64
+		expected   float64
+	}{
+		{"below", 300, 1},
+		{"at first", 400, 1},
+		{"between", 450, 1.5},
+		{"at middle", 500, 2},
+		{"between uneven", 650, 0.5},
+		{"at last", 700, 0},
+		{"above", 800, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, tab.Lookup(tt.wavelength), 1e-12)
+		})
+	}
+}
+
+func TestNewTabulated_Panics(t *testing.T) {
+	assert.Panics(t, func() { NewTabulated([]float64{400, 500}, []float64{1}) })
+	assert.Panics(t, func() { NewTabulated(nil, nil) })
+	assert.Panics(t, func() { NewTabulated([]float64{500, 400}, []float64{1, 2}) })
+}
+
+func TestWater(t *testing.T) {
+	// sodium d-line
+	assert.InDelta(t, 1.333, WaterIOR.Lookup(589), 0.001)
+	// red is absorbed much more than blue
+	assert.Greater(t, WaterAbsorption.Lookup(650), 10*WaterAbsorption.Lookup(450))
+}
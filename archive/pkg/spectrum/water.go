@@ -0,0 +1,23 @@
+package spectrum
+
+// WaterIOR is the refractive index of pure water at 25C, from Hale and Querry
+// (1973).
+//
+// https://refractiveindex.info/?shelf=main&book=H2O&page=Hale
+var WaterIOR = NewTabulated(
+	[]float64{350, 375, 400, 425, 450, 475, 500, 525, 550, 575, 600, 625, 650, 675, 700, 725, 750, 775, 800},
+	[]float64{1.343, 1.341, 1.339, 1.338, 1.337, 1.336, 1.335, 1.334, 1.333, 1.333, 1.332, 1.332, 1.331, 1.331, 1.331, 1.330, 1.330, 1.330, 1.329},
+)
+
+// WaterAbsorption is the absorption coefficient of pure water, in inverse
+// meters. Values are from Pope and Fry (1997) up to 720nm, and Kou et al.
+// (1993) above that, rounded.
+//
+// Water barely absorbs blue light but noticeably absorbs red, which is why
+// deep water looks blue.
+//
+// https://omlc.org/spectra/water/abs/index.html
+var WaterAbsorption = NewTabulated(
+	[]float64{380, 400, 420, 440, 460, 480, 500, 520, 540, 560, 580, 600, 620, 640, 660, 680, 700, 720, 740, 760, 780},
+	[]float64{0.0114, 0.0066, 0.0045, 0.0064, 0.0092, 0.0150, 0.0204, 0.0474, 0.0568, 0.0708, 0.0896, 0.2224, 0.2755, 0.3112, 0.4100, 0.4648, 0.6240, 1.169, 2.40, 2.55, 2.36},
+)
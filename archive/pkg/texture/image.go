@@ -0,0 +1,97 @@
+package texture
+
+import (
+	"math"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/gmhorn/gremlin/archive/pkg/imageio"
+	"github.com/gmhorn/gremlin/archive/pkg/metrics"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+)
+
+// Image is a texture backed by an image, like a photo of wood grain loaded
+// with imageio.LoadImage. The image covers the unit square of surface
+// coordinates and repeats outside it. As in OBJ files, v goes up, so (0, 0)
+// is the bottom left of the image.
+//
+// Lookups are bilinearly filtered between the four nearest pixels.
+//
+// https://www.pbr-book.org/3ed-2018/Texture/Image_Texture
+type Image struct {
+	Image *imageio.RGB
+	Scale float64
+
+	// held is the image retained in metrics.Textures, until Release
+	held atomic.Pointer[imageio.RGB]
+}
+
+// NewImage creates an image texture, with its values multiplied by scale.
+//
+// The image's pixels are counted in metrics.TextureCacheBytes, once however
+// many textures share the image, until every texture using it is released
+// (or garbage collected).
+func NewImage(img *imageio.RGB, scale float64) *Image {
+	metrics.Textures.Retain(img, int64(len(img.Pix))*8)
+	t := &Image{Image: img, Scale: scale}
+	t.held.Store(img)
+	runtime.SetFinalizer(t, (*Image).Release)
+	return t
+}
+
+// Release stops counting the texture's image in metrics.TextureCacheBytes,
+// once no other texture uses it. It's safe to call more than once.
+func (t *Image) Release() {
+	if img := t.held.Swap(nil); img != nil {
+		metrics.Textures.Release(img)
+	}
+}
+
+// Eval implements Texture.
+func (t *Image) Eval(tc Coords) *spectrum.Sampled {
+	return spectrum.FromRGB(t.RGB(tc))
+}
+
+// Value implements Scalar, with the image's luminance, so grayscale maps like
+// masks can be images too.
+func (t *Image) Value(tc Coords) float64 {
+	r, g, b := t.RGB(tc)
+	return 0.2126*r + 0.7152*g + 0.0722*b
+}
+
+// RGB returns the bilinearly filtered, scaled color at the coordinates, for
+// maps whose channels aren't colors (like normal maps).
+func (t *Image) RGB(tc Coords) (r, g, b float64) {
+	w, h := t.Image.Width, t.Image.Height
+
+	// pixel centers are at half-integer coordinates
+	x := tc.U*float64(w) - 0.5
+	y := (1-tc.V)*float64(h) - 0.5
+	x0, y0 := math.Floor(x), math.Floor(y)
+	dx, dy := x-x0, y-y0
+
+	for _, p := range [4]struct {
+		x, y   int
+		weight float64
+	}{
+		{int(x0), int(y0), (1 - dx) * (1 - dy)},
+		{int(x0) + 1, int(y0), dx * (1 - dy)},
+		{int(x0), int(y0) + 1, (1 - dx) * dy},
+		{int(x0) + 1, int(y0) + 1, dx * dy},
+	} {
+		pr, pg, pb := t.Image.At(wrap(p.x, w), wrap(p.y, h))
+		r += p.weight * pr
+		g += p.weight * pg
+		b += p.weight * pb
+	}
+	return t.Scale * r, t.Scale * g, t.Scale * b
+}
+
+// wrap returns i modulo n, in [0, n).
+func wrap(i, n int) int {
+	i %= n
+	if i < 0 {
+		i += n
+	}
+	return i
+}
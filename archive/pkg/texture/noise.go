@@ -0,0 +1,120 @@
+package texture
+
+import (
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/gmhorn/gremlin/archive/pkg/util"
+)
+
+// Noise is a solid texture of fractal Perlin noise, blending between the
+// textures A and B. It's looked up by position, not surface coordinates, so
+// it needs no UVs and doesn't stretch over a surface, which suits marble,
+// stains and other natural variation.
+//
+// Frequency scales the position before the lookup, so features are around
+// 1/Frequency across. Each of the Octaves doubles the frequency and halves
+// the amplitude of the one before, adding finer detail.
+//
+// https://www.pbr-book.org/3ed-2018/Texture/Noise
+type Noise struct {
+	A, B      Texture
+	Frequency float64
+	Octaves   int
+}
+
+// NewNoise creates a noise texture.
+func NewNoise(a, b Texture, frequency float64, octaves int) *Noise {
+	if octaves < 1 {
+		panic("noise needs at least one octave")
+	}
+	return &Noise{A: a, B: b, Frequency: frequency, Octaves: octaves}
+}
+
+// Eval implements Texture.
+func (n *Noise) Eval(tc Coords) *spectrum.Sampled {
+	return n.B.Eval(tc).Lerp(n.A.Eval(tc), n.Value(tc))
+}
+
+// Value implements Scalar. It's in [0, 1], and 0.5 on average.
+func (n *Noise) Value(tc Coords) float64 {
+	p := tc.P.Scale(n.Frequency)
+	sum, amplitude, norm := 0.0, 1.0, 0.0
+	for i := 0; i < n.Octaves; i++ {
+		sum += amplitude * perlin(p)
+		norm += amplitude
+		p = p.Scale(2)
+		amplitude /= 2
+	}
+	return math.Max(0, math.Min(1, 0.5+0.5*sum/norm))
+}
+
+// perm is a random permutation of 0-255, repeated so that lookups of
+// perm[i]+j don't need wrapping.
+var perm = func() [512]int {
+	var p [512]int
+	for i := 0; i < 256; i++ {
+		p[i] = i
+	}
+	rnd := util.NewRand(0)
+	rnd.Shuffle(256, func(i, j int) { p[i], p[j] = p[j], p[i] })
+	copy(p[256:], p[:256])
+	return p
+}()
+
+// perlin is Ken Perlin's improved gradient noise, in roughly [-1, 1] and 0 at
+// integer lattice points.
+//
+// https://mrl.cs.nyu.edu/~perlin/noise/
+func perlin(p geo.Vec) float64 {
+	fx, fy, fz := math.Floor(p.X), math.Floor(p.Y), math.Floor(p.Z)
+	x, y, z := p.X-fx, p.Y-fy, p.Z-fz
+	ix, iy, iz := int(fx)&255, int(fy)&255, int(fz)&255
+	u, v, w := fade(x), fade(y), fade(z)
+
+	a := perm[ix] + iy
+	aa, ab := perm[a]+iz, perm[a+1]+iz
+	b := perm[ix+1] + iy
+	ba, bb := perm[b]+iz, perm[b+1]+iz
+
+	return lerp(w,
+		lerp(v,
+			lerp(u, grad(perm[aa], x, y, z), grad(perm[ba], x-1, y, z)),
+			lerp(u, grad(perm[ab], x, y-1, z), grad(perm[bb], x-1, y-1, z))),
+		lerp(v,
+			lerp(u, grad(perm[aa+1], x, y, z-1), grad(perm[ba+1], x-1, y, z-1)),
+			lerp(u, grad(perm[ab+1], x, y-1, z-1), grad(perm[bb+1], x-1, y-1, z-1))))
+}
+
+// fade is the smootherstep curve 6t^5 - 15t^4 + 10t^3.
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func lerp(t, a, b float64) float64 {
+	return a + t*(b-a)
+}
+
+// grad is the dot product of (x, y, z) with one of 12 gradient directions, the
+// edges of a cube, chosen by the hash.
+func grad(hash int, x, y, z float64) float64 {
+	h := hash & 15
+	u := y
+	if h < 8 {
+		u = x
+	}
+	v := z
+	if h < 4 {
+		v = y
+	} else if h == 12 || h == 14 {
+		v = x
+	}
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+	return u + v
+}
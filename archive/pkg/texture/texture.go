@@ -0,0 +1,84 @@
+// Package texture has values that vary over surfaces: colors for materials
+// (Texture) and scalars like blend masks (Scalar).
+//
+// https://www.pbr-book.org/3ed-2018/Texture
+package texture
+
+import (
+	"math"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+)
+
+// Coords is where a texture is looked up: the surface coordinates (U, V) of a
+// shape interaction, and the point P in world space, for solid textures like
+// Noise.
+type Coords struct {
+	U, V float64
+	P    geo.Vec
+}
+
+// Texture is a spectrum that varies over a surface.
+type Texture interface {
+	Eval(tc Coords) *spectrum.Sampled
+}
+
+// Scalar is a value that varies over a surface, like a black and white
+// texture. Values are usually in the range [0, 1].
+type Scalar interface {
+	Value(tc Coords) float64
+}
+
+// ScalarFunc is a convenience typedef to make it easy to define a Scalar from
+// a function.
+type ScalarFunc func(tc Coords) float64
+
+// Value just calls the ScalarFunc itself.
+func (sf ScalarFunc) Value(tc Coords) float64 {
+	return sf(tc)
+}
+
+// Constant is the same spectrum everywhere.
+type Constant struct {
+	S *spectrum.Sampled
+}
+
+// NewConstant creates a constant texture.
+func NewConstant(dist spectrum.Distribution) *Constant {
+	return &Constant{S: spectrum.Sample(dist)}
+}
+
+// Eval implements Texture.
+func (c *Constant) Eval(tc Coords) *spectrum.Sampled {
+	return c.S
+}
+
+// Checkerboard alternates between the textures A and B in squares of the
+// surface coordinates, Frequency squares per unit of u and v.
+//
+// https://www.pbr-book.org/3ed-2018/Texture/Solid_and_Procedural_Texturing#Checkerboard
+type Checkerboard struct {
+	A, B      Texture
+	Frequency float64
+}
+
+// NewCheckerboard creates a checkerboard.
+func NewCheckerboard(a, b Texture, frequency float64) *Checkerboard {
+	return &Checkerboard{A: a, B: b, Frequency: frequency}
+}
+
+// Eval implements Texture.
+func (c *Checkerboard) Eval(tc Coords) *spectrum.Sampled {
+	if c.Value(tc) == 0 {
+		return c.A.Eval(tc)
+	}
+	return c.B.Eval(tc)
+}
+
+// Value implements Scalar. It's 0 on A's squares and 1 on B's, so a
+// checkerboard can also mask between two materials.
+func (c *Checkerboard) Value(tc Coords) float64 {
+	x := int(math.Floor(tc.U*c.Frequency)) + int(math.Floor(tc.V*c.Frequency))
+	return float64(x & 1)
+}
@@ -0,0 +1,102 @@
+package texture
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmhorn/gremlin/archive/pkg/geo"
+	"github.com/gmhorn/gremlin/archive/pkg/imageio"
+	"github.com/gmhorn/gremlin/archive/pkg/metrics"
+	"github.com/gmhorn/gremlin/archive/pkg/spectrum"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckerboard(t *testing.T) {
+	black := NewConstant(spectrum.Flat(0))
+	white := NewConstant(spectrum.Flat(1))
+	c := NewCheckerboard(black, white, 2)
+
+	tests := []struct {
+		u, v     float64
+		expected float64
+	}{
+		{0.1, 0.1, 0},
+		{0.6, 0.1, 1},
+		{0.6, 0.6, 0},
+		{-0.1, 0.1, 1},
+		{-0.1, -0.1, 0},
+	}
+	for _, test := range tests {
+		tc := Coords{U: test.u, V: test.v}
+		assert.Equal(t, test.expected, c.Value(tc), "(%v, %v)", test.u, test.v)
+		assert.Equal(t, test.expected, c.Eval(tc)[0], "(%v, %v)", test.u, test.v)
+	}
+}
+
+func TestImage(t *testing.T) {
+	// a 2x2 image: black, white on top; red, black below
+	img := imageio.NewRGB(2, 2)
+	img.Set(1, 0, 1, 1, 1)
+	img.Set(0, 1, 1, 0, 0)
+	tex := NewImage(img, 2)
+	defer tex.Release()
+
+	// pixel centers are exact
+	assert.InDelta(t, 2, tex.Value(Coords{U: 0.75, V: 0.75}), 1e-12)
+	assert.InDelta(t, 2*0.2126, tex.Value(Coords{U: 0.25, V: 0.25}), 1e-12)
+	assert.InDelta(t, 0, tex.Value(Coords{U: 0.25, V: 0.75}), 1e-12)
+
+	// halfway between black and white
+	assert.InDelta(t, 1, tex.Value(Coords{U: 0.5, V: 0.75}), 1e-12)
+
+	// it repeats, so the left edge blends with the right
+	assert.InDelta(t, 1, tex.Value(Coords{U: 0, V: 0.75}), 1e-12)
+	assert.InDelta(t, tex.Value(Coords{U: 0.3, V: 0.6}), tex.Value(Coords{U: 2.3, V: -0.4}), 1e-12)
+
+	// red comes out red
+	red := tex.Eval(Coords{U: 0.25, V: 0.25})
+	assert.Greater(t, red[len(red)-1], red[0])
+}
+
+func TestImage_CacheBytes(t *testing.T) {
+	img := imageio.NewRGB(2, 2)
+	before := metrics.TextureCacheBytes.Get()
+
+	// textures sharing an image count it once
+	a, b := NewImage(img, 1), NewImage(img, 2)
+	assert.Equal(t, int64(2*2*3*8), metrics.TextureCacheBytes.Get()-before)
+
+	// until they've all been released
+	a.Release()
+	a.Release()
+	assert.Equal(t, int64(2*2*3*8), metrics.TextureCacheBytes.Get()-before)
+	b.Release()
+	assert.Equal(t, int64(0), metrics.TextureCacheBytes.Get()-before)
+}
+
+func TestNoise(t *testing.T) {
+	black := NewConstant(spectrum.Flat(0))
+	white := NewConstant(spectrum.Flat(1))
+	n := NewNoise(black, white, 3, 4)
+
+	// 0.5 (no noise) at lattice points of every octave
+	assert.InDelta(t, 0.5, n.Value(Coords{P: geo.V(1, 2, 3)}), 1e-12)
+
+	min, max, sum := 1.0, 0.0, 0.0
+	for i := 0; i < 1000; i++ {
+		p := geo.V(float64(i)*0.0137, float64(i%37)*0.071, float64(i%11)*0.19)
+		v := n.Value(Coords{P: p})
+		assert.InDelta(t, v, n.Eval(Coords{P: p})[0], 1e-12)
+		min, max, sum = math.Min(min, v), math.Max(max, v), sum+v
+	}
+	assert.GreaterOrEqual(t, min, 0.0)
+	assert.LessOrEqual(t, max, 1.0)
+	assert.Greater(t, max-min, 0.3)
+	assert.InDelta(t, 0.5, sum/1000, 0.1)
+
+	// it's continuous
+	p := geo.V(0.3, 0.4, 0.5)
+	assert.InDelta(t, n.Value(Coords{P: p}), n.Value(Coords{P: p.Plus(geo.V(1e-6, 0, 0))}), 1e-4)
+
+	assert.Panics(t, func() { NewNoise(black, white, 1, 0) })
+}
@@ -0,0 +1,71 @@
+package util
+
+import "math"
+
+// MachineEpsilon is the bound on relative error from rounding a real number to
+// the nearest float64; half the gap between 1 and the next float64.
+//
+// https://www.pbr-book.org/3ed-2018/Shapes/Managing_Rounding_Error#FloatingPointArithmetic
+const MachineEpsilon = 0x1p-53
+
+// NextFloatUp returns the smallest float64 greater than v. Infinities and NaN
+// are returned unchanged.
+func NextFloatUp(v float64) float64 {
+	if math.IsInf(v, 1) || math.IsNaN(v) {
+		return v
+	}
+	return math.Nextafter(v, math.Inf(1))
+}
+
+// NextFloatDown returns the largest float64 less than v. Infinities and NaN
+// are returned unchanged.
+func NextFloatDown(v float64) float64 {
+	if math.IsInf(v, -1) || math.IsNaN(v) {
+		return v
+	}
+	return math.Nextafter(v, math.Inf(-1))
+}
+
+// ULPDiff returns the number of representable float64 values between a and b
+// (their distance in "units in the last place"). Positive and negative zero
+// are 0 apart. Returns math.MaxUint64 if either value is NaN.
+func ULPDiff(a, b float64) uint64 {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.MaxUint64
+	}
+
+	ia, ib := orderedBits(a), orderedBits(b)
+	if ia > ib {
+		return uint64(ia - ib)
+	}
+	return uint64(ib - ia)
+}
+
+// AlmostEqualULPs returns true if a and b are within maxULPs representable
+// values of each other. Unlike comparing against a fixed epsilon, this scales
+// with the magnitude of the values.
+func AlmostEqualULPs(a, b float64, maxULPs uint64) bool {
+	return ULPDiff(a, b) <= maxULPs
+}
+
+// Gamma returns the conservative bound on the relative error accumulated by n
+// successive floating-point operations:
+//
+//	γn = nε / (1 - nε)
+//
+// https://www.pbr-book.org/3ed-2018/Shapes/Managing_Rounding_Error#ErrorPropagation
+func Gamma(n int) float64 {
+	ne := float64(n) * MachineEpsilon
+	return ne / (1 - ne)
+}
+
+// orderedBits maps a float64 to an int64 such that the ordering of the ints
+// matches the ordering of the floats, and adjacent floats map to adjacent ints.
+func orderedBits(v float64) int64 {
+	bits := int64(math.Float64bits(v))
+	if bits < 0 {
+		// negative floats are sign-magnitude; flip them to two's complement
+		bits = math.MinInt64 - bits
+	}
+	return bits
+}
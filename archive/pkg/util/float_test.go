@@ -0,0 +1,51 @@
+package util
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextFloatUpDown(t *testing.T) {
+	assert.Greater(t, NextFloatUp(1.0), 1.0)
+	assert.Less(t, NextFloatDown(1.0), 1.0)
+	assert.Equal(t, 1.0, NextFloatDown(NextFloatUp(1.0)))
+	assert.Greater(t, NextFloatUp(math.Copysign(0, -1)), 0.0)
+	assert.True(t, math.IsInf(NextFloatUp(math.Inf(1)), 1))
+}
+
+func TestULPDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     float64
+		expected uint64
+	}{
+		{"equal", 1.0, 1.0, 0},
+		{"adjacent", 1.0, NextFloatUp(1.0), 1},
+		{"two apart", NextFloatDown(1.0), NextFloatUp(1.0), 2},
+		{"signed zeros", 0, math.Copysign(0, -1), 0},
+		{"across zero", NextFloatDown(0), NextFloatUp(0), 2},
+		{"NaN", math.NaN(), 1.0, math.MaxUint64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ULPDiff(tt.a, tt.b))
+			assert.Equal(t, tt.expected, ULPDiff(tt.b, tt.a))
+		})
+	}
+}
+
+func TestAlmostEqualULPs(t *testing.T) {
+	// use variables, otherwise Go evaluates 0.1+0.2 exactly at compile time
+	a, b := 0.1, 0.2
+	assert.True(t, AlmostEqualULPs(a+b, 0.3, 1))
+	assert.False(t, AlmostEqualULPs(a+b, 0.3, 0))
+}
+
+func TestGamma(t *testing.T) {
+	assert.Equal(t, 0.0, Gamma(0))
+	assert.InEpsilon(t, 3*MachineEpsilon, Gamma(3), 1e-10)
+	assert.Greater(t, Gamma(3), 3*MachineEpsilon)
+}
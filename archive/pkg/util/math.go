@@ -1,6 +1,9 @@
 package util
 
-import "math"
+import (
+	"math"
+	"sort"
+)
 
 // SolveQuadratic finds the real roots, if they exist, of the quadratic
 // equation
@@ -18,7 +21,8 @@ func SolveQuadratic(a, b, c float64) (float64, float64, bool) {
 		return -b / (2 * a), -b / (2 * a), true
 	}
 
-	q := -0.5 * (b + Sign(b)*math.Sqrt(disc))
+	// Copysign rather than Sign, so b == 0 doesn't give q == 0
+	q := -0.5 * (b + math.Copysign(math.Sqrt(disc), b))
 	r0, r1 := q/a, c/q
 
 	if r1 < r0 {
@@ -28,6 +32,162 @@ func SolveQuadratic(a, b, c float64) (float64, float64, bool) {
 	return r0, r1, true
 }
 
+// SolveCubic finds the real roots of the cubic equation
+//
+//	ax^3 + bx^2 + cx + d
+//
+// Roots are returned in ascending order, with repeated roots returned once per
+// multiplicity (like SolveQuadratic). If a is 0 this falls back to
+// SolveQuadratic, and if b is 0 as well, to the linear equation's one root.
+//
+// Uses the trigonometric method when there are three real roots and Cardano's
+// formula otherwise, in the numerically careful form from Numerical Recipes
+// (5.6), followed by a couple of Newton iterations to polish the roots.
+func SolveCubic(a, b, c, d float64) []float64 {
+	if a == 0 && b == 0 {
+		if c == 0 {
+			return nil
+		}
+		return []float64{-d / c}
+	}
+	if a == 0 {
+		r0, r1, found := SolveQuadratic(b, c, d)
+		if !found {
+			return nil
+		}
+		return []float64{r0, r1}
+	}
+
+	// normalize to x^3 + a x^2 + b x + c
+	a, b, c = b/a, c/a, d/a
+
+	q := (a*a - 3*b) / 9
+	r := (2*a*a*a - 9*a*b + 27*c) / 54
+	q3 := q * q * q
+
+	var roots []float64
+	if r*r < q3 {
+		theta := math.Acos(r / math.Sqrt(q3))
+		sq := -2 * math.Sqrt(q)
+		roots = []float64{
+			sq*math.Cos(theta/3) - a/3,
+			sq*math.Cos((theta+2*math.Pi)/3) - a/3,
+			sq*math.Cos((theta-2*math.Pi)/3) - a/3,
+		}
+	} else {
+		disc := r*r - q3
+		A := -Sign(r) * math.Cbrt(math.Abs(r)+math.Sqrt(disc))
+		B := 0.0
+		if A != 0 {
+			B = q / A
+		}
+		roots = []float64{A + B - a/3}
+		// if the discriminant (nearly) vanishes, the complex pair collapses
+		// into a double real root
+		if disc <= 1e-10*q3 {
+			root := -(A+B)/2 - a/3
+			roots = append(roots, root, root)
+		}
+	}
+
+	for i := range roots {
+		roots[i] = polish(roots[i], 1, a, b, c)
+	}
+	sort.Float64s(roots)
+	return roots
+}
+
+// SolveQuartic finds the real roots of the quartic equation
+//
+//	ax^4 + bx^3 + cx^2 + dx + e
+//
+// Roots are returned in ascending order. Repeated roots may be returned more
+// than once. If a is 0 this falls back to SolveCubic.
+//
+// Uses Ferrari's method: the quartic is depressed, then factored into two
+// quadratics using a root of the resolvent cubic. Roots are polished with
+// Newton iterations against the original polynomial.
+//
+// https://en.wikipedia.org/wiki/Quartic_function#Ferrari's_solution
+func SolveQuartic(a, b, c, d, e float64) []float64 {
+	if a == 0 {
+		return SolveCubic(b, c, d, e)
+	}
+
+	// normalize to x^4 + a x^3 + b x^2 + c x + d
+	a, b, c, d = b/a, c/a, d/a, e/a
+
+	// depress with x = y - a/4 to get y^4 + p y^2 + q y + r
+	aa := a * a
+	p := b - 3*aa/8
+	q := c - a*b/2 + aa*a/8
+	r := d - a*c/4 + aa*b/16 - 3*aa*aa/256
+
+	var ys []float64
+	if math.Abs(q) < 1e-14 {
+		// biquadratic: z^2 + p z + r with z = y^2
+		z0, z1, found := SolveQuadratic(1, p, r)
+		if found {
+			for _, z := range [2]float64{z0, z1} {
+				if z >= 0 {
+					sz := math.Sqrt(z)
+					ys = append(ys, -sz, sz)
+				}
+			}
+		}
+	} else {
+		// the resolvent cubic always has a positive root when q != 0
+		m := 0.0
+		for _, root := range SolveCubic(1, p, p*p/4-r, -q*q/8) {
+			m = math.Max(m, root)
+		}
+		if m <= 0 {
+			return nil
+		}
+
+		s := math.Sqrt(2 * m)
+		if y0, y1, found := SolveQuadratic(1, -s, p/2+m+q/(2*s)); found {
+			ys = append(ys, y0, y1)
+		}
+		if y0, y1, found := SolveQuadratic(1, s, p/2+m-q/(2*s)); found {
+			ys = append(ys, y0, y1)
+		}
+	}
+
+	roots := make([]float64, len(ys))
+	for i, y := range ys {
+		roots[i] = polish(y-a/4, 1, a, b, c, d)
+	}
+	sort.Float64s(roots)
+	return roots
+}
+
+// polish refines the root x of the polynomial with the given coefficients
+// (highest degree first) with a few Newton-Raphson iterations. If an iteration
+// makes things worse, the previous value is kept.
+func polish(x float64, coeffs ...float64) float64 {
+	eval := func(x float64) (f, df float64) {
+		for _, k := range coeffs {
+			df = df*x + f
+			f = f*x + k
+		}
+		return
+	}
+
+	for i := 0; i < 4; i++ {
+		f, df := eval(x)
+		if f == 0 || df == 0 {
+			break
+		}
+		next := x - f/df
+		if fNext, _ := eval(next); math.Abs(fNext) >= math.Abs(f) {
+			break
+		}
+		x = next
+	}
+	return x
+}
+
 // Sign returns -1.0 if n is negative, 1.0 if n is positive, and 0 if n is
 // identically equal to 0
 func Sign(n float64) float64 {
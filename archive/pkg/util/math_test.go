@@ -21,12 +21,112 @@ func TestSolveQuadratic(t *testing.T) {
 		assert.Equal(t, r2, 2.0)
 	})
 
+	t.Run("no linear term", func(t *testing.T) {
+		r1, r2, result := SolveQuadratic(1, 0, -4)
+		assert.True(t, result)
+		assert.Equal(t, r1, -2.0)
+		assert.Equal(t, r2, 2.0)
+	})
+
+	t.Run("cancellation", func(t *testing.T) {
+		// (x - 1e-8)(x - 1e8): the textbook formula loses the small root to
+		// cancellation between -b and the square root
+		r1, r2, result := SolveQuadratic(1, -(1e8 + 1e-8), 1)
+		assert.True(t, result)
+		assert.InEpsilon(t, 1e-8, r1, 1e-12)
+		assert.InEpsilon(t, 1e8, r2, 1e-12)
+	})
+
 	t.Run("no real roots", func(t *testing.T) {
 		_, _, result := SolveQuadratic(1, 0, 1)
 		assert.False(t, result)
 	})
 }
 
+func TestSolveCubic(t *testing.T) {
+	tests := []struct {
+		name       string
+		a, b, c, d float64
+		expected   []float64
+	}{{
+		"three real roots",
+		1, -6, 11, -6, // (x-1)(x-2)(x-3)
+		[]float64{1, 2, 3},
+	}, {
+		"one real root",
+		2, 0, 2, -4, // 2(x-1)(x^2+x+2)
+		[]float64{1},
+	}, {
+		"double root",
+		1, -4, 5, -2, // (x-1)^2(x-2)
+		[]float64{1, 1, 2},
+	}, {
+		"degenerate quadratic",
+		0, 1, -4, 3,
+		[]float64{1, 3},
+	}, {
+		"degenerate linear",
+		0, 0, 2, -4,
+		[]float64{2},
+	}, {
+		"degenerate constant",
+		0, 0, 0, 1,
+		[]float64{},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertRootsEqual(t, tt.expected, SolveCubic(tt.a, tt.b, tt.c, tt.d))
+		})
+	}
+}
+
+func TestSolveQuartic(t *testing.T) {
+	tests := []struct {
+		name          string
+		a, b, c, d, e float64
+		expected      []float64
+	}{{
+		"four real roots",
+		1, -2, -13, 14, 24, // (x+1)(x-2)(x+3)(x-4)
+		[]float64{-3, -1, 2, 4},
+	}, {
+		"root at zero",
+		2, 0, -14, 12, 0, // 2x(x-1)(x-2)(x+3)
+		[]float64{-3, 0, 1, 2},
+	}, {
+		"biquadratic",
+		1, 0, -5, 0, 4, // (x^2-1)(x^2-4)
+		[]float64{-2, -1, 1, 2},
+	}, {
+		"biquadratic with no square term",
+		1, 0, 0, 0, -1, // (x^2-1)(x^2+1)
+		[]float64{-1, 1},
+	}, {
+		"two real roots",
+		1, -3, 3, -3, 2, // (x-1)(x-2)(x^2+1)
+		[]float64{1, 2},
+	}, {
+		"no real roots",
+		1, 0, 2, 0, 5,
+		[]float64{},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertRootsEqual(t, tt.expected, SolveQuartic(tt.a, tt.b, tt.c, tt.d, tt.e))
+		})
+	}
+}
+
+func assertRootsEqual(t *testing.T, expected, actual []float64) {
+	if assert.Len(t, actual, len(expected)) {
+		for i := range expected {
+			assert.InDelta(t, expected[i], actual[i], 1e-6)
+		}
+	}
+}
+
 func TestPartition(t *testing.T) {
 	tests := []struct {
 		name        string
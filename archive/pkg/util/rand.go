@@ -0,0 +1,52 @@
+package util
+
+import "math/rand"
+
+// SplitMix64 is a small, fast PRNG that implements rand.Source64. Its state is
+// a single uint64, so seeding it from a hash of (seed, tile, pixel, sample...)
+// gives cheap, statistically independent streams. This makes renders
+// reproducible no matter how work is scheduled across goroutines.
+//
+// https://prng.di.unimi.it/splitmix64.c
+type SplitMix64 uint64
+
+// Uint64 returns the next pseudo-random value.
+func (s *SplitMix64) Uint64() uint64 {
+	*s += 0x9e3779b97f4a7c15
+	return mix64(uint64(*s))
+}
+
+// Int63 returns the next pseudo-random value as a non-negative int64.
+func (s *SplitMix64) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// Seed resets the state to the given seed.
+func (s *SplitMix64) Seed(seed int64) {
+	*s = SplitMix64(seed)
+}
+
+// Hash deterministically combines the given keys into a single well-mixed
+// value, suitable for seeding.
+func Hash(keys ...uint64) uint64 {
+	h := uint64(0)
+	for _, k := range keys {
+		h = mix64(h ^ mix64(k+0x9e3779b97f4a7c15))
+	}
+	return h
+}
+
+// NewRand returns a new *rand.Rand whose stream is determined entirely by the
+// seed and keys. Typically keys identify a unit of work (e.g. tile offset,
+// or pixel index and sample number).
+func NewRand(seed uint64, keys ...uint64) *rand.Rand {
+	src := SplitMix64(Hash(append([]uint64{seed}, keys...)...))
+	return rand.New(&src)
+}
+
+// mix64 is the SplitMix64 finalizer (a variant of MurmurHash3's fmix64).
+func mix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
@@ -0,0 +1,25 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitMix64(t *testing.T) {
+	// Reference values from https://prng.di.unimi.it/splitmix64.c seeded with 0
+	s := SplitMix64(0)
+	assert.Equal(t, uint64(0xe220a8397b1dcdaf), s.Uint64())
+	assert.Equal(t, uint64(0x6e789e6aa1b965f4), s.Uint64())
+	assert.Equal(t, uint64(0x06c45d188009454f), s.Uint64())
+}
+
+func TestNewRand(t *testing.T) {
+	a := NewRand(42, 1, 2)
+	b := NewRand(42, 1, 2)
+	c := NewRand(42, 2, 1)
+
+	x, y, z := a.Float64(), b.Float64(), c.Float64()
+	assert.Equal(t, x, y)
+	assert.NotEqual(t, x, z)
+}